@@ -4,56 +4,277 @@ import (
 	"context"
 	"fmt"
 	"github.com/gorilla/mux"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"tender_service/internal/config"
 	"tender_service/internal/database"
 	"tender_service/internal/handles"
+	"tender_service/internal/logger"
+	"tender_service/internal/metrics"
+	"tender_service/internal/middleware"
+	"tender_service/internal/ratelimit"
+	"tender_service/internal/scheduler"
+	"tender_service/internal/sealing"
 	"tender_service/internal/service"
+	"tender_service/internal/tracing"
+	"time"
 )
 
 func main() {
-	dbhost := os.Getenv("POSTGRES_HOST")
-	dbname := os.Getenv("POSTGRES_DATABASE")
-	dbport := os.Getenv("POSTGRES_PORT")
-	dbusername := os.Getenv("POSTGRES_USERNAME")
-	dbpassword := os.Getenv("POSTGRES_PASSWORD")
-	server_addres := os.Getenv("SERVER_ADDRESS")
-	conn_str := fmt.Sprintf("dbname=%s user=%s password=%s host=%s port=%s sslmode=verify-full sslrootcert=./root.crt",
-		dbname,
-		dbusername,
-		dbpassword,
-		dbhost,
-		dbport,
-	)
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	slog.SetDefault(logger.New(os.Stdout, cfg.LogLevel))
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Features.TracesExporter)
+	if err != nil {
+		slog.Error("tracing setup failed", "err", err)
+		os.Exit(1)
+	}
+	defer shutdownTracing(context.Background())
+
+	conn_str, err := cfg.Postgres.DSN()
+	if err != nil {
+		slog.Error("invalid database config", "err", err)
+		os.Exit(1)
+	}
 	db, err := database.New(conn_str)
 	if err != nil {
-		log.Fatal(err)
+		slog.Error("database connection failed", "err", err)
+		os.Exit(1)
 	}
-	ctx := context.Background()
+	db.SetMaxOpenConns(cfg.Postgres.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Postgres.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.Postgres.ConnMaxLifetime)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
 	storage := database.NewService(db)
 	srv := service.New(storage)
-	handle := handles.New(ctx, srv)
+	if cfg.Sealing.Key != "" {
+		key, err := sealing.ParseKey(cfg.Sealing.Key)
+		if err != nil {
+			slog.Error("sealing key is invalid", "err", err)
+			os.Exit(1)
+		}
+		sealer, err := sealing.New(key)
+		if err != nil {
+			slog.Error("sealing setup failed", "err", err)
+			os.Exit(1)
+		}
+		srv.UseSealer(sealer)
+	}
+	sched := scheduler.New()
+	if cfg.Features.AnalyticsViews {
+		srv.UseAnalyticsViews()
+		sched.Add(scheduler.Job{
+			Name:     "analytics_refresh",
+			Interval: cfg.Features.AnalyticsRefresh,
+			Run:      srv.RefreshAnalytics,
+		})
+	}
+	proxies, err := cfg.Server.Proxies()
+	if err != nil {
+		slog.Error("invalid trusted proxies", "err", err)
+		os.Exit(1)
+	}
+	handle := handles.New(srv, sched)
 	router := mux.NewRouter()
+	router.Use(middleware.RequestID, middleware.ClientIP(proxies), middleware.Tracing, middleware.AccessLog)
+	if cfg.Features.Metrics {
+		router.Use(middleware.Metrics)
+		metrics.RegisterDBStats(metrics.Default, db)
+		router.Handle("/metrics", metrics.Default.Handler()).Methods("GET")
+	}
+	router.Use(middleware.MaxBody(cfg.Server.MaxBodyBytes))
+	if cfg.RateLimit.Enabled {
+		policy, err := cfg.RateLimit.Policy()
+		if err != nil {
+			slog.Error("invalid rate limit config", "err", err)
+			os.Exit(1)
+		}
+		limiter := ratelimit.NewMemoryStore()
+		router.Use(middleware.RateLimit(limiter, policy, srv.UserOrganizationID))
+		sched.Add(scheduler.Job{
+			Name:     "ratelimit_cleanup",
+			Interval: time.Minute,
+			Run: func(ctx context.Context) error {
+				limiter.Cleanup(time.Now(), time.Hour)
+				return nil
+			},
+		})
+	}
+	if cfg.Idempotency.Enabled {
+		router.Use(middleware.Idempotency(database.NewIdempotencyStore(storage), cfg.Idempotency.TTL))
+		sched.Add(scheduler.Job{
+			Name:     "idempotency_cleanup",
+			Interval: 10 * time.Minute,
+			Run: func(ctx context.Context) error {
+				_, err := storage.DeleteExpiredIdempotencyKeys(ctx, time.Now())
+				return err
+			},
+		})
+	}
 
+	sched.Add(scheduler.Job{
+		Name:     "auction_close",
+		Interval: 30 * time.Second,
+		Run: func(ctx context.Context) error {
+			_, err := srv.CloseEndedAuctions(ctx)
+			return err
+		},
+	})
+
+	router.HandleFunc("/healthz", handle.Healthz).Methods("GET")
+	router.HandleFunc("/readyz", handle.Readyz).Methods("GET")
 	router.HandleFunc("/api/ping", handle.Ping).Methods("GET")
 	router.HandleFunc("/api/tenders", handle.TenderList)
 	router.HandleFunc("/api/tenders/new", handle.NewTender)
+	router.HandleFunc("/api/tenders/import", handle.ImportTenders).Methods("POST")
+	router.HandleFunc("/api/tenders/from-template/{id}", handle.TenderFromTemplate).Methods("POST")
 	router.HandleFunc("/api/tenders/my", handle.TenderMyList)
+	router.HandleFunc("/api/tenders/my/export", handle.TenderMyExport).Methods("GET")
 	router.HandleFunc("/api/tenders/{id}/status", handle.GetTenderStatus).Methods("GET")
 	router.HandleFunc("/api/tenders/{id}/status", handle.ChangeTenderStatus).Methods("PUT")
 	router.HandleFunc("/api/tenders/{id}/edit", handle.ChangeTender).Methods("PATCH")
 	router.HandleFunc("/api/tenders/{id}/rollback/{version}", handle.RollbackTender).Methods("PUT")
+	router.HandleFunc("/api/tenders/{id}/clone", handle.CloneTender).Methods("POST")
+	router.HandleFunc("/api/tenders/{id}/amendments", handle.AmendmentList).Methods("GET")
+	router.HandleFunc("/api/tenders/{id}/open-bids", handle.OpenBids).Methods("POST")
+	router.HandleFunc("/api/tenders/{id}/bid-opening", handle.BidOpening).Methods("GET")
+	router.HandleFunc("/api/tenders/{id}/auction", handle.AuctionGet).Methods("GET")
+	router.HandleFunc("/api/tenders/{id}/auction", handle.AuctionSet).Methods("PUT")
+	router.HandleFunc("/api/tenders/{id}/auction/bids", handle.AuctionBid).Methods("POST")
+	router.HandleFunc("/api/tenders/{id}/lots", handle.LotList).Methods("GET")
+	router.HandleFunc("/api/tenders/{id}/lots", handle.LotAdd).Methods("POST")
+	router.HandleFunc("/api/tenders/{id}/lots/{lotId}/cancel", handle.LotCancel).Methods("PUT")
+	router.HandleFunc("/api/tenders/{id}/questions", handle.QuestionList).Methods("GET")
+	router.HandleFunc("/api/tenders/{id}/questions/new", handle.QuestionNew).Methods("POST")
+	router.HandleFunc("/api/tenders/{id}/questions/{questionId}/answer", handle.QuestionAnswer).Methods("PUT")
+	router.HandleFunc("/api/service-types", handle.ServiceTypeList).Methods("GET")
+	router.HandleFunc("/api/service-types/new", handle.ServiceTypeNew).Methods("POST")
+	router.HandleFunc("/api/service-types/{code}", handle.ServiceTypeGet).Methods("GET")
+	router.HandleFunc("/api/service-types/{code}/edit", handle.ServiceTypeEdit).Methods("PATCH")
+	router.HandleFunc("/api/templates", handle.TemplateList).Methods("GET")
+	router.HandleFunc("/api/templates/new", handle.TemplateNew).Methods("POST")
+	router.HandleFunc("/api/templates/{id}", handle.TemplateGet).Methods("GET")
+	router.HandleFunc("/api/templates/{id}", handle.TemplateDelete).Methods("DELETE")
+	router.HandleFunc("/api/templates/{id}/edit", handle.TemplateEdit).Methods("PATCH")
 	router.HandleFunc("/api/bids/new", handle.BidNew).Methods("POST")
 	router.HandleFunc("/api/bids/{tenderID}/list", handle.BidsTender).Methods("GET")
+	router.HandleFunc("/api/bids/{tenderID}/export", handle.BidsTenderExport).Methods("GET")
+	router.HandleFunc("/api/bids/{tenderID}/approvals/export", handle.ApprovalsExport).Methods("GET")
 	router.HandleFunc("/api/bids/my", handle.MyBids).Methods("GET")
 	router.HandleFunc("/api/bids/{bidid}/status", handle.BidStatus).Methods("GET")
 	router.HandleFunc("/api/bids/{bidid}/status", handle.BidStatus).Methods("PUT")
 	router.HandleFunc("/api/bids/{bidid}/edit", handle.ChangeBid).Methods("PATCH")
 	router.HandleFunc("/api/bids/{bidid}/rollback/{version}", handle.RollbackBid).Methods("PUT")
+	router.HandleFunc("/api/bids/{bidid}/confirm", handle.BidConfirm).Methods("PUT")
 	router.HandleFunc("/api/bids/{bidid}/submit_decision", handle.Submit_Decision).Methods("PUT")
 	router.HandleFunc("/api/bids/{bidid}/feedback", handle.Feedback).Methods("PUT")
 	router.HandleFunc("/api/bids/{tenderid}/feedback", handle.Reviews).Methods("GET")
-	fmt.Println("Сервер запущен на ", server_addres)
-	log.Fatal(http.ListenAndServe(server_addres, router))
+	router.HandleFunc("/api/reviews/received", handle.ReviewReceivedList).Methods("GET")
+	router.HandleFunc("/api/reviews/disputes", handle.ReviewDisputeList).Methods("GET")
+	router.HandleFunc("/api/reviews/{id}", handle.ReviewDelete).Methods("DELETE")
+	router.HandleFunc("/api/reviews/{id}/reply", handle.ReviewReply).Methods("POST")
+	router.HandleFunc("/api/reviews/{id}/dispute", handle.ReviewDispute).Methods("PUT")
+	router.HandleFunc("/api/reviews/{id}/moderate", handle.ReviewModerate).Methods("PUT")
+	router.HandleFunc("/api/reviews/{id}/edit", handle.ReviewEdit).Methods("PATCH")
+	router.HandleFunc("/api/notifications", handle.NotificationList).Methods("GET")
+	router.HandleFunc("/api/notifications/{id}/read", handle.NotificationRead).Methods("PUT")
+	router.HandleFunc("/api/tenders/{id}/invitations", handle.InvitationTenderList).Methods("GET")
+	router.HandleFunc("/api/tenders/{id}/invitations", handle.InvitationNew).Methods("POST")
+	router.HandleFunc("/api/invitations", handle.InvitationMyList).Methods("GET")
+	router.HandleFunc("/api/invitations/{id}/respond", handle.InvitationRespond).Methods("PUT")
+	router.HandleFunc("/api/qualifications", handle.QualificationList).Methods("GET")
+	router.HandleFunc("/api/qualifications", handle.QualificationApply).Methods("POST")
+	router.HandleFunc("/api/qualifications/{id}/decision", handle.QualificationDecide).Methods("PUT")
+	router.HandleFunc("/api/qualifications/{id}/revoke", handle.QualificationRevoke).Methods("PUT")
+	router.HandleFunc("/api/organizations/{id}/vendors", handle.VendorList).Methods("GET")
+	router.HandleFunc("/api/organizations/{id}/reputation", handle.Reputation).Methods("GET")
+	router.HandleFunc("/api/organizations/{id}/conflict-policy", handle.ConflictPolicyGet).Methods("GET")
+	router.HandleFunc("/api/organizations/{id}/conflict-policy", handle.ConflictPolicySet).Methods("PUT")
+	router.HandleFunc("/api/me", handle.Me).Methods("GET")
+	router.HandleFunc("/api/me", handle.ChangeMe).Methods("PATCH")
+	router.HandleFunc("/api/employees", handle.EmployeeList).Methods("GET")
+	router.HandleFunc("/api/employees/new", handle.EmployeeNew).Methods("POST")
+	router.HandleFunc("/api/employees/{id}/deactivate", handle.EmployeeDeactivate).Methods("PUT")
+	router.HandleFunc("/api/audit", handle.AuditList).Methods("GET")
+	router.HandleFunc("/api/audit/verify", handle.AuditVerify).Methods("GET")
+	router.HandleFunc("/api/analytics/tenders", handle.AnalyticsTenders).Methods("GET")
+	router.HandleFunc("/api/analytics/bids", handle.AnalyticsBids).Methods("GET")
+	router.HandleFunc("/api/analytics/award-time", handle.AnalyticsAwardTime).Methods("GET")
+	router.HandleFunc("/api/analytics/approvals", handle.AnalyticsApprovals).Methods("GET")
+	router.HandleFunc("/api/analytics/savings", handle.AnalyticsSavings).Methods("GET")
+
+	server := &http.Server{
+		Addr:         cfg.Server.Address,
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+
+	sched.Start(ctx)
+	errCh := make(chan error, 1)
+	go func() {
+		slog.Info("Сервер запущен", "addr", cfg.Server.Address)
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		if err != nil && err != http.ErrServerClosed {
+			slog.Error("server stopped", "err", err)
+		}
+	case <-ctx.Done():
+		slog.Info("shutdown signal received, draining connections")
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("graceful shutdown failed", "err", err)
+	}
+	sched.Stop()
+	if err := db.Close(); err != nil {
+		slog.Error("database close failed", "err", err)
+	}
+	slog.Info("Сервер остановлен")
+}
+
+// runCommand выполняет служебные подкоманды вместо запуска сервера
+func runCommand(args []string) int {
+	if len(args) == 2 && args[0] == "config" && args[1] == "print" {
+		// настройки печатаются до проверки, чтобы было видно, что в них не так
+		cfg, err := config.Read()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		out, err := cfg.Print()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Print(out)
+		if err := cfg.Validate(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprintln(os.Stderr, "usage: main [config print]")
+	return 2
 }
@@ -0,0 +1,379 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// envNames - переменные, которые читает Load в этих тестах. Перед каждым
+// тестом они снимаются, чтобы окружение машины не влияло на результат.
+var envNames = []string{
+	"CONFIG_FILE", "SERVER_ADDRESS", "LOG_LEVEL", "POSTGRES_CONN", "POSTGRES_HOST",
+	"POSTGRES_DATABASE", "POSTGRES_USERNAME", "POSTGRES_PASSWORD", "POSTGRES_JDBC_URL",
+	"IDEMPOTENCY_TTL", "SERVER_TRUSTED_PROXIES", "SEALED_BID_KEY",
+}
+
+// unsetenv снимает переменную на время теста. t.Setenv запоминает прежнее
+// значение и вернет его, в том числе если переменную выставит godotenv.
+func unsetenv(t *testing.T, name string) {
+	t.Setenv(name, "")
+	os.Unsetenv(name)
+}
+
+// chdir переходит в dir на время теста: Load ищет .env в рабочей директории
+func chdir(t *testing.T, dir string) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func writeFile(t *testing.T, path, content string) {
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	const yamlConfig = `server:
+  address: "127.0.0.1:7000"
+postgres:
+  conn: "postgres://yaml@db/tender"
+log_level: debug
+`
+	tests := []struct {
+		name        string
+		yaml        bool
+		dotenv      string
+		env         map[string]string
+		wantAddress string
+		wantLevel   string
+		wantConn    string
+	}{
+		{
+			name:        "defaults",
+			env:         map[string]string{"POSTGRES_CONN": "postgres://env@db/tender"},
+			wantAddress: "0.0.0.0:8080",
+			wantLevel:   "info",
+			wantConn:    "postgres://env@db/tender",
+		},
+		{
+			name:        "yaml over defaults",
+			yaml:        true,
+			wantAddress: "127.0.0.1:7000",
+			wantLevel:   "debug",
+			wantConn:    "postgres://yaml@db/tender",
+		},
+		{
+			name:        "dotenv over yaml",
+			yaml:        true,
+			dotenv:      "SERVER_ADDRESS=127.0.0.1:7100\nLOG_LEVEL=warn\n",
+			wantAddress: "127.0.0.1:7100",
+			wantLevel:   "warn",
+			wantConn:    "postgres://yaml@db/tender",
+		},
+		{
+			name:        "environment over dotenv and yaml",
+			yaml:        true,
+			dotenv:      "SERVER_ADDRESS=127.0.0.1:7100\nLOG_LEVEL=warn\n",
+			env:         map[string]string{"SERVER_ADDRESS": "127.0.0.1:7200"},
+			wantAddress: "127.0.0.1:7200",
+			wantLevel:   "warn",
+			wantConn:    "postgres://yaml@db/tender",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range envNames {
+				unsetenv(t, name)
+			}
+			dir := t.TempDir()
+			chdir(t, dir)
+			if tt.yaml {
+				path := filepath.Join(dir, "config.yaml")
+				writeFile(t, path, yamlConfig)
+				t.Setenv("CONFIG_FILE", path)
+			}
+			if tt.dotenv != "" {
+				writeFile(t, filepath.Join(dir, ".env"), tt.dotenv)
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.Server.Address != tt.wantAddress {
+				t.Errorf("server.address = %q, want %q", cfg.Server.Address, tt.wantAddress)
+			}
+			if cfg.LogLevel != tt.wantLevel {
+				t.Errorf("log_level = %q, want %q", cfg.LogLevel, tt.wantLevel)
+			}
+			if cfg.Postgres.Conn != tt.wantConn {
+				t.Errorf("postgres.conn = %q, want %q", cfg.Postgres.Conn, tt.wantConn)
+			}
+		})
+	}
+}
+
+func TestLoadInvalidEnvironment(t *testing.T) {
+	for _, name := range envNames {
+		unsetenv(t, name)
+	}
+	chdir(t, t.TempDir())
+	t.Setenv("POSTGRES_CONN", "postgres://env@db/tender")
+	t.Setenv("IDEMPOTENCY_TTL", "a day")
+
+	_, err := Load()
+	if err == nil || !strings.Contains(err.Error(), "IDEMPOTENCY_TTL") {
+		t.Fatalf("Load err = %v, want IDEMPOTENCY_TTL error", err)
+	}
+}
+
+func validConfig() Config {
+	cfg := Default()
+	cfg.Postgres.Conn = "postgres://user:secret@db:5432/tender"
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{name: "valid", modify: func(c *Config) {}},
+		{name: "host fields instead of conn", modify: func(c *Config) {
+			c.Postgres.Conn = ""
+			c.Postgres.Host, c.Postgres.Database, c.Postgres.Username = "db", "tender", "user"
+		}},
+		{name: "bad address", modify: func(c *Config) { c.Server.Address = "localhost" }, wantErr: "server.address must be host:port"},
+		{name: "zero timeout", modify: func(c *Config) { c.Server.ReadTimeout = 0 }, wantErr: "server timeouts must be positive"},
+		{name: "bad trusted proxy", modify: func(c *Config) { c.Server.TrustedProxies = []string{"10.0.0.0/33"} }, wantErr: "server.trusted_proxies"},
+		{name: "conn is not postgres", modify: func(c *Config) { c.Postgres.Conn = "mysql://db/tender" }, wantErr: "postgres.conn must be a postgres:// URL"},
+		{name: "no database settings", modify: func(c *Config) { c.Postgres.Conn = "" }, wantErr: "postgres.conn or postgres.host"},
+		{name: "jdbc without username", modify: func(c *Config) {
+			c.Postgres.Conn = ""
+			c.Postgres.JDBCURL = "jdbc:postgresql://db:5432/tender"
+		}, wantErr: "postgres.username or user in jdbc_url is required"},
+		{name: "jdbc with user in url", modify: func(c *Config) {
+			c.Postgres.Conn = ""
+			c.Postgres.JDBCURL = "jdbc:postgresql://db:5432/tender?user=app&password=secret"
+		}},
+		{name: "jdbc is not postgres", modify: func(c *Config) {
+			c.Postgres.Conn = ""
+			c.Postgres.JDBCURL = "jdbc:mysql://db:3306/tender?user=app"
+		}, wantErr: "postgres.jdbc_url must be a jdbc:postgresql:// URL"},
+		{name: "bad sslmode", modify: func(c *Config) { c.Postgres.SSLMode = "strict" }, wantErr: "postgres.sslmode must be one of"},
+		{name: "idle over open", modify: func(c *Config) { c.Postgres.MaxIdleConns = c.Postgres.MaxOpenConns + 1 }, wantErr: "max_idle_conns must not exceed"},
+		{name: "bad rate limit", modify: func(c *Config) { c.RateLimit.Default.IP = "fast" }, wantErr: "rate_limit"},
+		{name: "bad sealing key", modify: func(c *Config) { c.Sealing.Key = "c2hvcnQ=" }, wantErr: "sealing.key"},
+		{name: "bad log level", modify: func(c *Config) { c.LogLevel = "trace" }, wantErr: "log_level must be"},
+		{name: "analytics without refresh", modify: func(c *Config) {
+			c.Features.AnalyticsViews = true
+			c.Features.AnalyticsRefresh = 0
+		}, wantErr: "features.analytics_refresh must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(&cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate err = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateCollectsAllErrors(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Address = ""
+	cfg.LogLevel = "trace"
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate must fail")
+	}
+	for _, want := range []string{"server.address is required", "log_level must be"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate err = %v, want it to contain %q", err, want)
+		}
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	const key = "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="
+	cfg := validConfig()
+	cfg.Postgres.Password = "pg-secret"
+	cfg.Postgres.JDBCURL = "jdbc:postgresql://db:5432/tender?user=app&password=jdbc-secret"
+	cfg.Sealing.Key = key
+
+	got := cfg.Redacted()
+	if got.Postgres.Password != redacted {
+		t.Errorf("postgres.password = %q", got.Postgres.Password)
+	}
+	if got.Postgres.Conn != "postgres://user:"+redacted+"@db:5432/tender" {
+		t.Errorf("postgres.conn = %q", got.Postgres.Conn)
+	}
+	if got.Postgres.JDBCURL != "jdbc:postgresql://db:5432/tender?password="+redacted+"&user=app" {
+		t.Errorf("postgres.jdbc_url = %q", got.Postgres.JDBCURL)
+	}
+	if got.Sealing.Key != redacted {
+		t.Errorf("sealing.key = %q", got.Sealing.Key)
+	}
+
+	// исходные настройки не меняются
+	if cfg.Postgres.Password != "pg-secret" || !strings.Contains(cfg.Postgres.JDBCURL, "jdbc-secret") || cfg.Sealing.Key != key {
+		t.Errorf("Redacted modified the original config: %+v", cfg)
+	}
+
+	printed, err := cfg.Print()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"pg-secret", "secret@", "jdbc-secret", key} {
+		if strings.Contains(printed, secret) {
+			t.Errorf("Print leaks %q", secret)
+		}
+	}
+}
+
+func TestRedactedKeepsEmptySecrets(t *testing.T) {
+	cfg := Default()
+	cfg.Postgres.Conn = "postgres://user@db/tender"
+	got := cfg.Redacted()
+	if got.Postgres.Password != "" || got.Postgres.JDBCURL != "" || got.Sealing.Key != "" {
+		t.Errorf("empty secrets must stay empty: %+v", got)
+	}
+	if got.Postgres.Conn != cfg.Postgres.Conn {
+		t.Errorf("postgres.conn without password = %q, want %q", got.Postgres.Conn, cfg.Postgres.Conn)
+	}
+}
+
+func TestRedactDSN(t *testing.T) {
+	tests := []struct {
+		name string
+		dsn  string
+		want string
+	}{
+		{name: "empty", dsn: "", want: ""},
+		{name: "url without password", dsn: "postgres://user@db/tender", want: "postgres://user@db/tender"},
+		{name: "url userinfo", dsn: "postgres://user:secret@db/tender", want: "postgres://user:" + redacted + "@db/tender"},
+		{name: "url query", dsn: "postgres://db/tender?user=app&password=secret",
+			want: "postgres://db/tender?password=" + redacted + "&user=app"},
+		{name: "url userinfo and query", dsn: "postgresql://user:secret@db/tender?password=secret",
+			want: "postgresql://user:" + redacted + "@db/tender?password=" + redacted},
+		{name: "key value", dsn: "host=db user=app password=secret dbname=tender",
+			want: "host=db user=app password=" + redacted + " dbname=tender"},
+		{name: "key value quoted", dsn: `host=db password='sec ret\'s' dbname=tender`,
+			want: "host=db password=" + redacted + " dbname=tender"},
+		{name: "key value spaces", dsn: "host=db password = secret", want: "host=db password = " + redacted},
+		{name: "unparsable url", dsn: "postgres://user:se%cret@db/tender?password=secret",
+			want: "postgres://user:" + redacted + "@db/tender?password=" + redacted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redactDSN(tt.dsn)
+			if got != tt.want {
+				t.Errorf("redactDSN(%q) = %q, want %q", tt.dsn, got, tt.want)
+			}
+			if strings.Contains(got, "secret") || strings.Contains(got, "sec ret") {
+				t.Errorf("redactDSN(%q) leaks the password: %q", tt.dsn, got)
+			}
+		})
+	}
+}
+
+func TestPostgresDSN(t *testing.T) {
+	tests := []struct {
+		name string
+		p    PostgresConfig
+		want []string
+	}{
+		{name: "host fields", p: PostgresConfig{Host: "db", Port: "5432", Database: "tender", Username: "app", Password: "pw"},
+			want: []string{"host=db", "port=5432", "dbname=tender", "user=app", "password=pw"}},
+		{name: "jdbc with fields", p: PostgresConfig{JDBCURL: "jdbc:postgresql://db:6432/tender", Username: "app", Password: "pw"},
+			want: []string{"host=db", "port=6432", "dbname=tender", "user=app", "password=pw"}},
+		{name: "jdbc with credentials", p: PostgresConfig{JDBCURL: "jdbc:postgresql://db/tender?user=jdbc&password=jdbc%20pw"},
+			want: []string{"host=db", "dbname=tender", "user=jdbc", "password='jdbc pw'"}},
+		{name: "jdbc credentials win over fields", p: PostgresConfig{JDBCURL: "jdbc:postgresql://db/tender?user=jdbc&password=jpw",
+			Username: "app", Password: "pw"}, want: []string{"user=jdbc", "password=jpw"}},
+		{name: "jdbc user with password field", p: PostgresConfig{JDBCURL: "jdbc:postgresql://db/tender?user=jdbc", Password: "pw"},
+			want: []string{"user=jdbc", "password=pw"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.p.SSLMode = "disable"
+			dsn, err := tt.p.DSN()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(" "+dsn+" ", " "+want+" ") {
+					t.Errorf("DSN = %q, want it to contain %q", dsn, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadDoesNotValidate(t *testing.T) {
+	for _, name := range envNames {
+		unsetenv(t, name)
+	}
+	chdir(t, t.TempDir())
+	t.Setenv("POSTGRES_CONN", "mysql://user:secret@db/tender")
+	t.Setenv("LOG_LEVEL", "trace")
+
+	cfg, err := Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if cfg.LogLevel != "trace" {
+		t.Errorf("log_level = %q, want trace", cfg.LogLevel)
+	}
+	printed, err := cfg.Print()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(printed, "secret") {
+		t.Errorf("Print leaks the password: %s", printed)
+	}
+	if _, err := Load(); err == nil {
+		t.Error("Load must validate what Read returns")
+	}
+}
+
+func TestServerProxies(t *testing.T) {
+	s := ServerConfig{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1", "::1"}}
+	proxies, err := s.Proxies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.0/8", "192.168.1.1/32", "::1/128"}
+	if len(proxies) != len(want) {
+		t.Fatalf("proxies = %v, want %v", proxies, want)
+	}
+	for i := range want {
+		if proxies[i].String() != want[i] {
+			t.Errorf("proxies[%d] = %s, want %s", i, proxies[i], want[i])
+		}
+	}
+	if _, err := (ServerConfig{TrustedProxies: []string{"proxy.local"}}).Proxies(); err == nil {
+		t.Error("hostname must be rejected")
+	}
+}
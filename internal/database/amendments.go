@@ -0,0 +1,217 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"time"
+)
+
+type TenderAmendment struct {
+	ID                    uuid.UUID
+	TenderID              uuid.UUID
+	AuthorID              uuid.UUID
+	Version               int32
+	Reason                string
+	Changes               string
+	OldSubmissionDeadline sql.NullTime
+	NewSubmissionDeadline sql.NullTime
+	AffectedBids          int32
+	CreatedAt             time.Time
+}
+
+const amendmentColumns = `id, tender_id, author_id, version, reason, changes::text,
+       old_submission_deadline, new_submission_deadline, affected_bids, created_at`
+
+func scanAmendment(row rowScanner) (*TenderAmendment, error) {
+	var i TenderAmendment
+	if err := row.Scan(
+		&i.ID,
+		&i.TenderID,
+		&i.AuthorID,
+		&i.Version,
+		&i.Reason,
+		&i.Changes,
+		&i.OldSubmissionDeadline,
+		&i.NewSubmissionDeadline,
+		&i.AffectedBids,
+		&i.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+type AmendTenderParams struct {
+	Change  TenderChangeParam
+	History CreateTenderHistoryParams
+	// SubmissionDeadline - новый срок подачи, NULL - срок не меняется
+	SubmissionDeadline    sql.NullTime
+	OldSubmissionDeadline sql.NullTime
+	Author_id             string
+	Reason                string
+	Changes               string
+	// Notification - текст уведомления авторам затронутых предложений
+	Notification string
+}
+
+// AmendTenderTx в одной транзакции меняет опубликованный тендер, сохраняет
+// прежнюю версию и поправку, помечает действующие предложения как требующие
+// подтверждения и уведомляет их авторов
+func (q *Queries) AmendTenderTx(ctx context.Context, params AmendTenderParams) (*Tender, *TenderAmendment, error) {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	sqlquery := `UPDATE tender SET
+                  name = COALESCE(NULLIF($2, ''), name),
+                  description = COALESCE(NULLIF($3, ''), description),
+                  service_type = COALESCE(NULLIF($4, ''), service_type),
+                  submission_deadline = COALESCE($5, submission_deadline),
+                  version = version + 1,
+                  updated_at = CURRENT_TIMESTAMP
+                  WHERE id = $1
+                  RETURNING id, name, description, status, service_type, version, created_at`
+	var tender Tender
+	err = tx.QueryRowContext(ctx, sqlquery,
+		params.Change.Tender_id,
+		params.Change.Name,
+		params.Change.Description,
+		params.Change.Service_type,
+		params.SubmissionDeadline,
+	).Scan(
+		&tender.ID,
+		&tender.Name,
+		&tender.Description,
+		&tender.Status,
+		&tender.ServiceType,
+		&tender.Version,
+		&tender.CreatedAt,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO tender_history (tender_id, creator_id, service_type, name, description, version)
+	VALUES ($1,$2,$3,$4,$5,$6)`,
+		params.History.Tender_id,
+		params.History.Creator_id,
+		params.History.ServiceType,
+		params.History.Name,
+		params.History.Description,
+		params.History.OldVersion,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `UPDATE offer SET needs_confirmation = TRUE
+	   WHERE tender_id = $1 AND status IN ('Created', 'Published')
+	   RETURNING creator_id`, params.Change.Tender_id)
+	if err != nil {
+		return nil, nil, err
+	}
+	var authors []string
+	seen := map[string]bool{}
+	for rows.Next() {
+		var author_id string
+		if err := rows.Scan(&author_id); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		if !seen[author_id] {
+			seen[author_id] = true
+			authors = append(authors, author_id)
+		}
+	}
+	affected := len(authors)
+	if err := rows.Close(); err != nil {
+		return nil, nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	sqlquery = `INSERT INTO tender_amendment (tender_id, author_id, version, reason, changes,
+	old_submission_deadline, new_submission_deadline, affected_bids)
+	VALUES ($1,$2,$3,$4,$5::json,$6,$7,$8) RETURNING ` + amendmentColumns
+	amendment, err := scanAmendment(tx.QueryRowContext(ctx, sqlquery,
+		params.Change.Tender_id,
+		params.Author_id,
+		tender.Version,
+		params.Reason,
+		params.Changes,
+		params.OldSubmissionDeadline,
+		params.SubmissionDeadline,
+		affected,
+	))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, author_id := range authors {
+		err := insertNotification(ctx, tx, CreateNotificationParams{
+			User_id:    author_id,
+			Kind:       "tender_amended",
+			EntityType: "tender",
+			EntityID:   params.Change.Tender_id,
+			Message:    params.Notification,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return &tender, amendment, tx.Commit()
+}
+
+type ListAmendmentsParams struct {
+	Tender_id string
+	Offset    int32
+	Limit     int32
+}
+
+func (q *Queries) ListAmendments(ctx context.Context, params ListAmendmentsParams) ([]TenderAmendment, error) {
+	sqlquery := "SELECT " + amendmentColumns + ` FROM tender_amendment
+	   WHERE tender_id = $1 ORDER BY created_at, version OFFSET $2 LIMIT $3`
+	rows, err := q.db.QueryContext(ctx, sqlquery, params.Tender_id, params.Offset, params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TenderAmendment
+	for rows.Next() {
+		i, err := scanAmendment(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// ConfirmOffer снимает отметку needs_confirmation с предложения
+func (q *Queries) ConfirmOffer(ctx context.Context, offer_id string) (*Offer, error) {
+	sqlquery := `UPDATE offer SET needs_confirmation = FALSE
+                  WHERE id = $1
+                  RETURNING id, name, status, author_type, creator_id, version, created_at, needs_confirmation`
+	var i Offer
+	err := q.db.QueryRowContext(ctx, sqlquery, offer_id).Scan(
+		&i.ID,
+		&i.Name,
+		&i.Status,
+		&i.AuthorType,
+		&i.AuthorId,
+		&i.Version,
+		&i.CreatedAt,
+		&i.NeedsConfirmation,
+	)
+	return &i, err
+}
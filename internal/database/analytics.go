@@ -0,0 +1,300 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"github.com/lib/pq"
+	"strings"
+	"time"
+)
+
+const (
+	analyticsLiveSource = "analytics_tender_facts_live"
+	analyticsViewSource = "analytics_tender_facts"
+)
+
+// AnalyticsParams - фильтры отчетов по тендерам организации. From и To
+// ограничивают дату создания тендера, для решений - дату голоса.
+type AnalyticsParams struct {
+	Organization_id string
+	From            *time.Time
+	To              *time.Time
+	Service_types   []string
+	// UseView - читать снимок из материализованного представления,
+	// если оно уже заполнено
+	UseView bool
+}
+
+type TenderCount struct {
+	ServiceType string
+	Status      string
+	Count       int64
+}
+
+type BidStats struct {
+	ServiceType string
+	Tenders     int64
+	Bids        int64
+}
+
+type AwardTime struct {
+	ServiceType string
+	Tenders     int64
+	// AvgHours, MinHours, MaxHours - часы от публикации до выбора победителя
+	AvgHours float64
+	MinHours float64
+	MaxHours float64
+}
+
+type Savings struct {
+	ServiceType string
+	Tenders     int64
+	Budget      float64
+	Awarded     float64
+}
+
+type ApprovalRate struct {
+	User_id  string
+	Username string
+	Approved int64
+	Rejected int64
+	// Blocked - голоса, не учтенные из-за конфликта интересов
+	Blocked int64
+}
+
+// analyticsSource выбирает источник показателей тендеров: снимок
+// используется только после первого обновления. Представление ищется в
+// текущей схеме, одноименное в другой схеме не учитывается.
+func (q *Queries) analyticsSource(ctx context.Context, use_view bool) (string, error) {
+	if !use_view {
+		return analyticsLiveSource, nil
+	}
+	var populated bool
+	err := q.db.QueryRowContext(ctx, `SELECT COALESCE((SELECT ispopulated FROM pg_matviews
+	   WHERE schemaname = current_schema() AND matviewname = $1), FALSE)`, analyticsViewSource).Scan(&populated)
+	if err != nil {
+		return "", err
+	}
+	if !populated {
+		return analyticsLiveSource, nil
+	}
+	return analyticsViewSource, nil
+}
+
+// analyticsQuery собирает запрос к показателям тендеров с фильтрами params.
+// В columns и tail имя источника подставлять не нужно, он доступен как f.
+func (q *Queries) analyticsQuery(ctx context.Context, params AnalyticsParams, columns, tail string, extra ...string) (string, []interface{}, error) {
+	source, err := q.analyticsSource(ctx, params.UseView)
+	if err != nil {
+		return "", nil, err
+	}
+	where := append([]string{}, extra...)
+	var args []interface{}
+	addFilter := func(clause string, value interface{}) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+	addFilter("f.organization_id = $%d", params.Organization_id)
+	if params.From != nil {
+		addFilter("f.created_at >= $%d", params.From.UTC())
+	}
+	if params.To != nil {
+		addFilter("f.created_at < $%d", params.To.UTC())
+	}
+	if len(params.Service_types) > 0 {
+		addFilter("f.service_type = ANY($%d)", pq.Array(params.Service_types))
+	}
+	sqlquery := "SELECT " + columns + " FROM " + source + " f WHERE " + strings.Join(where, " AND ") + " " + tail
+	return sqlquery, args, nil
+}
+
+func (q *Queries) CountTendersByStatus(ctx context.Context, params AnalyticsParams) ([]TenderCount, error) {
+	sqlquery, args, err := q.analyticsQuery(ctx, params, "f.service_type, f.status, COUNT(*)",
+		"GROUP BY f.service_type, f.status ORDER BY f.service_type, f.status")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.db.QueryContext(ctx, sqlquery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TenderCount
+	for rows.Next() {
+		var i TenderCount
+		if err := rows.Scan(&i.ServiceType, &i.Status, &i.Count); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetBidStats - число опубликованных тендеров и поданных на них
+// предложений по видам услуг
+func (q *Queries) GetBidStats(ctx context.Context, params AnalyticsParams) ([]BidStats, error) {
+	sqlquery, args, err := q.analyticsQuery(ctx, params, "f.service_type, COUNT(*), SUM(f.bids_count)",
+		"GROUP BY f.service_type ORDER BY f.service_type", "f.status <> 'Created'")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.db.QueryContext(ctx, sqlquery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BidStats
+	for rows.Next() {
+		var i BidStats
+		if err := rows.Scan(&i.ServiceType, &i.Tenders, &i.Bids); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetAwardTimes - время от публикации до выбора победителя. Тендеры,
+// опубликованные до появления published_at и без записи в аудите, не
+// учитываются.
+func (q *Queries) GetAwardTimes(ctx context.Context, params AnalyticsParams) ([]AwardTime, error) {
+	hours := "EXTRACT(EPOCH FROM f.awarded_at - f.published_at) / 3600"
+	sqlquery, args, err := q.analyticsQuery(ctx, params,
+		"f.service_type, COUNT(*), AVG("+hours+")::float8, MIN("+hours+")::float8, MAX("+hours+")::float8",
+		"GROUP BY f.service_type ORDER BY f.service_type",
+		"f.published_at IS NOT NULL", "f.awarded_at >= f.published_at")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.db.QueryContext(ctx, sqlquery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AwardTime
+	for rows.Next() {
+		var i AwardTime
+		if err := rows.Scan(&i.ServiceType, &i.Tenders, &i.AvgHours, &i.MinHours, &i.MaxHours); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetSavings - оценка бюджета и цена победителя по тендерам, где известны обе
+func (q *Queries) GetSavings(ctx context.Context, params AnalyticsParams) ([]Savings, error) {
+	sqlquery, args, err := q.analyticsQuery(ctx, params,
+		"f.service_type, COUNT(*), SUM(f.estimated_budget)::float8, SUM(f.awarded_price)::float8",
+		"GROUP BY f.service_type ORDER BY f.service_type",
+		"f.estimated_budget IS NOT NULL", "f.awarded_price IS NOT NULL")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.db.QueryContext(ctx, sqlquery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Savings
+	for rows.Next() {
+		var i Savings
+		if err := rows.Scan(&i.ServiceType, &i.Tenders, &i.Budget, &i.Awarded); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// ListApprovalRates - решения ответственных по предложениям на тендеры
+// организации. Всегда считается по живым таблицам.
+func (q *Queries) ListApprovalRates(ctx context.Context, params AnalyticsParams) ([]ApprovalRate, error) {
+	where := []string{"t.organization_id = $1"}
+	args := []interface{}{params.Organization_id}
+	addFilter := func(clause string, value interface{}) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+	if params.From != nil {
+		addFilter("ap.created_at >= $%d", params.From.UTC())
+	}
+	if params.To != nil {
+		addFilter("ap.created_at < $%d", params.To.UTC())
+	}
+	if len(params.Service_types) > 0 {
+		addFilter("t.service_type = ANY($%d)", pq.Array(params.Service_types))
+	}
+	sqlquery := `SELECT e.id, e.username,
+	   COUNT(*) FILTER (WHERE ap.decision = 'Approved' AND NOT ap.blocked),
+	   COUNT(*) FILTER (WHERE ap.decision = 'Rejected' AND NOT ap.blocked),
+	   COUNT(*) FILTER (WHERE ap.blocked)
+	   FROM approval ap
+	   JOIN offer o ON o.id = ap.offer_id
+	   JOIN tender t ON t.id = o.tender_id
+	   JOIN employee e ON e.id = ap.user_id
+	   WHERE ` + strings.Join(where, " AND ") + `
+	   GROUP BY e.id, e.username ORDER BY e.username`
+	rows, err := q.db.QueryContext(ctx, sqlquery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ApprovalRate
+	for rows.Next() {
+		var i ApprovalRate
+		if err := rows.Scan(&i.User_id, &i.Username, &i.Approved, &i.Rejected, &i.Blocked); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// RefreshAnalytics обновляет снимок показателей. CONCURRENTLY не блокирует
+// чтение, но работает только с заполненным представлением, поэтому первое
+// заполнение выполняется обычным REFRESH.
+func (q *Queries) RefreshAnalytics(ctx context.Context) error {
+	var populated bool
+	err := q.db.QueryRowContext(ctx, `SELECT ispopulated FROM pg_matviews
+	   WHERE schemaname = current_schema() AND matviewname = $1`, analyticsViewSource).Scan(&populated)
+	if err != nil {
+		return err
+	}
+	sqlquery := "REFRESH MATERIALIZED VIEW " + analyticsViewSource
+	if populated {
+		sqlquery = "REFRESH MATERIALIZED VIEW CONCURRENTLY " + analyticsViewSource
+	}
+	_, err = q.db.ExecContext(ctx, sqlquery)
+	return err
+}
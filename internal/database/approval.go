@@ -2,22 +2,45 @@ package database
 
 import (
 	"context"
+	"database/sql"
 )
 
 type NewDecisionParams struct {
 	Offer_id string
 	User_id  string
 	Decision string
+	// Lot_id - лот, по которому принято решение, пустой для тендера без лотов
+	Lot_id   string
+	Conflict DecisionConflict
+}
+
+// DecisionConflict - конфликт интересов голосующего. Policy заполняется
+// только при наличии конфликта, заблокированный голос не учитывается
+// в кворуме.
+type DecisionConflict struct {
+	Declared bool
+	Detected bool
+	Comment  string
+	Policy   string
+	Blocked  bool
 }
 
 func (q *Queries) NewDecision(ctx context.Context, params NewDecisionParams) error {
-	sqlquery := `INSERT INTO approval (offer_id, user_id, decision) VALUES ($1,$2,$3)`
-	_, err := q.db.ExecContext(ctx, sqlquery, params.Offer_id, params.User_id, params.Decision)
+	sqlquery := `INSERT INTO approval (offer_id, user_id, decision, lot_id,
+	    conflict_declared, conflict_detected, conflict_comment, conflict_policy, blocked)
+	    VALUES ($1,$2,$3,NULLIF($4, '')::uuid,$5,$6,NULLIF($7, ''),NULLIF($8, ''),$9)`
+	_, err := q.db.ExecContext(ctx, sqlquery, params.Offer_id, params.User_id, params.Decision, params.Lot_id,
+		params.Conflict.Declared,
+		params.Conflict.Detected,
+		params.Conflict.Comment,
+		params.Conflict.Policy,
+		params.Conflict.Blocked,
+	)
 	return err
 }
 
 func (q *Queries) CountDecision(ctx context.Context, offer_id string) (int32, error) {
-	sqlquery := `SELECT COUNT(id) FROM approval WHERE offer_id = $1 AND decision ='Approved'`
+	sqlquery := `SELECT COUNT(id) FROM approval WHERE offer_id = $1 AND decision ='Approved' AND lot_id IS NULL AND NOT blocked`
 	row := q.db.QueryRowContext(ctx, sqlquery, offer_id)
 	var count int32
 	if err := row.Scan(&count); err != nil {
@@ -25,3 +48,36 @@ func (q *Queries) CountDecision(ctx context.Context, offer_id string) (int32, er
 	}
 	return count, nil
 }
+
+// SharesOrganization - состоит ли голосующий в одной организации
+// с автором предложения
+func (q *Queries) SharesOrganization(ctx context.Context, user_id, author_id string) (bool, error) {
+	sqlquery := `SELECT EXISTS (SELECT 1 FROM organization_responsible v
+	   JOIN organization_responsible a ON a.organization_id = v.organization_id
+	   WHERE v.user_id = $1 AND a.user_id = $2)`
+	var shares bool
+	if err := q.db.QueryRowContext(ctx, sqlquery, user_id, author_id).Scan(&shares); err != nil {
+		return false, err
+	}
+	return shares, nil
+}
+
+func (q *Queries) GetConflictPolicy(ctx context.Context, org_id string) (string, error) {
+	var policy string
+	err := q.db.QueryRowContext(ctx, `SELECT conflict_policy FROM organization WHERE id = $1`, org_id).Scan(&policy)
+	return policy, err
+}
+
+// SetConflictPolicy - sql.ErrNoRows, если организации нет
+func (q *Queries) SetConflictPolicy(ctx context.Context, org_id, policy string) error {
+	res, err := q.db.ExecContext(ctx, `UPDATE organization SET conflict_policy = $2 WHERE id = $1`, org_id, policy)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
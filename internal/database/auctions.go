@@ -0,0 +1,283 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"time"
+)
+
+type Auction struct {
+	TenderID        uuid.UUID
+	StartAt         time.Time
+	EndAt           time.Time
+	OriginalEndAt   time.Time
+	StartPrice      sql.NullFloat64
+	DecrementType   string
+	DecrementValue  float64
+	ExtensionWindow int32
+	Extension       int32
+	Extensions      int32
+	ProposedOfferID sql.NullString
+	ClosedAt        sql.NullTime
+}
+
+const auctionColumns = `tender_id, start_at, end_at, original_end_at, start_price, decrement_type,
+       decrement_value, extension_window, extension, extensions, proposed_offer_id::text, closed_at`
+
+func scanAuction(row rowScanner) (*Auction, error) {
+	var i Auction
+	if err := row.Scan(
+		&i.TenderID,
+		&i.StartAt,
+		&i.EndAt,
+		&i.OriginalEndAt,
+		&i.StartPrice,
+		&i.DecrementType,
+		&i.DecrementValue,
+		&i.ExtensionWindow,
+		&i.Extension,
+		&i.Extensions,
+		&i.ProposedOfferID,
+		&i.ClosedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+type AuctionParams struct {
+	Tender_id       string
+	StartAt         time.Time
+	EndAt           time.Time
+	StartPrice      sql.NullFloat64
+	DecrementType   string
+	DecrementValue  float64
+	ExtensionWindow int32
+	Extension       int32
+}
+
+// SetAuction создает или заменяет правила аукциона, пока по нему нет ставок
+func (q *Queries) SetAuction(ctx context.Context, params AuctionParams) (*Auction, error) {
+	sqlquery := `INSERT INTO tender_auction (tender_id, start_at, end_at, original_end_at, start_price,
+	decrement_type, decrement_value, extension_window, extension)
+	VALUES ($1,$2,$3,$3,$4,$5,$6,$7,$8)
+	ON CONFLICT (tender_id) DO UPDATE SET
+	    start_at = EXCLUDED.start_at,
+	    end_at = EXCLUDED.end_at,
+	    original_end_at = EXCLUDED.original_end_at,
+	    start_price = EXCLUDED.start_price,
+	    decrement_type = EXCLUDED.decrement_type,
+	    decrement_value = EXCLUDED.decrement_value,
+	    extension_window = EXCLUDED.extension_window,
+	    extension = EXCLUDED.extension,
+	    updated_at = CURRENT_TIMESTAMP
+	WHERE NOT EXISTS (SELECT 1 FROM auction_bid WHERE tender_id = $1)
+	RETURNING ` + auctionColumns
+	row := q.db.QueryRowContext(ctx, sqlquery,
+		params.Tender_id,
+		params.StartAt,
+		params.EndAt,
+		params.StartPrice,
+		params.DecrementType,
+		params.DecrementValue,
+		params.ExtensionWindow,
+		params.Extension,
+	)
+	return scanAuction(row)
+}
+
+func (q *Queries) GetAuction(ctx context.Context, tender_id string) (*Auction, error) {
+	sqlquery := "SELECT " + auctionColumns + " FROM tender_auction WHERE tender_id = $1"
+	return scanAuction(q.db.QueryRowContext(ctx, sqlquery, tender_id))
+}
+
+type AuctionBid struct {
+	ID        uuid.UUID
+	TenderID  uuid.UUID
+	OfferID   uuid.UUID
+	BidderID  uuid.UUID
+	Price     float64
+	CreatedAt time.Time
+}
+
+type AuctionBidParams struct {
+	Tender_id string
+	Offer_id  string
+	Bidder_id string
+	Price     float64
+	Now       time.Time
+}
+
+// PlaceAuctionBidTx принимает ставку под блокировкой аукциона. check
+// получает текущие правила, лучшую цену среди незакрытых предложений
+// и статус предложения участника, проверяет ставку и возвращает новое
+// время окончания аукциона.
+func (q *Queries) PlaceAuctionBidTx(ctx context.Context, params AuctionBidParams, check func(*Auction, sql.NullFloat64, string) (time.Time, error)) (*AuctionBid, *Auction, error) {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	auction, err := scanAuction(tx.QueryRowContext(ctx,
+		"SELECT "+auctionColumns+" FROM tender_auction WHERE tender_id = $1 FOR UPDATE", params.Tender_id))
+	if err != nil {
+		return nil, nil, err
+	}
+	// статус предложения не должен измениться, пока ставка не записана
+	var offer_status string
+	err = tx.QueryRowContext(ctx, `SELECT status FROM offer WHERE id = $1 FOR SHARE`, params.Offer_id).Scan(&offer_status)
+	if err != nil {
+		return nil, nil, err
+	}
+	var best sql.NullFloat64
+	err = tx.QueryRowContext(ctx, `SELECT MIN(b.price) FROM auction_bid b
+	    JOIN offer o ON o.id = b.offer_id
+	    WHERE b.tender_id = $1 AND o.status <> 'Canceled'`, params.Tender_id).Scan(&best)
+	if err != nil {
+		return nil, nil, err
+	}
+	end_at, err := check(auction, best, offer_status)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var bid AuctionBid
+	err = tx.QueryRowContext(ctx, `INSERT INTO auction_bid (tender_id, offer_id, bidder_id, price, created_at)
+	VALUES ($1,$2,$3,$4,$5) RETURNING id, tender_id, offer_id, bidder_id, price, created_at`,
+		params.Tender_id,
+		params.Offer_id,
+		params.Bidder_id,
+		params.Price,
+		params.Now,
+	).Scan(&bid.ID, &bid.TenderID, &bid.OfferID, &bid.BidderID, &bid.Price, &bid.CreatedAt)
+	if err != nil {
+		return nil, nil, err
+	}
+	if end_at.After(auction.EndAt) {
+		auction, err = scanAuction(tx.QueryRowContext(ctx, `UPDATE tender_auction SET
+		    end_at = $2, extensions = extensions + 1, updated_at = CURRENT_TIMESTAMP
+		    WHERE tender_id = $1 RETURNING `+auctionColumns, params.Tender_id, end_at))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return &bid, auction, tx.Commit()
+}
+
+// AuctionStanding - лучшая ставка одного предложения
+type AuctionStanding struct {
+	OfferID        uuid.UUID
+	OfferName      string
+	OrganizationID uuid.UUID
+	Price          float64
+	BidAt          time.Time
+	// FirstBidAt - время первой ставки, по нему участники нумеруются
+	FirstBidAt time.Time
+	BidCount   int32
+}
+
+// AuctionLeaderboard возвращает лучшие ставки предложений по возрастанию
+// цены, при равной цене выше та, что сделана раньше. Отмененные предложения
+// в рейтинг не попадают.
+func (q *Queries) AuctionLeaderboard(ctx context.Context, tender_id string) ([]AuctionStanding, error) {
+	sqlquery := `WITH best AS (
+	       SELECT DISTINCT ON (offer_id) offer_id, price, created_at
+	       FROM auction_bid WHERE tender_id = $1
+	       ORDER BY offer_id, price, created_at
+	   ), stats AS (
+	       SELECT offer_id, MIN(created_at) AS first_at, COUNT(*) AS n
+	       FROM auction_bid WHERE tender_id = $1 GROUP BY offer_id
+	   )
+	   SELECT b.offer_id, o.name, o.organization_id, b.price, b.created_at, s.first_at, s.n
+	   FROM best b
+	   JOIN stats s ON s.offer_id = b.offer_id
+	   JOIN offer o ON o.id = b.offer_id
+	   WHERE o.status <> 'Canceled'
+	   ORDER BY b.price, b.created_at`
+	rows, err := q.db.QueryContext(ctx, sqlquery, tender_id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuctionStanding
+	for rows.Next() {
+		var i AuctionStanding
+		if err := rows.Scan(
+			&i.OfferID,
+			&i.OfferName,
+			&i.OrganizationID,
+			&i.Price,
+			&i.BidAt,
+			&i.FirstBidAt,
+			&i.BidCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (q *Queries) ListEndedAuctions(ctx context.Context, now time.Time) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, `SELECT tender_id FROM tender_auction
+	   WHERE closed_at IS NULL AND end_at <= $1 ORDER BY end_at`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var tender_id string
+		if err := rows.Scan(&tender_id); err != nil {
+			return nil, err
+		}
+		items = append(items, tender_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// CloseAuctionTx завершает аукцион, предлагает к утверждению незакрытое
+// предложение с лучшей ставкой и уведомляет автора тендера. Если аукцион уже закрыт
+// или еще идет, возвращает sql.ErrNoRows.
+func (q *Queries) CloseAuctionTx(ctx context.Context, tender_id string, now time.Time, message string) (*Auction, error) {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	sqlquery := `UPDATE tender_auction SET
+	    closed_at = $2,
+	    proposed_offer_id = (SELECT b.offer_id FROM auction_bid b
+	                         JOIN offer o ON o.id = b.offer_id
+	                         WHERE b.tender_id = $1 AND o.status <> 'Canceled'
+	                         ORDER BY b.price, b.created_at LIMIT 1),
+	    updated_at = CURRENT_TIMESTAMP
+	    WHERE tender_id = $1 AND closed_at IS NULL AND end_at <= $2
+	    RETURNING ` + auctionColumns
+	auction, err := scanAuction(tx.QueryRowContext(ctx, sqlquery, tender_id, now))
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO notification (user_id, kind, entity_type, entity_id, message)
+	    SELECT creator_id, 'auction_closed', 'tender', id::text, $2 FROM tender WHERE id = $1`,
+		tender_id, message)
+	if err != nil {
+		return nil, err
+	}
+	return auction, tx.Commit()
+}
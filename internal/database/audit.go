@@ -0,0 +1,275 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"github.com/google/uuid"
+	"strings"
+	"time"
+)
+
+// auditLockKey - ключ advisory-блокировки, под которой дописывается цепочка хэшей
+const auditLockKey = 7270312
+
+// AuditGenesisHash - prev_hash первой записи журнала
+var AuditGenesisHash = strings.Repeat("0", 64)
+
+type AuditLog struct {
+	ID             int64
+	ActorID        string
+	OrganizationID string
+	EntityType     string
+	EntityID       string
+	Action         string
+	Before         string
+	After          string
+	RequestID      string
+	IP             string
+	PrevHash       string
+	Hash           string
+	CreatedAt      time.Time
+}
+
+// AuditHash считает хэш записи журнала, включающий хэш предыдущей записи.
+// Каждое поле пишется с префиксом длины, чтобы значения с разделителями
+// (request id, json) не сдвигали границы соседних полей.
+func AuditHash(entry AuditLog) string {
+	h := sha256.New()
+	for _, field := range []string{
+		entry.PrevHash,
+		entry.ActorID,
+		entry.OrganizationID,
+		entry.EntityType,
+		entry.EntityID,
+		entry.Action,
+		entry.Before,
+		entry.After,
+		entry.RequestID,
+		entry.IP,
+		entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+	} {
+		fmt.Fprintf(h, "%d:%s", len(field), field)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// canonicalUUID приводит id к виду, в котором его вернет uuid::text, иначе
+// хэш записи с id в верхнем регистре или в фигурных скобках не сойдется
+// при проверке цепочки
+func canonicalUUID(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+type AppendAuditLogParams struct {
+	ActorID        string
+	OrganizationID string
+	EntityType     string
+	EntityID       string
+	Action         string
+	Before         string
+	After          string
+	RequestID      string
+	IP             string
+}
+
+func (q *Queries) AppendAuditLog(ctx context.Context, params AppendAuditLogParams) error {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, auditLockKey); err != nil {
+		return err
+	}
+
+	prev_hash := AuditGenesisHash
+	row := tx.QueryRowContext(ctx, `SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1`)
+	if err := row.Scan(&prev_hash); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	actor_id, err := canonicalUUID(params.ActorID)
+	if err != nil {
+		return err
+	}
+	org_id, err := canonicalUUID(params.OrganizationID)
+	if err != nil {
+		return err
+	}
+	entry := AuditLog{
+		ActorID:        actor_id,
+		OrganizationID: org_id,
+		EntityType:     params.EntityType,
+		EntityID:       params.EntityID,
+		Action:         params.Action,
+		Before:         params.Before,
+		After:          params.After,
+		RequestID:      params.RequestID,
+		IP:             params.IP,
+		PrevHash:       prev_hash,
+		CreatedAt:      time.Now().UTC().Truncate(time.Microsecond),
+	}
+	entry.Hash = AuditHash(entry)
+
+	sqlquery := `INSERT INTO audit_log (actor_id, organization_id, entity_type, entity_id, action,
+	before_data, after_data, request_id, ip, prev_hash, hash, created_at)
+	VALUES (NULLIF($1, '')::uuid, NULLIF($2, '')::uuid, $3, $4, $5,
+	NULLIF($6, '')::json, NULLIF($7, '')::json, $8, $9, $10, $11, $12)`
+	_, err = tx.ExecContext(ctx, sqlquery,
+		entry.ActorID,
+		entry.OrganizationID,
+		entry.EntityType,
+		entry.EntityID,
+		entry.Action,
+		entry.Before,
+		entry.After,
+		entry.RequestID,
+		entry.IP,
+		entry.PrevHash,
+		entry.Hash,
+		entry.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type ListAuditLogParams struct {
+	OrganizationID string
+	ActorID        string
+	EntityType     string
+	EntityID       string
+	Action         string
+	From           *time.Time
+	To             *time.Time
+	Offset         int32
+	Limit          int32
+}
+
+const auditLogColumns = `id, COALESCE(actor_id::text, ''), COALESCE(organization_id::text, ''),
+       entity_type, entity_id, action, COALESCE(before_data::text, ''), COALESCE(after_data::text, ''),
+       request_id, ip, prev_hash, hash, created_at`
+
+func scanAuditLog(row rowScanner) (*AuditLog, error) {
+	var i AuditLog
+	if err := row.Scan(
+		&i.ID,
+		&i.ActorID,
+		&i.OrganizationID,
+		&i.EntityType,
+		&i.EntityID,
+		&i.Action,
+		&i.Before,
+		&i.After,
+		&i.RequestID,
+		&i.IP,
+		&i.PrevHash,
+		&i.Hash,
+		&i.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+func (q *Queries) ListAuditLog(ctx context.Context, params ListAuditLogParams) ([]AuditLog, error) {
+	var where []string
+	var args []interface{}
+	addFilter := func(clause string, value interface{}) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+	if params.OrganizationID != "" {
+		addFilter("organization_id = $%d", params.OrganizationID)
+	}
+	if params.ActorID != "" {
+		addFilter("actor_id = $%d", params.ActorID)
+	}
+	if params.EntityType != "" {
+		addFilter("entity_type = $%d", params.EntityType)
+	}
+	if params.EntityID != "" {
+		addFilter("entity_id = $%d", params.EntityID)
+	}
+	if params.Action != "" {
+		addFilter("action = $%d", params.Action)
+	}
+	if params.From != nil {
+		addFilter("created_at >= $%d", params.From.UTC())
+	}
+	if params.To != nil {
+		addFilter("created_at < $%d", params.To.UTC())
+	}
+
+	sqlquery := "SELECT " + auditLogColumns + " FROM audit_log"
+	if len(where) > 0 {
+		sqlquery += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append(args, params.Offset, params.Limit)
+	sqlquery += fmt.Sprintf(" ORDER BY id DESC OFFSET $%d LIMIT $%d", len(args)-1, len(args))
+
+	rows, err := q.db.QueryContext(ctx, sqlquery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AuditLog
+	for rows.Next() {
+		i, err := scanAuditLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// continuesAuditChain - запись ссылается на prev_hash и ее хэш не изменен
+func continuesAuditChain(prev_hash string, entry AuditLog) bool {
+	return entry.PrevHash == prev_hash && AuditHash(entry) == entry.Hash
+}
+
+// VerifyAuditChain проходит журнал по порядку и возвращает id первой записи,
+// у которой не сходится хэш или ссылка на предыдущую запись. 0 - цепочка цела.
+func (q *Queries) VerifyAuditChain(ctx context.Context) (int64, int64, error) {
+	rows, err := q.db.QueryContext(ctx, "SELECT "+auditLogColumns+" FROM audit_log ORDER BY id")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+	prev_hash := AuditGenesisHash
+	var checked int64
+	for rows.Next() {
+		i, err := scanAuditLog(rows)
+		if err != nil {
+			return 0, checked, err
+		}
+		if !continuesAuditChain(prev_hash, *i) {
+			return i.ID, checked, nil
+		}
+		prev_hash = i.Hash
+		checked++
+	}
+	if err := rows.Close(); err != nil {
+		return 0, checked, err
+	}
+	return 0, checked, rows.Err()
+}
@@ -0,0 +1,185 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+// auditChain строит цепочку из n записей так же, как AppendAuditLog
+func auditChain(n int) []AuditLog {
+	created := time.Date(2024, 3, 1, 10, 0, 0, 123456000, time.UTC)
+	prev_hash := AuditGenesisHash
+	var entries []AuditLog
+	for i := 0; i < n; i++ {
+		entry := AuditLog{
+			ID:             int64(i + 1),
+			ActorID:        "5f1b2c9e-0000-4000-8000-000000000001",
+			OrganizationID: "5f1b2c9e-0000-4000-8000-000000000002",
+			EntityType:     "tender",
+			EntityID:       "5f1b2c9e-0000-4000-8000-000000000003",
+			Action:         "update",
+			Before:         `{"status":"Created"}`,
+			After:          `{"status":"Published"}`,
+			RequestID:      "req-1",
+			IP:             "203.0.113.5",
+			PrevHash:       prev_hash,
+			CreatedAt:      created.Add(time.Duration(i) * time.Second),
+		}
+		entry.Hash = AuditHash(entry)
+		prev_hash = entry.Hash
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// firstBreak повторяет обход VerifyAuditChain по записям в памяти
+func firstBreak(entries []AuditLog) int64 {
+	prev_hash := AuditGenesisHash
+	for _, entry := range entries {
+		if !continuesAuditChain(prev_hash, entry) {
+			return entry.ID
+		}
+		prev_hash = entry.Hash
+	}
+	return 0
+}
+
+func TestAuditHash(t *testing.T) {
+	entry := auditChain(1)[0]
+	if len(entry.Hash) != 64 {
+		t.Fatalf("hash length = %d, want 64", len(entry.Hash))
+	}
+	if AuditHash(entry) != entry.Hash {
+		t.Fatal("hash must be deterministic")
+	}
+
+	// время в другой зоне - тот же момент, хэш не меняется
+	moved := entry
+	moved.CreatedAt = entry.CreatedAt.In(time.FixedZone("MSK", 3*60*60))
+	if AuditHash(moved) != entry.Hash {
+		t.Error("hash must not depend on the time zone")
+	}
+
+	// ID и сам Hash в хэш не входят
+	renumbered := entry
+	renumbered.ID = 42
+	renumbered.Hash = ""
+	if AuditHash(renumbered) != entry.Hash {
+		t.Error("hash must not depend on ID or Hash")
+	}
+}
+
+func TestAuditHashCoversFields(t *testing.T) {
+	base := auditChain(1)[0]
+	tests := []struct {
+		name   string
+		modify func(*AuditLog)
+	}{
+		{name: "prev hash", modify: func(e *AuditLog) { e.PrevHash = e.Hash }},
+		{name: "actor", modify: func(e *AuditLog) { e.ActorID = "someone-else" }},
+		{name: "organization", modify: func(e *AuditLog) { e.OrganizationID = "" }},
+		{name: "entity type", modify: func(e *AuditLog) { e.EntityType = "bid" }},
+		{name: "entity id", modify: func(e *AuditLog) { e.EntityID = "other" }},
+		{name: "action", modify: func(e *AuditLog) { e.Action = "delete" }},
+		{name: "before", modify: func(e *AuditLog) { e.Before = `{"status":"Canceled"}` }},
+		{name: "after", modify: func(e *AuditLog) { e.After = `{}` }},
+		{name: "request id", modify: func(e *AuditLog) { e.RequestID = "req-2" }},
+		{name: "ip", modify: func(e *AuditLog) { e.IP = "198.51.100.1" }},
+		{name: "created at", modify: func(e *AuditLog) { e.CreatedAt = e.CreatedAt.Add(time.Microsecond) }},
+		// префикс длины не дает перенести символы между соседними полями
+		{name: "field boundary", modify: func(e *AuditLog) {
+			e.EntityType, e.EntityID = e.EntityType+"5", e.EntityID[1:]
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := base
+			tt.modify(&entry)
+			if AuditHash(entry) == base.Hash {
+				t.Errorf("changing %s must change the hash", tt.name)
+			}
+		})
+	}
+}
+
+func TestVerifyAuditChainBreaks(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func([]AuditLog) []AuditLog
+		want   int64
+	}{
+		{name: "intact", tamper: func(e []AuditLog) []AuditLog { return e }, want: 0},
+		{name: "empty", tamper: func(e []AuditLog) []AuditLog { return nil }, want: 0},
+		{name: "edited payload", tamper: func(e []AuditLog) []AuditLog {
+			e[2].After = `{"status":"Closed"}`
+			return e
+		}, want: 3},
+		{name: "edited payload with recomputed hash", tamper: func(e []AuditLog) []AuditLog {
+			e[1].Action = "delete"
+			e[1].Hash = AuditHash(e[1])
+			return e
+		}, want: 3},
+		{name: "deleted entry", tamper: func(e []AuditLog) []AuditLog {
+			return append(e[:1], e[2:]...)
+		}, want: 3},
+		{name: "reordered entries", tamper: func(e []AuditLog) []AuditLog {
+			e[3], e[4] = e[4], e[3]
+			return e
+		}, want: 5},
+		{name: "wrong genesis", tamper: func(e []AuditLog) []AuditLog {
+			e[0].PrevHash = e[4].Hash
+			e[0].Hash = AuditHash(e[0])
+			return e
+		}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := firstBreak(tt.tamper(auditChain(5))); got != tt.want {
+				t.Errorf("first broken entry = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditHashFieldBoundaries(t *testing.T) {
+	base := auditChain(1)[0]
+	a, b := base, base
+	a.RequestID, a.IP = "req|1", "203.0.113.5"
+	b.RequestID, b.IP = "req", "1|203.0.113.5"
+	if AuditHash(a) == AuditHash(b) {
+		t.Error("moving a separator between fields must change the hash")
+	}
+	a, b = base, base
+	a.Before, a.After = `{"a":"|"}`, `{}`
+	b.Before, b.After = `{"a":"`, `"}|{}`
+	if AuditHash(a) == AuditHash(b) {
+		t.Error("moving a separator between before and after must change the hash")
+	}
+}
+
+func TestCanonicalUUID(t *testing.T) {
+	const want = "5f1b2c9e-0000-4000-8000-00000000000a"
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "", want: ""},
+		{in: want, want: want},
+		{in: "5F1B2C9E-0000-4000-8000-00000000000A", want: want},
+		{in: "{5f1b2c9e-0000-4000-8000-00000000000a}", want: want},
+		{in: "urn:uuid:5f1b2c9e-0000-4000-8000-00000000000a", want: want},
+		{in: "5f1b2c9e00004000800000000000000a", want: want},
+		{in: "not-a-uuid", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := canonicalUUID(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("canonicalUUID(%q) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("canonicalUUID(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
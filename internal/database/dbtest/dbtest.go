@@ -0,0 +1,215 @@
+// Package dbtest поднимает схему базы для интеграционных тестов.
+//
+// Тесты работают с внешним Postgres из переменной TEST_POSTGRES_CONN:
+// для каждого теста создается отдельная схема, в ней таблицы сотрудников и
+// организаций из условия задания и все миграции репозитория. Без переменной
+// тесты пропускаются.
+package dbtest
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	_ "github.com/lib/pq"
+	"io/fs"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"tender_service/internal/database"
+	"testing"
+)
+
+const ConnEnv = "TEST_POSTGRES_CONN"
+
+// baseSchema - таблицы, которые в задании считаются уже созданными
+const baseSchema = `
+CREATE TABLE employee (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    username VARCHAR(50) UNIQUE NOT NULL,
+    first_name VARCHAR(50),
+    last_name VARCHAR(50),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TYPE organization_type AS ENUM (
+    'IE',
+    'LLC',
+    'JSC'
+);
+CREATE TABLE organization (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    type organization_type,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE organization_responsible (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID REFERENCES organization(id) ON DELETE CASCADE,
+    user_id UUID REFERENCES employee(id) ON DELETE CASCADE
+);`
+
+// Open возвращает соединение с новой схемой, в которой применены все
+// миграции. Схема удаляется по окончании теста.
+func Open(t testing.TB) *sql.DB {
+	t.Helper()
+	conn := lookupConn(t)
+
+	admin, err := sql.Open("postgres", conn)
+	if err != nil {
+		t.Fatalf("dbtest: open: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+	// пакеты тестируются параллельно и могут одновременно создавать расширение
+	if _, err := admin.Exec(`CREATE EXTENSION IF NOT EXISTS "uuid-ossp"`); err != nil {
+		var exists bool
+		if admin.QueryRow(`SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'uuid-ossp')`).Scan(&exists); !exists {
+			t.Fatalf("dbtest: uuid-ossp: %v", err)
+		}
+	}
+
+	schema := "dbtest_" + randomSuffix(t)
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatalf("dbtest: create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`); err != nil {
+			t.Errorf("dbtest: drop schema: %v", err)
+		}
+	})
+
+	db, err := sql.Open("postgres", withSearchPath(t, conn, schema+",public"))
+	if err != nil {
+		t.Fatalf("dbtest: open schema: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := db.Exec(baseSchema); err != nil {
+		t.Fatalf("dbtest: base schema: %v", err)
+	}
+	if err := migrate(db); err != nil {
+		t.Fatalf("dbtest: %v", err)
+	}
+	return db
+}
+
+// Queries - Open, обернутый в database.Queries
+func Queries(t testing.TB) (*sql.DB, *database.Queries) {
+	t.Helper()
+	db := Open(t)
+	return db, database.NewService(db)
+}
+
+func lookupConn(t testing.TB) string {
+	t.Helper()
+	conn := strings.TrimSpace(os.Getenv(ConnEnv))
+	if conn == "" {
+		t.Skipf("%s не задана, интеграционный тест пропущен", ConnEnv)
+	}
+	return conn
+}
+
+// withSearchPath добавляет search_path в строку подключения. lib/pq
+// передает незнакомые параметры серверу как параметры сессии, поэтому
+// схема действует на каждом соединении пула.
+func withSearchPath(t testing.TB, conn, search_path string) string {
+	t.Helper()
+	if strings.HasPrefix(conn, "postgres://") || strings.HasPrefix(conn, "postgresql://") {
+		u, err := url.Parse(conn)
+		if err != nil {
+			t.Fatalf("dbtest: %s: %v", ConnEnv, err)
+		}
+		query := u.Query()
+		query.Set("search_path", search_path)
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+	return conn + " search_path='" + search_path + "'"
+}
+
+// gooseTable - таблица версий goose, по ней проверка готовности сверяет
+// примененные миграции
+const gooseTable = `CREATE TABLE goose_db_version (
+    id SERIAL PRIMARY KEY,
+    version_id BIGINT NOT NULL,
+    is_applied BOOLEAN NOT NULL,
+    tstamp TIMESTAMP DEFAULT now()
+)`
+
+// migrate применяет Up-секции миграций по порядку версий и отмечает их
+// в goose_db_version. Разметка StatementBegin/End - комментарии, файл
+// выполняется одним запросом.
+func migrate(db *sql.DB) error {
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, gooseTable); err != nil {
+		return err
+	}
+	files := database.Migrations()
+	names, err := fs.Glob(files, "*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		body, err := fs.ReadFile(files, name)
+		if err != nil {
+			return err
+		}
+		up, _, _ := strings.Cut(string(body), "-- +goose Down")
+		if _, err := db.ExecContext(ctx, up); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		_, err = db.ExecContext(ctx, `INSERT INTO goose_db_version (version_id, is_applied) VALUES ($1, TRUE)`, version)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Employee создает пользователя и возвращает его id
+func Employee(t testing.TB, db *sql.DB, username string) string {
+	t.Helper()
+	var id string
+	err := db.QueryRow(`INSERT INTO employee (username) VALUES ($1) RETURNING id`, username).Scan(&id)
+	if err != nil {
+		t.Fatalf("dbtest: employee %s: %v", username, err)
+	}
+	return id
+}
+
+// Organization создает организацию с ответственными и возвращает ее id
+func Organization(t testing.TB, db *sql.DB, name string, responsible ...string) string {
+	t.Helper()
+	var id string
+	err := db.QueryRow(`INSERT INTO organization (name, type) VALUES ($1, 'LLC') RETURNING id`, name).Scan(&id)
+	if err != nil {
+		t.Fatalf("dbtest: organization %s: %v", name, err)
+	}
+	for _, user_id := range responsible {
+		_, err := db.Exec(`INSERT INTO organization_responsible (organization_id, user_id) VALUES ($1, $2)`, id, user_id)
+		if err != nil {
+			t.Fatalf("dbtest: responsible %s: %v", user_id, err)
+		}
+	}
+	return id
+}
+
+func randomSuffix(t testing.TB) string {
+	t.Helper()
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("dbtest: %v", err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"github.com/google/uuid"
+	"strings"
 	"time"
 )
 
@@ -32,20 +33,28 @@ type User struct {
 	Username  string
 	Firstname string
 	Lastname  string
+	IsActive  bool
+	IsAdmin   bool
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
-func (q *Queries) FetchUser(ctx context.Context, user_id string) (*User, error) {
-	sqlquery := "SELECT * FROM employee WHERE id = $1 LIMIT 1"
-	row := q.db.QueryRowContext(ctx, sqlquery, user_id)
+const userColumns = `id, username, COALESCE(first_name, ''), COALESCE(last_name, ''),
+       is_active, is_admin, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
+func scanUser(row rowScanner) (*User, error) {
 	var user User
 	if err := row.Scan(
 		&user.ID,
 		&user.Username,
 		&user.Firstname,
 		&user.Lastname,
+		&user.IsActive,
+		&user.IsAdmin,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	); err != nil {
@@ -54,6 +63,133 @@ func (q *Queries) FetchUser(ctx context.Context, user_id string) (*User, error)
 	return &user, nil
 }
 
+func (q *Queries) FetchUser(ctx context.Context, user_id string) (*User, error) {
+	sqlquery := "SELECT " + userColumns + " FROM employee WHERE id = $1 LIMIT 1"
+	row := q.db.QueryRowContext(ctx, sqlquery, user_id)
+	return scanUser(row)
+}
+
+func (q *Queries) FetchUserByUsername(ctx context.Context, username string) (*User, error) {
+	sqlquery := "SELECT " + userColumns + " FROM employee WHERE username = $1 LIMIT 1"
+	row := q.db.QueryRowContext(ctx, sqlquery, username)
+	return scanUser(row)
+}
+
+type UpdateUserNameParams struct {
+	User_id   string
+	FirstName string
+	LastName  string
+}
+
+// UpdateUserName меняет только непустые поля имени
+func (q *Queries) UpdateUserName(ctx context.Context, params UpdateUserNameParams) (*User, error) {
+	sqlquery := `UPDATE employee SET
+                  first_name = COALESCE(NULLIF($2, ''), first_name),
+                  last_name = COALESCE(NULLIF($3, ''), last_name),
+                  updated_at = CURRENT_TIMESTAMP
+                  WHERE id = $1
+                  RETURNING ` + userColumns
+	row := q.db.QueryRowContext(ctx, sqlquery, params.User_id, params.FirstName, params.LastName)
+	return scanUser(row)
+}
+
+type SearchEmployeesParams struct {
+	Query  string
+	Offset int32
+	Limit  int32
+}
+
+// escapeLike экранирует спецсимволы LIKE, чтобы строка поиска
+// сравнивалась буквально. Запрос должен указывать ESCAPE '\'.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+func (q *Queries) SearchEmployees(ctx context.Context, params SearchEmployeesParams) ([]User, error) {
+	sqlquery := `SELECT ` + userColumns + `
+	   FROM employee
+	   WHERE username ILIKE '%' || $1 || '%' ESCAPE '\'
+	      OR first_name ILIKE '%' || $1 || '%' ESCAPE '\'
+	      OR last_name ILIKE '%' || $1 || '%' ESCAPE '\'
+	   ORDER BY username OFFSET $2 LIMIT $3`
+	rows, err := q.db.QueryContext(ctx, sqlquery, escapeLike(params.Query), params.Offset, params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		i, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+type CreateEmployeeParams struct {
+	Username  string
+	FirstName string
+	LastName  string
+}
+
+func (q *Queries) CreateEmployee(ctx context.Context, params CreateEmployeeParams) (*User, error) {
+	sqlquery := `INSERT INTO employee (username, first_name, last_name)
+	VALUES ($1,$2,$3) RETURNING ` + userColumns
+	row := q.db.QueryRowContext(ctx, sqlquery, params.Username, params.FirstName, params.LastName)
+	return scanUser(row)
+}
+
+func (q *Queries) DeactivateEmployee(ctx context.Context, user_id string) (*User, error) {
+	sqlquery := `UPDATE employee SET
+                  is_active = FALSE,
+                  updated_at = CURRENT_TIMESTAMP
+                  WHERE id = $1
+                  RETURNING ` + userColumns
+	row := q.db.QueryRowContext(ctx, sqlquery, user_id)
+	return scanUser(row)
+}
+
+type UserOrganization struct {
+	ID   uuid.UUID
+	Name string
+	Type string
+}
+
+func (q *Queries) ListUserOrganizations(ctx context.Context, user_id string) ([]UserOrganization, error) {
+	sqlquery := `SELECT o.id, o.name, COALESCE(o.type::text, '')
+	   FROM organization o
+	   JOIN organization_responsible r ON r.organization_id = o.id
+	   WHERE r.user_id = $1 ORDER BY o.name`
+	rows, err := q.db.QueryContext(ctx, sqlquery, user_id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserOrganization
+	for rows.Next() {
+		var i UserOrganization
+		if err := rows.Scan(&i.ID, &i.Name, &i.Type); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 func (q *Queries) IsResponsible(ctx context.Context, org_id, user_id string) (bool, error) {
 	sqlquery := "SELECT user_id FROM organization_responsible WHERE organization_id = $1 LIMIT 1"
 	row := q.db.QueryRowContext(ctx, sqlquery, org_id)
@@ -0,0 +1,44 @@
+package database_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"tender_service/internal/database"
+	"tender_service/internal/database/dbtest"
+	"testing"
+)
+
+func TestSearchEmployeesMatchesLiterally(t *testing.T) {
+	db, query := dbtest.Queries(t)
+	for _, username := range []string{"ivan_petrov", "ivanXpetrov", "sale100%", "sale1000", `back\slash`} {
+		dbtest.Employee(t, db, username)
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "ivan_", want: []string{"ivan_petrov"}},
+		{query: "IVAN", want: []string{"ivan_petrov", "ivanXpetrov"}},
+		{query: "100%", want: []string{"sale100%"}},
+		{query: "%", want: []string{"sale100%"}},
+		{query: `\`, want: []string{`back\slash`}},
+		{query: "_", want: []string{"ivan_petrov"}},
+	}
+	for _, tt := range tests {
+		users, err := query.SearchEmployees(context.Background(), database.SearchEmployeesParams{Query: tt.query, Limit: 10})
+		if err != nil {
+			t.Fatalf("SearchEmployees(%q): %v", tt.query, err)
+		}
+		var got []string
+		for _, user := range users {
+			got = append(got, user.Username)
+		}
+		// порядок имен зависит от сортировки базы, сравниваются множества
+		sort.Strings(got)
+		sort.Strings(tt.want)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SearchEmployees(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package database
+
+import "testing"
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "", want: ""},
+		{in: "ivan", want: "ivan"},
+		{in: "100%", want: `100\%`},
+		{in: "ivan_petrov", want: `ivan\_petrov`},
+		{in: `back\slash`, want: `back\\slash`},
+		{in: `\%_`, want: `\\\%\_`},
+		{in: "Иван", want: "Иван"},
+	}
+	for _, tt := range tests {
+		if got := escapeLike(tt.in); got != tt.want {
+			t.Errorf("escapeLike(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Выгрузки читают строки по одной и передают их в fn, не собирая список в памяти
+
+func (q *Queries) ExportMyTenders(ctx context.Context, user_id string, fn func(Tender) error) error {
+	sqlquery := `SELECT id, organization_id, creator_id, status, version, service_type, name,
+       description, created_at, updated_at, bidding_mode, visibility
+	   FROM tender
+	   WHERE creator_id = $1 ORDER BY name`
+	rows, err := q.db.QueryContext(ctx, sqlquery, user_id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var i Tender
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrganizationID,
+			&i.CreatorID,
+			&i.Status,
+			&i.Version,
+			&i.ServiceType,
+			&i.Name,
+			&i.Description,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.BiddingMode,
+			&i.Visibility,
+		); err != nil {
+			return err
+		}
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	return rows.Err()
+}
+
+type OfferExport struct {
+	Offer
+	Description   string
+	ApprovedCount int32
+	RejectedCount int32
+	ReviewCount   int32
+	LastReview    string
+}
+
+// ExportTenderOffers - те же правила видимости, что и в TenderListOffers,
+// плюс итоги голосования и отзывы по каждому предложению
+func (q *Queries) ExportTenderOffers(ctx context.Context, tender_id, org_id string, fn func(OfferExport) error) error {
+	sqlquery := `SELECT o.id, o.name, o.status, o.author_type, o.creator_id, o.version, o.created_at,
+       COALESCE(o.description, ''),
+       (SELECT COUNT(id) FROM approval WHERE offer_id = o.id AND decision = 'Approved' AND NOT blocked),
+       (SELECT COUNT(id) FROM approval WHERE offer_id = o.id AND decision = 'Rejected' AND NOT blocked),
+       (SELECT COUNT(id) FROM review WHERE offer_id = o.id AND dispute_status IS DISTINCT FROM 'Upheld'),
+       COALESCE((SELECT content FROM review WHERE offer_id = o.id AND dispute_status IS DISTINCT FROM 'Upheld'
+                 ORDER BY created_at DESC LIMIT 1), '')
+	   FROM offer o
+	   WHERE o.tender_id = $1 AND (
+    (o.organization_id = $2 AND o.status IN ('Approved','Created', 'Published', 'Canceled'))
+    OR (o.organization_id != $2 AND o.status = 'Published')
+)  ORDER BY o.name`
+	rows, err := q.db.QueryContext(ctx, sqlquery, tender_id, org_id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var i OfferExport
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Status,
+			&i.AuthorType,
+			&i.AuthorId,
+			&i.Version,
+			&i.CreatedAt,
+			&i.Description,
+			&i.ApprovedCount,
+			&i.RejectedCount,
+			&i.ReviewCount,
+			&i.LastReview,
+		); err != nil {
+			return err
+		}
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	return rows.Err()
+}
+
+type ApprovalExport struct {
+	ID        string
+	OfferID   string
+	OfferName string
+	Username  string
+	Decision  string
+	// Conflict - flagged или blocked, пусто без конфликта интересов
+	Conflict        string
+	ConflictComment string
+	CreatedAt       time.Time
+}
+
+func (q *Queries) ExportTenderApprovals(ctx context.Context, tender_id string, fn func(ApprovalExport) error) error {
+	sqlquery := `SELECT a.id, o.id, o.name, COALESCE(e.username, ''), COALESCE(a.decision, ''),
+       CASE WHEN a.blocked THEN 'blocked' WHEN a.conflict_policy IS NOT NULL THEN 'flagged' ELSE '' END,
+       COALESCE(a.conflict_comment, ''), a.created_at
+	   FROM approval a
+	   JOIN offer o ON o.id = a.offer_id
+	   LEFT JOIN employee e ON e.id = a.user_id
+	   WHERE o.tender_id = $1 ORDER BY a.created_at, a.id`
+	rows, err := q.db.QueryContext(ctx, sqlquery, tender_id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var i ApprovalExport
+		var created_at sql.NullTime
+		if err := rows.Scan(
+			&i.ID,
+			&i.OfferID,
+			&i.OfferName,
+			&i.Username,
+			&i.Decision,
+			&i.Conflict,
+			&i.ConflictComment,
+			&created_at,
+		); err != nil {
+			return err
+		}
+		i.CreatedAt = created_at.Time
+		if err := fn(i); err != nil {
+			return err
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	return rows.Err()
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Migrations - встроенные goose миграции, файлы лежат в корне
+func Migrations() fs.FS {
+	sub, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// ExpectedMigrationVersion - номер последней миграции в репозитории
+func ExpectedMigrationVersion() (int64, error) {
+	entries, err := migrations.ReadDir("migrations")
+	if err != nil {
+		return 0, err
+	}
+	var latest int64
+	for _, entry := range entries {
+		prefix, _, _ := strings.Cut(path.Base(entry.Name()), "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+		if version > latest {
+			latest = version
+		}
+	}
+	return latest, nil
+}
+
+func (q *Queries) Ping(ctx context.Context) error {
+	var one int
+	return q.db.QueryRowContext(ctx, `SELECT 1`).Scan(&one)
+}
+
+// MigrationVersion - последняя примененная goose миграция
+func (q *Queries) MigrationVersion(ctx context.Context) (int64, error) {
+	sqlquery := `SELECT COALESCE(MAX(version_id), 0) FROM goose_db_version WHERE is_applied`
+	var version int64
+	err := q.db.QueryRowContext(ctx, sqlquery).Scan(&version)
+	return version, err
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"tender_service/internal/idempotency"
+	"time"
+)
+
+// IdempotencyStore хранит ключи идемпотентности в таблице idempotency_key
+type IdempotencyStore struct {
+	q *Queries
+}
+
+func NewIdempotencyStore(q *Queries) *IdempotencyStore {
+	return &IdempotencyStore{q: q}
+}
+
+func (s *IdempotencyStore) Reserve(ctx context.Context, record idempotency.Record) (*idempotency.Record, bool, error) {
+	return s.q.ReserveIdempotencyKey(ctx, record)
+}
+
+func (s *IdempotencyStore) Complete(ctx context.Context, record idempotency.Record) error {
+	return s.q.CompleteIdempotencyKey(ctx, record)
+}
+
+func (s *IdempotencyStore) Release(ctx context.Context, scope, key string) error {
+	return s.q.ReleaseIdempotencyKey(ctx, scope, key)
+}
+
+func (q *Queries) ReserveIdempotencyKey(ctx context.Context, record idempotency.Record) (*idempotency.Record, bool, error) {
+	// истекший ключ можно использовать заново
+	if _, err := q.db.ExecContext(ctx,
+		`DELETE FROM idempotency_key WHERE scope = $1 AND key = $2 AND expires_at < $3`,
+		record.Scope, record.Key, time.Now().UTC()); err != nil {
+		return nil, false, err
+	}
+
+	sqlquery := `INSERT INTO idempotency_key (scope, key, request_hash, expires_at)
+	VALUES ($1, $2, $3, $4) ON CONFLICT (scope, key) DO NOTHING`
+	res, err := q.db.ExecContext(ctx, sqlquery, record.Scope, record.Key, record.RequestHash, record.ExpiresAt.UTC())
+	if err != nil {
+		return nil, false, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, false, err
+	} else if n == 1 {
+		return &record, true, nil
+	}
+
+	sqlquery = `SELECT scope, key, request_hash, status_code, content_type, response_body, expires_at
+	FROM idempotency_key WHERE scope = $1 AND key = $2`
+	var existing idempotency.Record
+	var status sql.NullInt32
+	err = q.db.QueryRowContext(ctx, sqlquery, record.Scope, record.Key).Scan(
+		&existing.Scope,
+		&existing.Key,
+		&existing.RequestHash,
+		&status,
+		&existing.ContentType,
+		&existing.Body,
+		&existing.ExpiresAt,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	existing.Completed = status.Valid
+	existing.StatusCode = int(status.Int32)
+	return &existing, false, nil
+}
+
+func (q *Queries) CompleteIdempotencyKey(ctx context.Context, record idempotency.Record) error {
+	sqlquery := `UPDATE idempotency_key SET status_code = $3, content_type = $4, response_body = $5
+	WHERE scope = $1 AND key = $2`
+	_, err := q.db.ExecContext(ctx, sqlquery, record.Scope, record.Key, record.StatusCode, record.ContentType, record.Body)
+	return err
+}
+
+func (q *Queries) ReleaseIdempotencyKey(ctx context.Context, scope, key string) error {
+	_, err := q.db.ExecContext(ctx, `DELETE FROM idempotency_key WHERE scope = $1 AND key = $2`, scope, key)
+	return err
+}
+
+func (q *Queries) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	res, err := q.db.ExecContext(ctx, `DELETE FROM idempotency_key WHERE expires_at < $1`, now.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,77 @@
+package database_test
+
+import (
+	"context"
+	"tender_service/internal/database"
+	"tender_service/internal/database/dbtest"
+	"tender_service/internal/idempotency"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyStore(t *testing.T) {
+	_, query := dbtest.Queries(t)
+	store := database.NewIdempotencyStore(query)
+	ctx := context.Background()
+	record := idempotency.Record{
+		Scope:       "alice",
+		Key:         "k1",
+		RequestHash: "hash",
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+
+	if _, reserved, err := store.Reserve(ctx, record); err != nil || !reserved {
+		t.Fatalf("Reserve = %v, %v; want reserved", reserved, err)
+	}
+	existing, reserved, err := store.Reserve(ctx, record)
+	if err != nil || reserved || existing.Completed {
+		t.Fatalf("second Reserve = %+v, %v, %v; want in-flight record", existing, reserved, err)
+	}
+
+	record.Completed = true
+	record.StatusCode = 200
+	record.ContentType = "application/json"
+	record.Body = []byte(`{"id":"1"}`)
+	if err := store.Complete(ctx, record); err != nil {
+		t.Fatal(err)
+	}
+	existing, _, err = store.Reserve(ctx, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !existing.Completed || existing.StatusCode != 200 || existing.ContentType != "application/json" || string(existing.Body) != `{"id":"1"}` {
+		t.Errorf("completed record = %+v", existing)
+	}
+
+	// другой пользователь с тем же ключом не пересекается
+	other := record
+	other.Scope = "bob"
+	if _, reserved, err := store.Reserve(ctx, other); err != nil || !reserved {
+		t.Errorf("other scope Reserve = %v, %v", reserved, err)
+	}
+
+	if err := store.Release(ctx, "alice", "k1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, reserved, err := store.Reserve(ctx, record); err != nil || !reserved {
+		t.Errorf("Reserve after Release = %v, %v", reserved, err)
+	}
+
+	// истекший ключ занимается заново и удаляется очисткой
+	expired := idempotency.Record{Scope: "carol", Key: "k1", RequestHash: "old", ExpiresAt: time.Now().Add(-time.Minute)}
+	if _, reserved, err := store.Reserve(ctx, expired); err != nil || !reserved {
+		t.Fatalf("Reserve expired = %v, %v", reserved, err)
+	}
+	fresh := expired
+	fresh.RequestHash = "new"
+	fresh.ExpiresAt = time.Now().Add(time.Hour)
+	if _, reserved, err := store.Reserve(ctx, fresh); err != nil || !reserved {
+		t.Errorf("Reserve over expired = %v, %v", reserved, err)
+	}
+	if _, err := query.DeleteExpiredIdempotencyKeys(ctx, time.Now().Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, reserved, err := store.Reserve(ctx, record); err != nil || !reserved {
+		t.Errorf("Reserve after cleanup = %v, %v", reserved, err)
+	}
+}
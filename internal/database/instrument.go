@@ -0,0 +1,249 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"runtime"
+	"strings"
+	"tender_service/internal/logger"
+	"tender_service/internal/metrics"
+	"tender_service/internal/tracing"
+	"time"
+)
+
+// sqlConn - общее у *sql.DB и *sql.Tx
+type sqlConn interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	PrepareContext(context.Context, string) (*sql.Stmt, error)
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}
+
+// sqlRows - результат QueryContext. Запрос считается выполненным, когда
+// строки закрыты, поэтому вместо *sql.Rows возвращается обертка.
+type sqlRows interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+	Close() error
+	Err() error
+}
+
+// instrumentedConn открывает span на каждый запрос к базе, пишет его в логгер
+// запроса (неудачные на уровне error) и замеряет время по методам Queries.
+// Одинаково работает вне транзакции и внутри нее.
+type instrumentedConn struct {
+	conn sqlConn
+	// span - span транзакции, запросы внутри нее становятся его детьми
+	span trace.Span
+}
+
+func (l *instrumentedConn) spanContext(ctx context.Context) context.Context {
+	if l.span == nil {
+		return ctx
+	}
+	return trace.ContextWithSpan(ctx, l.span)
+}
+
+// instrumentedDB - пул соединений, транзакции которого тоже инструментированы
+type instrumentedDB struct {
+	instrumentedConn
+	db DBTX
+}
+
+func newInstrumentedDB(db DBTX) *instrumentedDB {
+	return &instrumentedDB{instrumentedConn: instrumentedConn{conn: db}, db: db}
+}
+
+// instrumentedTx - транзакция, запросы которой попадают в трейс, метрики
+// и лог так же, как запросы вне транзакции
+type instrumentedTx struct {
+	instrumentedConn
+	tx *sql.Tx
+	// ctx - контекст со span транзакции, к нему привязываются COMMIT и ROLLBACK
+	ctx      context.Context
+	finished bool
+}
+
+// instrumentedRows завершает замер запроса при закрытии строк
+type instrumentedRows struct {
+	*sql.Rows
+	query  string
+	done   func(query string, err error)
+	closed bool
+}
+
+func (r *instrumentedRows) Close() error {
+	err := r.Rows.Close()
+	if !r.closed {
+		r.closed = true
+		result := err
+		if result == nil {
+			result = r.Rows.Err()
+		}
+		r.done(r.query, result)
+	}
+	return err
+}
+
+// instrumentedStmt - подготовленный запрос, каждое выполнение замеряется
+type instrumentedStmt struct {
+	stmt  *sql.Stmt
+	query string
+}
+
+func (s *instrumentedStmt) QueryRowContext(ctx context.Context, args ...interface{}) *sql.Row {
+	ctx, done := begin(ctx)
+	row := s.stmt.QueryRowContext(ctx, args...)
+	done(s.query, row.Err())
+	return row
+}
+
+func (s *instrumentedStmt) Close() error {
+	return s.stmt.Close()
+}
+
+func compactQuery(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+// queryMethod возвращает имя метода Queries, из которого выполняется запрос
+func queryMethod() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if i := strings.Index(frame.Function, queriesPrefix); i >= 0 {
+			return frame.Function[i+len(queriesPrefix):]
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+const queriesPrefix = "database.(*Queries)."
+
+// begin открывает span на запрос и возвращает функцию, которая закрывает его,
+// пишет лог и замеряет время выполнения
+func begin(ctx context.Context) (context.Context, func(query string, err error)) {
+	start := time.Now()
+	method := queryMethod()
+	ctx, span := tracing.Start(ctx, "db."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation.name", method),
+		),
+	)
+	return ctx, func(query string, err error) {
+		defer span.End()
+		elapsed := time.Since(start)
+		result := "ok"
+		if err != nil && err != sql.ErrNoRows {
+			result = "error"
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "query failed")
+		}
+		metrics.DBQueryDuration.Observe(elapsed.Seconds(), method, result)
+
+		l := logger.FromContext(ctx)
+		duration := float64(elapsed.Microseconds()) / 1000
+		if result == "error" {
+			l.ErrorContext(ctx, "db query failed", "method", method, "query", compactQuery(query), "duration_ms", duration, "err", err)
+			return
+		}
+		l.DebugContext(ctx, "db query", "method", method, "query", compactQuery(query), "duration_ms", duration)
+	}
+}
+
+func (l *instrumentedConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, done := begin(l.spanContext(ctx))
+	res, err := l.conn.ExecContext(ctx, query, args...)
+	done(query, err)
+	return res, err
+}
+
+func (l *instrumentedConn) PrepareContext(ctx context.Context, query string) (*instrumentedStmt, error) {
+	ctx, done := begin(l.spanContext(ctx))
+	stmt, err := l.conn.PrepareContext(ctx, query)
+	done(query, err)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedStmt{stmt: stmt, query: query}, nil
+}
+
+func (l *instrumentedConn) QueryContext(ctx context.Context, query string, args ...interface{}) (sqlRows, error) {
+	ctx, done := begin(l.spanContext(ctx))
+	rows, err := l.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		done(query, err)
+		return nil, err
+	}
+	return &instrumentedRows{Rows: rows, query: query, done: done}, nil
+}
+
+func (l *instrumentedConn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, done := begin(l.spanContext(ctx))
+	row := l.conn.QueryRowContext(ctx, query, args...)
+	done(query, row.Err())
+	return row
+}
+
+// BeginTx открывает span db.<метод>.tx, который длится до COMMIT или
+// ROLLBACK и объединяет все запросы транзакции
+func (l *instrumentedDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*instrumentedTx, error) {
+	method := queryMethod()
+	ctx, span := tracing.Start(ctx, "db."+method+".tx",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation.name", method),
+		),
+	)
+	span_ctx, done := begin(ctx)
+	tx, err := l.db.BeginTx(span_ctx, opts)
+	done("BEGIN", err)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "begin failed")
+		span.End()
+		return nil, err
+	}
+	return &instrumentedTx{instrumentedConn: instrumentedConn{conn: tx, span: span}, tx: tx, ctx: ctx}, nil
+}
+
+// finish закрывает span транзакции с исходом commit или rollback
+func (t *instrumentedTx) finish(query string, outcome func() error) error {
+	t.finished = true
+	defer t.span.End()
+	_, done := begin(t.ctx)
+	err := outcome()
+	done(query, err)
+	t.span.SetAttributes(attribute.String("db.transaction.outcome", strings.ToLower(query)))
+	if err != nil {
+		t.span.RecordError(err)
+		t.span.SetStatus(codes.Error, strings.ToLower(query)+" failed")
+	}
+	return err
+}
+
+func (t *instrumentedTx) Commit() error {
+	if t.finished {
+		return t.tx.Commit()
+	}
+	return t.finish("COMMIT", t.tx.Commit)
+}
+
+// Rollback после Commit (обычный defer tx.Rollback()) ничего не пишет
+// в трейс и лог
+func (t *instrumentedTx) Rollback() error {
+	if t.finished {
+		return t.tx.Rollback()
+	}
+	return t.finish("ROLLBACK", t.tx.Rollback)
+}
@@ -0,0 +1,254 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"strings"
+	"tender_service/internal/logger"
+	"tender_service/internal/tracing"
+	"testing"
+)
+
+// fakeDriver - драйвер без базы: каждый запрос возвращает одну строку с 1,
+// запрос со словом fail завершается ошибкой
+type fakeDriver struct{}
+
+type fakeConn struct{}
+
+type fakeStmt struct{ query string }
+
+type fakeRows struct{ done bool }
+
+type fakeTx struct{}
+
+var errFakeQuery = errors.New("fake query failed")
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{query: query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	if strings.Contains(s.query, "fail") {
+		return nil, errFakeQuery
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	if strings.Contains(s.query, "fail") {
+		return nil, errFakeQuery
+	}
+	return &fakeRows{}, nil
+}
+
+func (r *fakeRows) Columns() []string { return []string{"n"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+func init() {
+	sql.Register("instrumenttest", fakeDriver{})
+}
+
+// recordSpans подменяет глобальный TracerProvider на время теста
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func fakeQueries(t *testing.T) *Queries {
+	db, err := sql.Open("instrumenttest", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewService(db)
+}
+
+func spanAttr(span sdktrace.ReadOnlySpan, key attribute.Key) string {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+// testTx - метод Queries, чтобы запросы подписывались его именем
+func (q *Queries) testTx(ctx context.Context, queries ...string) error {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	rows, err := tx.QueryContext(ctx, `SELECT 1`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+	}
+	rows.Close()
+	return tx.Commit()
+}
+
+func (q *Queries) testExec(ctx context.Context, query string) error {
+	_, err := q.db.ExecContext(ctx, query)
+	return err
+}
+
+func TestInstrumentedQuerySpan(t *testing.T) {
+	recorder := recordSpans(t)
+	q := fakeQueries(t)
+	ctx, parent := tracing.Start(context.Background(), "service.Test")
+	if err := q.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	ended := recorder.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("spans = %d, want query and parent", len(ended))
+	}
+	span := ended[0]
+	if span.Name() != "db.Ping" || span.SpanKind() != trace.SpanKindClient {
+		t.Errorf("span = %s %v, want db.Ping client", span.Name(), span.SpanKind())
+	}
+	if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("query span must be a child of the caller span")
+	}
+	if got := spanAttr(span, "db.operation.name"); got != "Ping" {
+		t.Errorf("db.operation.name = %q", got)
+	}
+	if got := spanAttr(span, "db.system"); got != "postgresql" {
+		t.Errorf("db.system = %q", got)
+	}
+}
+
+func TestInstrumentedTransactionSpans(t *testing.T) {
+	recorder := recordSpans(t)
+	q := fakeQueries(t)
+	ctx, parent := tracing.Start(context.Background(), "service.Test")
+	if err := q.testTx(ctx, `UPDATE tender SET version = version + 1`); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	var tx sdktrace.ReadOnlySpan
+	var queries []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		switch span.Name() {
+		case "db.testTx.tx":
+			tx = span
+		case "db.testTx":
+			queries = append(queries, span)
+		}
+	}
+	if tx == nil {
+		t.Fatal("transaction span is missing")
+	}
+	if tx.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Error("transaction span must be a child of the caller span")
+	}
+	if got := spanAttr(tx, "db.transaction.outcome"); got != "commit" {
+		t.Errorf("db.transaction.outcome = %q, want commit", got)
+	}
+	// BEGIN, UPDATE, SELECT и COMMIT; Rollback после Commit не пишется
+	if len(queries) != 4 {
+		t.Fatalf("query spans = %d, want 4", len(queries))
+	}
+	for _, span := range queries {
+		if span.Parent().SpanID() != tx.SpanContext().SpanID() {
+			t.Errorf("query span %v must be a child of the transaction span", span.SpanContext().SpanID())
+		}
+	}
+}
+
+func TestInstrumentedRowsEndOnClose(t *testing.T) {
+	recorder := recordSpans(t)
+	q := fakeQueries(t)
+	rows, err := q.db.QueryContext(context.Background(), `SELECT 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	if n := len(recorder.Ended()); n != 0 {
+		t.Fatalf("query span ended before rows were closed: %d", n)
+	}
+	rows.Close()
+	rows.Close()
+	if n := len(recorder.Ended()); n != 1 {
+		t.Fatalf("spans after close = %d, want 1", n)
+	}
+}
+
+func TestInstrumentedQueryError(t *testing.T) {
+	recorder := recordSpans(t)
+	q := fakeQueries(t)
+	var logs bytes.Buffer
+	ctx := logger.WithContext(context.Background(), logger.New(&logs, "info"))
+	if err := q.testExec(ctx, `UPDATE fail`); err == nil {
+		t.Fatal("query must fail")
+	}
+
+	ended := recorder.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("spans = %d, want 1", len(ended))
+	}
+	if ended[0].Status().Code != codes.Error {
+		t.Errorf("status = %v, want error", ended[0].Status())
+	}
+	if !strings.Contains(logs.String(), `"msg":"db query failed"`) || !strings.Contains(logs.String(), `"method":"testExec"`) {
+		t.Errorf("failed query must be logged at error level: %s", logs.String())
+	}
+}
+
+func TestInstrumentedRollback(t *testing.T) {
+	recorder := recordSpans(t)
+	q := fakeQueries(t)
+	if err := q.testTx(context.Background(), `UPDATE fail`); err == nil {
+		t.Fatal("transaction must fail")
+	}
+	for _, span := range recorder.Ended() {
+		if span.Name() == "db.testTx.tx" {
+			if got := spanAttr(span, "db.transaction.outcome"); got != "rollback" {
+				t.Errorf("db.transaction.outcome = %q, want rollback", got)
+			}
+			return
+		}
+	}
+	t.Fatal("transaction span is missing")
+}
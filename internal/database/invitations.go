@@ -0,0 +1,239 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"time"
+)
+
+type Invitation struct {
+	ID               uuid.UUID
+	TenderID         uuid.UUID
+	TenderName       string
+	OrganizationID   uuid.UUID
+	OrganizationName string
+	// InvitedBy и RespondedBy - имена пользователей
+	InvitedBy   string
+	Status      string
+	RespondedBy sql.NullString
+	RespondedAt sql.NullTime
+	CreatedAt   time.Time
+}
+
+const invitationSelect = `SELECT i.id, i.tender_id, t.name, i.organization_id, o.name, inv.username,
+       i.status, resp.username, i.responded_at, i.created_at
+   FROM tender_invitation i
+   JOIN tender t ON t.id = i.tender_id
+   JOIN organization o ON o.id = i.organization_id
+   JOIN employee inv ON inv.id = i.invited_by
+   LEFT JOIN employee resp ON resp.id = i.responded_by`
+
+func scanInvitation(row rowScanner) (*Invitation, error) {
+	var i Invitation
+	if err := row.Scan(
+		&i.ID,
+		&i.TenderID,
+		&i.TenderName,
+		&i.OrganizationID,
+		&i.OrganizationName,
+		&i.InvitedBy,
+		&i.Status,
+		&i.RespondedBy,
+		&i.RespondedAt,
+		&i.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// querier - общее у соединения и транзакции для запросов списком
+type querier interface {
+	QueryContext(context.Context, string, ...interface{}) (sqlRows, error)
+}
+
+func queryInvitations(ctx context.Context, db querier, sqlquery string, args ...interface{}) ([]Invitation, error) {
+	rows, err := db.QueryContext(ctx, sqlquery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Invitation
+	for rows.Next() {
+		i, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+type InviteOrganizationsParams struct {
+	Tender_id        string
+	Invited_by       string
+	Organization_ids []string
+	Notification     string
+}
+
+// InviteOrganizationsTx приглашает организации в тендер и уведомляет их
+// ответственных. Уже приглашенные организации пропускаются, отказавшиеся
+// приглашаются заново.
+func (q *Queries) InviteOrganizationsTx(ctx context.Context, params InviteOrganizationsParams) ([]Invitation, error) {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `INSERT INTO tender_invitation (tender_id, organization_id, invited_by)
+	    SELECT $1, org_id, $3 FROM unnest($2::uuid[]) AS org_id
+	    ON CONFLICT (tender_id, organization_id) DO UPDATE SET
+	        status = 'Pending',
+	        invited_by = EXCLUDED.invited_by,
+	        responded_by = NULL,
+	        responded_at = NULL,
+	        created_at = CURRENT_TIMESTAMP
+	    WHERE tender_invitation.status = 'Declined'
+	    RETURNING id::text`,
+		params.Tender_id, pq.Array(params.Organization_ids), params.Invited_by)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, tx.Commit()
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO notification (user_id, kind, entity_type, entity_id, message)
+	    SELECT DISTINCT r.user_id, 'tender_invitation', 'tender', $1::text, $3
+	    FROM tender_invitation i
+	    JOIN organization_responsible r ON r.organization_id = i.organization_id
+	    WHERE i.id = ANY($2::uuid[])`,
+		params.Tender_id, pq.Array(ids), params.Notification)
+	if err != nil {
+		return nil, err
+	}
+	items, err := queryInvitations(ctx, tx, invitationSelect+" WHERE i.id = ANY($1::uuid[]) ORDER BY o.name", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	return items, tx.Commit()
+}
+
+func (q *Queries) ListTenderInvitations(ctx context.Context, tender_id string) ([]Invitation, error) {
+	return queryInvitations(ctx, q.db, invitationSelect+" WHERE i.tender_id = $1 ORDER BY o.name", tender_id)
+}
+
+type ListUserInvitationsParams struct {
+	User_id string
+	// Status - фильтр по статусу, пустой - все
+	Status string
+	Offset int32
+	Limit  int32
+}
+
+// ListUserInvitations возвращает приглашения организаций, в которых
+// пользователь ответственный
+func (q *Queries) ListUserInvitations(ctx context.Context, params ListUserInvitationsParams) ([]Invitation, error) {
+	return queryInvitations(ctx, q.db, invitationSelect+`
+	   WHERE i.organization_id IN (SELECT organization_id FROM organization_responsible WHERE user_id = $1)
+	   AND ($2 = '' OR i.status = $2)
+	   ORDER BY i.created_at DESC, i.id OFFSET $3 LIMIT $4`,
+		params.User_id, params.Status, params.Offset, params.Limit)
+}
+
+func (q *Queries) GetInvitation(ctx context.Context, invitation_id string) (*Invitation, error) {
+	return scanInvitation(q.db.QueryRowContext(ctx, invitationSelect+" WHERE i.id = $1", invitation_id))
+}
+
+// GetInvitationStatus возвращает статус приглашения организации в тендер,
+// sql.ErrNoRows - организация не приглашена
+func (q *Queries) GetInvitationStatus(ctx context.Context, tender_id, org_id string) (string, error) {
+	var status string
+	err := q.db.QueryRowContext(ctx, `SELECT status FROM tender_invitation
+	   WHERE tender_id = $1 AND organization_id = $2`, tender_id, org_id).Scan(&status)
+	return status, err
+}
+
+// CanViewTender - тендер по приглашению виден ответственным его организации
+// и приглашенных организаций, кроме отказавшихся. Правило совпадает
+// с фильтром PublishedListTenders.
+func (q *Queries) CanViewTender(ctx context.Context, tender_id, user_id string) (bool, error) {
+	sqlquery := `SELECT EXISTS (SELECT 1 FROM tender t WHERE t.id = $1
+	   AND (t.visibility = 'public'
+	        OR EXISTS (SELECT 1 FROM organization_responsible r
+	                   WHERE r.organization_id = t.organization_id AND r.user_id = $2)
+	        OR EXISTS (SELECT 1 FROM tender_invitation i
+	                   JOIN organization_responsible r ON r.organization_id = i.organization_id
+	                   WHERE i.tender_id = t.id AND i.status <> 'Declined' AND r.user_id = $2)))`
+	var visible bool
+	err := q.db.QueryRowContext(ctx, sqlquery, tender_id, user_id).Scan(&visible)
+	return visible, err
+}
+
+type RespondInvitationParams struct {
+	Invitation_id string
+	User_id       string
+	Status        string
+	Kind          string
+	Notification  string
+}
+
+// RespondInvitationTx записывает ответ на приглашение и уведомляет автора
+// тендера. Если на приглашение уже ответили, возвращает sql.ErrNoRows.
+func (q *Queries) RespondInvitationTx(ctx context.Context, params RespondInvitationParams) (*Invitation, error) {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var tender_id, creator_id string
+	err = tx.QueryRowContext(ctx, `UPDATE tender_invitation i SET
+	    status = $3, responded_by = $2, responded_at = CURRENT_TIMESTAMP
+	    FROM tender t
+	    WHERE i.id = $1 AND i.status = 'Pending' AND t.id = i.tender_id
+	    RETURNING t.id::text, t.creator_id::text`,
+		params.Invitation_id, params.User_id, params.Status).Scan(&tender_id, &creator_id)
+	if err != nil {
+		return nil, err
+	}
+	err = insertNotification(ctx, tx, CreateNotificationParams{
+		User_id:    creator_id,
+		Kind:       params.Kind,
+		EntityType: "tender",
+		EntityID:   tender_id,
+		Message:    params.Notification,
+	})
+	if err != nil {
+		return nil, err
+	}
+	invitation, err := scanInvitation(tx.QueryRowContext(ctx, invitationSelect+" WHERE i.id = $1", params.Invitation_id))
+	if err != nil {
+		return nil, err
+	}
+	return invitation, tx.Commit()
+}
@@ -0,0 +1,287 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"time"
+)
+
+type Lot struct {
+	ID             uuid.UUID
+	TenderID       uuid.UUID
+	Number         int32
+	Name           string
+	Description    string
+	Quantity       float64
+	ServiceType    string
+	Status         string
+	AwardedOfferID sql.NullString
+	AwardedPrice   sql.NullFloat64
+	CancelReason   sql.NullString
+	ClosedAt       sql.NullTime
+	CreatedAt      time.Time
+}
+
+const lotColumns = `id, tender_id, number, name, description, quantity, service_type, status,
+       awarded_offer_id::text, awarded_price, cancel_reason, closed_at, created_at`
+
+func scanLot(row rowScanner) (*Lot, error) {
+	var i Lot
+	if err := row.Scan(
+		&i.ID,
+		&i.TenderID,
+		&i.Number,
+		&i.Name,
+		&i.Description,
+		&i.Quantity,
+		&i.ServiceType,
+		&i.Status,
+		&i.AwardedOfferID,
+		&i.AwardedPrice,
+		&i.CancelReason,
+		&i.ClosedAt,
+		&i.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+type LotParams struct {
+	Name        string
+	Description string
+	Quantity    float64
+	ServiceType string
+}
+
+// lotArrays раскладывает лоты по массивам для unnest, номера лотов
+// задает порядок в массиве
+func lotArrays(lots []LotParams) (names, descriptions []string, quantities []float64, service_types []string) {
+	for _, lot := range lots {
+		names = append(names, lot.Name)
+		descriptions = append(descriptions, lot.Description)
+		quantities = append(quantities, lot.Quantity)
+		service_types = append(service_types, lot.ServiceType)
+	}
+	return
+}
+
+func (q *Queries) ListLots(ctx context.Context, tender_id string) ([]Lot, error) {
+	sqlquery := "SELECT " + lotColumns + " FROM tender_lot WHERE tender_id = $1 ORDER BY number"
+	rows, err := q.db.QueryContext(ctx, sqlquery, tender_id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Lot
+	for rows.Next() {
+		i, err := scanLot(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// AddLot добавляет лот в конец списка. Пока по тендеру есть предложения,
+// состав лотов не меняется - возвращает sql.ErrNoRows.
+func (q *Queries) AddLot(ctx context.Context, tender_id string, params LotParams) (*Lot, error) {
+	sqlquery := `INSERT INTO tender_lot (tender_id, number, name, description, quantity, service_type)
+	SELECT $1, m.n, $2, $3, $4, $5
+	FROM (SELECT COALESCE(MAX(number), 0) + 1 AS n FROM tender_lot WHERE tender_id = $1) m
+	WHERE NOT EXISTS (SELECT 1 FROM offer WHERE tender_id = $1)
+	RETURNING ` + lotColumns
+	return scanLot(q.db.QueryRowContext(ctx, sqlquery,
+		tender_id,
+		params.Name,
+		params.Description,
+		params.Quantity,
+		params.ServiceType,
+	))
+}
+
+type OfferLotParams struct {
+	Lot_id string
+	Price  float64
+}
+
+type OfferLot struct {
+	OfferID   uuid.UUID
+	LotID     uuid.UUID
+	LotNumber int32
+	Price     float64
+	Status    string
+}
+
+// ListOfferLots возвращает цены по лотам для набора предложений
+func (q *Queries) ListOfferLots(ctx context.Context, offer_ids []string) ([]OfferLot, error) {
+	sqlquery := `SELECT ol.offer_id, ol.lot_id, l.number, ol.price, ol.status
+	   FROM offer_lot ol
+	   JOIN tender_lot l ON l.id = ol.lot_id
+	   WHERE ol.offer_id = ANY($1::uuid[])
+	   ORDER BY ol.offer_id, l.number`
+	rows, err := q.db.QueryContext(ctx, sqlquery, pq.Array(offer_ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []OfferLot
+	for rows.Next() {
+		var i OfferLot
+		if err := rows.Scan(
+			&i.OfferID,
+			&i.LotID,
+			&i.LotNumber,
+			&i.Price,
+			&i.Status,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (q *Queries) CountLotDecision(ctx context.Context, offer_id, lot_id string) (int32, error) {
+	sqlquery := `SELECT COUNT(id) FROM approval WHERE offer_id = $1 AND lot_id = $2 AND decision = 'Approved' AND NOT blocked`
+	var count int32
+	if err := q.db.QueryRowContext(ctx, sqlquery, offer_id, lot_id).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// RejectOfferLot снимает предложение с лота и возвращает число лотов,
+// по которым предложение еще участвует или победило
+func (q *Queries) RejectOfferLot(ctx context.Context, offer_id, lot_id string) (int32, error) {
+	sqlquery := `WITH r AS (
+	       UPDATE offer_lot SET status = 'Rejected'
+	       WHERE offer_id = $1 AND lot_id = $2 AND status = 'Pending'
+	       RETURNING lot_id
+	   )
+	   SELECT COUNT(*) FROM offer_lot
+	   WHERE offer_id = $1 AND status <> 'Rejected' AND lot_id NOT IN (SELECT lot_id FROM r)`
+	var remaining int32
+	if err := q.db.QueryRowContext(ctx, sqlquery, offer_id, lot_id).Scan(&remaining); err != nil {
+		return 0, err
+	}
+	return remaining, nil
+}
+
+// closeTenderIfDone закрывает тендер, когда по всем лотам выбран
+// победитель или лоты отменены
+func closeTenderIfDone(ctx context.Context, tx *instrumentedTx, tender_id uuid.UUID) (bool, error) {
+	res, err := tx.ExecContext(ctx, `UPDATE tender SET status = 'Closed'
+	    WHERE id = $1 AND status <> 'Closed'
+	    AND NOT EXISTS (SELECT 1 FROM tender_lot WHERE tender_id = $1 AND status = 'Open')`, tender_id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+type AwardLotParams struct {
+	Lot_id       string
+	Offer_id     string
+	Notification string
+}
+
+// AwardLotTx отдает лот предложению: лот и цена по нему получают статус
+// Awarded, предложение - Approved, автор получает уведомление. Тендер
+// закрывается, если открытых лотов не осталось. Если лот уже закрыт,
+// возвращает sql.ErrNoRows.
+func (q *Queries) AwardLotTx(ctx context.Context, params AwardLotParams) (*Lot, bool, error) {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	sqlquery := `UPDATE tender_lot SET
+	    status = 'Awarded',
+	    awarded_offer_id = $2,
+	    awarded_price = (SELECT price FROM offer_lot WHERE offer_id = $2 AND lot_id = $1),
+	    closed_at = CURRENT_TIMESTAMP
+	    WHERE id = $1 AND status = 'Open'
+	    RETURNING ` + lotColumns
+	lot, err := scanLot(tx.QueryRowContext(ctx, sqlquery, params.Lot_id, params.Offer_id))
+	if err != nil {
+		return nil, false, err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE offer_lot SET status = 'Awarded' WHERE offer_id = $1 AND lot_id = $2`,
+		params.Offer_id, params.Lot_id)
+	if err != nil {
+		return nil, false, err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE offer SET status = 'Approved' WHERE id = $1`, params.Offer_id)
+	if err != nil {
+		return nil, false, err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO notification (user_id, kind, entity_type, entity_id, message)
+	    SELECT creator_id, 'lot_awarded', 'bid', id::text, $2 FROM offer WHERE id = $1`,
+		params.Offer_id, params.Notification)
+	if err != nil {
+		return nil, false, err
+	}
+	closed, err := closeTenderIfDone(ctx, tx, lot.TenderID)
+	if err != nil {
+		return nil, false, err
+	}
+	return lot, closed, tx.Commit()
+}
+
+type CancelLotParams struct {
+	Lot_id       string
+	Reason       string
+	Notification string
+}
+
+// CancelLotTx отменяет открытый лот и уведомляет авторов поданных на него
+// предложений. Тендер закрывается, если открытых лотов не осталось.
+func (q *Queries) CancelLotTx(ctx context.Context, params CancelLotParams) (*Lot, bool, error) {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	sqlquery := `UPDATE tender_lot SET
+	    status = 'Cancelled',
+	    cancel_reason = $2,
+	    closed_at = CURRENT_TIMESTAMP
+	    WHERE id = $1 AND status = 'Open'
+	    RETURNING ` + lotColumns
+	lot, err := scanLot(tx.QueryRowContext(ctx, sqlquery, params.Lot_id, params.Reason))
+	if err != nil {
+		return nil, false, err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO notification (user_id, kind, entity_type, entity_id, message)
+	    SELECT DISTINCT o.creator_id, 'lot_cancelled', 'tender', o.tender_id::text, $2
+	    FROM offer_lot ol JOIN offer o ON o.id = ol.offer_id
+	    WHERE ol.lot_id = $1 AND o.status <> 'Canceled'`,
+		params.Lot_id, params.Notification)
+	if err != nil {
+		return nil, false, err
+	}
+	closed, err := closeTenderIfDone(ctx, tx, lot.TenderID)
+	if err != nil {
+		return nil, false, err
+	}
+	return lot, closed, tx.Commit()
+}
@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id UUID NULL,
+    organization_id UUID NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id VARCHAR(64) NOT NULL DEFAULT '',
+    action VARCHAR(50) NOT NULL,
+    before_data JSON NULL,
+    after_data JSON NULL,
+    request_id VARCHAR(100) NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    prev_hash CHAR(64) NOT NULL,
+    hash CHAR(64) NOT NULL,
+    created_at TIMESTAMP(6) WITHOUT TIME ZONE NOT NULL
+);
+
+CREATE INDEX audit_log_organization_id_idx ON audit_log (organization_id, id);
+CREATE INDEX audit_log_entity_idx ON audit_log (entity_type, entity_id);
+
+-- журнал только дополняется, изменение и удаление записей запрещено
+CREATE FUNCTION audit_log_forbid_change() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_forbid_change();
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER audit_log_append_only ON audit_log;
+DROP FUNCTION audit_log_forbid_change();
+DROP TABLE audit_log;
+
+-- +goose StatementEnd
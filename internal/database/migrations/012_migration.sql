@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE tender_template (
+    id UUID NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    organization_id UUID NOT NULL REFERENCES organization (id),
+    creator_id UUID NOT NULL REFERENCES employee (id),
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    service_type VARCHAR(50) NOT NULL,
+    evaluation_criteria JSON NULL,
+    -- сроки по умолчанию в днях от создания тендера
+    submission_days INTEGER NULL CHECK (submission_days >= 0),
+    decision_days INTEGER NULL CHECK (decision_days >= 0),
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX tender_template_organization_id_idx ON tender_template (organization_id, name);
+
+ALTER TABLE tender ADD COLUMN evaluation_criteria JSON NULL;
+ALTER TABLE tender ADD COLUMN submission_deadline TIMESTAMP(0) WITHOUT TIME ZONE NULL;
+ALTER TABLE tender ADD COLUMN decision_deadline TIMESTAMP(0) WITHOUT TIME ZONE NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tender DROP COLUMN decision_deadline;
+ALTER TABLE tender DROP COLUMN submission_deadline;
+ALTER TABLE tender DROP COLUMN evaluation_criteria;
+DROP TABLE tender_template;
+-- +goose StatementEnd
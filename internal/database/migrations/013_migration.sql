@@ -0,0 +1,56 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE service_type (
+    code VARCHAR(50) NOT NULL PRIMARY KEY,
+    parent_code VARCHAR(50) NULL REFERENCES service_type (code),
+    -- названия по языкам: {"ru": "Строительство", "en": "Construction"}
+    names JSON NOT NULL DEFAULT '{}',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (parent_code IS NULL OR parent_code <> code)
+);
+
+-- "Construction" и "construction" - один и тот же тип
+CREATE UNIQUE INDEX service_type_code_lower_idx ON service_type (lower(code));
+CREATE INDEX service_type_parent_code_idx ON service_type (parent_code);
+
+INSERT INTO service_type (code, names) VALUES
+    ('Construction', '{"ru": "Строительство", "en": "Construction"}'),
+    ('Delivery', '{"ru": "Поставка", "en": "Delivery"}'),
+    ('Manufacture', '{"ru": "Производство", "en": "Manufacture"}');
+
+-- приводим существующие значения к кодам справочника
+UPDATE tender SET service_type = st.code FROM service_type st
+    WHERE lower(tender.service_type) = lower(st.code) AND tender.service_type <> st.code;
+UPDATE tender_template SET service_type = st.code FROM service_type st
+    WHERE lower(tender_template.service_type) = lower(st.code) AND tender_template.service_type <> st.code;
+UPDATE tender_history SET service_type = st.code FROM service_type st
+    WHERE lower(tender_history.service_type) = lower(st.code) AND tender_history.service_type <> st.code;
+
+-- остальные ранее использованные значения становятся корневыми типами,
+-- включая старые версии тендеров, чтобы к ним можно было откатиться
+INSERT INTO service_type (code, names)
+    SELECT DISTINCT ON (lower(service_type)) service_type, '{}'
+    FROM (SELECT service_type FROM tender UNION SELECT service_type FROM tender_template
+          UNION SELECT service_type FROM tender_history) used
+    WHERE NOT EXISTS (SELECT 1 FROM service_type st WHERE lower(st.code) = lower(used.service_type))
+    ORDER BY lower(service_type), service_type;
+
+UPDATE tender SET service_type = st.code FROM service_type st
+    WHERE lower(tender.service_type) = lower(st.code) AND tender.service_type <> st.code;
+UPDATE tender_template SET service_type = st.code FROM service_type st
+    WHERE lower(tender_template.service_type) = lower(st.code) AND tender_template.service_type <> st.code;
+UPDATE tender_history SET service_type = st.code FROM service_type st
+    WHERE lower(tender_history.service_type) = lower(st.code) AND tender_history.service_type <> st.code;
+
+ALTER TABLE tender ADD CONSTRAINT tender_service_type_foreign FOREIGN KEY (service_type) REFERENCES service_type (code) ON UPDATE CASCADE;
+ALTER TABLE tender_template ADD CONSTRAINT tender_template_service_type_foreign FOREIGN KEY (service_type) REFERENCES service_type (code) ON UPDATE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tender_template DROP CONSTRAINT tender_template_service_type_foreign;
+ALTER TABLE tender DROP CONSTRAINT tender_service_type_foreign;
+DROP TABLE service_type;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE tender_question (
+    id UUID NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    tender_id UUID NOT NULL REFERENCES tender (id),
+    author_id UUID NOT NULL REFERENCES employee (id),
+    question TEXT NOT NULL,
+    answer TEXT NULL,
+    answered_by UUID NULL REFERENCES employee (id),
+    -- public - ответ видят все участники, private - только автор вопроса
+    visibility VARCHAR(10) NULL CHECK (visibility IN ('public', 'private')),
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    answered_at TIMESTAMP(0) WITHOUT TIME ZONE NULL
+);
+
+CREATE INDEX tender_question_tender_id_idx ON tender_question (tender_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE tender_question;
+-- +goose StatementEnd
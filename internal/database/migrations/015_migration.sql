@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE tender_amendment (
+    id UUID NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    tender_id UUID NOT NULL REFERENCES tender (id),
+    author_id UUID NOT NULL REFERENCES employee (id),
+    -- версия тендера после изменения
+    version INTEGER NOT NULL,
+    reason TEXT NOT NULL,
+    changes JSON NOT NULL,
+    old_submission_deadline TIMESTAMP(0) WITHOUT TIME ZONE NULL,
+    new_submission_deadline TIMESTAMP(0) WITHOUT TIME ZONE NULL,
+    affected_bids INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX tender_amendment_tender_id_idx ON tender_amendment (tender_id, created_at);
+
+-- предложение написано по старым условиям тендера и ждет подтверждения автора
+ALTER TABLE offer ADD COLUMN needs_confirmation BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE notification (
+    id UUID NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES employee (id),
+    kind VARCHAR(50) NOT NULL,
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id VARCHAR(64) NOT NULL,
+    message TEXT NOT NULL,
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    read_at TIMESTAMP(0) WITHOUT TIME ZONE NULL
+);
+
+CREATE INDEX notification_user_id_idx ON notification (user_id, created_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE notification;
+ALTER TABLE offer DROP COLUMN needs_confirmation;
+DROP TABLE tender_amendment;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- open - предложения видны как раньше, sealed - содержимое предложений
+-- зашифровано и скрыто до вскрытия после срока подачи
+ALTER TABLE tender ADD COLUMN bidding_mode VARCHAR(10) NOT NULL DEFAULT 'open'
+    CHECK (bidding_mode IN ('open', 'sealed'));
+ALTER TABLE tender ADD COLUMN bids_opened_at TIMESTAMP(0) WITHOUT TIME ZONE NULL;
+
+-- зашифрованные name и description закрытого предложения; после вскрытия
+-- содержимое переносится в открытые колонки, а sealed_content очищается
+ALTER TABLE offer ADD COLUMN sealed_content BYTEA NULL;
+
+CREATE TABLE bid_opening (
+    id UUID NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    tender_id UUID NOT NULL UNIQUE REFERENCES tender (id),
+    opened_by UUID NOT NULL REFERENCES employee (id),
+    bid_count INTEGER NOT NULL,
+    opened_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE bid_opening;
+ALTER TABLE offer DROP COLUMN sealed_content;
+ALTER TABLE tender DROP COLUMN bids_opened_at;
+ALTER TABLE tender DROP COLUMN bidding_mode;
+-- +goose StatementEnd
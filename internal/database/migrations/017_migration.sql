@@ -0,0 +1,54 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE tender DROP CONSTRAINT tender_bidding_mode_check;
+ALTER TABLE tender ADD CONSTRAINT tender_bidding_mode_check
+    CHECK (bidding_mode IN ('open', 'sealed', 'auction'));
+
+-- правила аукциона на понижение цены для тендера в режиме auction
+CREATE TABLE tender_auction (
+    tender_id UUID NOT NULL PRIMARY KEY REFERENCES tender (id),
+    start_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL,
+    -- end_at сдвигается при продлении, original_end_at - исходное окончание
+    end_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL,
+    original_end_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL,
+    -- start_price - максимальная цена первой ставки, NULL - без ограничения
+    start_price NUMERIC(18, 2) NULL CHECK (start_price > 0),
+    -- шаг понижения: фиксированная сумма или процент от лучшей цены
+    decrement_type VARCHAR(10) NOT NULL CHECK (decrement_type IN ('absolute', 'percent')),
+    decrement_value NUMERIC(18, 2) NOT NULL CHECK (decrement_value > 0),
+    -- ставка за extension_window секунд до конца продлевает аукцион
+    -- на extension секунд
+    extension_window INTEGER NOT NULL CHECK (extension_window >= 0),
+    extension INTEGER NOT NULL CHECK (extension >= 0),
+    extensions INTEGER NOT NULL DEFAULT 0,
+    proposed_offer_id UUID NULL REFERENCES offer (id),
+    closed_at TIMESTAMP(0) WITHOUT TIME ZONE NULL,
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (end_at > start_at)
+);
+
+CREATE INDEX tender_auction_open_idx ON tender_auction (end_at) WHERE closed_at IS NULL;
+
+CREATE TABLE auction_bid (
+    id UUID NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    tender_id UUID NOT NULL REFERENCES tender_auction (tender_id),
+    offer_id UUID NOT NULL REFERENCES offer (id),
+    bidder_id UUID NOT NULL REFERENCES employee (id),
+    price NUMERIC(18, 2) NOT NULL CHECK (price > 0),
+    -- миллисекунды нужны, чтобы упорядочить равные по времени ставки
+    created_at TIMESTAMP(3) WITHOUT TIME ZONE NOT NULL
+);
+
+CREATE INDEX auction_bid_tender_id_idx ON auction_bid (tender_id, price, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE auction_bid;
+DROP TABLE tender_auction;
+UPDATE tender SET bidding_mode = 'open' WHERE bidding_mode = 'auction';
+ALTER TABLE tender DROP CONSTRAINT tender_bidding_mode_check;
+ALTER TABLE tender ADD CONSTRAINT tender_bidding_mode_check
+    CHECK (bidding_mode IN ('open', 'sealed'));
+-- +goose StatementEnd
@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+-- лоты тендера: у каждого свое описание, количество и вид услуг,
+-- победитель выбирается по каждому лоту отдельно
+CREATE TABLE tender_lot (
+    id UUID NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    tender_id UUID NOT NULL REFERENCES tender (id),
+    number INTEGER NOT NULL CHECK (number > 0),
+    name VARCHAR(100) NOT NULL,
+    description VARCHAR(500) NOT NULL DEFAULT '',
+    quantity NUMERIC(18, 3) NOT NULL CHECK (quantity > 0),
+    service_type VARCHAR(50) NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'Open' CHECK (status IN ('Open', 'Awarded', 'Cancelled')),
+    awarded_offer_id UUID NULL REFERENCES offer (id),
+    awarded_price NUMERIC(18, 2) NULL,
+    cancel_reason VARCHAR(500) NULL,
+    closed_at TIMESTAMP(0) WITHOUT TIME ZONE NULL,
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (tender_id, number)
+);
+
+-- цены предложения по лотам, на которые оно подано
+CREATE TABLE offer_lot (
+    offer_id UUID NOT NULL REFERENCES offer (id),
+    lot_id UUID NOT NULL REFERENCES tender_lot (id),
+    price NUMERIC(18, 2) NOT NULL CHECK (price > 0),
+    status VARCHAR(10) NOT NULL DEFAULT 'Pending' CHECK (status IN ('Pending', 'Awarded', 'Rejected')),
+    PRIMARY KEY (offer_id, lot_id)
+);
+
+CREATE INDEX offer_lot_lot_id_idx ON offer_lot (lot_id);
+
+-- решение по многолотовому тендеру принимается по конкретному лоту
+ALTER TABLE approval ADD COLUMN lot_id UUID NULL REFERENCES tender_lot (id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE approval DROP COLUMN lot_id;
+DROP TABLE offer_lot;
+DROP TABLE tender_lot;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- invite_only - тендер виден и доступен для подачи только приглашенным
+-- организациям
+ALTER TABLE tender ADD COLUMN visibility VARCHAR(12) NOT NULL DEFAULT 'public'
+    CHECK (visibility IN ('public', 'invite_only'));
+
+CREATE TABLE tender_invitation (
+    id UUID NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    tender_id UUID NOT NULL REFERENCES tender (id),
+    organization_id UUID NOT NULL REFERENCES organization (id),
+    invited_by UUID NOT NULL REFERENCES employee (id),
+    status VARCHAR(10) NOT NULL DEFAULT 'Pending' CHECK (status IN ('Pending', 'Accepted', 'Declined')),
+    responded_by UUID NULL REFERENCES employee (id),
+    responded_at TIMESTAMP(0) WITHOUT TIME ZONE NULL,
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (tender_id, organization_id)
+);
+
+CREATE INDEX tender_invitation_organization_id_idx ON tender_invitation (organization_id, status);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE tender_invitation;
+ALTER TABLE tender DROP COLUMN visibility;
+-- +goose StatementEnd
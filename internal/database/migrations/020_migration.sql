@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+-- квалификация поставщика у заказчика по виду услуг; одобренные и не
+-- истекшие квалификации образуют список допущенных поставщиков
+CREATE TABLE vendor_qualification (
+    id UUID NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    buyer_organization_id UUID NOT NULL REFERENCES organization (id),
+    supplier_organization_id UUID NOT NULL REFERENCES organization (id),
+    service_type VARCHAR(50) NOT NULL REFERENCES service_type (code),
+    status VARCHAR(10) NOT NULL DEFAULT 'Pending'
+        CHECK (status IN ('Pending', 'Approved', 'Rejected', 'Revoked')),
+    -- документы заявки: [{"name": "...", "url": "..."}]
+    documents JSONB NOT NULL DEFAULT '[]',
+    comment VARCHAR(1000) NOT NULL DEFAULT '',
+    applicant_id UUID NOT NULL REFERENCES employee (id),
+    reason VARCHAR(1000) NULL,
+    decided_by UUID NULL REFERENCES employee (id),
+    decided_at TIMESTAMP(0) WITHOUT TIME ZONE NULL,
+    -- NULL - квалификация бессрочная
+    expires_at TIMESTAMP(0) WITHOUT TIME ZONE NULL,
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (buyer_organization_id <> supplier_organization_id)
+);
+
+-- одна заявка на рассмотрении по паре организаций и виду услуг
+CREATE UNIQUE INDEX vendor_qualification_pending_idx
+    ON vendor_qualification (buyer_organization_id, supplier_organization_id, service_type)
+    WHERE status = 'Pending';
+CREATE INDEX vendor_qualification_supplier_idx ON vendor_qualification (supplier_organization_id, status);
+
+ALTER TABLE tender ADD COLUMN requires_qualification BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE tender DROP COLUMN requires_qualification;
+DROP TABLE vendor_qualification;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- conflict_policy - что делать с голосом при конфликте интересов:
+-- flag - учесть и пометить, block - записать, но не учитывать
+ALTER TABLE organization ADD COLUMN conflict_policy VARCHAR(5) NOT NULL DEFAULT 'flag'
+    CHECK (conflict_policy IN ('flag', 'block'));
+
+ALTER TABLE approval
+    ADD COLUMN conflict_declared BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN conflict_detected BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN conflict_comment TEXT NULL,
+    ADD COLUMN conflict_policy VARCHAR(5) NULL CHECK (conflict_policy IN ('flag', 'block')),
+    ADD COLUMN blocked BOOLEAN NOT NULL DEFAULT FALSE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE approval
+    DROP COLUMN conflict_declared,
+    DROP COLUMN conflict_detected,
+    DROP COLUMN conflict_comment,
+    DROP COLUMN conflict_policy,
+    DROP COLUMN blocked;
+ALTER TABLE organization DROP COLUMN conflict_policy;
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- оценки по шкале 1-5 задаются все вместе или не задаются вовсе,
+-- старые отзывы остаются без оценок
+ALTER TABLE review
+    ADD COLUMN organization_id UUID NULL REFERENCES organization (id),
+    ADD COLUMN quality SMALLINT NULL CHECK (quality BETWEEN 1 AND 5),
+    ADD COLUMN timeliness SMALLINT NULL CHECK (timeliness BETWEEN 1 AND 5),
+    ADD COLUMN communication SMALLINT NULL CHECK (communication BETWEEN 1 AND 5),
+    ADD CONSTRAINT review_ratings_check CHECK (
+        (quality IS NULL) = (timeliness IS NULL) AND (quality IS NULL) = (communication IS NULL)
+    );
+
+UPDATE review r SET organization_id = t.organization_id
+    FROM offer o JOIN tender t ON t.id = o.tender_id
+    WHERE o.id = r.offer_id;
+
+CREATE INDEX review_offer_id_idx ON review (offer_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX review_offer_id_idx;
+ALTER TABLE review
+    DROP CONSTRAINT review_ratings_check,
+    DROP COLUMN organization_id,
+    DROP COLUMN quality,
+    DROP COLUMN timeliness,
+    DROP COLUMN communication;
+-- +goose StatementEnd
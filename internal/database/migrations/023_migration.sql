@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+-- на отзыв отвечают один раз, ответ удаляется вместе с отзывом
+CREATE TABLE review_reply (
+    id UUID NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    review_id UUID NOT NULL UNIQUE REFERENCES review (id) ON DELETE CASCADE,
+    author_id UUID NOT NULL REFERENCES employee (id),
+    organization_id UUID NULL REFERENCES organization (id),
+    content TEXT NOT NULL,
+    created_at TIMESTAMP(0) WITHOUT TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Upheld - спор признан обоснованным, отзыв скрыт от заказчиков
+-- и не учитывается в репутации
+ALTER TABLE review
+    ADD COLUMN dispute_status VARCHAR(10) NULL CHECK (dispute_status IN ('Open', 'Upheld', 'Dismissed')),
+    ADD COLUMN dispute_reason TEXT NULL,
+    ADD COLUMN disputed_by UUID NULL REFERENCES employee (id),
+    ADD COLUMN disputed_at TIMESTAMP(0) WITHOUT TIME ZONE NULL,
+    ADD COLUMN moderated_by UUID NULL REFERENCES employee (id),
+    ADD COLUMN moderated_at TIMESTAMP(0) WITHOUT TIME ZONE NULL,
+    ADD COLUMN moderation_comment TEXT NULL;
+
+CREATE INDEX review_dispute_status_idx ON review (dispute_status, disputed_at) WHERE dispute_status IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX review_dispute_status_idx;
+ALTER TABLE review
+    DROP COLUMN dispute_status,
+    DROP COLUMN dispute_reason,
+    DROP COLUMN disputed_by,
+    DROP COLUMN disputed_at,
+    DROP COLUMN moderated_by,
+    DROP COLUMN moderated_at,
+    DROP COLUMN moderation_comment;
+DROP TABLE review_reply;
+-- +goose StatementEnd
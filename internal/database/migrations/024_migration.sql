@@ -0,0 +1,78 @@
+-- +goose Up
+-- +goose StatementBegin
+-- published_at - первая публикация тендера, estimated_budget - оценка
+-- бюджета заказчиком для расчета экономии
+ALTER TABLE tender ADD COLUMN published_at TIMESTAMP(0) WITHOUT TIME ZONE NULL;
+ALTER TABLE tender ADD COLUMN estimated_budget NUMERIC(18, 2) NULL CHECK (estimated_budget > 0);
+
+-- для уже опубликованных тендеров время берется из журнала аудита
+UPDATE tender t SET published_at = (
+    SELECT MIN(a.created_at) FROM audit_log a
+    WHERE a.entity_type = 'tender' AND a.entity_id = t.id::text
+      AND a.after_data->>'status' = 'Published')
+WHERE t.status <> 'Created';
+
+CREATE FUNCTION tender_set_published_at() RETURNS trigger AS $$
+BEGIN
+    IF NEW.status = 'Published' AND NEW.published_at IS NULL THEN
+        NEW.published_at := CURRENT_TIMESTAMP;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tender_published_at
+    BEFORE INSERT OR UPDATE OF status ON tender
+    FOR EACH ROW EXECUTE FUNCTION tender_set_published_at();
+
+-- показатели тендера для аналитики: число поданных предложений, время
+-- выбора победителя и цена победителя (сумма по лотам или ставка аукциона)
+CREATE VIEW analytics_tender_facts_live AS
+SELECT t.id AS tender_id,
+    t.organization_id,
+    t.service_type,
+    t.status,
+    t.created_at,
+    t.published_at,
+    t.estimated_budget,
+    (SELECT COUNT(*) FROM offer o
+        WHERE o.tender_id = t.id AND o.status <> 'Created') AS bids_count,
+    LEAST(
+        (SELECT MIN(d.decided_at) FROM (
+            SELECT MAX(ap.created_at) AS decided_at FROM offer o
+            JOIN approval ap ON ap.offer_id = o.id
+            WHERE o.tender_id = t.id AND o.status = 'Approved' AND ap.decision = 'Approved'
+              AND ap.lot_id IS NULL AND NOT ap.blocked
+            GROUP BY o.id) d),
+        (SELECT MIN(l.closed_at) FROM tender_lot l
+            WHERE l.tender_id = t.id AND l.status = 'Awarded')
+    ) AS awarded_at,
+    COALESCE(
+        (SELECT SUM(l.awarded_price) FROM tender_lot l
+            WHERE l.tender_id = t.id AND l.status = 'Awarded'),
+        (SELECT MIN(b.price) FROM tender_auction ta
+            JOIN offer o ON o.id = ta.proposed_offer_id AND o.status = 'Approved'
+            JOIN auction_bid b ON b.tender_id = ta.tender_id AND b.offer_id = o.id
+            WHERE ta.tender_id = t.id)
+    ) AS awarded_price
+FROM tender t;
+
+-- снимок тех же показателей, обновляется по расписанию при включенной
+-- опции analytics_views
+CREATE MATERIALIZED VIEW analytics_tender_facts AS
+SELECT * FROM analytics_tender_facts_live
+WITH NO DATA;
+
+CREATE UNIQUE INDEX analytics_tender_facts_tender_id_idx ON analytics_tender_facts (tender_id);
+CREATE INDEX analytics_tender_facts_organization_id_idx ON analytics_tender_facts (organization_id, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP MATERIALIZED VIEW analytics_tender_facts;
+DROP VIEW analytics_tender_facts_live;
+DROP TRIGGER tender_published_at ON tender;
+DROP FUNCTION tender_set_published_at();
+ALTER TABLE tender DROP COLUMN estimated_budget;
+ALTER TABLE tender DROP COLUMN published_at;
+-- +goose StatementEnd
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"time"
+)
+
+// execer - общее у соединения и транзакции, чтобы уведомления можно было писать
+// в той же транзакции, что и изменение, которое их вызвало
+type execer interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+}
+
+type Notification struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Kind       string
+	EntityType string
+	EntityID   string
+	Message    string
+	CreatedAt  time.Time
+	ReadAt     sql.NullTime
+}
+
+type CreateNotificationParams struct {
+	User_id    string
+	Kind       string
+	EntityType string
+	EntityID   string
+	Message    string
+}
+
+func insertNotification(ctx context.Context, db execer, params CreateNotificationParams) error {
+	sqlquery := `INSERT INTO notification (user_id, kind, entity_type, entity_id, message)
+	VALUES ($1,$2,$3,$4,$5)`
+	_, err := db.ExecContext(ctx, sqlquery,
+		params.User_id,
+		params.Kind,
+		params.EntityType,
+		params.EntityID,
+		params.Message,
+	)
+	return err
+}
+
+func (q *Queries) CreateNotification(ctx context.Context, params CreateNotificationParams) error {
+	return insertNotification(ctx, q.db, params)
+}
+
+type ListNotificationsParams struct {
+	User_id    string
+	UnreadOnly bool
+	Offset     int32
+	Limit      int32
+}
+
+func (q *Queries) ListNotifications(ctx context.Context, params ListNotificationsParams) ([]Notification, error) {
+	sqlquery := `SELECT id, user_id, kind, entity_type, entity_id, message, created_at, read_at
+	   FROM notification
+	   WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)
+	   ORDER BY created_at DESC, id OFFSET $3 LIMIT $4`
+	rows, err := q.db.QueryContext(ctx, sqlquery, params.User_id, params.UnreadOnly, params.Offset, params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Notification
+	for rows.Next() {
+		var i Notification
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Kind,
+			&i.EntityType,
+			&i.EntityID,
+			&i.Message,
+			&i.CreatedAt,
+			&i.ReadAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// MarkNotificationRead возвращает false, если уведомления нет или оно чужое
+func (q *Queries) MarkNotificationRead(ctx context.Context, notification_id, user_id string) (bool, error) {
+	sqlquery := `UPDATE notification SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP)
+	   WHERE id = $1 AND user_id = $2`
+	res, err := q.db.ExecContext(ctx, sqlquery, notification_id, user_id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"strings"
 	"time"
 )
@@ -21,6 +22,12 @@ type Offer struct {
 	AuthorId   uuid.UUID `json:"authorId"`
 	Version    int32     `json:"version"`
 	CreatedAt  time.Time `json:"createdAt"`
+	// NeedsConfirmation - тендер изменился после подачи предложения
+	NeedsConfirmation bool `json:"needsConfirmation"`
+	// SealedContent - зашифрованное содержимое невскрытого закрытого предложения
+	SealedContent []byte `json:"-"`
+	// TenderId читается вместе с SealedContent: шифротекст привязан к тендеру
+	TenderId uuid.UUID `json:"-"`
 }
 
 type CreateOfferParam struct {
@@ -30,11 +37,26 @@ type CreateOfferParam struct {
 	AuthorType      string
 	AuthorId        string
 	Organization_id string
+	SealedContent   []byte
+	// Lots - цены по лотам многолотового тендера
+	Lots []OfferLotParams
 }
 
 func (q *Queries) CreateOffer(ctx context.Context, param CreateOfferParam) (*Offer, error) {
-	sqlquery := `INSERT INTO offer (name, description, tender_id, author_type, creator_id,organization_id)
-	VALUES ($1,$2,$3,$4,$5) RETURNING id, name, status, author_type, creator_id, version, created_at`
+	sqlquery := `WITH o AS (
+	    INSERT INTO offer (name, description, tender_id, author_type, creator_id, organization_id, sealed_content)
+	    VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id, name, status, author_type, creator_id, version, created_at
+	), l AS (
+	    INSERT INTO offer_lot (offer_id, lot_id, price)
+	    SELECT o.id, x.lot_id, x.price FROM o, unnest($8::uuid[], $9::numeric[]) AS x(lot_id, price)
+	)
+	SELECT id, name, status, author_type, creator_id, version, created_at FROM o`
+	var lot_ids []string
+	var prices []float64
+	for _, lot := range param.Lots {
+		lot_ids = append(lot_ids, lot.Lot_id)
+		prices = append(prices, lot.Price)
+	}
 	row := q.db.QueryRowContext(ctx, sqlquery,
 		param.Name,
 		param.Description,
@@ -42,6 +64,9 @@ func (q *Queries) CreateOffer(ctx context.Context, param CreateOfferParam) (*Off
 		param.AuthorType,
 		param.AuthorId,
 		param.Organization_id,
+		param.SealedContent,
+		pq.Array(lot_ids),
+		pq.Array(prices),
 	)
 	var i Offer
 	err := row.Scan(
@@ -64,7 +89,8 @@ type MyListOffersParams struct {
 }
 
 func (q *Queries) MyListOffers(ctx context.Context, params *MyListOffersParams) ([]Offer, error) {
-	sqlquery := `SELECT id, name, status, author_type, creator_id, version, created_at
+	sqlquery := `SELECT id, name, status, author_type, creator_id, version, created_at, needs_confirmation,
+	   sealed_content, tender_id
 	   FROM offer 
 	   WHERE creator_id = $1 ORDER BY name OFFSET $2 LIMIT $3`
 	rows, err := q.db.QueryContext(ctx, sqlquery, params.Creator_id, params.Offset, params.Limit)
@@ -83,6 +109,9 @@ func (q *Queries) MyListOffers(ctx context.Context, params *MyListOffersParams)
 			&i.AuthorId,
 			&i.Version,
 			&i.CreatedAt,
+			&i.NeedsConfirmation,
+			&i.SealedContent,
+			&i.TenderId,
 		); err != nil {
 			return nil, err
 		}
@@ -102,16 +131,20 @@ type TenderListOffersParams struct {
 	Offset          int32
 	Limit           int32
 	Organization_id string
+	// Sealed - предложения закрытого тендера до вскрытия видны только
+	// организации автора
+	Sealed bool
 }
 
 func (q *Queries) TenderListOffers(ctx context.Context, params *TenderListOffersParams) ([]Offer, error) {
-	sqlquery := `SELECT id, name, status, author_type, creator_id, version, created_at
+	sqlquery := `SELECT id, name, status, author_type, creator_id, version, created_at, needs_confirmation,
+	   sealed_content, tender_id
 	   FROM offer 
 	   WHERE tender_id = $1 AND (
     (organization_id = $2 AND status IN ('Approved','Created', 'Published', 'Canceled'))
-    OR (organization_id != $2 AND status = 'Published')
+    OR (organization_id != $2 AND status = 'Published' AND NOT $5)
 )  ORDER BY name OFFSET $3 LIMIT $4`
-	rows, err := q.db.QueryContext(ctx, sqlquery, params.Tender_id, params.Organization_id, params.Offset, params.Limit)
+	rows, err := q.db.QueryContext(ctx, sqlquery, params.Tender_id, params.Organization_id, params.Offset, params.Limit, params.Sealed)
 	if err != nil {
 		return nil, err
 	}
@@ -127,6 +160,9 @@ func (q *Queries) TenderListOffers(ctx context.Context, params *TenderListOffers
 			&i.AuthorId,
 			&i.Version,
 			&i.CreatedAt,
+			&i.NeedsConfirmation,
+			&i.SealedContent,
+			&i.TenderId,
 		); err != nil {
 			return nil, err
 		}
@@ -154,19 +190,19 @@ type OfferFull struct {
 	Version         int32
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
+	// NeedsConfirmation - тендер изменился после подачи предложения
+	NeedsConfirmation bool
+	SealedContent     []byte
 }
 
 func (q *Queries) GetOffer(ctx context.Context, offer_id string) (*OfferFull, error) {
 	sqlquery := `SELECT id, tender_id, creator_id, organization_id,
        author_type, status, version, name, 
-       description, created_at, updated_at FROM offer WHERE id = $1 LIMIT 1`
-	rows, err := q.db.QueryContext(ctx, sqlquery, offer_id)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+       description, created_at, updated_at, needs_confirmation, sealed_content
+	   FROM offer WHERE id = $1 LIMIT 1`
+	row := q.db.QueryRowContext(ctx, sqlquery, offer_id)
 	var offer OfferFull
-	if err := rows.Scan(
+	if err := row.Scan(
 		&offer.ID,
 		&offer.Tender_ID,
 		&offer.Creator_ID,
@@ -178,6 +214,8 @@ func (q *Queries) GetOffer(ctx context.Context, offer_id string) (*OfferFull, er
 		&offer.Description,
 		&offer.CreatedAt,
 		&offer.UpdatedAt,
+		&offer.NeedsConfirmation,
+		&offer.SealedContent,
 	); err != nil {
 		return nil, err
 	}
@@ -224,7 +262,7 @@ type OfferChangeParam struct {
 }
 
 func buildUpdateQueryOffer(param OfferChangeParam) string {
-	query := "UPDATE offer SET version = version + 1, "
+	query := "UPDATE offer SET version = version + 1, needs_confirmation = FALSE, "
 
 	var setClauses []string
 	if param.Name != "" {
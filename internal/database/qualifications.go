@@ -0,0 +1,267 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"time"
+)
+
+type Qualification struct {
+	ID                uuid.UUID
+	BuyerOrgID        uuid.UUID
+	BuyerOrgName      string
+	SupplierOrgID     uuid.UUID
+	SupplierOrgName   string
+	ServiceType       string
+	Status            string
+	Documents         string
+	Comment           string
+	ApplicantID       uuid.UUID
+	ApplicantUsername string
+	Reason            sql.NullString
+	DecidedBy         sql.NullString
+	DecidedAt         sql.NullTime
+	ExpiresAt         sql.NullTime
+	CreatedAt         time.Time
+}
+
+const qualificationSelect = `SELECT q.id, q.buyer_organization_id, b.name, q.supplier_organization_id, s.name,
+       q.service_type, q.status, q.documents::text, q.comment, q.applicant_id, a.username,
+       q.reason, d.username, q.decided_at, q.expires_at, q.created_at
+   FROM vendor_qualification q
+   JOIN organization b ON b.id = q.buyer_organization_id
+   JOIN organization s ON s.id = q.supplier_organization_id
+   JOIN employee a ON a.id = q.applicant_id
+   LEFT JOIN employee d ON d.id = q.decided_by`
+
+func scanQualification(row rowScanner) (*Qualification, error) {
+	var i Qualification
+	if err := row.Scan(
+		&i.ID,
+		&i.BuyerOrgID,
+		&i.BuyerOrgName,
+		&i.SupplierOrgID,
+		&i.SupplierOrgName,
+		&i.ServiceType,
+		&i.Status,
+		&i.Documents,
+		&i.Comment,
+		&i.ApplicantID,
+		&i.ApplicantUsername,
+		&i.Reason,
+		&i.DecidedBy,
+		&i.DecidedAt,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+func queryQualifications(ctx context.Context, db querier, sqlquery string, args ...interface{}) ([]Qualification, error) {
+	rows, err := db.QueryContext(ctx, sqlquery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Qualification
+	for rows.Next() {
+		i, err := scanQualification(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+type CreateQualificationParams struct {
+	Buyer_org_id    string
+	Supplier_org_id string
+	ServiceType     string
+	Documents       string
+	Comment         string
+	Applicant_id    string
+	Notification    string
+}
+
+// CreateQualificationTx подает заявку на квалификацию и уведомляет
+// ответственных организации заказчика
+func (q *Queries) CreateQualificationTx(ctx context.Context, params CreateQualificationParams) (*Qualification, error) {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id string
+	err = tx.QueryRowContext(ctx, `INSERT INTO vendor_qualification
+	    (buyer_organization_id, supplier_organization_id, service_type, documents, comment, applicant_id)
+	    VALUES ($1,$2,$3,$4::jsonb,$5,$6) RETURNING id::text`,
+		params.Buyer_org_id,
+		params.Supplier_org_id,
+		params.ServiceType,
+		params.Documents,
+		params.Comment,
+		params.Applicant_id,
+	).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO notification (user_id, kind, entity_type, entity_id, message)
+	    SELECT user_id, 'qualification_requested', 'vendor_qualification', $2, $3
+	    FROM organization_responsible WHERE organization_id = $1`,
+		params.Buyer_org_id, id, params.Notification)
+	if err != nil {
+		return nil, err
+	}
+	qualification, err := scanQualification(tx.QueryRowContext(ctx, qualificationSelect+" WHERE q.id = $1", id))
+	if err != nil {
+		return nil, err
+	}
+	return qualification, tx.Commit()
+}
+
+func (q *Queries) GetQualification(ctx context.Context, qualification_id string) (*Qualification, error) {
+	return scanQualification(q.db.QueryRowContext(ctx, qualificationSelect+" WHERE q.id = $1", qualification_id))
+}
+
+type ListQualificationsParams struct {
+	User_id string
+	// AsBuyer - заявки к организациям пользователя, иначе - заявки от них
+	AsBuyer bool
+	// Status - фильтр по статусу, Expired - одобренные с истекшим сроком
+	Status      string
+	ServiceType string
+	Now         time.Time
+	Offset      int32
+	Limit       int32
+}
+
+func (q *Queries) ListQualifications(ctx context.Context, params ListQualificationsParams) ([]Qualification, error) {
+	return queryQualifications(ctx, q.db, qualificationSelect+`
+	   WHERE (CASE WHEN $2 THEN q.buyer_organization_id ELSE q.supplier_organization_id END)
+	         IN (SELECT organization_id FROM organization_responsible WHERE user_id = $1)
+	   AND ($3 = '' OR (CASE WHEN q.status = 'Approved' AND q.expires_at <= $5 THEN 'Expired' ELSE q.status END) = $3)
+	   AND ($4 = '' OR q.service_type = $4)
+	   ORDER BY q.created_at DESC, q.id OFFSET $6 LIMIT $7`,
+		params.User_id,
+		params.AsBuyer,
+		params.Status,
+		params.ServiceType,
+		params.Now,
+		params.Offset,
+		params.Limit,
+	)
+}
+
+type DecideQualificationParams struct {
+	Qualification_id string
+	User_id          string
+	Status           string
+	Reason           string
+	ExpiresAt        sql.NullTime
+	Kind             string
+	Notification     string
+}
+
+// DecideQualificationTx записывает решение по заявке и уведомляет
+// заявителя. Если заявка уже рассмотрена, возвращает sql.ErrNoRows.
+func (q *Queries) DecideQualificationTx(ctx context.Context, params DecideQualificationParams) (*Qualification, error) {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var applicant_id string
+	err = tx.QueryRowContext(ctx, `UPDATE vendor_qualification SET
+	    status = $3, reason = NULLIF($4, ''), expires_at = $5,
+	    decided_by = $2, decided_at = CURRENT_TIMESTAMP
+	    WHERE id = $1 AND status = 'Pending'
+	    RETURNING applicant_id::text`,
+		params.Qualification_id,
+		params.User_id,
+		params.Status,
+		params.Reason,
+		params.ExpiresAt,
+	).Scan(&applicant_id)
+	if err != nil {
+		return nil, err
+	}
+	err = insertNotification(ctx, tx, CreateNotificationParams{
+		User_id:    applicant_id,
+		Kind:       params.Kind,
+		EntityType: "vendor_qualification",
+		EntityID:   params.Qualification_id,
+		Message:    params.Notification,
+	})
+	if err != nil {
+		return nil, err
+	}
+	qualification, err := scanQualification(tx.QueryRowContext(ctx, qualificationSelect+" WHERE q.id = $1", params.Qualification_id))
+	if err != nil {
+		return nil, err
+	}
+	return qualification, tx.Commit()
+}
+
+// RevokeQualification отзывает одобренную квалификацию, sql.ErrNoRows -
+// квалификация не одобрена
+func (q *Queries) RevokeQualification(ctx context.Context, qualification_id, user_id, reason string) (*Qualification, error) {
+	res, err := q.db.ExecContext(ctx, `UPDATE vendor_qualification SET
+	    status = 'Revoked', reason = $3, decided_by = $2, decided_at = CURRENT_TIMESTAMP
+	    WHERE id = $1 AND status = 'Approved'`, qualification_id, user_id, reason)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return q.GetQualification(ctx, qualification_id)
+}
+
+type ListVendorsParams struct {
+	Buyer_org_id string
+	// ServiceTypes - фильтр по видам услуг, пустой - все
+	ServiceTypes []string
+	Now          time.Time
+}
+
+// ListVendors - список допущенных поставщиков: одобренные квалификации,
+// срок которых не истек
+func (q *Queries) ListVendors(ctx context.Context, params ListVendorsParams) ([]Qualification, error) {
+	return queryQualifications(ctx, q.db, qualificationSelect+`
+	   WHERE q.buyer_organization_id = $1 AND q.status = 'Approved'
+	   AND (q.expires_at IS NULL OR q.expires_at > $3)
+	   AND (COALESCE(cardinality($2::varchar[]), 0) = 0 OR q.service_type = ANY($2))
+	   ORDER BY s.name, q.service_type`,
+		params.Buyer_org_id, pq.Array(params.ServiceTypes), params.Now)
+}
+
+// LatestQualification возвращает последнюю одобренную квалификацию
+// поставщика по виду услуг или по одной из его родительских категорий
+// вместе с истекшими. sql.ErrNoRows - квалификации нет.
+func (q *Queries) LatestQualification(ctx context.Context, buyer_org_id, supplier_org_id, service_type string) (*Qualification, error) {
+	sqlquery := `WITH RECURSIVE ancestors AS (
+	       SELECT code, parent_code FROM service_type WHERE code = $3
+	       UNION
+	       SELECT st.code, st.parent_code FROM service_type st JOIN ancestors a ON st.code = a.parent_code
+	   )
+	` + qualificationSelect + `
+	   WHERE q.buyer_organization_id = $1 AND q.supplier_organization_id = $2 AND q.status = 'Approved'
+	   AND q.service_type IN (SELECT code FROM ancestors)
+	   ORDER BY q.expires_at DESC NULLS FIRST LIMIT 1`
+	return scanQualification(q.db.QueryRowContext(ctx, sqlquery, buyer_org_id, supplier_org_id, service_type))
+}
@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"time"
+)
+
+type TenderQuestion struct {
+	ID             uuid.UUID
+	TenderID       uuid.UUID
+	AuthorID       uuid.UUID
+	AuthorUsername string
+	Question       string
+	Answer         string
+	AnsweredBy     string
+	Visibility     string
+	CreatedAt      time.Time
+	AnsweredAt     sql.NullTime
+}
+
+const questionColumns = `q.id, q.tender_id, q.author_id, e.username, q.question, COALESCE(q.answer, ''),
+       COALESCE(q.answered_by::text, ''), COALESCE(q.visibility, ''), q.created_at, q.answered_at`
+
+func scanQuestion(row rowScanner) (*TenderQuestion, error) {
+	var i TenderQuestion
+	if err := row.Scan(
+		&i.ID,
+		&i.TenderID,
+		&i.AuthorID,
+		&i.AuthorUsername,
+		&i.Question,
+		&i.Answer,
+		&i.AnsweredBy,
+		&i.Visibility,
+		&i.CreatedAt,
+		&i.AnsweredAt,
+	); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+func (q *Queries) CreateQuestion(ctx context.Context, tender_id, author_id, question string) (*TenderQuestion, error) {
+	sqlquery := `WITH q AS (
+	       INSERT INTO tender_question (tender_id, author_id, question) VALUES ($1, $2, $3) RETURNING *
+	   )
+	   SELECT ` + questionColumns + ` FROM q JOIN employee e ON e.id = q.author_id`
+	row := q.db.QueryRowContext(ctx, sqlquery, tender_id, author_id, question)
+	return scanQuestion(row)
+}
+
+func (q *Queries) GetQuestion(ctx context.Context, question_id string) (*TenderQuestion, error) {
+	sqlquery := "SELECT " + questionColumns + ` FROM tender_question q JOIN employee e ON e.id = q.author_id
+	   WHERE q.id = $1 LIMIT 1`
+	row := q.db.QueryRowContext(ctx, sqlquery, question_id)
+	return scanQuestion(row)
+}
+
+type AnswerQuestionParams struct {
+	Question_id string
+	User_id     string
+	Answer      string
+	Visibility  string
+}
+
+func (q *Queries) AnswerQuestion(ctx context.Context, params AnswerQuestionParams) (*TenderQuestion, error) {
+	sqlquery := `WITH q AS (
+	       UPDATE tender_question SET
+	           answer = $2,
+	           answered_by = $3,
+	           visibility = $4,
+	           answered_at = CURRENT_TIMESTAMP
+	       WHERE id = $1 RETURNING *
+	   )
+	   SELECT ` + questionColumns + ` FROM q JOIN employee e ON e.id = q.author_id`
+	row := q.db.QueryRowContext(ctx, sqlquery, params.Question_id, params.Answer, params.User_id, params.Visibility)
+	return scanQuestion(row)
+}
+
+type ListQuestionsParams struct {
+	Tender_id string
+	// All - показывать все вопросы (для ответственных). Иначе только
+	// публичные ответы и собственные вопросы Viewer_id.
+	All       bool
+	Viewer_id string
+	Offset    int32
+	Limit     int32
+}
+
+func (q *Queries) ListQuestions(ctx context.Context, params ListQuestionsParams) ([]TenderQuestion, error) {
+	sqlquery := "SELECT " + questionColumns + ` FROM tender_question q JOIN employee e ON e.id = q.author_id
+	   WHERE q.tender_id = $1 AND ($2 OR q.visibility = 'public' OR q.author_id::text = $3)
+	   ORDER BY q.created_at, q.id OFFSET $4 LIMIT $5`
+	rows, err := q.db.QueryContext(ctx, sqlquery, params.Tender_id, params.All, params.Viewer_id, params.Offset, params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TenderQuestion
+	for rows.Next() {
+		i, err := scanQuestion(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -1,49 +1,356 @@
 package database
 
-import "context"
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"strings"
+	"time"
+)
 
-type NewReviewParams struct {
-	Offer_id string
-	Content  string
-	User_id  string
+// Ratings - оценки отзыва, nil у отзывов без оценок
+type Ratings struct {
+	Quality       int16
+	Timeliness    int16
+	Communication int16
 }
 
-func (q *Queries) NewReview(ctx context.Context, params NewReviewParams) error {
-	sqlquery := `INSERT INTO review (creator_id, offer_id, content) VALUES ($1,$2,$3)`
-	_, err := q.db.ExecContext(ctx, sqlquery, params.User_id, params.Offer_id, params.Content)
-	return err
+func ratingArgs(r *Ratings) (sql.NullInt16, sql.NullInt16, sql.NullInt16) {
+	if r == nil {
+		return sql.NullInt16{}, sql.NullInt16{}, sql.NullInt16{}
+	}
+	return sql.NullInt16{Int16: r.Quality, Valid: true},
+		sql.NullInt16{Int16: r.Timeliness, Valid: true},
+		sql.NullInt16{Int16: r.Communication, Valid: true}
 }
 
-type ListReviewParam struct {
-	Offer_id string
-	Limit    int32
-	Offset   int32
+type NewReviewParams struct {
+	Offer_id        string
+	Content         string
+	User_id         string
+	Organization_id string
+	Ratings         *Ratings
+	// Author_id - автор предложения, получает уведомление об отзыве
+	Author_id    string
+	Notification string
+}
+
+// NewReview создает отзыв и возвращает его id
+func (q *Queries) NewReview(ctx context.Context, params NewReviewParams) (string, error) {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	quality, timeliness, communication := ratingArgs(params.Ratings)
+	var id string
+	sqlquery := `INSERT INTO review (creator_id, offer_id, content, organization_id, quality, timeliness, communication)
+	    VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id::text`
+	err = tx.QueryRowContext(ctx, sqlquery, params.User_id, params.Offer_id, params.Content,
+		params.Organization_id, quality, timeliness, communication).Scan(&id)
+	if err != nil {
+		return "", err
+	}
+	if params.Author_id != "" {
+		err = insertNotification(ctx, tx, CreateNotificationParams{
+			User_id:    params.Author_id,
+			Kind:       "review_received",
+			EntityType: "review",
+			EntityID:   id,
+			Message:    params.Notification,
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return id, tx.Commit()
 }
 
 type Review struct {
-	ID          string
-	Description string
-	CreatedAt   string
+	ID               string
+	OfferID          uuid.UUID
+	Description      string
+	CreatorID        uuid.UUID
+	CreatorUsername  string
+	OrganizationID   uuid.NullUUID
+	OrganizationName sql.NullString
+	Quality          sql.NullInt16
+	Timeliness       sql.NullInt16
+	Communication    sql.NullInt16
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	// автор и организация предложения, на которое оставлен отзыв
+	OfferAuthorID string
+	OfferOrgID    string
+	TenderID      string
+	TenderName    string
+	// ответ поставщика
+	ReplyID        sql.NullString
+	ReplyContent   sql.NullString
+	ReplyAuthor    sql.NullString
+	ReplyCreatedAt sql.NullTime
+	// спор по отзыву
+	DisputeStatus     sql.NullString
+	DisputeReason     sql.NullString
+	DisputedBy        sql.NullString
+	DisputedAt        sql.NullTime
+	ModeratedBy       sql.NullString
+	ModeratedAt       sql.NullTime
+	ModerationComment sql.NullString
 }
 
-func (q *Queries) ListReviw(ctx context.Context, params ListReviewParam) ([]Review, error) {
-	sqlquery := `SELECT id, content, created_at FROM review WHERE offer_id = $1 LIMIT $2 OFFSET $3`
-	rows, err := q.db.QueryContext(ctx, sqlquery, params.Offer_id, params.Offset, params.Limit)
+const reviewSelect = `SELECT r.id, r.offer_id, COALESCE(r.content, ''), r.creator_id, COALESCE(e.username, ''),
+       r.organization_id, o.name, r.quality, r.timeliness, r.communication, r.created_at, r.updated_at,
+       COALESCE(b.creator_id::text, ''), COALESCE(b.organization_id::text, ''),
+       COALESCE(b.tender_id::text, ''), COALESCE(t.name, ''),
+       rr.id::text, rr.content, re.username, rr.created_at,
+       r.dispute_status, r.dispute_reason, de.username, r.disputed_at,
+       me.username, r.moderated_at, r.moderation_comment
+	   FROM review r
+	   JOIN offer b ON b.id = r.offer_id
+	   LEFT JOIN tender t ON t.id = b.tender_id
+	   LEFT JOIN employee e ON e.id = r.creator_id
+	   LEFT JOIN organization o ON o.id = r.organization_id
+	   LEFT JOIN review_reply rr ON rr.review_id = r.id
+	   LEFT JOIN employee re ON re.id = rr.author_id
+	   LEFT JOIN employee de ON de.id = r.disputed_by
+	   LEFT JOIN employee me ON me.id = r.moderated_by`
+
+func scanReview(row rowScanner) (*Review, error) {
+	var i Review
+	if err := row.Scan(
+		&i.ID,
+		&i.OfferID,
+		&i.Description,
+		&i.CreatorID,
+		&i.CreatorUsername,
+		&i.OrganizationID,
+		&i.OrganizationName,
+		&i.Quality,
+		&i.Timeliness,
+		&i.Communication,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OfferAuthorID,
+		&i.OfferOrgID,
+		&i.TenderID,
+		&i.TenderName,
+		&i.ReplyID,
+		&i.ReplyContent,
+		&i.ReplyAuthor,
+		&i.ReplyCreatedAt,
+		&i.DisputeStatus,
+		&i.DisputeReason,
+		&i.DisputedBy,
+		&i.DisputedAt,
+		&i.ModeratedBy,
+		&i.ModeratedAt,
+		&i.ModerationComment,
+	); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+type ListAuthorReviewsParams struct {
+	Author_id string
+	// Organization_id - если задан, отзывы на предложения всех авторов
+	// этой организации вместо одного автора
+	Organization_id string
+	// Reviewer_org_id - фильтр по организации, оставившей отзыв
+	Reviewer_org_id string
+	From            *time.Time
+	To              *time.Time
+	// MinRating, MaxRating - фильтр по средней оценке, 0 - без фильтра.
+	// Отзывы без оценок при фильтре не попадают в выборку.
+	MinRating int16
+	MaxRating int16
+	Offset    int32
+	Limit     int32
+}
+
+// ListAuthorReviews - отзывы на все предложения автора или организации
+// по всем тендерам, без скрытых модератором
+func (q *Queries) ListAuthorReviews(ctx context.Context, params ListAuthorReviewsParams) ([]Review, error) {
+	where := []string{"r.dispute_status IS DISTINCT FROM 'Upheld'"}
+	var args []interface{}
+	addFilter := func(clause string, value interface{}) {
+		args = append(args, value)
+		where = append(where, fmt.Sprintf(clause, len(args)))
+	}
+	if params.Organization_id != "" {
+		addFilter("b.organization_id = $%d", params.Organization_id)
+	} else {
+		addFilter("b.creator_id = $%d", params.Author_id)
+	}
+	if params.Reviewer_org_id != "" {
+		addFilter("r.organization_id = $%d", params.Reviewer_org_id)
+	}
+	if params.From != nil {
+		addFilter("r.created_at >= $%d", params.From.UTC())
+	}
+	if params.To != nil {
+		addFilter("r.created_at < $%d", params.To.UTC())
+	}
+	if params.MinRating > 0 {
+		addFilter("(r.quality + r.timeliness + r.communication) / 3.0 >= $%d", params.MinRating)
+	}
+	if params.MaxRating > 0 {
+		addFilter("(r.quality + r.timeliness + r.communication) / 3.0 <= $%d", params.MaxRating)
+	}
+	args = append(args, params.Offset, params.Limit)
+	sqlquery := reviewSelect + " WHERE " + strings.Join(where, " AND ") +
+		fmt.Sprintf(" ORDER BY r.created_at DESC, r.id OFFSET $%d LIMIT $%d", len(args)-1, len(args))
+	return queryReviews(ctx, q.db, sqlquery, args...)
+}
+
+func queryReviews(ctx context.Context, db querier, sqlquery string, args ...interface{}) ([]Review, error) {
+	rows, err := db.QueryContext(ctx, sqlquery, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var items []Review
 	for rows.Next() {
-		var i Review
+		i, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (q *Queries) GetReview(ctx context.Context, review_id string) (*Review, error) {
+	return scanReview(q.db.QueryRowContext(ctx, reviewSelect+` WHERE r.id = $1`, review_id))
+}
+
+type EditReviewParams struct {
+	Review_id string
+	Content   string
+	Ratings   *Ratings
+	// Window - сколько после создания отзыв можно изменить
+	Window time.Duration
+}
+
+// EditReview - sql.ErrNoRows, если окно редактирования закрылось,
+// на отзыв ответили или открыли по нему спор
+func (q *Queries) EditReview(ctx context.Context, params EditReviewParams) (*Review, error) {
+	quality, timeliness, communication := ratingArgs(params.Ratings)
+	res, err := q.db.ExecContext(ctx, `UPDATE review SET content = $2,
+	    quality = $3, timeliness = $4, communication = $5, updated_at = CURRENT_TIMESTAMP
+	    WHERE id = $1 AND created_at > CURRENT_TIMESTAMP - make_interval(secs => $6)
+	    AND dispute_status IS NULL AND NOT EXISTS (SELECT 1 FROM review_reply WHERE review_id = $1)`,
+		params.Review_id, params.Content, quality, timeliness, communication, params.Window.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return q.GetReview(ctx, params.Review_id)
+}
+
+// DeleteReview - sql.ErrNoRows на тех же условиях, что и EditReview
+func (q *Queries) DeleteReview(ctx context.Context, review_id string, window time.Duration) error {
+	res, err := q.db.ExecContext(ctx, `DELETE FROM review
+	    WHERE id = $1 AND created_at > CURRENT_TIMESTAMP - make_interval(secs => $2)
+	    AND dispute_status IS NULL AND NOT EXISTS (SELECT 1 FROM review_reply WHERE review_id = $1)`, review_id, window.Seconds())
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Reputation - средние оценки по отзывам на предложения организации,
+// отзывы без оценок не учитываются
+type Reputation struct {
+	OrganizationID   uuid.UUID
+	OrganizationName string
+	Reviews          int32
+	Score            sql.NullFloat64
+	Quality          sql.NullFloat64
+	Timeliness       sql.NullFloat64
+	Communication    sql.NullFloat64
+}
+
+const reputationColumns = `COUNT(r.id),
+       ROUND(AVG((r.quality + r.timeliness + r.communication) / 3.0), 2)::float8,
+       ROUND(AVG(r.quality), 2)::float8, ROUND(AVG(r.timeliness), 2)::float8,
+       ROUND(AVG(r.communication), 2)::float8`
+
+// GetReputation - sql.ErrNoRows, если организации нет
+func (q *Queries) GetReputation(ctx context.Context, org_id string) (*Reputation, error) {
+	sqlquery := `SELECT org.id, org.name, ` + reputationColumns + `
+	   FROM organization org
+	   LEFT JOIN offer o ON o.organization_id = org.id
+	   LEFT JOIN review r ON r.offer_id = o.id AND r.quality IS NOT NULL
+	       AND r.dispute_status IS DISTINCT FROM 'Upheld'
+	   WHERE org.id = $1 GROUP BY org.id, org.name`
+	var i Reputation
+	err := q.db.QueryRowContext(ctx, sqlquery, org_id).Scan(
+		&i.OrganizationID,
+		&i.OrganizationName,
+		&i.Reviews,
+		&i.Score,
+		&i.Quality,
+		&i.Timeliness,
+		&i.Communication,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// ListOfferReputations - репутация организаций-авторов предложений,
+// ключ - id предложения
+func (q *Queries) ListOfferReputations(ctx context.Context, offer_ids []string) (map[string]Reputation, error) {
+	sqlquery := `SELECT b.id::text, org.id, org.name, ` + reputationColumns + `
+	   FROM offer b
+	   JOIN organization org ON org.id = b.organization_id
+	   LEFT JOIN offer o ON o.organization_id = org.id
+	   LEFT JOIN review r ON r.offer_id = o.id AND r.quality IS NOT NULL
+	       AND r.dispute_status IS DISTINCT FROM 'Upheld'
+	   WHERE b.id = ANY($1::uuid[]) GROUP BY b.id, org.id, org.name`
+	rows, err := q.db.QueryContext(ctx, sqlquery, pq.Array(offer_ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := map[string]Reputation{}
+	for rows.Next() {
+		var offer_id string
+		var i Reputation
 		if err := rows.Scan(
-			&i.ID,
-			&i.Description,
-			&i.CreatedAt,
+			&offer_id,
+			&i.OrganizationID,
+			&i.OrganizationName,
+			&i.Reviews,
+			&i.Score,
+			&i.Quality,
+			&i.Timeliness,
+			&i.Communication,
 		); err != nil {
 			return nil, err
 		}
-		items = append(items, i)
+		items[offer_id] = i
 	}
 	if err := rows.Close(); err != nil {
 		return nil, err
@@ -53,3 +360,166 @@ func (q *Queries) ListReviw(ctx context.Context, params ListReviewParam) ([]Revi
 	}
 	return items, nil
 }
+
+type ListReceivedReviewsParams struct {
+	User_id string
+	// Offer_id - фильтр по предложению, пустой - все
+	Offer_id string
+	Offset   int32
+	Limit    int32
+}
+
+// ListReceivedReviews - отзывы на предложения пользователя и организаций,
+// в которых он ответственный, включая скрытые модератором
+func (q *Queries) ListReceivedReviews(ctx context.Context, params ListReceivedReviewsParams) ([]Review, error) {
+	return queryReviews(ctx, q.db, reviewSelect+`
+	   WHERE (b.creator_id = $1 OR b.organization_id IN
+	         (SELECT organization_id FROM organization_responsible WHERE user_id = $1))
+	   AND ($2 = '' OR r.offer_id::text = $2)
+	   ORDER BY r.created_at DESC, r.id OFFSET $3 LIMIT $4`,
+		params.User_id, params.Offer_id, params.Offset, params.Limit)
+}
+
+type CreateReplyParams struct {
+	Review_id       string
+	User_id         string
+	Organization_id string
+	Content         string
+	// Reviewer_id - автор отзыва, получает уведомление об ответе
+	Reviewer_id  string
+	Notification string
+}
+
+// CreateReplyTx сохраняет ответ на отзыв. Повторный ответ нарушает
+// уникальность review_id (23505).
+func (q *Queries) CreateReplyTx(ctx context.Context, params CreateReplyParams) (*Review, error) {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO review_reply (review_id, author_id, organization_id, content)
+	    VALUES ($1,$2,NULLIF($3, '')::uuid,$4)`,
+		params.Review_id, params.User_id, params.Organization_id, params.Content)
+	if err != nil {
+		return nil, err
+	}
+	err = insertNotification(ctx, tx, CreateNotificationParams{
+		User_id:    params.Reviewer_id,
+		Kind:       "review_reply",
+		EntityType: "review",
+		EntityID:   params.Review_id,
+		Message:    params.Notification,
+	})
+	if err != nil {
+		return nil, err
+	}
+	review, err := scanReview(tx.QueryRowContext(ctx, reviewSelect+` WHERE r.id = $1`, params.Review_id))
+	if err != nil {
+		return nil, err
+	}
+	return review, tx.Commit()
+}
+
+type DisputeReviewParams struct {
+	Review_id    string
+	User_id      string
+	Reason       string
+	Notification string
+}
+
+// DisputeReviewTx открывает спор и уведомляет администраторов.
+// sql.ErrNoRows - спор по отзыву уже открывали.
+func (q *Queries) DisputeReviewTx(ctx context.Context, params DisputeReviewParams) (*Review, error) {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE review SET dispute_status = 'Open', dispute_reason = $3,
+	    disputed_by = $2, disputed_at = CURRENT_TIMESTAMP
+	    WHERE id = $1 AND dispute_status IS NULL`, params.Review_id, params.User_id, params.Reason)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, sql.ErrNoRows
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO notification (user_id, kind, entity_type, entity_id, message)
+	    SELECT id, 'review_disputed', 'review', $1, $2 FROM employee WHERE is_admin AND is_active`,
+		params.Review_id, params.Notification)
+	if err != nil {
+		return nil, err
+	}
+	review, err := scanReview(tx.QueryRowContext(ctx, reviewSelect+` WHERE r.id = $1`, params.Review_id))
+	if err != nil {
+		return nil, err
+	}
+	return review, tx.Commit()
+}
+
+type ListDisputesParams struct {
+	Status string
+	Offset int32
+	Limit  int32
+}
+
+// ListDisputes - очередь модерации, старые споры первыми
+func (q *Queries) ListDisputes(ctx context.Context, params ListDisputesParams) ([]Review, error) {
+	return queryReviews(ctx, q.db, reviewSelect+` WHERE r.dispute_status = $1
+	   ORDER BY r.disputed_at, r.id OFFSET $2 LIMIT $3`, params.Status, params.Offset, params.Limit)
+}
+
+type ModerateReviewParams struct {
+	Review_id    string
+	User_id      string
+	Status       string
+	Comment      string
+	Notification string
+}
+
+// ModerateReviewTx закрывает спор и уведомляет автора отзыва и того,
+// кто открыл спор. sql.ErrNoRows - открытого спора нет.
+func (q *Queries) ModerateReviewTx(ctx context.Context, params ModerateReviewParams) (*Review, error) {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var reviewer_id, disputed_by string
+	err = tx.QueryRowContext(ctx, `UPDATE review SET dispute_status = $3, moderation_comment = NULLIF($4, ''),
+	    moderated_by = $2, moderated_at = CURRENT_TIMESTAMP
+	    WHERE id = $1 AND dispute_status = 'Open'
+	    RETURNING creator_id::text, disputed_by::text`,
+		params.Review_id, params.User_id, params.Status, params.Comment,
+	).Scan(&reviewer_id, &disputed_by)
+	if err != nil {
+		return nil, err
+	}
+	recipients := []string{reviewer_id}
+	if disputed_by != reviewer_id {
+		recipients = append(recipients, disputed_by)
+	}
+	for _, user_id := range recipients {
+		err = insertNotification(ctx, tx, CreateNotificationParams{
+			User_id:    user_id,
+			Kind:       "review_moderated",
+			EntityType: "review",
+			EntityID:   params.Review_id,
+			Message:    params.Notification,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	review, err := scanReview(tx.QueryRowContext(ctx, reviewSelect+` WHERE r.id = $1`, params.Review_id))
+	if err != nil {
+		return nil, err
+	}
+	return review, tx.Commit()
+}
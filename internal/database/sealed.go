@@ -0,0 +1,140 @@
+package database
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"time"
+)
+
+// ResealOffer заменяет содержимое невскрытого закрытого предложения
+func (q *Queries) ResealOffer(ctx context.Context, offer_id string, sealed []byte) (*Offer, error) {
+	sqlquery := `UPDATE offer SET
+                  sealed_content = $2,
+                  version = version + 1,
+                  needs_confirmation = FALSE,
+                  updated_at = CURRENT_TIMESTAMP
+                  WHERE id = $1 AND sealed_content IS NOT NULL
+                  RETURNING id, name, status, author_type, creator_id, version, created_at, needs_confirmation, sealed_content, tender_id`
+	var i Offer
+	err := q.db.QueryRowContext(ctx, sqlquery, offer_id, sealed).Scan(
+		&i.ID,
+		&i.Name,
+		&i.Status,
+		&i.AuthorType,
+		&i.AuthorId,
+		&i.Version,
+		&i.CreatedAt,
+		&i.NeedsConfirmation,
+		&i.SealedContent,
+		&i.TenderId,
+	)
+	return &i, err
+}
+
+type BidOpening struct {
+	ID       uuid.UUID
+	TenderID uuid.UUID
+	// OpenedBy - имя пользователя, вскрывшего предложения
+	OpenedBy string
+	BidCount int32
+	OpenedAt time.Time
+}
+
+// OpenedContent - расшифрованное содержимое предложения
+type OpenedContent struct {
+	Name        string
+	Description string
+}
+
+// SealedOffer - невскрытое предложение, которое передается в функцию
+// расшифровки при вскрытии
+type SealedOffer struct {
+	ID       string
+	AuthorID string
+	Content  []byte
+}
+
+// OpenSealedOffersTx вскрывает предложения тендера в одной транзакции:
+// отмечает тендер вскрытым, переносит расшифрованное содержимое в открытые
+// колонки и записывает событие вскрытия. Если тендер уже вскрыт,
+// возвращает sql.ErrNoRows.
+func (q *Queries) OpenSealedOffersTx(ctx context.Context, tender_id, user_id string, open func(SealedOffer) (OpenedContent, error)) (*BidOpening, error) {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var opened_at time.Time
+	err = tx.QueryRowContext(ctx, `UPDATE tender SET bids_opened_at = CURRENT_TIMESTAMP
+	   WHERE id = $1 AND bids_opened_at IS NULL RETURNING bids_opened_at`, tender_id).Scan(&opened_at)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, creator_id, sealed_content FROM offer
+	   WHERE tender_id = $1 AND sealed_content IS NOT NULL FOR UPDATE`, tender_id)
+	if err != nil {
+		return nil, err
+	}
+	var sealed []SealedOffer
+	for rows.Next() {
+		var i SealedOffer
+		if err := rows.Scan(&i.ID, &i.AuthorID, &i.Content); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		sealed = append(sealed, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, offer := range sealed {
+		content, err := open(offer)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE offer SET name = $2, description = $3, sealed_content = NULL
+		   WHERE id = $1`, offer.ID, content.Name, content.Description)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var i BidOpening
+	err = tx.QueryRowContext(ctx, `WITH b AS (
+	   INSERT INTO bid_opening (tender_id, opened_by, bid_count, opened_at)
+	   VALUES ($1,$2,$3,$4) RETURNING id, tender_id, opened_by, bid_count, opened_at
+	)
+	SELECT b.id, b.tender_id, e.username, b.bid_count, b.opened_at
+	   FROM b JOIN employee e ON e.id = b.opened_by`,
+		tender_id, user_id, len(sealed), opened_at,
+	).Scan(&i.ID, &i.TenderID, &i.OpenedBy, &i.BidCount, &i.OpenedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &i, tx.Commit()
+}
+
+// GetBidOpening возвращает событие вскрытия вместе с именем того, кто вскрыл
+func (q *Queries) GetBidOpening(ctx context.Context, tender_id string) (*BidOpening, error) {
+	sqlquery := `SELECT b.id, b.tender_id, e.username, b.bid_count, b.opened_at
+	   FROM bid_opening b JOIN employee e ON e.id = b.opened_by
+	   WHERE b.tender_id = $1`
+	var i BidOpening
+	err := q.db.QueryRowContext(ctx, sqlquery, tender_id).Scan(
+		&i.ID,
+		&i.TenderID,
+		&i.OpenedBy,
+		&i.BidCount,
+		&i.OpenedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 )
 
+// DBTX - соединение с базой, которое оборачивает Queries
 type DBTX interface {
 	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
 	PrepareContext(context.Context, string) (*sql.Stmt, error)
@@ -14,11 +15,11 @@ type DBTX interface {
 }
 
 func NewService(db DBTX) *Queries {
-	return &Queries{db: db}
+	return &Queries{db: newInstrumentedDB(db)}
 }
 
 type Queries struct {
-	db DBTX
+	db *instrumentedDB
 }
 
 //func (q *Queries) WithTx(tx *sql.Tx) *Queries {
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"github.com/lib/pq"
+	"strings"
+	"time"
+)
+
+type ServiceType struct {
+	Code       string
+	ParentCode string
+	Names      string
+	IsActive   bool
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+const serviceTypeColumns = `code, COALESCE(parent_code, ''), names::text, is_active, created_at, updated_at`
+
+func scanServiceType(row rowScanner) (*ServiceType, error) {
+	var i ServiceType
+	if err := row.Scan(
+		&i.Code,
+		&i.ParentCode,
+		&i.Names,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// GetServiceType ищет тип по коду без учета регистра
+func (q *Queries) GetServiceType(ctx context.Context, code string) (*ServiceType, error) {
+	sqlquery := "SELECT " + serviceTypeColumns + " FROM service_type WHERE lower(code) = lower($1) LIMIT 1"
+	row := q.db.QueryRowContext(ctx, sqlquery, code)
+	return scanServiceType(row)
+}
+
+type ListServiceTypesParams struct {
+	// Parent - только прямые потомки этого типа, пустая строка - все типы
+	Parent          string
+	IncludeInactive bool
+}
+
+func (q *Queries) ListServiceTypes(ctx context.Context, params ListServiceTypesParams) ([]ServiceType, error) {
+	sqlquery := "SELECT " + serviceTypeColumns + ` FROM service_type
+	   WHERE ($1 = '' OR lower(parent_code) = lower($1)) AND ($2 OR is_active)
+	   ORDER BY code`
+	rows, err := q.db.QueryContext(ctx, sqlquery, params.Parent, params.IncludeInactive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ServiceType
+	for rows.Next() {
+		i, err := scanServiceType(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+type ServiceTypeParams struct {
+	Code       string
+	ParentCode string
+	Names      string
+	IsActive   bool
+}
+
+func (q *Queries) CreateServiceType(ctx context.Context, params ServiceTypeParams) (*ServiceType, error) {
+	sqlquery := `INSERT INTO service_type (code, parent_code, names, is_active)
+	VALUES ($1, NULLIF($2, ''), $3::json, $4) RETURNING ` + serviceTypeColumns
+	row := q.db.QueryRowContext(ctx, sqlquery, params.Code, params.ParentCode, params.Names, params.IsActive)
+	return scanServiceType(row)
+}
+
+// UpdateServiceType меняет родителя, названия и активность. Код не меняется,
+// на него ссылаются тендеры.
+func (q *Queries) UpdateServiceType(ctx context.Context, params ServiceTypeParams) (*ServiceType, error) {
+	sqlquery := `UPDATE service_type SET
+                  parent_code = NULLIF($2, ''),
+                  names = $3::json,
+                  is_active = $4,
+                  updated_at = CURRENT_TIMESTAMP
+                  WHERE code = $1
+                  RETURNING ` + serviceTypeColumns
+	row := q.db.QueryRowContext(ctx, sqlquery, params.Code, params.ParentCode, params.Names, params.IsActive)
+	return scanServiceType(row)
+}
+
+// ExpandServiceTypes возвращает коды типов вместе со всеми их потомками.
+// Сравнение без учета регистра, неизвестные коды пропускаются.
+func (q *Queries) ExpandServiceTypes(ctx context.Context, codes []string) ([]string, error) {
+	sqlquery := `WITH RECURSIVE tree AS (
+	       SELECT code FROM service_type WHERE lower(code) = ANY($1)
+	       UNION
+	       SELECT st.code FROM service_type st JOIN tree ON st.parent_code = tree.code
+	   )
+	   SELECT code FROM tree ORDER BY code`
+	lower := make([]string, len(codes))
+	for n, code := range codes {
+		lower[n] = strings.ToLower(code)
+	}
+	rows, err := q.db.QueryContext(ctx, sqlquery, pq.Array(lower))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		items = append(items, code)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
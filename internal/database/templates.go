@@ -0,0 +1,221 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"time"
+)
+
+type TenderTemplate struct {
+	ID                 uuid.UUID
+	OrganizationID     uuid.UUID
+	CreatorID          uuid.UUID
+	Name               string
+	Description        string
+	ServiceType        string
+	EvaluationCriteria string
+	SubmissionDays     sql.NullInt32
+	DecisionDays       sql.NullInt32
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+const templateColumns = `id, organization_id, creator_id, name, description, service_type,
+       COALESCE(evaluation_criteria::text, ''), submission_days, decision_days, created_at, updated_at`
+
+func scanTemplate(row rowScanner) (*TenderTemplate, error) {
+	var i TenderTemplate
+	if err := row.Scan(
+		&i.ID,
+		&i.OrganizationID,
+		&i.CreatorID,
+		&i.Name,
+		&i.Description,
+		&i.ServiceType,
+		&i.EvaluationCriteria,
+		&i.SubmissionDays,
+		&i.DecisionDays,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+type TemplateParams struct {
+	OrganizationID     string
+	CreatorID          string
+	Name               string
+	Description        string
+	ServiceType        string
+	EvaluationCriteria string
+	SubmissionDays     sql.NullInt32
+	DecisionDays       sql.NullInt32
+}
+
+func (q *Queries) CreateTemplate(ctx context.Context, params TemplateParams) (*TenderTemplate, error) {
+	sqlquery := `INSERT INTO tender_template (organization_id, creator_id, name, description, service_type,
+	evaluation_criteria, submission_days, decision_days)
+	VALUES ($1,$2,$3,$4,$5,NULLIF($6, '')::json,$7,$8) RETURNING ` + templateColumns
+	row := q.db.QueryRowContext(ctx, sqlquery,
+		params.OrganizationID,
+		params.CreatorID,
+		params.Name,
+		params.Description,
+		params.ServiceType,
+		params.EvaluationCriteria,
+		params.SubmissionDays,
+		params.DecisionDays,
+	)
+	return scanTemplate(row)
+}
+
+func (q *Queries) GetTemplate(ctx context.Context, template_id string) (*TenderTemplate, error) {
+	sqlquery := "SELECT " + templateColumns + " FROM tender_template WHERE id = $1 LIMIT 1"
+	row := q.db.QueryRowContext(ctx, sqlquery, template_id)
+	return scanTemplate(row)
+}
+
+type ListTemplatesParams struct {
+	Organization_id string
+	Offset          int32
+	Limit           int32
+}
+
+func (q *Queries) ListTemplates(ctx context.Context, params ListTemplatesParams) ([]TenderTemplate, error) {
+	sqlquery := "SELECT " + templateColumns + ` FROM tender_template
+	   WHERE organization_id = $1 ORDER BY name OFFSET $2 LIMIT $3`
+	rows, err := q.db.QueryContext(ctx, sqlquery, params.Organization_id, params.Offset, params.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []TenderTemplate
+	for rows.Next() {
+		i, err := scanTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// UpdateTemplate перезаписывает все поля шаблона, кроме организации и автора
+func (q *Queries) UpdateTemplate(ctx context.Context, template_id string, params TemplateParams) (*TenderTemplate, error) {
+	sqlquery := `UPDATE tender_template SET
+                  name = $2,
+                  description = $3,
+                  service_type = $4,
+                  evaluation_criteria = NULLIF($5, '')::json,
+                  submission_days = $6,
+                  decision_days = $7,
+                  updated_at = CURRENT_TIMESTAMP
+                  WHERE id = $1
+                  RETURNING ` + templateColumns
+	row := q.db.QueryRowContext(ctx, sqlquery,
+		template_id,
+		params.Name,
+		params.Description,
+		params.ServiceType,
+		params.EvaluationCriteria,
+		params.SubmissionDays,
+		params.DecisionDays,
+	)
+	return scanTemplate(row)
+}
+
+func (q *Queries) DeleteTemplate(ctx context.Context, template_id string) error {
+	_, err := q.db.ExecContext(ctx, `DELETE FROM tender_template WHERE id = $1`, template_id)
+	return err
+}
+
+// TenderTerms - условия тендера, которые не входят в Tender: критерии
+// оценки и сроки
+type TenderTerms struct {
+	EvaluationCriteria string
+	SubmissionDeadline sql.NullTime
+	DecisionDeadline   sql.NullTime
+	// BiddingMode - open или sealed, пустое значение - open
+	BiddingMode  string
+	BidsOpenedAt sql.NullTime
+	// Visibility - public или invite_only, пустое значение - public
+	Visibility string
+	// RequiresQualification - предложения принимаются только от поставщиков,
+	// прошедших квалификацию организации тендера
+	RequiresQualification bool
+	// EstimatedBudget - оценка бюджета заказчиком, сравнивается с ценой
+	// победителя в аналитике
+	EstimatedBudget sql.NullFloat64
+}
+
+func (q *Queries) GetTenderTerms(ctx context.Context, tender_id string) (*TenderTerms, error) {
+	sqlquery := `SELECT COALESCE(evaluation_criteria::text, ''), submission_deadline, decision_deadline,
+	   bidding_mode, bids_opened_at, visibility, requires_qualification, estimated_budget
+	   FROM tender WHERE id = $1 LIMIT 1`
+	var i TenderTerms
+	err := q.db.QueryRowContext(ctx, sqlquery, tender_id).Scan(
+		&i.EvaluationCriteria,
+		&i.SubmissionDeadline,
+		&i.DecisionDeadline,
+		&i.BiddingMode,
+		&i.BidsOpenedAt,
+		&i.Visibility,
+		&i.RequiresQualification,
+		&i.EstimatedBudget,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// CreateTenderWithTerms создает тендер вместе с критериями, сроками и лотами
+func (q *Queries) CreateTenderWithTerms(ctx context.Context, params CreateTenderParams, terms TenderTerms) (CreateTenderRow, error) {
+	sqlquery := `WITH t AS (
+	    INSERT INTO tender (organization_id, creator_id, status, service_type, name, description,
+	    evaluation_criteria, submission_deadline, decision_deadline, bidding_mode, visibility,
+	    requires_qualification, estimated_budget)
+	    VALUES ($1,$2,$3,$4,$5,$6,NULLIF($7, '')::json,$8,$9,COALESCE(NULLIF($10, ''), 'open'),
+	        COALESCE(NULLIF($15, ''), 'public'), $16, $17)
+	    RETURNING id, version, created_at
+	), l AS (
+	    INSERT INTO tender_lot (tender_id, number, name, description, quantity, service_type)
+	    SELECT t.id, x.n, x.name, x.description, x.quantity, x.service_type
+	    FROM t, unnest($11::varchar[], $12::varchar[], $13::numeric[], $14::varchar[])
+	        WITH ORDINALITY AS x(name, description, quantity, service_type, n)
+	)
+	SELECT id, version, created_at FROM t`
+	names, descriptions, quantities, service_types := lotArrays(params.Lots)
+	row := q.db.QueryRowContext(ctx, sqlquery,
+		params.OrganizationID,
+		params.CreatorID,
+		params.Status,
+		params.ServiceType,
+		params.Name,
+		params.Description,
+		terms.EvaluationCriteria,
+		terms.SubmissionDeadline,
+		terms.DecisionDeadline,
+		terms.BiddingMode,
+		pq.Array(names),
+		pq.Array(descriptions),
+		pq.Array(quantities),
+		pq.Array(service_types),
+		terms.Visibility,
+		terms.RequiresQualification,
+		terms.EstimatedBudget,
+	)
+	var i CreateTenderRow
+	err := row.Scan(&i.ID, &i.Version, &i.CreatedAt)
+	return i, err
+}
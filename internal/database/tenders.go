@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"strings"
 	"time"
 )
@@ -12,6 +13,9 @@ type ListTendersParams struct {
 	Service_type []string
 	Offset       int32
 	Limit        int32
+	// Viewer_id - пользователь, которому показываются тендеры по приглашению,
+	// пустой для анонимного просмотра
+	Viewer_id string
 }
 
 type Tender struct {
@@ -25,17 +29,23 @@ type Tender struct {
 	Description    string    `json:"description"`
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
+	BiddingMode    string    `json:"bidding_mode"`
+	Visibility     string    `json:"visibility"`
 }
 
 func (q *Queries) PublishedListTenders(ctx context.Context, params ListTendersParams) ([]Tender, error) {
-	var sqlquery string
-	if len(params.Service_type) > 0 {
-		service_type := strings.Join(params.Service_type, "', '")
-		sqlquery = fmt.Sprintf("SELECT id, organization_id, creator_id, status, version, service_type, name, description, created_at, updated_at FROM tender WHERE status = 'Published' AND service_type in ('%s') ORDER BY name OFFSET $1 LIMIT $2", service_type)
-	} else {
-		sqlquery = "SELECT id, organization_id, creator_id, status, version, service_type, name, description, created_at, updated_at FROM tender WHERE status = 'Published' ORDER BY name OFFSET $1 LIMIT $2"
-	}
-	rows, err := q.db.QueryContext(ctx, sqlquery, params.Offset, params.Limit)
+	sqlquery := `SELECT id, organization_id, creator_id, status, version, service_type, name, description, created_at, updated_at,
+	   bidding_mode, visibility
+	   FROM tender
+	   WHERE status = 'Published' AND (COALESCE(cardinality($3::varchar[]), 0) = 0 OR service_type = ANY($3))
+	   AND (visibility = 'public'
+	        OR EXISTS (SELECT 1 FROM organization_responsible r
+	                   WHERE r.organization_id = tender.organization_id AND r.user_id::text = $4)
+	        OR EXISTS (SELECT 1 FROM tender_invitation i
+	                   JOIN organization_responsible r ON r.organization_id = i.organization_id
+	                   WHERE i.tender_id = tender.id AND i.status <> 'Declined' AND r.user_id::text = $4))
+	   ORDER BY name OFFSET $1 LIMIT $2`
+	rows, err := q.db.QueryContext(ctx, sqlquery, params.Offset, params.Limit, pq.Array(params.Service_type), params.Viewer_id)
 	if err != nil {
 		return nil, err
 	}
@@ -54,6 +64,8 @@ func (q *Queries) PublishedListTenders(ctx context.Context, params ListTendersPa
 			&i.Description,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.BiddingMode,
+			&i.Visibility,
 		); err != nil {
 			return nil, err
 		}
@@ -75,6 +87,8 @@ type CreateTenderParams struct {
 	ServiceType    string `json:"service_type"`
 	Name           string `json:"name"`
 	Description    string `json:"description"`
+	// Lots учитываются только в CreateTenderWithTerms
+	Lots []LotParams `json:"lots"`
 }
 
 type CreateTenderRow struct {
@@ -108,7 +122,7 @@ type MyListTendersParams struct {
 func (q *Queries) GetTender(ctx context.Context, tender_id string) (Tender, error) {
 	sqlquery := `SELECT id, 
        organization_id, creator_id, status, version, service_type, name, 
-       description, created_at, updated_at 
+       description, created_at, updated_at, bidding_mode, visibility
 	   FROM tender 
 	   WHERE id = $1 LIMIT 1`
 	row := q.db.QueryRowContext(ctx, sqlquery, tender_id)
@@ -124,6 +138,8 @@ func (q *Queries) GetTender(ctx context.Context, tender_id string) (Tender, erro
 		&t.Description,
 		&t.CreatedAt,
 		&t.UpdatedAt,
+		&t.BiddingMode,
+		&t.Visibility,
 	)
 	return t, err
 }
@@ -131,7 +147,7 @@ func (q *Queries) GetTender(ctx context.Context, tender_id string) (Tender, erro
 func (q *Queries) MyListTenders(ctx context.Context, params *MyListTendersParams) ([]Tender, error) {
 	sqlquery := `SELECT id, 
        organization_id, creator_id, status, version, service_type, name, 
-       description, created_at, updated_at 
+       description, created_at, updated_at, bidding_mode, visibility
 	   FROM tender 
 	   WHERE creator_id = $1 ORDER BY name OFFSET $2 LIMIT $3`
 	rows, err := q.db.QueryContext(ctx, sqlquery, params.User_id, params.Offset, params.Limit)
@@ -153,6 +169,8 @@ func (q *Queries) MyListTenders(ctx context.Context, params *MyListTendersParams
 			&i.Description,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.BiddingMode,
+			&i.Visibility,
 		); err != nil {
 			return nil, err
 		}
@@ -318,3 +336,39 @@ func (q *Queries) GetTenderHistory(ctx context.Context, tender_id string, versio
 	)
 	return &i, err
 }
+
+// CreateTendersTx создает тендеры в одной транзакции. При ошибке
+// возвращает индекс строки, на которой она произошла.
+func (q *Queries) CreateTendersTx(ctx context.Context, items []CreateTenderParams) ([]CreateTenderRow, int, error) {
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer tx.Rollback()
+
+	sqlquery := `INSERT INTO tender (organization_id, creator_id, status, service_type, name, description)
+	VALUES ($1,$2,$3,$4,$5,$6) RETURNING id, version, created_at`
+	stmt, err := tx.PrepareContext(ctx, sqlquery)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer stmt.Close()
+
+	rows := make([]CreateTenderRow, 0, len(items))
+	for n, params := range items {
+		var i CreateTenderRow
+		err := stmt.QueryRowContext(ctx,
+			params.OrganizationID,
+			params.CreatorID,
+			params.Status,
+			params.ServiceType,
+			params.Name,
+			params.Description,
+		).Scan(&i.ID, &i.Version, &i.CreatedAt)
+		if err != nil {
+			return nil, n, err
+		}
+		rows = append(rows, i)
+	}
+	return rows, -1, tx.Commit()
+}
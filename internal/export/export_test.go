@@ -0,0 +1,204 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testRows = [][]string{
+	{"id", "name", "status"},
+	{"1", "Поставка бумаги", "Published"},
+	{"2", `Ремонт, "срочно"`, "Created"},
+	{"3", "строка 1\nстрока 2", ""},
+	{"4", "<b>&amp;</b> ' \"", "  пробелы  "},
+}
+
+func writeRows(t *testing.T, w Writer, rows [][]string) {
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatalf("WriteRow: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		format      string
+		wantErr     error
+		contentType string
+	}{
+		{format: FormatCSV, contentType: "text/csv; charset=utf-8"},
+		{format: FormatXLSX, contentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+		{format: "pdf", wantErr: UnknownFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			_, err := New(tt.format, io.Discard)
+			if err != tt.wantErr {
+				t.Fatalf("New err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && ContentType(tt.format) != tt.contentType {
+				t.Errorf("ContentType = %q, want %q", ContentType(tt.format), tt.contentType)
+			}
+		})
+	}
+}
+
+func TestCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		rows [][]string
+	}{
+		{name: "empty", rows: nil},
+		{name: "header only", rows: testRows[:1]},
+		{name: "quoting and cyrillic", rows: testRows},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writeRows(t, NewCSV(&buf), tt.rows)
+
+			data := buf.Bytes()
+			if !bytes.HasPrefix(data, []byte("\uFEFF")) {
+				t.Fatal("CSV must start with a BOM")
+			}
+			got, err := csv.NewReader(bytes.NewReader(data[len("\uFEFF"):])).ReadAll()
+			if err != nil {
+				t.Fatalf("read back: %v", err)
+			}
+			if len(got) == 0 && len(tt.rows) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.rows) {
+				t.Errorf("rows = %q, want %q", got, tt.rows)
+			}
+		})
+	}
+}
+
+// readSheet разбирает лист книги обратно в строки
+func readSheet(t *testing.T, data []byte) (map[string]bool, [][]string) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("xlsx is not a zip: %v", err)
+	}
+	parts := map[string]bool{}
+	var sheet []byte
+	for _, f := range zr.File {
+		parts[f.Name] = true
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var probe interface{}
+		if err := xml.Unmarshal(body, &probe); err != nil && err != io.EOF {
+			t.Fatalf("%s is not valid XML: %v", f.Name, err)
+		}
+		if f.Name == "xl/worksheets/sheet1.xml" {
+			sheet = body
+		}
+	}
+
+	var doc struct {
+		Rows []struct {
+			Cells []struct {
+				Type string `xml:"t,attr"`
+				Text string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(sheet, &doc); err != nil {
+		t.Fatalf("parse sheet: %v", err)
+	}
+	var rows [][]string
+	for _, row := range doc.Rows {
+		values := []string{}
+		for _, cell := range row.Cells {
+			if cell.Type != "inlineStr" {
+				t.Errorf("cell type = %q, want inlineStr", cell.Type)
+			}
+			values = append(values, cell.Text)
+		}
+		rows = append(rows, values)
+	}
+	return parts, rows
+}
+
+func TestXLSX(t *testing.T) {
+	tests := []struct {
+		name string
+		rows [][]string
+	}{
+		{name: "empty", rows: nil},
+		{name: "header only", rows: testRows[:1]},
+		{name: "escaping and cyrillic", rows: testRows},
+		{name: "empty row", rows: [][]string{{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewXLSX(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			writeRows(t, w, tt.rows)
+
+			parts, rows := readSheet(t, buf.Bytes())
+			for _, name := range []string{
+				"[Content_Types].xml",
+				"_rels/.rels",
+				"xl/workbook.xml",
+				"xl/_rels/workbook.xml.rels",
+				"xl/worksheets/sheet1.xml",
+			} {
+				if !parts[name] {
+					t.Errorf("missing part %s", name)
+				}
+			}
+			if !reflect.DeepEqual(rows, tt.rows) {
+				t.Errorf("rows = %q, want %q", rows, tt.rows)
+			}
+		})
+	}
+}
+
+func TestXLSXStreamsRows(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewXLSX(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// случайные строки почти не сжимаются и не помещаются в буферы zip
+	random := rand.New(rand.NewSource(1))
+	const letters = "abcdefghijklmnopqrstuvwxyz0123456789"
+	for i := 0; i < 256; i++ {
+		var value strings.Builder
+		for j := 0; j < 1024; j++ {
+			value.WriteByte(letters[random.Intn(len(letters))])
+		}
+		if err := w.WriteRow([]string{value.String()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if buf.Len() == 0 {
+		t.Error("rows must be written before Close")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,166 @@
+package handles
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"tender_service/internal/service"
+	"tender_service/internal/utils"
+)
+
+func writeAmendmentError(w http.ResponseWriter, err error, err_response map[string]interface{}) {
+	err_response["reason"] = err.Error()
+	switch err {
+	case service.UserNotFound:
+		w.WriteHeader(http.StatusUnauthorized)
+	case service.UserDeactivated, service.IsNotResponsible, service.IsNotAuthor:
+		w.WriteHeader(http.StatusForbidden)
+	case service.TenderNotFound, service.BidNotFound, service.NotificationNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(err_response)
+}
+
+// pageParams разбирает limit и offset так же, как остальные списки
+func pageParams(r *http.Request) (int32, int32) {
+	queryParams := r.URL.Query()
+	var limit, offset int32
+	limit_param := queryParams.Get("limit")
+	offset_param := queryParams.Get("offset")
+
+	if limit_param == "" || !utils.IsNumeric(limit_param) {
+		limit = 5
+	} else {
+		tl, _ := strconv.Atoi(limit_param)
+		limit = int32(tl)
+	}
+
+	if offset_param == "" || !utils.IsNumeric(offset_param) {
+		offset = 0
+	} else {
+		tl, _ := strconv.Atoi(offset_param)
+		offset = int32(tl)
+	}
+	return limit, offset
+}
+
+func (h *Handle) AmendmentList(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodGet {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	tender_id, ok := pathID(r, "/api/tenders/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id тендера"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	limit, offset := pageParams(r)
+
+	amendments, err := h.srv.ListAmendments(h.requestContext(r), service.ListAmendmentsRequest{
+		Username:  r.URL.Query().Get("username"),
+		Tender_id: tender_id,
+		Offset:    offset,
+		Limit:     limit,
+	})
+	if err != nil {
+		writeAmendmentError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(amendments)
+}
+
+func (h *Handle) BidConfirm(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPut {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	bid_id, ok := pathID(r, "/api/bids/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id предложения"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	bid, err := h.srv.ConfirmBid(h.requestContext(r), service.ConfirmBidRequest{
+		Username: r.URL.Query().Get("username"),
+		Bid_id:   bid_id,
+	})
+	if err != nil {
+		writeAmendmentError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(bid)
+}
+
+func (h *Handle) NotificationList(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodGet {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	limit, offset := pageParams(r)
+	unread, _ := strconv.ParseBool(r.URL.Query().Get("unread"))
+
+	notifications, err := h.srv.ListNotifications(h.requestContext(r), service.ListNotificationsRequest{
+		Username:   r.URL.Query().Get("username"),
+		UnreadOnly: unread,
+		Offset:     offset,
+		Limit:      limit,
+	})
+	if err != nil {
+		writeAmendmentError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(notifications)
+}
+
+func (h *Handle) NotificationRead(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPut {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	notification_id, ok := pathID(r, "/api/notifications/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id уведомления"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	err := h.srv.ReadNotification(h.requestContext(r), r.URL.Query().Get("username"), notification_id)
+	if err != nil {
+		writeAmendmentError(w, err, err_response)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
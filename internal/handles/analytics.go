@@ -0,0 +1,118 @@
+package handles
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"net/http"
+	"strings"
+	"tender_service/internal/service"
+)
+
+func writeAnalyticsError(w http.ResponseWriter, err error, err_response map[string]interface{}) {
+	err_response["reason"] = err.Error()
+	switch err {
+	case service.UserNotFound:
+		w.WriteHeader(http.StatusUnauthorized)
+	case service.UserDeactivated, service.IsNotResponsible:
+		w.WriteHeader(http.StatusForbidden)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(err_response)
+}
+
+// analyticsRequest разбирает общие параметры отчетов: organizationId,
+// from, to и serviceType (можно повторять или перечислить через запятую)
+func analyticsRequest(r *http.Request) (service.AnalyticsRequest, string) {
+	queryParams := r.URL.Query()
+	params := service.AnalyticsRequest{
+		Username:        queryParams.Get("username"),
+		Organization_id: queryParams.Get("organizationId"),
+	}
+	if _, err := uuid.Parse(params.Organization_id); err != nil {
+		return params, InvalidParams + ": неверный формат поля organizationId"
+	}
+	var err error
+	if params.From, err = parseTimeParam(queryParams.Get("from")); err != nil {
+		return params, InvalidParams + ": неверный формат поля from"
+	}
+	if params.To, err = parseTimeParam(queryParams.Get("to")); err != nil {
+		return params, InvalidParams + ": неверный формат поля to"
+	}
+	if params.From != nil && params.To != nil && !params.From.Before(*params.To) {
+		return params, InvalidParams + ": from должен быть раньше to"
+	}
+	for _, value := range queryParams["serviceType"] {
+		for _, service_type := range strings.Split(value, ",") {
+			if service_type = strings.TrimSpace(service_type); service_type != "" {
+				params.Service_types = append(params.Service_types, service_type)
+			}
+		}
+	}
+	return params, ""
+}
+
+// analytics - общая часть GET-отчетов по организации
+func (h *Handle) analytics(w http.ResponseWriter, r *http.Request, report func(context.Context, service.AnalyticsRequest) (interface{}, error)) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodGet {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	params, reason := analyticsRequest(r)
+	if reason != "" {
+		err_response["reason"] = reason
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	result, err := report(h.requestContext(r), params)
+	if err != nil {
+		writeAnalyticsError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+// AnalyticsTenders - число тендеров по статусам и видам услуг
+func (h *Handle) AnalyticsTenders(w http.ResponseWriter, r *http.Request) {
+	h.analytics(w, r, func(ctx context.Context, params service.AnalyticsRequest) (interface{}, error) {
+		return h.srv.CountTenders(ctx, params)
+	})
+}
+
+// AnalyticsBids - среднее число предложений на тендер
+func (h *Handle) AnalyticsBids(w http.ResponseWriter, r *http.Request) {
+	h.analytics(w, r, func(ctx context.Context, params service.AnalyticsRequest) (interface{}, error) {
+		return h.srv.GetBidStats(ctx, params)
+	})
+}
+
+// AnalyticsAwardTime - время от публикации до выбора победителя
+func (h *Handle) AnalyticsAwardTime(w http.ResponseWriter, r *http.Request) {
+	h.analytics(w, r, func(ctx context.Context, params service.AnalyticsRequest) (interface{}, error) {
+		return h.srv.GetAwardTime(ctx, params)
+	})
+}
+
+// AnalyticsApprovals - доля одобрений по ответственным
+func (h *Handle) AnalyticsApprovals(w http.ResponseWriter, r *http.Request) {
+	h.analytics(w, r, func(ctx context.Context, params service.AnalyticsRequest) (interface{}, error) {
+		return h.srv.ListApprovalRates(ctx, params)
+	})
+}
+
+// AnalyticsSavings - экономия относительно оценки бюджета
+func (h *Handle) AnalyticsSavings(w http.ResponseWriter, r *http.Request) {
+	h.analytics(w, r, func(ctx context.Context, params service.AnalyticsRequest) (interface{}, error) {
+		return h.srv.GetSavings(ctx, params)
+	})
+}
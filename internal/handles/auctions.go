@@ -0,0 +1,209 @@
+package handles
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"net/http"
+	"tender_service/internal/service"
+	"tender_service/internal/utils"
+	"time"
+)
+
+func writeAuctionError(w http.ResponseWriter, err error, err_response map[string]interface{}) {
+	err_response["reason"] = err.Error()
+	switch err {
+	case service.UserNotFound:
+		w.WriteHeader(http.StatusUnauthorized)
+	case service.UserDeactivated, service.IsNotResponsible:
+		w.WriteHeader(http.StatusForbidden)
+	case service.TenderNotFound, service.BidNotFound, service.AuctionNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case service.AuctionStarted, service.PriceTooHigh, service.BidNotPublished:
+		w.WriteHeader(http.StatusConflict)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(err_response)
+}
+
+type AuctionParam struct {
+	StartAt        time.Time `json:"startAt"`
+	EndAt          time.Time `json:"endAt"`
+	StartPrice     *float64  `json:"startPrice"`
+	DecrementType  string    `json:"decrementType"`
+	DecrementValue float64   `json:"decrementValue"`
+	// ExtensionWindow и Extension в секундах
+	ExtensionWindow *int32 `json:"extensionWindow"`
+	Extension       *int32 `json:"extension"`
+}
+
+// по умолчанию ставка за 2 минуты до конца продлевает аукцион на 2 минуты
+const defaultAuctionExtension int32 = 120
+
+func (h *Handle) AuctionSet(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPut {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	tender_id, ok := pathID(r, "/api/tenders/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id тендера"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	var param AuctionParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		err_response["reason"] = InvalidParams
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	reason := ""
+	switch {
+	case param.StartAt.IsZero():
+		reason = "startAt" + FieldRequired
+	case param.EndAt.IsZero():
+		reason = "endAt" + FieldRequired
+	case !param.EndAt.After(param.StartAt):
+		reason = InvalidParams + ": endAt должен быть позже startAt"
+	case !utils.CheckString(param.DecrementType, []string{service.DecrementAbsolute, service.DecrementPercent}):
+		reason = InvalidParams + ": decrementType может быть absolute или percent"
+	case param.DecrementValue <= 0:
+		reason = InvalidParams + ": decrementValue должен быть больше нуля"
+	case param.DecrementType == service.DecrementPercent && param.DecrementValue >= 100:
+		reason = InvalidParams + ": decrementValue в процентах должен быть меньше 100"
+	case param.StartPrice != nil && *param.StartPrice <= 0:
+		reason = InvalidParams + ": startPrice должен быть больше нуля"
+	case param.ExtensionWindow != nil && *param.ExtensionWindow < 0,
+		param.Extension != nil && *param.Extension < 0:
+		reason = InvalidParams + ": extensionWindow и extension не могут быть отрицательными"
+	}
+	if reason != "" {
+		err_response["reason"] = reason
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	window, extension := defaultAuctionExtension, defaultAuctionExtension
+	if param.ExtensionWindow != nil {
+		window = *param.ExtensionWindow
+	}
+	if param.Extension != nil {
+		extension = *param.Extension
+	}
+
+	auction, err := h.srv.SetAuction(h.requestContext(r), service.SetAuctionRequest{
+		Username:        r.URL.Query().Get("username"),
+		Tender_id:       tender_id,
+		StartAt:         param.StartAt,
+		EndAt:           param.EndAt,
+		StartPrice:      param.StartPrice,
+		DecrementType:   param.DecrementType,
+		DecrementValue:  param.DecrementValue,
+		ExtensionWindow: window,
+		Extension:       extension,
+	})
+	if err != nil {
+		writeAuctionError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(auction)
+}
+
+func (h *Handle) AuctionGet(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodGet {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	tender_id, ok := pathID(r, "/api/tenders/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id тендера"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	auction, err := h.srv.GetAuction(h.requestContext(r), service.GetAuctionRequest{
+		Username:  r.URL.Query().Get("username"),
+		Tender_id: tender_id,
+	})
+	if err != nil {
+		writeAuctionError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(auction)
+}
+
+type AuctionBidParam struct {
+	BidID string  `json:"bidId"`
+	Price float64 `json:"price"`
+}
+
+func (h *Handle) AuctionBid(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPost {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	tender_id, ok := pathID(r, "/api/tenders/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id тендера"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	var param AuctionBidParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		err_response["reason"] = InvalidParams
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if _, err := uuid.Parse(param.BidID); err != nil {
+		err_response["reason"] = InvalidParams + ": некорректный формат bidId"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if param.Price < 0.01 {
+		err_response["reason"] = InvalidParams + ": price должен быть больше нуля"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	auction, err := h.srv.PlaceAuctionBid(h.requestContext(r), service.PlaceAuctionBidRequest{
+		Username:  r.URL.Query().Get("username"),
+		Tender_id: tender_id,
+		Bid_id:    param.BidID,
+		Price:     param.Price,
+	})
+	if err != nil {
+		writeAuctionError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(auction)
+}
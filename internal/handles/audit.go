@@ -0,0 +1,141 @@
+package handles
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"net/http"
+	"strconv"
+	"tender_service/internal/service"
+	"tender_service/internal/utils"
+	"time"
+)
+
+func writeAuditError(w http.ResponseWriter, err error, err_response map[string]interface{}) {
+	err_response["reason"] = err.Error()
+	switch err {
+	case service.UserNotFound:
+		w.WriteHeader(http.StatusUnauthorized)
+	case service.UserDeactivated, service.IsNotAdmin, service.IsNotResponsible:
+		w.WriteHeader(http.StatusForbidden)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(err_response)
+}
+
+func parseTimeParam(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (h *Handle) AuditList(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodGet {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	queryParams := r.URL.Query()
+	var limit, offset int32
+	limit_param := queryParams.Get("limit")
+	offset_param := queryParams.Get("offset")
+	username := queryParams.Get("username")
+	organization_id := queryParams.Get("organizationId")
+	actor_id := queryParams.Get("actorId")
+
+	if limit_param == "" || !utils.IsNumeric(limit_param) {
+		limit = 5
+	} else {
+		tl, _ := strconv.Atoi(limit_param)
+		limit = int32(tl)
+	}
+
+	if offset_param == "" || !utils.IsNumeric(offset_param) {
+		offset = 0
+	} else {
+		tl, _ := strconv.Atoi(offset_param)
+		offset = int32(tl)
+	}
+
+	if organization_id != "" {
+		if _, err := uuid.Parse(organization_id); err != nil {
+			err_response["reason"] = InvalidParams + ": неверный формат поля organizationId"
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
+	}
+	if actor_id != "" {
+		if _, err := uuid.Parse(actor_id); err != nil {
+			err_response["reason"] = InvalidParams + ": неверный формат поля actorId"
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
+	}
+	from, err := parseTimeParam(queryParams.Get("from"))
+	if err != nil {
+		err_response["reason"] = InvalidParams + ": неверный формат поля from"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	to, err := parseTimeParam(queryParams.Get("to"))
+	if err != nil {
+		err_response["reason"] = InvalidParams + ": неверный формат поля to"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	records, err := h.srv.ListAudit(h.requestContext(r), service.ListAuditRequest{
+		Username:       username,
+		OrganizationID: organization_id,
+		ActorID:        actor_id,
+		EntityType:     queryParams.Get("entityType"),
+		EntityID:       queryParams.Get("entityId"),
+		Action:         queryParams.Get("action"),
+		From:           from,
+		To:             to,
+		Offset:         offset,
+		Limit:          limit,
+	})
+	if err != nil {
+		writeAuditError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(records)
+}
+
+func (h *Handle) AuditVerify(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodGet {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	username := r.URL.Query().Get("username")
+
+	result, err := h.srv.VerifyAudit(h.requestContext(r), username)
+	if err != nil {
+		writeAuditError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,96 @@
+package handles
+
+import (
+	"encoding/json"
+	"net/http"
+	"tender_service/internal/service"
+)
+
+func writeConflictPolicyError(w http.ResponseWriter, err error, err_response map[string]interface{}) {
+	err_response["reason"] = err.Error()
+	switch err {
+	case service.UserNotFound:
+		w.WriteHeader(http.StatusUnauthorized)
+	case service.UserDeactivated, service.IsNotResponsible:
+		w.WriteHeader(http.StatusForbidden)
+	case service.OrganizationNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(err_response)
+}
+
+func (h *Handle) ConflictPolicyGet(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodGet {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	org_id, ok := pathID(r, "/api/organizations/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": неверный формат id организации"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	policy, err := h.srv.GetConflictPolicy(h.requestContext(r), service.ConflictPolicyRequest{
+		Username:        r.URL.Query().Get("username"),
+		Organization_id: org_id,
+	})
+	if err != nil {
+		writeConflictPolicyError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(policy)
+}
+
+type ConflictPolicyParam struct {
+	Policy string `json:"policy"`
+}
+
+func (h *Handle) ConflictPolicySet(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPut {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	org_id, ok := pathID(r, "/api/organizations/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": неверный формат id организации"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	var param ConflictPolicyParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		err_response["reason"] = InvalidParams
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	policy, err := h.srv.SetConflictPolicy(h.requestContext(r), service.ConflictPolicyRequest{
+		Username:        r.URL.Query().Get("username"),
+		Organization_id: org_id,
+		Policy:          param.Policy,
+	})
+	if err != nil {
+		writeConflictPolicyError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(policy)
+}
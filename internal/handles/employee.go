@@ -0,0 +1,231 @@
+package handles
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"net/http"
+	"strconv"
+	"strings"
+	"tender_service/internal/service"
+	"tender_service/internal/utils"
+)
+
+func writeEmployeeError(w http.ResponseWriter, err error, err_response map[string]interface{}) {
+	err_response["reason"] = err.Error()
+	switch err {
+	case service.UserNotFound:
+		w.WriteHeader(http.StatusUnauthorized)
+	case service.UserDeactivated, service.IsNotAdmin:
+		w.WriteHeader(http.StatusForbidden)
+	case service.EmployeeNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case service.UsernameTaken:
+		w.WriteHeader(http.StatusConflict)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(err_response)
+}
+
+func (h *Handle) Me(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodGet {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	username := r.URL.Query().Get("username")
+
+	profile, err := h.srv.FetchProfile(h.requestContext(r), username)
+	if err != nil {
+		writeEmployeeError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(profile)
+}
+
+type ProfileChangeRequest struct {
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+}
+
+func (h *Handle) ChangeMe(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPatch {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	username := r.URL.Query().Get("username")
+
+	var param ProfileChangeRequest
+	err := json.NewDecoder(r.Body).Decode(&param)
+	if err != nil {
+		err_response["reason"] = InvalidParams
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if len(param.FirstName) > 50 || len(param.LastName) > 50 {
+		err_response["reason"] = InvalidParams + ": имя и фамилия не длиннее 50 символов"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	profile, err := h.srv.EditProfile(h.requestContext(r), service.EditProfileRequest{
+		Username:  username,
+		FirstName: param.FirstName,
+		LastName:  param.LastName,
+	})
+	if err != nil {
+		writeEmployeeError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(profile)
+}
+
+func (h *Handle) EmployeeList(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodGet {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	queryParams := r.URL.Query()
+	var limit, offset int32
+	limit_param := queryParams.Get("limit")
+	offset_param := queryParams.Get("offset")
+	username := queryParams.Get("username")
+
+	if limit_param == "" || !utils.IsNumeric(limit_param) {
+		limit = 5
+	} else {
+		tl, _ := strconv.Atoi(limit_param)
+		limit = int32(tl)
+	}
+
+	if offset_param == "" || !utils.IsNumeric(offset_param) {
+		offset = 0
+	} else {
+		tl, _ := strconv.Atoi(offset_param)
+		offset = int32(tl)
+	}
+
+	employees, err := h.srv.SearchEmployees(h.requestContext(r), service.SearchEmployeesRequest{
+		Username: username,
+		Query:    queryParams.Get("q"),
+		Offset:   offset,
+		Limit:    limit,
+	})
+	if err != nil {
+		writeEmployeeError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(employees)
+}
+
+type NewEmployeeParam struct {
+	Username  string `json:"username"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+}
+
+func (h *Handle) EmployeeNew(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPost {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	username := r.URL.Query().Get("username")
+
+	var params NewEmployeeParam
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		err_response["reason"] = InvalidParams
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if params.Username == "" {
+		err_response["reason"] = "username" + FieldRequired
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if len(params.Username) > 50 || len(params.FirstName) > 50 || len(params.LastName) > 50 {
+		err_response["reason"] = InvalidParams + ": поля не длиннее 50 символов"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	employee, err := h.srv.CreateEmployee(h.requestContext(r), service.CreateEmployeeRequest{
+		AdminUsername: username,
+		Username:      params.Username,
+		FirstName:     params.FirstName,
+		LastName:      params.LastName,
+	})
+	if err != nil {
+		writeEmployeeError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(employee)
+}
+
+func (h *Handle) EmployeeDeactivate(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPut {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	pathParts := r.URL.Path[len("/api/employees/"):]
+	employee_id := strings.Split(pathParts, "/")[0]
+
+	_, err := uuid.Parse(employee_id)
+	if err != nil {
+		err_response["reason"] = InvalidParams + ": некорректный формат id сотрудника"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	username := r.URL.Query().Get("username")
+
+	employee, err := h.srv.DeactivateEmployee(h.requestContext(r), service.DeactivateEmployeeRequest{
+		AdminUsername: username,
+		Employee_id:   employee_id,
+	})
+	if err != nil {
+		writeEmployeeError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(employee)
+}
@@ -0,0 +1,127 @@
+package handles
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"net/http"
+	"strings"
+	"tender_service/internal/export"
+	"tender_service/internal/logger"
+	"tender_service/internal/service"
+	"time"
+)
+
+func writeExportError(w http.ResponseWriter, err error, err_response map[string]interface{}) {
+	err_response["reason"] = err.Error()
+	switch err {
+	case service.UserNotFound:
+		w.WriteHeader(http.StatusUnauthorized)
+	case service.UserDeactivated, service.IsNotResponsible:
+		w.WriteHeader(http.StatusForbidden)
+	case service.TenderNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(err_response)
+}
+
+// exportFormat читает параметр format, по умолчанию csv
+func exportFormat(r *http.Request) (string, bool) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = export.FormatCSV
+	}
+	return format, format == export.FormatCSV || format == export.FormatXLSX
+}
+
+// runExport выполняет выгрузку. Пока строки не начали писаться, ошибка
+// возвращается как обычно в JSON, после - ответ просто обрывается.
+func runExport(w http.ResponseWriter, r *http.Request, name string, run func(open service.OpenExport) error) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodGet {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	format, ok := exportFormat(r)
+	if !ok {
+		err_response["reason"] = InvalidParams + ": format может быть csv или xlsx"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	started := false
+	err := run(func() (export.Writer, error) {
+		started = true
+		// большие выгрузки не должны упираться в WriteTimeout сервера
+		http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		filename := name + "-" + time.Now().Format("20060102-150405") + "." + format
+		w.Header().Set("Content-Type", export.ContentType(format))
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		w.WriteHeader(http.StatusOK)
+		return export.New(format, w)
+	})
+	if err != nil {
+		if started {
+			logger.FromContext(r.Context()).Error("export aborted", "export", name, "err", err)
+			return
+		}
+		writeExportError(w, err, err_response)
+	}
+}
+
+func (h *Handle) TenderMyExport(w http.ResponseWriter, r *http.Request) {
+	username := r.URL.Query().Get("username")
+	runExport(w, r, "tenders", func(open service.OpenExport) error {
+		return h.srv.ExportMyTenders(h.requestContext(r), service.ExportRequest{
+			Username: username,
+		}, open)
+	})
+}
+
+// exportTenderID достает id тендера из пути /api/bids/{tenderID}/...
+func exportTenderID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	pathParts := r.URL.Path[len("/api/bids/"):]
+	tender_id := strings.Split(pathParts, "/")[0]
+	if _, err := uuid.Parse(tender_id); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"reason": InvalidParams + ": некорректный формат id тендера",
+		})
+		return "", false
+	}
+	return tender_id, true
+}
+
+func (h *Handle) BidsTenderExport(w http.ResponseWriter, r *http.Request) {
+	tender_id, ok := exportTenderID(w, r)
+	if !ok {
+		return
+	}
+	username := r.URL.Query().Get("username")
+	runExport(w, r, "bids", func(open service.OpenExport) error {
+		return h.srv.ExportTenderBids(h.requestContext(r), service.ExportRequest{
+			Username:  username,
+			Tender_id: tender_id,
+		}, open)
+	})
+}
+
+func (h *Handle) ApprovalsExport(w http.ResponseWriter, r *http.Request) {
+	tender_id, ok := exportTenderID(w, r)
+	if !ok {
+		return
+	}
+	username := r.URL.Query().Get("username")
+	runExport(w, r, "approvals", func(open service.OpenExport) error {
+		return h.srv.ExportTenderApprovals(h.requestContext(r), service.ExportRequest{
+			Username:  username,
+			Tender_id: tender_id,
+		}, open)
+	})
+}
@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"tender_service/internal/middleware"
+	"tender_service/internal/scheduler"
 	"tender_service/internal/service"
 	"tender_service/internal/utils"
 	"time"
@@ -22,17 +24,26 @@ var (
 )
 
 type Handle struct {
-	srv *service.Service
-	ctx context.Context
+	srv   *service.Service
+	sched *scheduler.Scheduler
 }
 
-func New(ctx context.Context, s *service.Service) *Handle {
+func New(s *service.Service, sched *scheduler.Scheduler) *Handle {
 	return &Handle{
-		srv: s,
-		ctx: ctx,
+		srv:   s,
+		sched: sched,
 	}
 }
 
+// requestContext добавляет к контексту запроса данные для журнала аудита.
+// Контекст отменяется вместе с запросом, прерывая его SQL запросы.
+func (h *Handle) requestContext(r *http.Request) context.Context {
+	return service.WithRequestMeta(r.Context(), service.RequestMeta{
+		RequestID: r.Header.Get("X-Request-Id"),
+		IP:        middleware.RequestIP(r),
+	})
+}
+
 type Tender struct {
 	ID          string    `json:"id"`
 	Name        string    `json:"name"`
@@ -96,10 +107,23 @@ func (h *Handle) TenderList(w http.ResponseWriter, r *http.Request) {
 		Service_type: service_type,
 		Offset:       offset,
 		Limit:        limit,
+		Username:     queryParams.Get("username"),
 	}
 
-	listTenders, err := h.srv.FetchPublishedTenders(h.ctx, tender_list_request)
+	listTenders, err := h.srv.FetchPublishedTenders(h.requestContext(r), tender_list_request)
 	if err != nil {
+		if err == service.UserNotFound {
+			err_response["reason"] = err.Error()
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
+		if err == service.UserDeactivated {
+			err_response["reason"] = err.Error()
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
 		if err == service.CreateTenderError {
 			err_response["reason"] = err.Error()
 			w.WriteHeader(http.StatusBadRequest)
@@ -117,6 +141,40 @@ func (h *Handle) TenderList(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(listTenders)
 }
 
+// validateTenderParams проверяет поля нового тендера и возвращает причину
+// отказа или пустую строку
+func validateTenderParams(params service.TenderParams) string {
+	if params.Name == "" {
+		return "name" + FieldRequired
+	}
+	if params.ServiceType == "" {
+		return "serviceType" + FieldRequired
+	}
+	if params.Status == "" {
+		return "status" + FieldRequired
+	}
+	if _, err := uuid.Parse(params.OrganizationId); err != nil {
+		return InvalidParams + ": неверный формат поля organizationId"
+	}
+	if params.BiddingMode != "" && !utils.CheckString(params.BiddingMode,
+		[]string{service.BiddingModeOpen, service.BiddingModeSealed, service.BiddingModeAuction}) {
+		return InvalidParams + ": biddingMode может быть open, sealed или auction"
+	}
+	if params.Visibility != "" && !utils.CheckString(params.Visibility,
+		[]string{service.TenderPublic, service.TenderInviteOnly}) {
+		return InvalidParams + ": visibility может быть public или invite_only"
+	}
+	if params.EstimatedBudget != nil && *params.EstimatedBudget <= 0 {
+		return InvalidParams + ": estimatedBudget должен быть больше нуля"
+	}
+	for n, lot := range params.Lots {
+		if reason := validateLot(lot, n); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
 func (h *Handle) NewTender(w http.ResponseWriter, r *http.Request) {
 	err_response := map[string]interface{}{
 		"reason": "",
@@ -137,32 +195,13 @@ func (h *Handle) NewTender(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if params.Name == "" {
-		err_response["reason"] = "name" + FieldRequired
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(err_response)
-		return
-	}
-	if params.ServiceType == "" {
-		err_response["reason"] = "serviceType" + FieldRequired
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(err_response)
-		return
-	}
-	if params.Status == "" {
-		err_response["reason"] = "status" + FieldRequired
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(err_response)
-		return
-	}
-	_, err = uuid.Parse(params.OrganizationId)
-	if err != nil {
-		err_response["reason"] = InvalidParams + ": неверный формат поля organizationId"
+	if reason := validateTenderParams(params); reason != "" {
+		err_response["reason"] = reason
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(err_response)
 		return
 	}
-	tenderResponse, err := h.srv.CreateNewTender(h.ctx, params)
+	tenderResponse, err := h.srv.CreateNewTender(h.requestContext(r), params)
 	if err != nil {
 		if err == service.IsNotResponsible {
 			err_response["reason"] = err.Error()
@@ -176,6 +215,19 @@ func (h *Handle) NewTender(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(err_response)
 			return
 		}
+		if err == service.UserDeactivated {
+			err_response["reason"] = err.Error()
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
+		if err == service.ServiceTypeNotFound || err == service.SealingDisabled || err == service.DeadlineRequired ||
+			err == service.LotsNotSupported {
+			err_response["reason"] = err.Error()
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
 		err_response["reason"] = service.UnknowError
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(err_response)
@@ -216,7 +268,7 @@ func (h *Handle) TenderMyList(w http.ResponseWriter, r *http.Request) {
 		offset = int32(tl)
 	}
 
-	listTenders, err := h.srv.FetchMyTenders(h.ctx, service.ListMyTendersRequest{
+	listTenders, err := h.srv.FetchMyTenders(h.requestContext(r), service.ListMyTendersRequest{
 		Username: username,
 		Offset:   offset,
 		Limit:    limit,
@@ -228,6 +280,12 @@ func (h *Handle) TenderMyList(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(err_response)
 			return
 		}
+		if err == service.UserDeactivated {
+			err_response["reason"] = err.Error()
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
 		err_response["reason"] = err.Error()
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(err_response)
@@ -263,7 +321,7 @@ func (h *Handle) GetTenderStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tender_status, err := h.srv.FetchTenderStatus(h.ctx, username, tender_id)
+	tender_status, err := h.srv.FetchTenderStatus(h.requestContext(r), username, tender_id)
 	if err != nil {
 		if err == service.UserNotFound {
 			err_response["reason"] = err.Error()
@@ -271,6 +329,12 @@ func (h *Handle) GetTenderStatus(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(err_response)
 			return
 		}
+		if err == service.UserDeactivated {
+			err_response["reason"] = err.Error()
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
 		if err == service.IsNotResponsible {
 			err_response["reason"] = err.Error()
 			w.WriteHeader(http.StatusForbidden)
@@ -335,7 +399,7 @@ func (h *Handle) ChangeTenderStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tender, err := h.srv.EditTenderStatus(h.ctx, service.EditTenderStatusRequest{
+	tender, err := h.srv.EditTenderStatus(h.requestContext(r), service.EditTenderStatusRequest{
 		Username:   username,
 		Tender_id:  tender_id,
 		New_status: newstatus,
@@ -347,6 +411,12 @@ func (h *Handle) ChangeTenderStatus(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(err_response)
 			return
 		}
+		if err == service.UserDeactivated {
+			err_response["reason"] = err.Error()
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
 		if err == service.IsNotResponsible {
 			err_response["reason"] = err.Error()
 			w.WriteHeader(http.StatusForbidden)
@@ -372,6 +442,9 @@ type TenderChangeRequest struct {
 	Name         string `json:"name"`
 	Description  string `json:"description"`
 	Service_type string `json:"serviceType"`
+	// Reason и SubmissionDeadline относятся к изменению опубликованного тендера
+	Reason             string     `json:"reason"`
+	SubmissionDeadline *time.Time `json:"submissionDeadline"`
 }
 
 func (h *Handle) ChangeTender(w http.ResponseWriter, r *http.Request) {
@@ -409,12 +482,14 @@ func (h *Handle) ChangeTender(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	new_tender, err := h.srv.EditTender(h.ctx, service.EditTenderRequest{
-		Username:     username,
-		Tender_id:    tender_id,
-		Name:         param.Name,
-		Description:  param.Description,
-		Service_type: param.Service_type,
+	new_tender, err := h.srv.EditTender(h.requestContext(r), service.EditTenderRequest{
+		Username:           username,
+		Tender_id:          tender_id,
+		Name:               param.Name,
+		Description:        param.Description,
+		Service_type:       param.Service_type,
+		Reason:             param.Reason,
+		SubmissionDeadline: param.SubmissionDeadline,
 	})
 	if err != nil {
 		if err == service.UserNotFound {
@@ -423,6 +498,12 @@ func (h *Handle) ChangeTender(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(err_response)
 			return
 		}
+		if err == service.UserDeactivated {
+			err_response["reason"] = err.Error()
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
 		if err == service.IsNotResponsible {
 			err_response["reason"] = err.Error()
 			w.WriteHeader(http.StatusForbidden)
@@ -480,7 +561,7 @@ func (h *Handle) RollbackTender(w http.ResponseWriter, r *http.Request) {
 	queryParams := r.URL.Query()
 
 	username := queryParams.Get("username")
-	tender, err := h.srv.RollbackTender(h.ctx, service.RollbackTenderRequest{
+	tender, err := h.srv.RollbackTender(h.requestContext(r), service.RollbackTenderRequest{
 		Username:  username,
 		Tender_id: tender_id,
 		Version:   int32(version),
@@ -492,6 +573,12 @@ func (h *Handle) RollbackTender(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(err_response)
 			return
 		}
+		if err == service.UserDeactivated {
+			err_response["reason"] = err.Error()
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
 		if err == service.IsNotResponsible {
 			err_response["reason"] = err.Error()
 			w.WriteHeader(http.StatusForbidden)
@@ -525,6 +612,8 @@ type NewBidParam struct {
 	TenderId    string `json:"tenderId"`
 	AuthorType  string `json:"authorType"`
 	AuthorId    string `json:"authorId"`
+	// Lots - цены по лотам, обязательны для многолотового тендера
+	Lots []service.BidLot `json:"lots"`
 }
 
 func (h *Handle) BidNew(w http.ResponseWriter, r *http.Request) {
@@ -576,13 +665,20 @@ func (h *Handle) BidNew(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(err_response)
 		return
 	}
+	if reason := validateBidLots(params.Lots); reason != "" {
+		err_response["reason"] = reason
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
 
-	offer, err := h.srv.CreateNewBid(h.ctx, service.CreateBidParam{
+	offer, err := h.srv.CreateNewBid(h.requestContext(r), service.CreateBidParam{
 		Name:        params.Name,
 		Description: params.Description,
 		TenderId:    params.TenderId,
 		AuthorType:  params.AuthorType,
 		AuthorId:    params.AuthorId,
+		Lots:        params.Lots,
 	})
 	if err != nil {
 		if err == service.NotAllowValue {
@@ -597,18 +693,32 @@ func (h *Handle) BidNew(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(err_response)
 			return
 		}
-		if err == service.TenderNotFound {
+		if err == service.UserDeactivated {
+			err_response["reason"] = err.Error()
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
+		if err == service.TenderNotFound || err == service.LotNotFound {
 			err_response["reason"] = err.Error()
 			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(err_response)
 			return
 		}
-		if err == service.IsResponsible {
+		if err == service.IsResponsible || err == service.NotInvited || err == service.InvitationPending ||
+			err == service.NotQualified || err == service.QualificationExpired {
 			err_response["reason"] = err.Error()
 			w.WriteHeader(http.StatusForbidden)
 			json.NewEncoder(w).Encode(err_response)
 			return
 		}
+		if err == service.SubmissionClosed || err == service.SealingDisabled || err == service.LotsRequired ||
+			err == service.TenderHasNoLots || err == service.LotClosed || err == service.DuplicateLot {
+			err_response["reason"] = err.Error()
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
 		err_response["reason"] = service.UnknowError
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(err_response)
@@ -648,7 +758,7 @@ func (h *Handle) MyBids(w http.ResponseWriter, r *http.Request) {
 		tl, _ := strconv.Atoi(offset_param)
 		offset = int32(tl)
 	}
-	listoffer, err := h.srv.ListMyBids(h.ctx, service.ListMyBidsRequest{
+	listoffer, err := h.srv.ListMyBids(h.requestContext(r), service.ListMyBidsRequest{
 		Username: username,
 		Offset:   offset,
 		Limit:    limit,
@@ -660,6 +770,12 @@ func (h *Handle) MyBids(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(err_response)
 			return
 		}
+		if err == service.UserDeactivated {
+			err_response["reason"] = err.Error()
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
 		err_response["reason"] = err.Error()
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(err_response)
@@ -710,7 +826,7 @@ func (h *Handle) BidsTender(w http.ResponseWriter, r *http.Request) {
 		tl, _ := strconv.Atoi(offset_param)
 		offset = int32(tl)
 	}
-	listoffer, err := h.srv.TenderListBids(h.ctx, service.TenderListBidsRequest{
+	listoffer, err := h.srv.TenderListBids(h.requestContext(r), service.TenderListBidsRequest{
 		Tender_id: tender_id,
 		Username:  username,
 		Offset:    offset,
@@ -723,6 +839,12 @@ func (h *Handle) BidsTender(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(err_response)
 			return
 		}
+		if err == service.UserDeactivated {
+			err_response["reason"] = err.Error()
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
 		if err == service.TenderNotFound {
 			err_response["reason"] = err.Error()
 			w.WriteHeader(http.StatusNotFound)
@@ -769,7 +891,7 @@ func (h *Handle) BidStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bid_status, err := h.srv.GetBidStatus(h.ctx, service.GetBidStatus{
+	bid_status, err := h.srv.GetBidStatus(h.requestContext(r), service.GetBidStatus{
 		Username: username,
 		BidID:    bid_id,
 	})
@@ -780,6 +902,12 @@ func (h *Handle) BidStatus(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(err_response)
 			return
 		}
+		if err == service.UserDeactivated {
+			err_response["reason"] = err.Error()
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
 		if err == service.BidNotFound {
 			err_response["reason"] = err.Error()
 			w.WriteHeader(http.StatusNotFound)
@@ -837,7 +965,7 @@ func (h *Handle) ChangeBidStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bid, err := h.srv.ChangeBidStatus(h.ctx, service.ChangeBidStatus{
+	bid, err := h.srv.ChangeBidStatus(h.requestContext(r), service.ChangeBidStatus{
 		Username: username,
 		BidID:    bid_id,
 		Status:   status,
@@ -849,6 +977,12 @@ func (h *Handle) ChangeBidStatus(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(err_response)
 			return
 		}
+		if err == service.UserDeactivated {
+			err_response["reason"] = err.Error()
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
 		if err == service.BidNotFound {
 			err_response["reason"] = err.Error()
 			w.WriteHeader(http.StatusNotFound)
@@ -918,7 +1052,7 @@ func (h *Handle) ChangeBid(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	new_bid, err := h.srv.EditBid(h.ctx, service.EditBidRequest{
+	new_bid, err := h.srv.EditBid(h.requestContext(r), service.EditBidRequest{
 		Username:    username,
 		Bid_id:      bid_id,
 		Name:        param.Name,
@@ -931,6 +1065,12 @@ func (h *Handle) ChangeBid(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(err_response)
 			return
 		}
+		if err == service.UserDeactivated {
+			err_response["reason"] = err.Error()
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
 		if err == service.IsNotResponsible {
 			err_response["reason"] = err.Error()
 			w.WriteHeader(http.StatusForbidden)
@@ -988,7 +1128,7 @@ func (h *Handle) RollbackBid(w http.ResponseWriter, r *http.Request) {
 	queryParams := r.URL.Query()
 
 	username := queryParams.Get("username")
-	tender, err := h.srv.RollbackOffer(h.ctx, service.RollbackOfferRequest{
+	tender, err := h.srv.RollbackOffer(h.requestContext(r), service.RollbackOfferRequest{
 		Username: username,
 		Offer_id: bid_id,
 		Version:  int32(version),
@@ -1000,6 +1140,12 @@ func (h *Handle) RollbackBid(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(err_response)
 			return
 		}
+		if err == service.UserDeactivated {
+			err_response["reason"] = err.Error()
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
 		if err == service.IsNotResponsible {
 			err_response["reason"] = err.Error()
 			w.WriteHeader(http.StatusForbidden)
@@ -1058,11 +1204,38 @@ func (h *Handle) Submit_Decision(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(err_response)
 		return
 	}
+	lot_id := queryParams.Get("lotId")
+	if _, err := uuid.Parse(lot_id); lot_id != "" && err != nil {
+		err_response["reason"] = InvalidParams + ": некорректный формат lotId"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	conflict_declared := false
+	if value := queryParams.Get("conflictOfInterest"); value != "" {
+		conflict_declared, err = strconv.ParseBool(value)
+		if err != nil {
+			err_response["reason"] = InvalidParams + ": conflictOfInterest может быть true или false"
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
+	}
+	conflict_comment := queryParams.Get("conflictComment")
+	if len([]rune(conflict_comment)) > 1000 {
+		err_response["reason"] = InvalidParams + ": conflictComment длиннее 1000 символов"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
 
-	bid, err := h.srv.DecisionSubmit(h.ctx, service.DecisionRequest{
-		Username: username,
-		Bid_id:   bid_id,
-		Desicion: decision,
+	bid, err := h.srv.DecisionSubmit(h.requestContext(r), service.DecisionRequest{
+		Username:         username,
+		Bid_id:           bid_id,
+		Desicion:         decision,
+		Lot_id:           lot_id,
+		ConflictDeclared: conflict_declared,
+		ConflictComment:  conflict_comment,
 	})
 	if err != nil {
 		if err == service.UserNotFound {
@@ -1071,13 +1244,19 @@ func (h *Handle) Submit_Decision(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(err_response)
 			return
 		}
-		if err == service.IsNotResponsible {
+		if err == service.UserDeactivated {
 			err_response["reason"] = err.Error()
 			w.WriteHeader(http.StatusForbidden)
 			json.NewEncoder(w).Encode(err_response)
 			return
 		}
-		if err == service.BidNotFound {
+		if err == service.IsNotResponsible || err == service.ConflictBlocked {
+			err_response["reason"] = err.Error()
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
+		if err == service.BidNotFound || err == service.LotNotFound {
 			err_response["reason"] = err.Error()
 			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(err_response)
@@ -1089,6 +1268,14 @@ func (h *Handle) Submit_Decision(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(err_response)
 			return
 		}
+		if err == service.BidsSealed || err == service.AuctionRunning || err == service.LotRequired ||
+			err == service.LotClosed || err == service.BidNotInLot || err == service.TenderHasNoLots ||
+			err == service.BidCanceled {
+			err_response["reason"] = err.Error()
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
 		err_response["reason"] = service.UnknowError
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(err_response)
@@ -1139,11 +1326,19 @@ func (h *Handle) Feedback(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(err_response)
 		return
 	}
+	ratings, reason := ratingsParam(queryParams)
+	if reason != "" {
+		err_response["reason"] = reason
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
 
-	bid, err := h.srv.NewFeedBack(h.ctx, service.NewFeedBackRequest{
+	bid, err := h.srv.NewFeedBack(h.requestContext(r), service.NewFeedBackRequest{
 		Bid_id:   bid_id,
 		Content:  content,
 		Username: username,
+		Ratings:  ratings,
 	})
 	if err != nil {
 		if err == service.UserNotFound {
@@ -1152,6 +1347,12 @@ func (h *Handle) Feedback(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(err_response)
 			return
 		}
+		if err == service.UserDeactivated {
+			err_response["reason"] = err.Error()
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
 		if err == service.IsNotResponsible {
 			err_response["reason"] = err.Error()
 			w.WriteHeader(http.StatusForbidden)
@@ -1231,14 +1432,20 @@ func (h *Handle) Reviews(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(err_response)
 		return
 	}
+	filter, reason := reviewFilterParams(queryParams)
+	if reason != "" {
+		err_response["reason"] = reason
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	filter.Tender_ID = tender_id
+	filter.AuthorUsername = authorUsername
+	filter.RequesterUsername = requesterUsername
+	filter.Limit = limit
+	filter.Offset = offset
 
-	reviews, err := h.srv.OfferAuthorReviews(h.ctx, service.OfferAuthorReviewsRequest{
-		Tender_ID:         tender_id,
-		AuthorUsername:    authorUsername,
-		RequesterUsername: requesterUsername,
-		Limit:             limit,
-		Offset:            offset,
-	})
+	reviews, err := h.srv.OfferAuthorReviews(h.requestContext(r), filter)
 
 	if err != nil {
 		if err == service.UserNotFound {
@@ -1247,6 +1454,12 @@ func (h *Handle) Reviews(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(err_response)
 			return
 		}
+		if err == service.UserDeactivated {
+			err_response["reason"] = err.Error()
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
 		if err == service.IsNotResponsible {
 			err_response["reason"] = err.Error()
 			w.WriteHeader(http.StatusForbidden)
@@ -0,0 +1,48 @@
+package handles
+
+import (
+	"encoding/json"
+	"net/http"
+	"tender_service/internal/scheduler"
+	"tender_service/internal/service"
+)
+
+// Healthz - проверка живости процесса, базу не трогает
+func (h *Handle) Healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+type readinessResponse struct {
+	service.Readiness
+	Scheduler string                `json:"scheduler"`
+	Jobs      []scheduler.JobStatus `json:"jobs"`
+}
+
+// Readyz - готовность принимать трафик: база, миграции и фоновые задачи
+func (h *Handle) Readyz(w http.ResponseWriter, r *http.Request) {
+	response := readinessResponse{
+		Readiness: h.srv.CheckReadiness(h.requestContext(r)),
+		Scheduler: "stopped",
+		Jobs:      []scheduler.JobStatus{},
+	}
+	if h.sched != nil {
+		if h.sched.Running() {
+			response.Scheduler = "running"
+		}
+		if jobs := h.sched.Status(); jobs != nil {
+			response.Jobs = jobs
+		}
+	}
+	ready := response.Ready && response.Scheduler == "running"
+	response.Ready = ready
+
+	w.Header().Set("Content-Type", "application/json")
+	if ready {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(response)
+}
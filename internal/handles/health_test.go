@@ -0,0 +1,92 @@
+package handles
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"tender_service/internal/database"
+	"tender_service/internal/database/dbtest"
+	"tender_service/internal/scheduler"
+	"tender_service/internal/service"
+	"testing"
+	"time"
+)
+
+type readyzBody struct {
+	Ready                    bool                  `json:"ready"`
+	Database                 string                `json:"database"`
+	MigrationVersion         int64                 `json:"migrationVersion"`
+	ExpectedMigrationVersion int64                 `json:"expectedMigrationVersion"`
+	Scheduler                string                `json:"scheduler"`
+	Jobs                     []scheduler.JobStatus `json:"jobs"`
+}
+
+func readyz(t *testing.T, h *Handle) (int, readyzBody) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var body readyzBody
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Jobs == nil {
+		t.Error("jobs must be an array, not null")
+	}
+	return w.Code, body
+}
+
+func TestHealthz(t *testing.T) {
+	w := httptest.NewRecorder()
+	New(nil, nil).Healthz(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "{\"status\":\"ok\"}\n" {
+		t.Errorf("healthz = %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestReadyzDatabaseUnavailable(t *testing.T) {
+	// на порту 1 никто не слушает, соединение сразу отклоняется
+	db, err := sql.Open("postgres", "host=127.0.0.1 port=1 sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	sched := scheduler.New()
+	sched.Start(context.Background())
+	defer sched.Stop()
+
+	code, body := readyz(t, New(service.New(database.NewService(db)), sched))
+	if code != http.StatusServiceUnavailable || body.Ready || body.Database != "unavailable" {
+		t.Errorf("readyz = %d %+v", code, body)
+	}
+	if body.Scheduler != "running" {
+		t.Errorf("scheduler = %q, want running", body.Scheduler)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	_, query := dbtest.Queries(t)
+	sched := scheduler.New()
+	sched.Add(scheduler.Job{Name: "refresh", Interval: time.Hour, Run: func(context.Context) error { return nil }})
+	h := New(service.New(query), sched)
+
+	// база готова, но фоновые задачи не запущены
+	code, body := readyz(t, h)
+	if code != http.StatusServiceUnavailable || body.Ready || body.Database != "ok" || body.Scheduler != "stopped" {
+		t.Errorf("readyz before start = %d %+v", code, body)
+	}
+
+	sched.Start(context.Background())
+	defer sched.Stop()
+	code, body = readyz(t, h)
+	if code != http.StatusOK || !body.Ready || body.Scheduler != "running" {
+		t.Errorf("readyz = %d %+v", code, body)
+	}
+	if body.MigrationVersion == 0 || body.MigrationVersion != body.ExpectedMigrationVersion {
+		t.Errorf("migration version = %d, expected %d", body.MigrationVersion, body.ExpectedMigrationVersion)
+	}
+	if len(body.Jobs) != 1 || body.Jobs[0].Name != "refresh" || body.Jobs[0].LastRun != nil {
+		t.Errorf("jobs = %+v", body.Jobs)
+	}
+}
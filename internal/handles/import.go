@@ -0,0 +1,224 @@
+package handles
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"tender_service/internal/service"
+	"tender_service/internal/utils"
+)
+
+// maxImportRows - ограничение на число тендеров в одном файле импорта
+const maxImportRows = 1000
+
+var tooManyImportRows = fmt.Errorf("в файле больше %d строк", maxImportRows)
+
+// importColumns - обязательные колонки CSV, порядок в файле любой
+var importColumns = []string{"name", "description", "serviceType", "status", "organizationId"}
+
+// validateImportRow дополняет проверки NewTender ограничениями схемы,
+// чтобы одна неверная строка не обрывала транзакцию импорта
+func validateImportRow(params service.TenderParams) string {
+	if reason := validateTenderParams(params); reason != "" {
+		return reason
+	}
+	if !utils.CheckString(params.Status, []string{"Created", "Published", "Closed"}) {
+		return InvalidParams + ": неверное значение status"
+	}
+	if len(params.Name) > 100 {
+		return InvalidParams + ": name не длиннее 100 символов"
+	}
+	if len(params.ServiceType) > 50 {
+		return InvalidParams + ": serviceType не длиннее 50 символов"
+	}
+	// CreateTendersTx сохраняет только основные поля тендера, условия
+	// торгов импортом не задаются, иначе они молча потеряются
+	if (params.BiddingMode != "" && params.BiddingMode != service.BiddingModeOpen) || params.SubmissionDeadline != nil {
+		return InvalidParams + ": закрытые тендеры, аукционы и сроки подачи импортом не задаются"
+	}
+	if params.Visibility != "" && params.Visibility != service.TenderPublic {
+		return InvalidParams + ": тендеры по приглашениям импортом не создаются"
+	}
+	if len(params.Lots) > 0 || params.RequiresQualification || params.EstimatedBudget != nil {
+		return InvalidParams + ": лоты, квалификация и оценка бюджета импортом не задаются"
+	}
+	return ""
+}
+
+func parseImportCSV(body io.Reader) ([]service.ImportRow, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать заголовок CSV")
+	}
+	columns := map[string]int{}
+	for n, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\uFEFF")))] = n
+	}
+	for _, name := range importColumns {
+		if _, ok := columns[strings.ToLower(name)]; !ok {
+			return nil, fmt.Errorf("в заголовке CSV нет колонки %s", name)
+		}
+	}
+	field := func(record []string, name string) string {
+		if n := columns[strings.ToLower(name)]; n < len(record) {
+			return record[n]
+		}
+		return ""
+	}
+
+	var rows []service.ImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+			rows = append(rows, service.ImportRow{Line: parseErr.StartLine, Error: InvalidParams + ": " + parseErr.Err.Error()})
+		} else {
+			line, _ := reader.FieldPos(0)
+			params := service.TenderParams{
+				Name:           field(record, "name"),
+				Description:    field(record, "description"),
+				ServiceType:    field(record, "serviceType"),
+				Status:         field(record, "status"),
+				OrganizationId: field(record, "organizationId"),
+			}
+			rows = append(rows, service.ImportRow{Line: line, Params: params, Error: validateImportRow(params)})
+		}
+		if len(rows) > maxImportRows {
+			return nil, tooManyImportRows
+		}
+	}
+	return rows, nil
+}
+
+func parseImportNDJSON(body io.Reader) ([]service.ImportRow, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	var rows []service.ImportRow
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var params service.TenderParams
+		row := service.ImportRow{Line: line}
+		if err := json.Unmarshal([]byte(text), &params); err != nil {
+			row.Error = InvalidParams
+		} else {
+			params.CreatorUsername = ""
+			row.Params = params
+			row.Error = validateImportRow(params)
+		}
+		rows = append(rows, row)
+		if len(rows) > maxImportRows {
+			return nil, tooManyImportRows
+		}
+	}
+	return rows, scanner.Err()
+}
+
+// importFormat берет формат из параметра format или из Content-Type
+func importFormat(r *http.Request) string {
+	if format := strings.ToLower(r.URL.Query().Get("format")); format != "" {
+		return format
+	}
+	content_type := strings.ToLower(r.Header.Get("Content-Type"))
+	switch {
+	case strings.Contains(content_type, "csv"):
+		return "csv"
+	case strings.Contains(content_type, "json"):
+		return "ndjson"
+	}
+	return ""
+}
+
+func (h *Handle) ImportTenders(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPost {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	queryParams := r.URL.Query()
+	username := queryParams.Get("username")
+	dry_run, _ := strconv.ParseBool(queryParams.Get("dryRun"))
+
+	var rows []service.ImportRow
+	var err error
+	switch importFormat(r) {
+	case "csv":
+		rows, err = parseImportCSV(r.Body)
+	case "ndjson":
+		rows, err = parseImportNDJSON(r.Body)
+	default:
+		err_response["reason"] = InvalidParams + ": формат должен быть csv или ndjson"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			err_response["reason"] = "Слишком большое тело запроса"
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
+		err_response["reason"] = InvalidParams + ": " + err.Error()
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if len(rows) == 0 {
+		err_response["reason"] = InvalidParams + ": файл не содержит строк"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	report, err := h.srv.ImportTenders(h.requestContext(r), service.ImportTendersRequest{
+		Username: username,
+		Rows:     rows,
+		DryRun:   dry_run,
+	})
+	if err != nil {
+		err_response["reason"] = err.Error()
+		switch err {
+		case service.UserNotFound:
+			w.WriteHeader(http.StatusUnauthorized)
+		case service.UserDeactivated:
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !report.DryRun && report.Invalid > 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,74 @@
+package handles
+
+import (
+	"strings"
+	"testing"
+)
+
+const importOrg = "550e8400-e29b-41d4-a716-446655440000"
+
+func TestParseImportNDJSONRejectsTenderTerms(t *testing.T) {
+	base := `"name":"Поставка","description":"d","serviceType":"Delivery","status":"Created","organizationId":"` + importOrg + `"`
+	tests := []struct {
+		name    string
+		row     string
+		wantErr string
+	}{
+		{name: "plain tender", row: `{` + base + `}`},
+		{name: "explicit public open tender", row: `{` + base + `,"visibility":"public","biddingMode":"open"}`},
+		{name: "invite only", row: `{` + base + `,"visibility":"invite_only"}`, wantErr: "по приглашениям"},
+		{name: "sealed", row: `{` + base + `,"biddingMode":"sealed"}`, wantErr: "закрытые тендеры"},
+		{name: "auction", row: `{` + base + `,"biddingMode":"auction"}`, wantErr: "аукционы"},
+		{name: "deadline", row: `{` + base + `,"submissionDeadline":"2030-01-01T00:00:00Z"}`, wantErr: "сроки подачи"},
+		{name: "lots", row: `{` + base + `,"lots":[{"name":"Лот 1","quantity":1}]}`, wantErr: "лоты"},
+		{name: "qualification", row: `{` + base + `,"requiresQualification":true}`, wantErr: "квалификация"},
+		{name: "budget", row: `{` + base + `,"estimatedBudget":1000}`, wantErr: "оценка бюджета"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := parseImportNDJSON(strings.NewReader(tt.row + "\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != 1 {
+				t.Fatalf("rows = %d, want 1", len(rows))
+			}
+			got := rows[0].Error
+			if tt.wantErr == "" {
+				if got != "" {
+					t.Fatalf("row error = %q, want none", got)
+				}
+				return
+			}
+			if !strings.Contains(got, tt.wantErr) {
+				t.Fatalf("row error = %q, want it to contain %q", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseImportCSV(t *testing.T) {
+	body := "\uFEFFname,description,serviceType,status,organizationId\n" +
+		"Поставка,d,Delivery,Created," + importOrg + "\n" +
+		",d,Delivery,Created," + importOrg + "\n" +
+		"Ремонт,d,Delivery,Closed,not-a-uuid\n"
+	rows, err := parseImportCSV(strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		line    int
+		wantErr bool
+	}{{2, false}, {3, true}, {4, true}}
+	if len(rows) != len(want) {
+		t.Fatalf("rows = %d, want %d", len(rows), len(want))
+	}
+	for n, row := range rows {
+		if row.Line != want[n].line || (row.Error != "") != want[n].wantErr {
+			t.Errorf("row %d: line=%d error=%q", n, row.Line, row.Error)
+		}
+	}
+	if _, err := parseImportCSV(strings.NewReader("name,status\n")); err == nil {
+		t.Error("header without required columns must be rejected")
+	}
+}
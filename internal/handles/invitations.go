@@ -0,0 +1,189 @@
+package handles
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"net/http"
+	"tender_service/internal/service"
+	"tender_service/internal/utils"
+)
+
+func writeInvitationError(w http.ResponseWriter, err error, err_response map[string]interface{}) {
+	err_response["reason"] = err.Error()
+	switch err {
+	case service.UserNotFound:
+		w.WriteHeader(http.StatusUnauthorized)
+	case service.UserDeactivated, service.IsNotResponsible:
+		w.WriteHeader(http.StatusForbidden)
+	case service.TenderNotFound, service.InvitationNotFound, service.OrganizationNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case service.InvitationAnswered:
+		w.WriteHeader(http.StatusConflict)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(err_response)
+}
+
+type InviteParam struct {
+	OrganizationIds []string `json:"organizationIds"`
+}
+
+func (h *Handle) InvitationNew(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPost {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	tender_id, ok := pathID(r, "/api/tenders/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id тендера"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	var param InviteParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		err_response["reason"] = InvalidParams
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if len(param.OrganizationIds) == 0 {
+		err_response["reason"] = "organizationIds" + FieldRequired
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	for _, org_id := range param.OrganizationIds {
+		if _, err := uuid.Parse(org_id); err != nil {
+			err_response["reason"] = InvalidParams + ": неверный формат id организации " + org_id
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
+	}
+
+	invitations, err := h.srv.InviteOrganizations(h.requestContext(r), service.InviteOrganizationsRequest{
+		Username:         r.URL.Query().Get("username"),
+		Tender_id:        tender_id,
+		Organization_ids: param.OrganizationIds,
+	})
+	if err != nil {
+		writeInvitationError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(invitations)
+}
+
+func (h *Handle) InvitationTenderList(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodGet {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	tender_id, ok := pathID(r, "/api/tenders/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id тендера"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	invitations, err := h.srv.ListTenderInvitations(h.requestContext(r), service.ListTenderInvitationsRequest{
+		Username:  r.URL.Query().Get("username"),
+		Tender_id: tender_id,
+	})
+	if err != nil {
+		writeInvitationError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(invitations)
+}
+
+func (h *Handle) InvitationMyList(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodGet {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	limit, offset := pageParams(r)
+	status := r.URL.Query().Get("status")
+	if status != "" && !utils.CheckString(status, []string{service.InvitationStatusPending,
+		service.InvitationStatusAccepted, service.InvitationStatusDeclined}) {
+		err_response["reason"] = InvalidParams + ": status может быть Pending, Accepted или Declined"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	invitations, err := h.srv.ListMyInvitations(h.requestContext(r), service.ListMyInvitationsRequest{
+		Username: r.URL.Query().Get("username"),
+		Status:   status,
+		Offset:   offset,
+		Limit:    limit,
+	})
+	if err != nil {
+		writeInvitationError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(invitations)
+}
+
+func (h *Handle) InvitationRespond(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPut {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	invitation_id, ok := pathID(r, "/api/invitations/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id приглашения"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	decision := r.URL.Query().Get("decision")
+	if !utils.CheckString(decision, []string{service.InvitationStatusAccepted, service.InvitationStatusDeclined}) {
+		err_response["reason"] = InvalidParams + ": decision может быть Accepted или Declined"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	invitation, err := h.srv.RespondInvitation(h.requestContext(r), service.RespondInvitationRequest{
+		Username:      r.URL.Query().Get("username"),
+		Invitation_id: invitation_id,
+		Decision:      decision,
+	})
+	if err != nil {
+		writeInvitationError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(invitation)
+}
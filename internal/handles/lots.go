@@ -0,0 +1,195 @@
+package handles
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"net/http"
+	"tender_service/internal/service"
+	"unicode/utf8"
+)
+
+func writeLotError(w http.ResponseWriter, err error, err_response map[string]interface{}) {
+	err_response["reason"] = err.Error()
+	switch err {
+	case service.UserNotFound:
+		w.WriteHeader(http.StatusUnauthorized)
+	case service.UserDeactivated, service.IsNotResponsible:
+		w.WriteHeader(http.StatusForbidden)
+	case service.TenderNotFound, service.LotNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case service.LotClosed, service.LotsLocked:
+		w.WriteHeader(http.StatusConflict)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(err_response)
+}
+
+// validateLot проверяет поля лота, n - номер лота для текста ошибки
+func validateLot(lot service.LotParams, n int) string {
+	prefix := fmt.Sprintf("lots[%d].", n)
+	if lot.Name == "" {
+		return prefix + "name" + FieldRequired
+	}
+	if utf8.RuneCountInString(lot.Name) > 100 {
+		return InvalidParams + ": " + prefix + "name длиннее 100 символов"
+	}
+	if utf8.RuneCountInString(lot.Description) > 500 {
+		return InvalidParams + ": " + prefix + "description длиннее 500 символов"
+	}
+	if lot.Quantity <= 0 {
+		return InvalidParams + ": " + prefix + "quantity должен быть больше нуля"
+	}
+	return ""
+}
+
+// validateBidLots проверяет цены предложения по лотам
+func validateBidLots(lots []service.BidLot) string {
+	for n, lot := range lots {
+		if _, err := uuid.Parse(lot.LotID); err != nil {
+			return InvalidParams + fmt.Sprintf(": некорректный формат lots[%d].lotId", n)
+		}
+		if lot.Price < 0.01 {
+			return InvalidParams + fmt.Sprintf(": lots[%d].price должен быть больше нуля", n)
+		}
+	}
+	return ""
+}
+
+func (h *Handle) LotList(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodGet {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	tender_id, ok := pathID(r, "/api/tenders/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id тендера"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	lots, err := h.srv.ListLots(h.requestContext(r), service.ListLotsRequest{
+		Username:  r.URL.Query().Get("username"),
+		Tender_id: tender_id,
+	})
+	if err != nil {
+		writeLotError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(lots)
+}
+
+func (h *Handle) LotAdd(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPost {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	tender_id, ok := pathID(r, "/api/tenders/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id тендера"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	var param service.LotParams
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		err_response["reason"] = InvalidParams
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if reason := validateLot(param, 0); reason != "" {
+		err_response["reason"] = reason
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	lot, err := h.srv.AddLot(h.requestContext(r), service.AddLotRequest{
+		Username:  r.URL.Query().Get("username"),
+		Tender_id: tender_id,
+		Lot:       param,
+	})
+	if err != nil {
+		writeLotError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(lot)
+}
+
+type LotCancelParam struct {
+	Reason string `json:"reason"`
+}
+
+func (h *Handle) LotCancel(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPut {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	vars := mux.Vars(r)
+	tender_id := vars["id"]
+	lot_id := vars["lotId"]
+	if _, err := uuid.Parse(tender_id); err != nil {
+		err_response["reason"] = InvalidParams + ": некорректный формат id тендера"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if _, err := uuid.Parse(lot_id); err != nil {
+		err_response["reason"] = InvalidParams + ": некорректный формат id лота"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	var param LotCancelParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		err_response["reason"] = InvalidParams
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if param.Reason == "" {
+		err_response["reason"] = "reason" + FieldRequired
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	lot, err := h.srv.CancelLot(h.requestContext(r), service.CancelLotRequest{
+		Username:  r.URL.Query().Get("username"),
+		Tender_id: tender_id,
+		Lot_id:    lot_id,
+		Reason:    param.Reason,
+	})
+	if err != nil {
+		writeLotError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(lot)
+}
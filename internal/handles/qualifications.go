@@ -0,0 +1,282 @@
+package handles
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"net/http"
+	"net/url"
+	"tender_service/internal/service"
+	"tender_service/internal/utils"
+	"time"
+)
+
+func writeQualificationError(w http.ResponseWriter, err error, err_response map[string]interface{}) {
+	err_response["reason"] = err.Error()
+	switch err {
+	case service.UserNotFound:
+		w.WriteHeader(http.StatusUnauthorized)
+	case service.UserDeactivated, service.IsNotResponsible:
+		w.WriteHeader(http.StatusForbidden)
+	case service.QualificationNotFound, service.OrganizationNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case service.QualificationPending, service.QualificationDecided, service.QualificationNotApproved:
+		w.WriteHeader(http.StatusConflict)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(err_response)
+}
+
+type QualificationApplyParam struct {
+	BuyerOrganizationId string                          `json:"buyerOrganizationId"`
+	ServiceType         string                          `json:"serviceType"`
+	Documents           []service.QualificationDocument `json:"documents"`
+	Comment             string                          `json:"comment"`
+}
+
+func validateQualificationApply(param QualificationApplyParam) string {
+	if _, err := uuid.Parse(param.BuyerOrganizationId); err != nil {
+		return InvalidParams + ": неверный формат поля buyerOrganizationId"
+	}
+	if param.ServiceType == "" {
+		return "serviceType" + FieldRequired
+	}
+	if len(param.Documents) == 0 {
+		return "documents" + FieldRequired
+	}
+	for n, doc := range param.Documents {
+		if doc.Name == "" {
+			return fmt.Sprintf("documents[%d].name", n) + FieldRequired
+		}
+		link, err := url.ParseRequestURI(doc.URL)
+		if err != nil || (link.Scheme != "http" && link.Scheme != "https") {
+			return InvalidParams + fmt.Sprintf(": documents[%d].url должен быть ссылкой http или https", n)
+		}
+	}
+	if len([]rune(param.Comment)) > 1000 {
+		return InvalidParams + ": comment длиннее 1000 символов"
+	}
+	return ""
+}
+
+func (h *Handle) QualificationApply(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPost {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	var param QualificationApplyParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		err_response["reason"] = InvalidParams
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if reason := validateQualificationApply(param); reason != "" {
+		err_response["reason"] = reason
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	qualification, err := h.srv.ApplyQualification(h.requestContext(r), service.ApplyQualificationRequest{
+		Username:     r.URL.Query().Get("username"),
+		Buyer_org_id: param.BuyerOrganizationId,
+		ServiceType:  param.ServiceType,
+		Documents:    param.Documents,
+		Comment:      param.Comment,
+	})
+	if err != nil {
+		writeQualificationError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(qualification)
+}
+
+func (h *Handle) QualificationList(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodGet {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	queryParams := r.URL.Query()
+	limit, offset := pageParams(r)
+	role := queryParams.Get("role")
+	if role != "" && !utils.CheckString(role, []string{"buyer", "supplier"}) {
+		err_response["reason"] = InvalidParams + ": role может быть buyer или supplier"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	status := queryParams.Get("status")
+	if status != "" && !utils.CheckString(status, []string{service.QualificationStatusPending,
+		service.QualificationStatusApproved, service.QualificationStatusRejected,
+		service.QualificationStatusRevoked, service.QualificationStatusExpired}) {
+		err_response["reason"] = InvalidParams + ": status может быть Pending, Approved, Rejected, Revoked или Expired"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	qualifications, err := h.srv.ListQualifications(h.requestContext(r), service.ListQualificationsRequest{
+		Username:    queryParams.Get("username"),
+		AsBuyer:     role == "buyer",
+		Status:      status,
+		ServiceType: queryParams.Get("serviceType"),
+		Offset:      offset,
+		Limit:       limit,
+	})
+	if err != nil {
+		writeQualificationError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(qualifications)
+}
+
+type QualificationDecisionParam struct {
+	Decision  string     `json:"decision"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+func (h *Handle) QualificationDecide(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPut {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	qualification_id, ok := pathID(r, "/api/qualifications/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id заявки"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	var param QualificationDecisionParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		err_response["reason"] = InvalidParams
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if !utils.CheckString(param.Decision, []string{service.QualificationStatusApproved, service.QualificationStatusRejected}) {
+		err_response["reason"] = InvalidParams + ": decision может быть Approved или Rejected"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	qualification, err := h.srv.DecideQualification(h.requestContext(r), service.DecideQualificationRequest{
+		Username:         r.URL.Query().Get("username"),
+		Qualification_id: qualification_id,
+		Decision:         param.Decision,
+		Reason:           param.Reason,
+		ExpiresAt:        param.ExpiresAt,
+	})
+	if err != nil {
+		writeQualificationError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(qualification)
+}
+
+type QualificationRevokeParam struct {
+	Reason string `json:"reason"`
+}
+
+func (h *Handle) QualificationRevoke(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPut {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	qualification_id, ok := pathID(r, "/api/qualifications/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id заявки"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	var param QualificationRevokeParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		err_response["reason"] = InvalidParams
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if param.Reason == "" {
+		err_response["reason"] = "reason" + FieldRequired
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	qualification, err := h.srv.RevokeQualification(h.requestContext(r), service.RevokeQualificationRequest{
+		Username:         r.URL.Query().Get("username"),
+		Qualification_id: qualification_id,
+		Reason:           param.Reason,
+	})
+	if err != nil {
+		writeQualificationError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(qualification)
+}
+
+// VendorList - допущенные поставщики организации заказчика
+func (h *Handle) VendorList(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodGet {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	org_id, ok := pathID(r, "/api/organizations/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": неверный формат id организации"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	vendors, err := h.srv.ListVendors(h.requestContext(r), service.ListVendorsRequest{
+		Username:        r.URL.Query().Get("username"),
+		Organization_id: org_id,
+		ServiceType:     r.URL.Query().Get("serviceType"),
+	})
+	if err != nil {
+		writeQualificationError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(vendors)
+}
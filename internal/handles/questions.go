@@ -0,0 +1,206 @@
+package handles
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"tender_service/internal/service"
+	"tender_service/internal/utils"
+)
+
+func writeQuestionError(w http.ResponseWriter, err error, err_response map[string]interface{}) {
+	err_response["reason"] = err.Error()
+	switch err {
+	case service.UserNotFound:
+		w.WriteHeader(http.StatusUnauthorized)
+	case service.UserDeactivated, service.IsNotResponsible:
+		w.WriteHeader(http.StatusForbidden)
+	case service.TenderNotFound, service.QuestionNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(err_response)
+}
+
+type QuestionParam struct {
+	Question string `json:"question"`
+}
+
+func (h *Handle) QuestionNew(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPost {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	tender_id, ok := pathID(r, "/api/tenders/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id тендера"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	username := r.URL.Query().Get("username")
+
+	var param QuestionParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		err_response["reason"] = InvalidParams
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if param.Question == "" {
+		err_response["reason"] = "question" + FieldRequired
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if len(param.Question) > 5000 {
+		err_response["reason"] = InvalidParams + ": вопрос не длиннее 5000 символов"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	question, err := h.srv.AskQuestion(h.requestContext(r), service.AskQuestionRequest{
+		Username:  username,
+		Tender_id: tender_id,
+		Question:  param.Question,
+	})
+	if err != nil {
+		writeQuestionError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(question)
+}
+
+type AnswerParam struct {
+	Answer     string `json:"answer"`
+	Visibility string `json:"visibility"`
+}
+
+func (h *Handle) QuestionAnswer(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPut {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	vars := mux.Vars(r)
+	tender_id := vars["id"]
+	question_id := vars["questionId"]
+	if _, err := uuid.Parse(tender_id); err != nil {
+		err_response["reason"] = InvalidParams + ": некорректный формат id тендера"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if _, err := uuid.Parse(question_id); err != nil {
+		err_response["reason"] = InvalidParams + ": некорректный формат id вопроса"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	username := r.URL.Query().Get("username")
+
+	var param AnswerParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		err_response["reason"] = InvalidParams
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if param.Answer == "" {
+		err_response["reason"] = "answer" + FieldRequired
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if param.Visibility == "" {
+		param.Visibility = service.VisibilityPublic
+	}
+	if !utils.CheckString(param.Visibility, []string{service.VisibilityPublic, service.VisibilityPrivate}) {
+		err_response["reason"] = InvalidParams + ": visibility может быть public или private"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	question, err := h.srv.AnswerQuestion(h.requestContext(r), service.AnswerQuestionRequest{
+		Username:    username,
+		Tender_id:   tender_id,
+		Question_id: question_id,
+		Answer:      param.Answer,
+		Visibility:  param.Visibility,
+	})
+	if err != nil {
+		writeQuestionError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(question)
+}
+
+func (h *Handle) QuestionList(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodGet {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	tender_id, ok := pathID(r, "/api/tenders/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id тендера"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	queryParams := r.URL.Query()
+	var limit, offset int32
+	limit_param := queryParams.Get("limit")
+	offset_param := queryParams.Get("offset")
+	username := queryParams.Get("username")
+
+	if limit_param == "" || !utils.IsNumeric(limit_param) {
+		limit = 5
+	} else {
+		tl, _ := strconv.Atoi(limit_param)
+		limit = int32(tl)
+	}
+
+	if offset_param == "" || !utils.IsNumeric(offset_param) {
+		offset = 0
+	} else {
+		tl, _ := strconv.Atoi(offset_param)
+		offset = int32(tl)
+	}
+
+	questions, err := h.srv.ListQuestions(h.requestContext(r), service.ListQuestionsRequest{
+		Username:  username,
+		Tender_id: tender_id,
+		Offset:    offset,
+		Limit:     limit,
+	})
+	if err != nil {
+		writeQuestionError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(questions)
+}
@@ -0,0 +1,421 @@
+package handles
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"net/http"
+	"net/url"
+	"strconv"
+	"tender_service/internal/service"
+	"tender_service/internal/utils"
+)
+
+func writeReviewError(w http.ResponseWriter, err error, err_response map[string]interface{}) {
+	err_response["reason"] = err.Error()
+	switch err {
+	case service.UserNotFound:
+		w.WriteHeader(http.StatusUnauthorized)
+	case service.UserDeactivated, service.NotReviewAuthor, service.IsNotResponsible, service.IsNotAdmin:
+		w.WriteHeader(http.StatusForbidden)
+	case service.ReviewNotFound, service.OrganizationNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case service.ReviewLocked, service.ReviewAnswered, service.ReplyExists,
+		service.DisputeExists, service.DisputeNotOpen:
+		w.WriteHeader(http.StatusConflict)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(err_response)
+}
+
+// ratingsParam читает оценки отзыва из параметров запроса. Если не задана
+// ни одна оценка, отзыв сохраняется без них.
+func ratingsParam(query url.Values) (*service.Ratings, string) {
+	names := []string{"quality", "timeliness", "communication"}
+	values := make([]int16, len(names))
+	found := false
+	for n, name := range names {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseInt(raw, 10, 16)
+		if err != nil {
+			return nil, InvalidParams + ": " + name + " должно быть числом от 1 до 5"
+		}
+		values[n] = int16(value)
+		found = true
+	}
+	if !found {
+		return nil, ""
+	}
+	return &service.Ratings{Quality: values[0], Timeliness: values[1], Communication: values[2]}, ""
+}
+
+type ReviewEditParam struct {
+	Description string           `json:"description"`
+	Ratings     *service.Ratings `json:"ratings"`
+}
+
+func (h *Handle) ReviewEdit(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPatch {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	review_id, ok := pathID(r, "/api/reviews/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id отзыва"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	var param ReviewEditParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		err_response["reason"] = InvalidParams
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if param.Description == "" {
+		err_response["reason"] = "description" + FieldRequired
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	review, err := h.srv.EditReview(h.requestContext(r), service.EditReviewRequest{
+		Username:  r.URL.Query().Get("username"),
+		Review_id: review_id,
+		Content:   param.Description,
+		Ratings:   param.Ratings,
+	})
+	if err != nil {
+		writeReviewError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(review)
+}
+
+func (h *Handle) ReviewDelete(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodDelete {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	review_id, ok := pathID(r, "/api/reviews/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id отзыва"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	err := h.srv.DeleteReview(h.requestContext(r), service.DeleteReviewRequest{
+		Username:  r.URL.Query().Get("username"),
+		Review_id: review_id,
+	})
+	if err != nil {
+		writeReviewError(w, err, err_response)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handle) Reputation(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodGet {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	org_id, ok := pathID(r, "/api/organizations/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": неверный формат id организации"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	reputation, err := h.srv.GetReputation(h.requestContext(r), service.GetReputationRequest{
+		Username:        r.URL.Query().Get("username"),
+		Organization_id: org_id,
+	})
+	if err != nil {
+		writeReviewError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reputation)
+}
+
+func (h *Handle) ReviewReceivedList(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodGet {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	limit, offset := pageParams(r)
+	bid_id := r.URL.Query().Get("bidId")
+	if _, err := uuid.Parse(bid_id); bid_id != "" && err != nil {
+		err_response["reason"] = InvalidParams + ": некорректный формат bidId"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	reviews, err := h.srv.ListReceivedReviews(h.requestContext(r), service.ListReceivedReviewsRequest{
+		Username: r.URL.Query().Get("username"),
+		Bid_id:   bid_id,
+		Offset:   offset,
+		Limit:    limit,
+	})
+	if err != nil {
+		writeReviewError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reviews)
+}
+
+type ReviewReplyParam struct {
+	Content string `json:"content"`
+}
+
+func (h *Handle) ReviewReply(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPost {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	review_id, ok := pathID(r, "/api/reviews/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id отзыва"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	var param ReviewReplyParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		err_response["reason"] = InvalidParams
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if param.Content == "" {
+		err_response["reason"] = "content" + FieldRequired
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	review, err := h.srv.ReplyReview(h.requestContext(r), service.ReplyReviewRequest{
+		Username:  r.URL.Query().Get("username"),
+		Review_id: review_id,
+		Content:   param.Content,
+	})
+	if err != nil {
+		writeReviewError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(review)
+}
+
+type ReviewDisputeParam struct {
+	Reason string `json:"reason"`
+}
+
+func (h *Handle) ReviewDispute(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPut {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	review_id, ok := pathID(r, "/api/reviews/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id отзыва"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	var param ReviewDisputeParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		err_response["reason"] = InvalidParams
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if param.Reason == "" {
+		err_response["reason"] = "reason" + FieldRequired
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	review, err := h.srv.DisputeReview(h.requestContext(r), service.DisputeReviewRequest{
+		Username:  r.URL.Query().Get("username"),
+		Review_id: review_id,
+		Reason:    param.Reason,
+	})
+	if err != nil {
+		writeReviewError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(review)
+}
+
+// ReviewDisputeList - очередь модерации для администраторов
+func (h *Handle) ReviewDisputeList(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodGet {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	limit, offset := pageParams(r)
+	status := r.URL.Query().Get("status")
+	if status != "" && !utils.CheckString(status, []string{service.DisputeOpen, service.DisputeUpheld, service.DisputeDismissed}) {
+		err_response["reason"] = InvalidParams + ": status может быть Open, Upheld или Dismissed"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	reviews, err := h.srv.ListDisputes(h.requestContext(r), service.ListDisputesRequest{
+		Username: r.URL.Query().Get("username"),
+		Status:   status,
+		Offset:   offset,
+		Limit:    limit,
+	})
+	if err != nil {
+		writeReviewError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reviews)
+}
+
+type ReviewModerateParam struct {
+	Decision string `json:"decision"`
+	Comment  string `json:"comment"`
+}
+
+func (h *Handle) ReviewModerate(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPut {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	review_id, ok := pathID(r, "/api/reviews/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id отзыва"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	var param ReviewModerateParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		err_response["reason"] = InvalidParams
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if !utils.CheckString(param.Decision, []string{service.DisputeUpheld, service.DisputeDismissed}) {
+		err_response["reason"] = InvalidParams + ": decision может быть Upheld или Dismissed"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	review, err := h.srv.ModerateReview(h.requestContext(r), service.ModerateReviewRequest{
+		Username:  r.URL.Query().Get("username"),
+		Review_id: review_id,
+		Decision:  param.Decision,
+		Comment:   param.Comment,
+	})
+	if err != nil {
+		writeReviewError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(review)
+}
+
+// reviewFilterParams читает фильтры истории отзывов автора
+func reviewFilterParams(query url.Values) (service.OfferAuthorReviewsRequest, string) {
+	var filter service.OfferAuthorReviewsRequest
+	by := query.Get("by")
+	if by != "" && !utils.CheckString(by, []string{"author", "organization"}) {
+		return filter, InvalidParams + ": by может быть author или organization"
+	}
+	filter.ByOrganization = by == "organization"
+	filter.Reviewer_org_id = query.Get("reviewerOrganizationId")
+	if _, err := uuid.Parse(filter.Reviewer_org_id); filter.Reviewer_org_id != "" && err != nil {
+		return filter, InvalidParams + ": неверный формат поля reviewerOrganizationId"
+	}
+	var err error
+	if filter.From, err = parseTimeParam(query.Get("from")); err != nil {
+		return filter, InvalidParams + ": неверный формат поля from"
+	}
+	if filter.To, err = parseTimeParam(query.Get("to")); err != nil {
+		return filter, InvalidParams + ": неверный формат поля to"
+	}
+	for _, rating := range []struct {
+		name string
+		dst  *int16
+	}{{"minRating", &filter.MinRating}, {"maxRating", &filter.MaxRating}} {
+		raw := query.Get(rating.name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 || value > 5 {
+			return filter, InvalidParams + ": " + rating.name + " должно быть числом от 1 до 5"
+		}
+		*rating.dst = int16(value)
+	}
+	if filter.MaxRating > 0 && filter.MinRating > filter.MaxRating {
+		return filter, InvalidParams + ": minRating больше maxRating"
+	}
+	return filter, ""
+}
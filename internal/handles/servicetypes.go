@@ -0,0 +1,200 @@
+package handles
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"strings"
+	"tender_service/internal/service"
+)
+
+func writeServiceTypeError(w http.ResponseWriter, err error, err_response map[string]interface{}) {
+	err_response["reason"] = err.Error()
+	switch err {
+	case service.UserNotFound:
+		w.WriteHeader(http.StatusUnauthorized)
+	case service.UserDeactivated, service.IsNotAdmin:
+		w.WriteHeader(http.StatusForbidden)
+	case service.ServiceTypeNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case service.ServiceTypeExists:
+		w.WriteHeader(http.StatusConflict)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(err_response)
+}
+
+// requestLanguage берет язык из параметра lang или заголовка Accept-Language
+func requestLanguage(r *http.Request) string {
+	lang := r.URL.Query().Get("lang")
+	if lang == "" {
+		lang = r.Header.Get("Accept-Language")
+	}
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if len(lang) > 2 {
+		lang = lang[:2]
+	}
+	if lang == "" {
+		return service.DefaultLanguage
+	}
+	return lang
+}
+
+func (h *Handle) ServiceTypeList(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodGet {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	queryParams := r.URL.Query()
+	include_inactive, _ := strconv.ParseBool(queryParams.Get("includeInactive"))
+
+	types, err := h.srv.ListServiceTypes(h.requestContext(r), service.ListServiceTypesRequest{
+		Parent:          queryParams.Get("parent"),
+		Lang:            requestLanguage(r),
+		IncludeInactive: include_inactive,
+	})
+	if err != nil {
+		writeServiceTypeError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(types)
+}
+
+func (h *Handle) ServiceTypeGet(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	code := mux.Vars(r)["code"]
+
+	service_type, err := h.srv.GetServiceType(h.requestContext(r), code, requestLanguage(r))
+	if err != nil {
+		writeServiceTypeError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(service_type)
+}
+
+type ServiceTypeParam struct {
+	Code     string            `json:"code"`
+	Parent   *string           `json:"parent"`
+	Names    map[string]string `json:"names"`
+	IsActive *bool             `json:"isActive"`
+}
+
+func validateServiceTypeParam(param ServiceTypeParam) string {
+	if len(param.Code) > 50 || (param.Parent != nil && len(*param.Parent) > 50) {
+		return InvalidParams + ": код не длиннее 50 символов"
+	}
+	if strings.ContainsAny(param.Code, ", ") {
+		return InvalidParams + ": код не может содержать пробелы и запятые"
+	}
+	for lang, name := range param.Names {
+		if lang == "" || name == "" || len(name) > 100 {
+			return InvalidParams + ": названия должны быть непустыми и не длиннее 100 символов"
+		}
+	}
+	return ""
+}
+
+func (h *Handle) ServiceTypeNew(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPost {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	username := r.URL.Query().Get("username")
+
+	var param ServiceTypeParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		err_response["reason"] = InvalidParams
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if param.Code == "" {
+		err_response["reason"] = "code" + FieldRequired
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if reason := validateServiceTypeParam(param); reason != "" {
+		err_response["reason"] = reason
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	service_type, err := h.srv.CreateServiceType(h.requestContext(r), service.ServiceTypeRequest{
+		Username: username,
+		Code:     param.Code,
+		Parent:   param.Parent,
+		Names:    param.Names,
+		IsActive: param.IsActive,
+	})
+	if err != nil {
+		writeServiceTypeError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(service_type)
+}
+
+func (h *Handle) ServiceTypeEdit(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPatch {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	code := mux.Vars(r)["code"]
+	username := r.URL.Query().Get("username")
+
+	var param ServiceTypeParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		err_response["reason"] = InvalidParams
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	param.Code = code
+	if reason := validateServiceTypeParam(param); reason != "" {
+		err_response["reason"] = reason
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	service_type, err := h.srv.EditServiceType(h.requestContext(r), service.ServiceTypeRequest{
+		Username: username,
+		Code:     code,
+		Parent:   param.Parent,
+		Names:    param.Names,
+		IsActive: param.IsActive,
+	})
+	if err != nil {
+		writeServiceTypeError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(service_type)
+}
@@ -0,0 +1,367 @@
+package handles
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"net/http"
+	"strconv"
+	"strings"
+	"tender_service/internal/service"
+	"tender_service/internal/utils"
+)
+
+func writeTemplateError(w http.ResponseWriter, err error, err_response map[string]interface{}) {
+	err_response["reason"] = err.Error()
+	switch err {
+	case service.UserNotFound:
+		w.WriteHeader(http.StatusUnauthorized)
+	case service.UserDeactivated, service.IsNotResponsible:
+		w.WriteHeader(http.StatusForbidden)
+	case service.TemplateNotFound, service.TenderNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(err_response)
+}
+
+type TemplateParam struct {
+	OrganizationId     string              `json:"organizationId"`
+	Name               string              `json:"name"`
+	Description        string              `json:"description"`
+	ServiceType        string              `json:"serviceType"`
+	EvaluationCriteria []service.Criterion `json:"evaluationCriteria"`
+	SubmissionDays     *int32              `json:"submissionDays"`
+	DecisionDays       *int32              `json:"decisionDays"`
+}
+
+func validateTemplateParam(param TemplateParam) string {
+	if param.Name == "" {
+		return "name" + FieldRequired
+	}
+	if param.ServiceType == "" {
+		return "serviceType" + FieldRequired
+	}
+	if len(param.Name) > 100 || len(param.ServiceType) > 50 {
+		return InvalidParams + ": name не длиннее 100, serviceType не длиннее 50 символов"
+	}
+	for _, c := range param.EvaluationCriteria {
+		if c.Name == "" || c.Weight < 0 || c.Weight > 100 {
+			return InvalidParams + ": у критерия должно быть имя и вес от 0 до 100"
+		}
+	}
+	if (param.SubmissionDays != nil && *param.SubmissionDays < 0) || (param.DecisionDays != nil && *param.DecisionDays < 0) {
+		return InvalidParams + ": сроки не могут быть отрицательными"
+	}
+	return ""
+}
+
+// pathID достает id из пути вида prefix{id}/...
+func pathID(r *http.Request, prefix string) (string, bool) {
+	id := strings.Split(strings.TrimPrefix(r.URL.Path, prefix), "/")[0]
+	_, err := uuid.Parse(id)
+	return id, err == nil
+}
+
+func (h *Handle) TemplateNew(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPost {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	username := r.URL.Query().Get("username")
+
+	var param TemplateParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		err_response["reason"] = InvalidParams
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if _, err := uuid.Parse(param.OrganizationId); err != nil {
+		err_response["reason"] = InvalidParams + ": неверный формат поля organizationId"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if reason := validateTemplateParam(param); reason != "" {
+		err_response["reason"] = reason
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	template, err := h.srv.CreateTemplate(h.requestContext(r), service.TemplateRequest{
+		Username:           username,
+		OrganizationID:     param.OrganizationId,
+		Name:               param.Name,
+		Description:        param.Description,
+		ServiceType:        param.ServiceType,
+		EvaluationCriteria: param.EvaluationCriteria,
+		SubmissionDays:     param.SubmissionDays,
+		DecisionDays:       param.DecisionDays,
+	})
+	if err != nil {
+		writeTemplateError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(template)
+}
+
+func (h *Handle) TemplateList(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodGet {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	queryParams := r.URL.Query()
+	var limit, offset int32
+	limit_param := queryParams.Get("limit")
+	offset_param := queryParams.Get("offset")
+	username := queryParams.Get("username")
+	organization_id := queryParams.Get("organizationId")
+
+	if limit_param == "" || !utils.IsNumeric(limit_param) {
+		limit = 5
+	} else {
+		tl, _ := strconv.Atoi(limit_param)
+		limit = int32(tl)
+	}
+
+	if offset_param == "" || !utils.IsNumeric(offset_param) {
+		offset = 0
+	} else {
+		tl, _ := strconv.Atoi(offset_param)
+		offset = int32(tl)
+	}
+
+	if organization_id != "" {
+		if _, err := uuid.Parse(organization_id); err != nil {
+			err_response["reason"] = InvalidParams + ": неверный формат поля organizationId"
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
+	}
+
+	templates, err := h.srv.ListTemplates(h.requestContext(r), service.ListTemplatesRequest{
+		Username:       username,
+		OrganizationID: organization_id,
+		Offset:         offset,
+		Limit:          limit,
+	})
+	if err != nil {
+		writeTemplateError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(templates)
+}
+
+func (h *Handle) TemplateGet(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	template_id, ok := pathID(r, "/api/templates/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id шаблона"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	username := r.URL.Query().Get("username")
+
+	template, err := h.srv.GetTemplate(h.requestContext(r), username, template_id)
+	if err != nil {
+		writeTemplateError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(template)
+}
+
+func (h *Handle) TemplateEdit(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPatch {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	template_id, ok := pathID(r, "/api/templates/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id шаблона"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	username := r.URL.Query().Get("username")
+
+	var param TemplateParam
+	if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+		err_response["reason"] = InvalidParams
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if reason := validateTemplateParam(param); reason != "" {
+		err_response["reason"] = reason
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	template, err := h.srv.EditTemplate(h.requestContext(r), service.TemplateRequest{
+		Username:           username,
+		Template_id:        template_id,
+		Name:               param.Name,
+		Description:        param.Description,
+		ServiceType:        param.ServiceType,
+		EvaluationCriteria: param.EvaluationCriteria,
+		SubmissionDays:     param.SubmissionDays,
+		DecisionDays:       param.DecisionDays,
+	})
+	if err != nil {
+		writeTemplateError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(template)
+}
+
+func (h *Handle) TemplateDelete(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodDelete {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	template_id, ok := pathID(r, "/api/templates/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id шаблона"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	username := r.URL.Query().Get("username")
+
+	if err := h.srv.DeleteTemplate(h.requestContext(r), username, template_id); err != nil {
+		writeTemplateError(w, err, err_response)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type FromTemplateParam struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+}
+
+func (h *Handle) TenderFromTemplate(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPost {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	template_id, ok := pathID(r, "/api/tenders/from-template/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id шаблона"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	username := r.URL.Query().Get("username")
+
+	// тело необязательно: без него берутся значения шаблона
+	var param FromTemplateParam
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&param); err != nil {
+			err_response["reason"] = InvalidParams
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(err_response)
+			return
+		}
+	}
+	if param.Status != "" && !utils.CheckString(param.Status, []string{"Created", "Published"}) {
+		err_response["reason"] = InvalidParams + ": неверное значение status"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	if len(param.Name) > 100 {
+		err_response["reason"] = InvalidParams + ": name не длиннее 100 символов"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+
+	tender, err := h.srv.CreateTenderFromTemplate(h.requestContext(r), service.FromTemplateRequest{
+		Username:    username,
+		Template_id: template_id,
+		Name:        param.Name,
+		Description: param.Description,
+		Status:      param.Status,
+	})
+	if err != nil {
+		writeTemplateError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tender)
+}
+
+func (h *Handle) CloneTender(w http.ResponseWriter, r *http.Request) {
+	err_response := map[string]interface{}{
+		"reason": "",
+	}
+	if r.Method != http.MethodPost {
+		err_response["reason"] = MethodNotAllowed
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	tender_id, ok := pathID(r, "/api/tenders/")
+	if !ok {
+		err_response["reason"] = InvalidParams + ": некорректный формат id тендера"
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(err_response)
+		return
+	}
+	username := r.URL.Query().Get("username")
+
+	tender, err := h.srv.CloneTender(h.requestContext(r), service.CloneTenderRequest{
+		Username:  username,
+		Tender_id: tender_id,
+	})
+	if err != nil {
+		writeTemplateError(w, err, err_response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tender)
+}
@@ -0,0 +1,45 @@
+// Package idempotency описывает хранилище ответов для повторных запросов
+// с заголовком Idempotency-Key
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+const Header = "Idempotency-Key"
+
+// MaxKeyLength совпадает с размером колонки idempotency_key.key
+const MaxKeyLength = 255
+
+type Record struct {
+	Scope       string
+	Key         string
+	RequestHash string
+	// Completed - false, пока первый запрос с этим ключом еще выполняется
+	Completed   bool
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+type Store interface {
+	// Reserve занимает ключ. Если ключ уже занят и не истек, возвращает
+	// существующую запись и false.
+	Reserve(ctx context.Context, record Record) (*Record, bool, error)
+	// Complete сохраняет ответ на запрос
+	Complete(ctx context.Context, record Record) error
+	// Release освобождает ключ, чтобы запрос можно было повторить
+	Release(ctx context.Context, scope, key string) error
+}
+
+// RequestHash - отпечаток запроса: метод, путь, параметры и тело
+func RequestHash(method, path, query string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "?" + query + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
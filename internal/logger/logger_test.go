@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in   string
+		want slog.Level
+	}{
+		{in: "debug", want: slog.LevelDebug},
+		{in: "INFO", want: slog.LevelInfo},
+		{in: "warn", want: slog.LevelWarn},
+		{in: "Warning", want: slog.LevelWarn},
+		{in: "error", want: slog.LevelError},
+		{in: "", want: slog.LevelInfo},
+		{in: "trace", want: slog.LevelInfo},
+	}
+	for _, tt := range tests {
+		if got := ParseLevel(tt.in); got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNewWritesJSONFromLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, "warn")
+	l.Info("skipped")
+	l.Warn("written", "tender_id", "t-1")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 1 {
+		t.Fatalf("lines = %q, want only the warning", buf.String())
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal(lines[0], &record); err != nil {
+		t.Fatalf("log line is not JSON: %v", err)
+	}
+	if record["msg"] != "written" || record["level"] != "WARN" || record["tender_id"] != "t-1" {
+		t.Errorf("record = %v", record)
+	}
+}
+
+func TestContextLogger(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("context without logger must return the default logger")
+	}
+	l := New(&bytes.Buffer{}, "info")
+	ctx := WithContext(context.Background(), l)
+	if FromContext(ctx) != l {
+		t.Error("FromContext must return the logger stored by WithContext")
+	}
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T, r *Registry) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	return w.Body.String()
+}
+
+func assertLines(t *testing.T, body string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, body)
+		}
+	}
+}
+
+func TestCounterVec(t *testing.T) {
+	r := NewRegistry()
+	requests := NewCounterVec("requests_total", "Запросы", "method", "route")
+	plain := NewCounterVec("plain_total", "Без меток")
+	r.Register(requests)
+	r.Register(plain)
+
+	requests.Inc("GET", "/api/ping")
+	requests.Inc("GET", "/api/ping")
+	requests.Add(2.5, "POST", `/api/"quoted"\path`)
+
+	assertLines(t, scrape(t, r),
+		"# HELP requests_total Запросы",
+		"# TYPE requests_total counter",
+		`requests_total{method="GET",route="/api/ping"} 2`,
+		`requests_total{method="POST",route="/api/\"quoted\"\\path"} 2.5`,
+		"# TYPE plain_total counter",
+		// счетчик без меток виден с нулем еще до первого события
+		"plain_total 0",
+	)
+}
+
+func TestHistogramVec(t *testing.T) {
+	r := NewRegistry()
+	h := NewHistogramVec("latency_seconds", "Задержка", []float64{0.1, 1}, "route")
+	r.Register(h)
+	h.Observe(0.05, "/a")
+	h.Observe(0.5, "/a")
+	h.Observe(2, "/a")
+
+	assertLines(t, scrape(t, r),
+		"# TYPE latency_seconds histogram",
+		`latency_seconds_bucket{route="/a",le="0.1"} 1`,
+		`latency_seconds_bucket{route="/a",le="1"} 2`,
+		`latency_seconds_bucket{route="/a",le="+Inf"} 3`,
+		`latency_seconds_sum{route="/a"} 2.55`,
+		`latency_seconds_count{route="/a"} 3`,
+	)
+}
+
+func TestGaugeFunc(t *testing.T) {
+	r := NewRegistry()
+	value := 3.0
+	r.Register(NewGaugeFunc("pool_open", "Открытые", func() float64 { return value }))
+	r.Register(NewCounterFunc("pool_waits_total", "Ожидания", func() float64 { return 7 }))
+
+	assertLines(t, scrape(t, r), "# TYPE pool_open gauge", "pool_open 3", "# TYPE pool_waits_total counter", "pool_waits_total 7")
+	value = 5
+	assertLines(t, scrape(t, r), "pool_open 5")
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"database/sql"
+)
+
+// Default - реестр, который отдается на /metrics
+var Default = NewRegistry()
+
+var (
+	HTTPRequests = NewCounterVec("http_requests_total",
+		"Количество HTTP запросов", "method", "route", "status")
+	HTTPDuration = NewHistogramVec("http_request_duration_seconds",
+		"Время обработки HTTP запросов", DefBuckets, "method", "route", "status")
+	DBQueryDuration = NewHistogramVec("db_query_duration_seconds",
+		"Время выполнения запросов к базе по методам Queries", DefBuckets, "method", "result")
+
+	TendersCreated = NewCounterVec("tenders_created_total",
+		"Количество созданных тендеров")
+	BidsSubmitted = NewCounterVec("bids_submitted_total",
+		"Количество поданных предложений")
+	Decisions = NewCounterVec("decisions_total",
+		"Количество решений по предложениям", "outcome")
+	QuorumReached = NewCounterVec("quorum_reached_total",
+		"Количество предложений, набравших кворум")
+)
+
+func init() {
+	Default.Register(HTTPRequests)
+	Default.Register(HTTPDuration)
+	Default.Register(DBQueryDuration)
+	Default.Register(TendersCreated)
+	Default.Register(BidsSubmitted)
+	Default.Register(Decisions)
+	Default.Register(QuorumReached)
+}
+
+// RegisterDBStats добавляет в реестр статистику пула соединений
+func RegisterDBStats(r *Registry, db *sql.DB) {
+	stat := func(f func(s sql.DBStats) float64) func() float64 {
+		return func() float64 { return f(db.Stats()) }
+	}
+	r.Register(NewGaugeFunc("db_pool_max_open_connections", "Максимум открытых соединений",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) })))
+	r.Register(NewGaugeFunc("db_pool_open_connections", "Открытые соединения",
+		stat(func(s sql.DBStats) float64 { return float64(s.OpenConnections) })))
+	r.Register(NewGaugeFunc("db_pool_in_use_connections", "Соединения в работе",
+		stat(func(s sql.DBStats) float64 { return float64(s.InUse) })))
+	r.Register(NewGaugeFunc("db_pool_idle_connections", "Свободные соединения",
+		stat(func(s sql.DBStats) float64 { return float64(s.Idle) })))
+	r.Register(NewCounterFunc("db_pool_wait_count_total", "Количество ожиданий соединения",
+		stat(func(s sql.DBStats) float64 { return float64(s.WaitCount) })))
+	r.Register(NewCounterFunc("db_pool_wait_duration_seconds_total", "Суммарное время ожидания соединения",
+		stat(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() })))
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"github.com/gorilla/mux"
+	"log/slog"
+	"net/http"
+	"tender_service/internal/logger"
+	"time"
+)
+
+// statusRecorder запоминает код ответа и размер тела
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// Unwrap нужен http.ResponseController, например для Flush и продления
+// дедлайна записи при выгрузках
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+func (rec *statusRecorder) Status() int {
+	if rec.status == 0 {
+		return http.StatusOK
+	}
+	return rec.status
+}
+
+// RouteTemplate возвращает шаблон маршрута mux, например /api/bids/{bidid}/status
+func RouteTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unmatched"
+}
+
+func requestUser(r *http.Request) string {
+	query := r.URL.Query()
+	if username := query.Get("username"); username != "" {
+		return username
+	}
+	return query.Get("requesterUsername")
+}
+
+// AccessLog пишет одну запись на каждый запрос
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		level := slog.LevelInfo
+		if rec.Status() >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logger.FromContext(r.Context()).Log(r.Context(), level, "http request",
+			"method", r.Method,
+			"route", RouteTemplate(r),
+			"path", r.URL.Path,
+			"status", rec.Status(),
+			"bytes", rec.bytes,
+			"latency_ms", float64(time.Since(start).Microseconds())/1000,
+			"user", requestUser(r),
+			"remote_addr", r.RemoteAddr,
+		)
+	})
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"tender_service/internal/logger"
+	"testing"
+)
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	router := mux.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := logger.WithContext(r.Context(), logger.New(&buf, "info"))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	router.Use(RequestID, AccessLog)
+	router.HandleFunc("/api/bids/{bidid}/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Published"))
+	})
+	router.HandleFunc("/api/fail", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	tests := []struct {
+		name      string
+		target    string
+		wantRoute string
+		wantCode  float64
+		wantLevel string
+		wantUser  string
+		wantBytes float64
+	}{
+		{name: "ok", target: "/api/bids/42/status?username=alice", wantRoute: "/api/bids/{bidid}/status",
+			wantCode: 200, wantLevel: "INFO", wantUser: "alice", wantBytes: 9},
+		{name: "requester username", target: "/api/bids/42/status?requesterUsername=bob", wantRoute: "/api/bids/{bidid}/status",
+			wantCode: 200, wantLevel: "INFO", wantUser: "bob", wantBytes: 9},
+		{name: "server error", target: "/api/fail", wantRoute: "/api/fail", wantCode: 500, wantLevel: "ERROR"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			r.Header.Set(RequestIDHeader, "req-"+strings.ReplaceAll(tt.name, " ", "-"))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			var record map[string]interface{}
+			if err := json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &record); err != nil {
+				t.Fatalf("access log %q: %v", buf.String(), err)
+			}
+			want := map[string]interface{}{
+				"msg":        "http request",
+				"level":      tt.wantLevel,
+				"route":      tt.wantRoute,
+				"status":     tt.wantCode,
+				"user":       tt.wantUser,
+				"bytes":      tt.wantBytes,
+				"request_id": "req-" + strings.ReplaceAll(tt.name, " ", "-"),
+			}
+			for key, value := range want {
+				if record[key] != value {
+					t.Errorf("%s = %v, want %v", key, record[key], value)
+				}
+			}
+			if _, ok := record["latency_ms"]; !ok {
+				t.Error("latency_ms is missing")
+			}
+		})
+	}
+}
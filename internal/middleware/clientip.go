@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type clientIPKey struct{}
+
+// ClientIP определяет адрес клиента и кладет его в контекст. X-Forwarded-For
+// учитывается, только если соединение пришло от доверенного прокси: список
+// просматривается справа налево, и адресом клиента считается первый адрес,
+// который не принадлежит доверенным прокси. Без доверенных прокси
+// заголовок игнорируется.
+func ClientIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), clientIPKey{}, resolveClientIP(r, trusted))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequestIP возвращает адрес, найденный ClientIP, или адрес соединения,
+// если middleware не подключен
+func RequestIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteHost(r)
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func isTrusted(trusted []netip.Prefix, value string) bool {
+	addr, err := netip.ParseAddr(strings.TrimSpace(value))
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func resolveClientIP(r *http.Request, trusted []netip.Prefix) string {
+	peer := remoteHost(r)
+	if len(trusted) == 0 || !isTrusted(trusted, peer) {
+		return peer
+	}
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for n := len(hops) - 1; n >= 0; n-- {
+		hop := strings.TrimSpace(hops[n])
+		addr, err := netip.ParseAddr(hop)
+		if err != nil {
+			// дальше цепочке верить нельзя
+			return peer
+		}
+		if !isTrusted(trusted, hop) {
+			return addr.Unmap().String()
+		}
+		peer = hop
+	}
+	return peer
+}
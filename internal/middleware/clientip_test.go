@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestResolveClientIP(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.168.1.1/32"),
+	}
+	tests := []struct {
+		name    string
+		remote  string
+		xff     []string
+		trusted []netip.Prefix
+		want    string
+	}{
+		{name: "no proxies configured ignores header", remote: "203.0.113.5:4000", xff: []string{"1.2.3.4"}, want: "203.0.113.5"},
+		{name: "untrusted peer ignores header", remote: "203.0.113.5:4000", xff: []string{"1.2.3.4"}, trusted: trusted, want: "203.0.113.5"},
+		{name: "trusted peer without header", remote: "10.0.0.2:4000", trusted: trusted, want: "10.0.0.2"},
+		{name: "trusted peer uses last hop", remote: "10.0.0.2:4000", xff: []string{"1.2.3.4"}, trusted: trusted, want: "1.2.3.4"},
+		{name: "spoofed left entries are skipped", remote: "10.0.0.2:4000", xff: []string{"6.6.6.6, 1.2.3.4"}, trusted: trusted, want: "1.2.3.4"},
+		{name: "chain of trusted proxies", remote: "10.0.0.2:4000", xff: []string{"1.2.3.4, 192.168.1.1", "10.1.1.1"}, trusted: trusted, want: "1.2.3.4"},
+		{name: "all hops trusted", remote: "10.0.0.2:4000", xff: []string{"10.0.0.3"}, trusted: trusted, want: "10.0.0.3"},
+		{name: "garbage hop falls back to peer", remote: "10.0.0.2:4000", xff: []string{"1.2.3.4, bogus"}, trusted: trusted, want: "10.0.0.2"},
+		{name: "mapped ipv4 is unmapped", remote: "10.0.0.2:4000", xff: []string{"::ffff:1.2.3.4"}, trusted: trusted, want: "1.2.3.4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/ping", nil)
+			r.RemoteAddr = tt.remote
+			for _, value := range tt.xff {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if got := resolveClientIP(r, tt.trusted); got != tt.want {
+				t.Errorf("resolveClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"tender_service/internal/idempotency"
+	"tender_service/internal/logger"
+	"time"
+)
+
+// bodyRecorder сохраняет копию ответа, чтобы отдать ее при повторе запроса
+type bodyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *bodyRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *bodyRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+func writeIdempotencyError(w http.ResponseWriter, status int, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"reason": reason,
+	})
+}
+
+// Idempotency обрабатывает заголовок Idempotency-Key на изменяющих запросах.
+// Ключ действует в пределах пользователя (параметр username) в течение ttl:
+// повтор с тем же телом получает сохраненный ответ, с другим телом - 422.
+// Ответы 5xx не сохраняются, чтобы запрос можно было повторить.
+func Idempotency(store idempotency.Store, ttl time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(idempotency.Header)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			switch r.Method {
+			case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			default:
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > idempotency.MaxKeyLength {
+				writeIdempotencyError(w, http.StatusBadRequest, "Idempotency-Key не длиннее 255 символов")
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					writeIdempotencyError(w, http.StatusRequestEntityTooLarge, "Слишком большое тело запроса")
+					return
+				}
+				writeIdempotencyError(w, http.StatusBadRequest, "Не удалось прочитать тело запроса")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			log := logger.FromContext(r.Context())
+			record := idempotency.Record{
+				Scope:       requestUser(r),
+				Key:         key,
+				RequestHash: idempotency.RequestHash(r.Method, r.URL.Path, r.URL.RawQuery, body),
+				ExpiresAt:   time.Now().Add(ttl),
+			}
+			existing, reserved, err := store.Reserve(r.Context(), record)
+			if err != nil {
+				log.Error("idempotency reserve failed", "err", err)
+				writeIdempotencyError(w, http.StatusInternalServerError, "Ошибка сервера")
+				return
+			}
+			if !reserved {
+				switch {
+				case existing.RequestHash != record.RequestHash:
+					writeIdempotencyError(w, http.StatusUnprocessableEntity, "Idempotency-Key уже использован с другим запросом")
+				case !existing.Completed:
+					writeIdempotencyError(w, http.StatusConflict, "Запрос с этим Idempotency-Key еще выполняется")
+				default:
+					if existing.ContentType != "" {
+						w.Header().Set("Content-Type", existing.ContentType)
+					}
+					w.Header().Set("Idempotent-Replayed", strconv.FormatBool(true))
+					w.WriteHeader(existing.StatusCode)
+					w.Write(existing.Body)
+				}
+				return
+			}
+
+			rec := &bodyRecorder{ResponseWriter: w}
+			// ответ нужно сохранить, даже если клиент уже отключился
+			saveCtx := context.WithoutCancel(r.Context())
+			defer func() {
+				// ключ освобождается и при панике обработчика
+				if rec.status == 0 || rec.status >= http.StatusInternalServerError {
+					if err := store.Release(saveCtx, record.Scope, record.Key); err != nil {
+						log.Error("idempotency release failed", "err", err)
+					}
+					return
+				}
+				record.Completed = true
+				record.StatusCode = rec.status
+				record.ContentType = rec.Header().Get("Content-Type")
+				record.Body = rec.body.Bytes()
+				if err := store.Complete(saveCtx, record); err != nil {
+					log.Error("idempotency complete failed", "err", err)
+				}
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"tender_service/internal/idempotency"
+	"testing"
+	"time"
+)
+
+// memStore - хранилище ключей в памяти с той же семантикой, что и таблица
+type memStore struct {
+	mu      sync.Mutex
+	records map[string]idempotency.Record
+}
+
+func newMemStore() *memStore {
+	return &memStore{records: map[string]idempotency.Record{}}
+}
+
+func (s *memStore) Reserve(_ context.Context, record idempotency.Record) (*idempotency.Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := record.Scope + "/" + record.Key
+	if existing, ok := s.records[id]; ok && existing.ExpiresAt.After(time.Now()) {
+		return &existing, false, nil
+	}
+	s.records[id] = record
+	return &record, true, nil
+}
+
+func (s *memStore) Complete(_ context.Context, record idempotency.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[record.Scope+"/"+record.Key] = record
+	return nil
+}
+
+func (s *memStore) Release(_ context.Context, scope, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, scope+"/"+key)
+	return nil
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	calls := 0
+	handler := Idempotency(newMemStore(), time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"call":` + strconv.Itoa(calls) + `,"echo":` + string(body) + `}`))
+	}))
+	send := func(key, username, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/tenders/new?username="+username, strings.NewReader(body))
+		if key != "" {
+			r.Header.Set(idempotency.Header, key)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	first := send("k1", "alice", `{"name":"a"}`)
+	if first.Code != http.StatusOK || first.Header().Get("Idempotent-Replayed") != "" {
+		t.Fatalf("first = %d %v", first.Code, first.Header())
+	}
+	replay := send("k1", "alice", `{"name":"a"}`)
+	if replay.Code != http.StatusOK || replay.Body.String() != first.Body.String() {
+		t.Errorf("replay = %d %q, want %q", replay.Code, replay.Body.String(), first.Body.String())
+	}
+	if replay.Header().Get("Idempotent-Replayed") != "true" || replay.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("replay headers = %v", replay.Header())
+	}
+	if calls != 1 {
+		t.Errorf("handler calls = %d, want 1", calls)
+	}
+
+	if w := send("k1", "alice", `{"name":"b"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("different body = %d, want 422", w.Code)
+	}
+	// ключ действует в пределах пользователя
+	if w := send("k1", "bob", `{"name":"a"}`); w.Code != http.StatusOK || calls != 2 {
+		t.Errorf("other user = %d, calls = %d", w.Code, calls)
+	}
+	if w := send("", "alice", `{"name":"a"}`); w.Code != http.StatusOK || calls != 3 {
+		t.Errorf("without key = %d, calls = %d", w.Code, calls)
+	}
+	if w := send(strings.Repeat("k", idempotency.MaxKeyLength+1), "alice", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("long key = %d, want 400", w.Code)
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	store := newMemStore()
+	store.Reserve(context.Background(), idempotency.Record{
+		Scope:       "alice",
+		Key:         "k1",
+		RequestHash: idempotency.RequestHash(http.MethodPost, "/api/bids/new", "username=alice", []byte(`{}`)),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	})
+	handler := Idempotency(store, time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler must not run while the first request is in flight")
+	}))
+	r := httptest.NewRequest(http.MethodPost, "/api/bids/new?username=alice", strings.NewReader(`{}`))
+	r.Header.Set(idempotency.Header, "k1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusConflict {
+		t.Errorf("in flight = %d, want 409", w.Code)
+	}
+}
+
+func TestIdempotencyReleasesOnServerError(t *testing.T) {
+	store := newMemStore()
+	status := http.StatusInternalServerError
+	calls := 0
+	handler := Idempotency(store, time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+	}))
+	send := func(method string) int {
+		r := httptest.NewRequest(method, "/api/tenders/new?username=alice", strings.NewReader(`{}`))
+		r.Header.Set(idempotency.Header, "k1")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	send(http.MethodPost)
+	status = http.StatusOK
+	if code := send(http.MethodPost); code != http.StatusOK || calls != 2 {
+		t.Errorf("retry after 5xx = %d, calls = %d; key must be released", code, calls)
+	}
+	// GET не изменяет данные, ключ на нем игнорируется
+	send(http.MethodGet)
+	send(http.MethodGet)
+	if calls != 4 {
+		t.Errorf("GET calls = %d, want 4", calls)
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"tender_service/internal/metrics"
+	"time"
+)
+
+// Metrics считает запросы и время их обработки по шаблону маршрута и коду ответа
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec, ok := w.(*statusRecorder)
+		if !ok {
+			rec = &statusRecorder{ResponseWriter: w}
+		}
+		next.ServeHTTP(rec, r)
+
+		route := RouteTemplate(r)
+		status := strconv.Itoa(rec.Status())
+		metrics.HTTPRequests.Inc(r.Method, route, status)
+		metrics.HTTPDuration.Observe(time.Since(start).Seconds(), r.Method, route, status)
+	})
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"tender_service/internal/metrics"
+	"testing"
+)
+
+func TestMetricsUsesRouteTemplate(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(Metrics)
+	router.HandleFunc("/api/tenders/{tenderId}/status", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	for _, id := range []string{"1", "2", "3"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/tenders/"+id+"/status", nil))
+	}
+
+	w := httptest.NewRecorder()
+	metrics.Default.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	// id из пути не попадают в метки, иначе число рядов растет без ограничений
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/api/tenders/{tenderId}/status",status="404"} 3`,
+		`http_request_duration_seconds_count{method="GET",route="/api/tenders/{tenderId}/status",status="404"} 3`,
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("missing %q", want)
+		}
+	}
+	if strings.Contains(body, "/api/tenders/1/status") {
+		t.Error("raw path must not be used as a label")
+	}
+}
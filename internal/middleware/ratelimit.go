@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"tender_service/internal/logger"
+	"tender_service/internal/ratelimit"
+	"time"
+)
+
+// publicPaths не ограничиваются: проверки доступности и метрики
+var publicPaths = map[string]bool{
+	"/api/ping": true,
+	"/healthz":  true,
+	"/readyz":   true,
+	"/metrics":  true,
+}
+
+// OrgResolver возвращает id организации пользователя или пустую строку
+type OrgResolver func(ctx context.Context, username string) string
+
+func setRateLimitHeaders(w http.ResponseWriter, res ratelimit.Result) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(res.Reset.Seconds()))))
+}
+
+// RateLimit ограничивает запросы по IP, пользователю (параметр username) и
+// его организации. Бюджет выбирается по шаблону маршрута. В ответ добавляются
+// заголовки RateLimit-* по самому строгому из сработавших лимитов.
+func RateLimit(store ratelimit.Store, policy ratelimit.Policy, resolveOrg OrgResolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if publicPaths[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			route := RouteTemplate(r)
+			budget := policy.For(r.Method, route)
+			username := requestUser(r)
+			now := time.Now()
+
+			type check struct {
+				key   string
+				limit ratelimit.Limit
+			}
+			checks := []check{{key: "ip:" + RequestIP(r), limit: budget.IP}}
+			if username != "" {
+				checks = append(checks, check{key: "user:" + username, limit: budget.User})
+				if !budget.Org.IsZero() && resolveOrg != nil {
+					if org_id := resolveOrg(r.Context(), username); org_id != "" {
+						checks = append(checks, check{key: "org:" + org_id, limit: budget.Org})
+					}
+				}
+			}
+
+			var strictest *ratelimit.Result
+			for _, c := range checks {
+				if c.limit.IsZero() {
+					continue
+				}
+				res, err := store.Take(r.Context(), route+"|"+c.key, c.limit, now)
+				if err != nil {
+					logger.FromContext(r.Context()).Error("rate limit store failed", "err", err)
+					continue
+				}
+				if strictest == nil || !res.Allowed || (strictest.Allowed && res.Remaining < strictest.Remaining) {
+					res := res
+					strictest = &res
+				}
+				if !res.Allowed {
+					break
+				}
+			}
+
+			if strictest != nil {
+				setRateLimitHeaders(w, *strictest)
+				if !strictest.Allowed {
+					w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(strictest.RetryAfter.Seconds()))))
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusTooManyRequests)
+					json.NewEncoder(w).Encode(map[string]interface{}{
+						"reason": "Слишком много запросов, повторите позже",
+					})
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// MaxBody ограничивает размер тела запросов с телом (POST, PUT, PATCH)
+func MaxBody(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limit > 0 && r.Body != nil {
+				switch r.Method {
+				case http.MethodPost, http.MethodPut, http.MethodPatch:
+					if r.ContentLength > limit {
+						w.Header().Set("Content-Type", "application/json")
+						w.WriteHeader(http.StatusRequestEntityTooLarge)
+						json.NewEncoder(w).Encode(map[string]interface{}{
+							"reason": "Слишком большое тело запроса",
+						})
+						return
+					}
+					r.Body = http.MaxBytesReader(w, r.Body, limit)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"github.com/google/uuid"
+	"net/http"
+	"tender_service/internal/logger"
+)
+
+const RequestIDHeader = "X-Request-Id"
+
+const maxRequestIDLen = 100
+
+// validRequestID - id клиента попадает в логи, аудит и ответ, поэтому
+// принимаются только буквы, цифры, точка, дефис и подчеркивание
+func validRequestID(request_id string) bool {
+	if request_id == "" || len(request_id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(request_id); i++ {
+		c := request_id[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '.' || c == '_' || c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// RequestID берет X-Request-Id из запроса или генерирует новый, если его
+// нет или он не подходит. id возвращается в ответе, а в контекст кладется
+// логгер с полем request_id
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request_id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(request_id) {
+			request_id = uuid.NewString()
+			r.Header.Set(RequestIDHeader, request_id)
+		}
+		w.Header().Set(RequestIDHeader, request_id)
+
+		l := logger.FromContext(r.Context()).With("request_id", request_id)
+		ctx := logger.WithContext(r.Context(), l)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
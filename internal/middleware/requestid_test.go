@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"github.com/google/uuid"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		given    string
+		wantKeep bool
+	}{
+		{name: "missing"},
+		{name: "uuid", given: "5f1b2c9e-0000-4000-8000-000000000001", wantKeep: true},
+		{name: "allowed punctuation", given: "svc.gateway_42-a", wantKeep: true},
+		{name: "max length", given: strings.Repeat("a", maxRequestIDLen), wantKeep: true},
+		{name: "too long", given: strings.Repeat("a", maxRequestIDLen+1)},
+		{name: "space", given: "req 1"},
+		{name: "log injection", given: "req-1\" level=ERROR msg=\"forged"},
+		{name: "html", given: "<script>"},
+		{name: "non ascii", given: "запрос-1"},
+		{name: "control character", given: "req\x1b[31m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = r.Header.Get(RequestIDHeader)
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/api/ping", nil)
+			if tt.given != "" {
+				r.Header.Set(RequestIDHeader, tt.given)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			got := w.Header().Get(RequestIDHeader)
+			if got != seen {
+				t.Errorf("response id %q differs from request id %q", got, seen)
+			}
+			if tt.wantKeep {
+				if got != tt.given {
+					t.Errorf("id = %q, want %q", got, tt.given)
+				}
+				return
+			}
+			if _, err := uuid.Parse(got); err != nil {
+				t.Errorf("id = %q, want a generated uuid", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"bytes"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"tender_service/internal/logger"
+	"testing"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	var logs bytes.Buffer
+	router := mux.NewRouter()
+	router.Use(Tracing)
+	router.HandleFunc("/api/tenders/{tenderId}/status", func(w http.ResponseWriter, r *http.Request) {
+		logger.FromContext(r.Context()).Info("handler")
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+	base := logger.New(&logs, "info")
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		router.ServeHTTP(w, r.WithContext(logger.WithContext(r.Context(), base)))
+	})
+
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	r := httptest.NewRequest(http.MethodGet, "/api/tenders/42/status", nil)
+	r.Header.Set("traceparent", parent)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	ended := recorder.Ended()
+	if len(ended) != 1 {
+		t.Fatalf("spans = %d, want 1", len(ended))
+	}
+	span := ended[0]
+	if span.Name() != "GET /api/tenders/{tenderId}/status" || span.SpanKind() != trace.SpanKindServer {
+		t.Errorf("span = %q %v", span.Name(), span.SpanKind())
+	}
+	if span.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace id = %s, want the one from traceparent", span.SpanContext().TraceID())
+	}
+	if span.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("parent span = %s", span.Parent().SpanID())
+	}
+	if got := w.Header().Get("traceparent"); !strings.Contains(got, span.SpanContext().SpanID().String()) {
+		t.Errorf("response traceparent = %q, want span %s", got, span.SpanContext().SpanID())
+	}
+	if !strings.Contains(logs.String(), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`) {
+		t.Errorf("handler log must carry trace_id: %s", logs.String())
+	}
+	if span.Status().Code == codes.Error {
+		t.Error("successful request must not be marked as error")
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/tenders/42/status?fail=1", nil))
+	failed := recorder.Ended()[1]
+	if failed.Status().Code != codes.Error {
+		t.Errorf("5xx status = %v, want error", failed.Status())
+	}
+	if failed.Parent().IsValid() {
+		t.Error("request without traceparent must start a new trace")
+	}
+}
@@ -0,0 +1,147 @@
+// Package ratelimit - ограничение частоты запросов по алгоритму token bucket
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit - Requests запросов за Period, допускается всплеск до Requests
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+func (l Limit) IsZero() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+func (l Limit) String() string {
+	if l.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// ParseLimit разбирает строку вида "100/1m". Пустая строка - без ограничения.
+func ParseLimit(s string) (Limit, error) {
+	if s == "" {
+		return Limit{}, nil
+	}
+	count, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, expected N/duration", s)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: bad request count", s)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: bad period", s)
+	}
+	return Limit{Requests: n, Period: d}, nil
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset - через сколько бакет наполнится полностью
+	Reset time.Duration
+	// RetryAfter - через сколько появится следующий токен, если запрос отклонен
+	RetryAfter time.Duration
+}
+
+// Store хранит состояние бакетов. Реализация в памяти подходит для одного
+// экземпляра сервиса, для нескольких нужна общая (например, в Redis).
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}}
+}
+
+func (m *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	capacity := float64(limit.Requests)
+	rate := capacity / limit.Period.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		m.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed*rate)
+		b.updated = now
+	}
+
+	res := Result{Limit: limit.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = time.Duration((capacity - b.tokens) / rate * float64(time.Second))
+	return res, nil
+}
+
+// Cleanup удаляет бакеты, которые не использовались дольше idle
+func (m *MemoryStore) Cleanup(now time.Time, idle time.Duration) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	removed := 0
+	for key, b := range m.buckets {
+		if now.Sub(b.updated) > idle {
+			delete(m.buckets, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Budget - лимиты для одного маршрута по IP, пользователю и организации.
+// Нулевой Limit означает отсутствие ограничения по этому ключу.
+type Budget struct {
+	IP   Limit
+	User Limit
+	Org  Limit
+}
+
+// Policy - лимиты по умолчанию и переопределения для маршрутов. Ключ
+// маршрута - шаблон mux, с методом ("PUT /api/bids/{bidid}/submit_decision")
+// или без него ("/api/bids/new").
+type Policy struct {
+	Default Budget
+	Routes  map[string]Budget
+}
+
+func (p Policy) For(method, route string) Budget {
+	if b, ok := p.Routes[method+" "+route]; ok {
+		return b
+	}
+	if b, ok := p.Routes[route]; ok {
+		return b
+	}
+	return p.Default
+}
@@ -0,0 +1,125 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limit := Limit{Requests: 3, Period: 3 * time.Second}
+
+	type take struct {
+		at         time.Duration
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+	}
+	tests := []struct {
+		name  string
+		takes []take
+	}{
+		{
+			name: "burst up to capacity",
+			takes: []take{
+				{at: 0, allowed: true, remaining: 2},
+				{at: 0, allowed: true, remaining: 1},
+				{at: 0, allowed: true, remaining: 0},
+			},
+		},
+		{
+			name: "reject when empty",
+			takes: []take{
+				{at: 0, allowed: true, remaining: 2},
+				{at: 0, allowed: true, remaining: 1},
+				{at: 0, allowed: true, remaining: 0},
+				{at: 0, allowed: false, remaining: 0, retryAfter: time.Second},
+				{at: 500 * time.Millisecond, allowed: false, remaining: 0, retryAfter: 500 * time.Millisecond},
+			},
+		},
+		{
+			name: "refill one token per period/requests",
+			takes: []take{
+				{at: 0, allowed: true, remaining: 2},
+				{at: 0, allowed: true, remaining: 1},
+				{at: 0, allowed: true, remaining: 0},
+				{at: time.Second, allowed: true, remaining: 0},
+				{at: time.Second, allowed: false, remaining: 0, retryAfter: time.Second},
+			},
+		},
+		{
+			name: "refill is capped at capacity",
+			takes: []take{
+				{at: 0, allowed: true, remaining: 2},
+				{at: time.Hour, allowed: true, remaining: 2},
+				{at: time.Hour, allowed: true, remaining: 1},
+				{at: time.Hour, allowed: true, remaining: 0},
+				{at: time.Hour, allowed: false, remaining: 0, retryAfter: time.Second},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			for n, step := range tt.takes {
+				res, err := store.Take(context.Background(), "key", limit, start.Add(step.at))
+				if err != nil {
+					t.Fatalf("take %d: %v", n, err)
+				}
+				if res.Allowed != step.allowed || res.Remaining != step.remaining {
+					t.Fatalf("take %d: allowed=%v remaining=%d, want allowed=%v remaining=%d",
+						n, res.Allowed, res.Remaining, step.allowed, step.remaining)
+				}
+				if res.RetryAfter != step.retryAfter {
+					t.Fatalf("take %d: retryAfter=%s, want %s", n, res.RetryAfter, step.retryAfter)
+				}
+				if res.Limit != limit.Requests {
+					t.Fatalf("take %d: limit=%d, want %d", n, res.Limit, limit.Requests)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreTakeKeysAreIndependent(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	limit := Limit{Requests: 1, Period: time.Minute}
+	if res, _ := store.Take(context.Background(), "a", limit, now); !res.Allowed {
+		t.Fatal("first take for a must be allowed")
+	}
+	if res, _ := store.Take(context.Background(), "a", limit, now); res.Allowed {
+		t.Fatal("second take for a must be rejected")
+	}
+	if res, _ := store.Take(context.Background(), "b", limit, now); !res.Allowed {
+		t.Fatal("take for b must not be affected by a")
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{in: "", want: Limit{}},
+		{in: "100/1m", want: Limit{Requests: 100, Period: time.Minute}},
+		{in: "5/10s", want: Limit{Requests: 5, Period: 10 * time.Second}},
+		{in: "100", wantErr: true},
+		{in: "0/1m", wantErr: true},
+		{in: "x/1m", wantErr: true},
+		{in: "10/0s", wantErr: true},
+		{in: "10/soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLimit(%q) err = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLimit(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
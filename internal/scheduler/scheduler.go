@@ -0,0 +1,114 @@
+// Package scheduler запускает периодические фоновые задачи сервиса
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+type JobStatus struct {
+	Name string `json:"name"`
+	// LastRun - nil, пока задача ни разу не запускалась
+	LastRun   *time.Time `json:"lastRun,omitempty"`
+	LastError string     `json:"lastError,omitempty"`
+	Runs      int64      `json:"runs"`
+}
+
+type Scheduler struct {
+	mu      sync.Mutex
+	jobs    []Job
+	status  map[string]*JobStatus
+	running bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func New() *Scheduler {
+	return &Scheduler{status: map[string]*JobStatus{}}
+}
+
+// Add регистрирует задачу. Задачи, добавленные после Start, не запускаются.
+func (s *Scheduler) Add(job Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, job)
+	s.status[job.Name] = &JobStatus{Name: job.Name}
+}
+
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.running = true
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, job)
+	}
+}
+
+// Stop останавливает задачи и ждет завершения текущих запусков
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	s.cancel()
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := job.Run(ctx)
+			finished := time.Now()
+			s.mu.Lock()
+			st := s.status[job.Name]
+			st.LastRun = &finished
+			st.Runs++
+			st.LastError = ""
+			if err != nil {
+				st.LastError = err.Error()
+			}
+			s.mu.Unlock()
+			if err != nil {
+				slog.Error("scheduled job failed", "job", job.Name, "err", err)
+			}
+		}
+	}
+}
+
+func (s *Scheduler) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []JobStatus
+	for _, job := range s.jobs {
+		items = append(items, *s.status[job.Name])
+	}
+	return items
+}
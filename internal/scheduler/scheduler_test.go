@@ -0,0 +1,88 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJobStatusJSON(t *testing.T) {
+	data, err := json.Marshal(JobStatus{Name: "refresh"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "lastRun") {
+		t.Errorf("job that never ran must omit lastRun: %s", data)
+	}
+
+	last := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	data, err = json.Marshal(JobStatus{Name: "refresh", LastRun: &last, Runs: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"lastRun":"2024-06-01T12:00:00Z"`) {
+		t.Errorf("lastRun missing: %s", data)
+	}
+}
+
+// waitStatus ждет, пока задача выполнится n раз
+func waitStatus(t *testing.T, s *Scheduler, n int64) JobStatus {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if st := s.Status()[0]; st.Runs >= n {
+			return st
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("job did not run %d times", n)
+	return JobStatus{}
+}
+
+func TestSchedulerRecordsRuns(t *testing.T) {
+	// каждый запуск ждет свой результат, так тест управляет запусками
+	results := make(chan error)
+	s := New()
+	s.Add(Job{Name: "refresh", Interval: time.Millisecond, Run: func(ctx context.Context) error {
+		select {
+		case err := <-results:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}})
+	if st := s.Status()[0]; st.LastRun != nil || st.Runs != 0 {
+		t.Fatalf("status before start = %+v", st)
+	}
+
+	s.Start(context.Background())
+	if !s.Running() {
+		t.Error("scheduler must be running after Start")
+	}
+	results <- errors.New("boom")
+	first := waitStatus(t, s, 1)
+	if first.LastRun == nil || first.LastError != "boom" {
+		t.Errorf("first run status = %+v", first)
+	}
+	results <- nil
+	second := waitStatus(t, s, 2)
+	if second.LastError != "" {
+		t.Errorf("successful run must clear the error: %+v", second)
+	}
+	if second.LastRun.Before(*first.LastRun) {
+		t.Errorf("lastRun went back: %v then %v", first.LastRun, second.LastRun)
+	}
+
+	s.Stop()
+	if s.Running() {
+		t.Error("scheduler must stop")
+	}
+	runs := s.Status()[0].Runs
+	time.Sleep(10 * time.Millisecond)
+	if got := s.Status()[0].Runs; got != runs {
+		t.Errorf("job ran after Stop: %d -> %d", runs, got)
+	}
+}
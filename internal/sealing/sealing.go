@@ -0,0 +1,75 @@
+// Package sealing шифрует содержимое закрытых предложений (AES-256-GCM),
+// чтобы до вскрытия конвертов оно не хранилось в базе в открытом виде
+package sealing
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// KeySize - длина ключа AES-256
+const KeySize = 32
+
+var ErrCorrupted = errors.New("sealing: ciphertext is corrupted or key is wrong")
+
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// ParseKey декодирует ключ из base64 (стандартного или URL варианта)
+func ParseKey(value string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		key, err = base64.URLEncoding.DecodeString(value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sealing key must be base64: %v", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("sealing key must be %d bytes, got %d", KeySize, len(key))
+	}
+	return key, nil
+}
+
+func New(key []byte) (*Sealer, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("sealing key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealer{aead: aead}, nil
+}
+
+// Seal шифрует plaintext. aad связывает шифротекст с записью, которой он
+// принадлежит (для предложений - "<id тендера>:<id автора>"): с другим aad
+// Open вернет ErrCorrupted.
+// Результат - nonce, за которым следует шифротекст.
+func (s *Sealer) Seal(plaintext []byte, aad string) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize(), s.aead.NonceSize()+len(plaintext)+s.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, plaintext, []byte(aad)), nil
+}
+
+func (s *Sealer) Open(sealed []byte, aad string) ([]byte, error) {
+	if len(sealed) < s.aead.NonceSize() {
+		return nil, ErrCorrupted
+	}
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	plaintext, err := s.aead.Open(nil, nonce, ciphertext, []byte(aad))
+	if err != nil {
+		return nil, ErrCorrupted
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,109 @@
+package sealing
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+func testKey(fill byte) []byte {
+	return bytes.Repeat([]byte{fill}, KeySize)
+}
+
+func TestParseKey(t *testing.T) {
+	key := testKey(0xfb)
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "standard base64", value: base64.StdEncoding.EncodeToString(key)},
+		{name: "url base64", value: base64.URLEncoding.EncodeToString(key)},
+		{name: "not base64", value: "not a key!", wantErr: true},
+		{name: "too short", value: base64.StdEncoding.EncodeToString(key[:16]), wantErr: true},
+		{name: "too long", value: base64.StdEncoding.EncodeToString(append(key, 0)), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKey(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKey err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(got, key) {
+				t.Errorf("ParseKey = %x, want %x", got, key)
+			}
+		})
+	}
+}
+
+func TestNewRejectsWrongKeySize(t *testing.T) {
+	if _, err := New(testKey(1)[:16]); err == nil {
+		t.Fatal("New must reject a 16 byte key")
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	sealer, err := New(testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := New(testKey(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const aad = "tender-1:author-1"
+	plaintext := []byte(`{"name":"Поставка","description":"Закрытое предложение"}`)
+
+	sealed, err := sealer.Seal(plaintext, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, plaintext) {
+		t.Fatal("sealed content must not contain the plaintext")
+	}
+	again, err := sealer.Seal(plaintext, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(sealed, again) {
+		t.Fatal("each Seal must use a fresh nonce")
+	}
+
+	flipped := append([]byte{}, sealed...)
+	flipped[len(flipped)-1] ^= 1
+
+	tests := []struct {
+		name    string
+		sealer  *Sealer
+		sealed  []byte
+		aad     string
+		wantErr bool
+	}{
+		{name: "round trip", sealer: sealer, sealed: sealed, aad: aad},
+		{name: "second ciphertext", sealer: sealer, sealed: again, aad: aad},
+		{name: "other tender", sealer: sealer, sealed: sealed, aad: "tender-2:author-1", wantErr: true},
+		{name: "other author", sealer: sealer, sealed: sealed, aad: "tender-1:author-2", wantErr: true},
+		{name: "empty aad", sealer: sealer, sealed: sealed, aad: "", wantErr: true},
+		{name: "other key", sealer: other, sealed: sealed, aad: aad, wantErr: true},
+		{name: "tampered", sealer: sealer, sealed: flipped, aad: aad, wantErr: true},
+		{name: "truncated", sealer: sealer, sealed: sealed[:8], aad: aad, wantErr: true},
+		{name: "empty", sealer: sealer, sealed: nil, aad: aad, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.sealer.Open(tt.sealed, tt.aad)
+			if tt.wantErr {
+				if err != ErrCorrupted {
+					t.Fatalf("Open err = %v, want ErrCorrupted", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Errorf("Open = %q, want %q", got, plaintext)
+			}
+		})
+	}
+}
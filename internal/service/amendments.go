@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"tender_service/internal/database"
+	"tender_service/internal/logger"
+	"tender_service/internal/tracing"
+	"time"
+)
+
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+type Amendment struct {
+	ID                    string                 `json:"id"`
+	TenderID              string                 `json:"tenderId"`
+	Version               int32                  `json:"version"`
+	Reason                string                 `json:"reason"`
+	Changes               map[string]FieldChange `json:"changes"`
+	OldSubmissionDeadline *time.Time             `json:"oldSubmissionDeadline,omitempty"`
+	NewSubmissionDeadline *time.Time             `json:"newSubmissionDeadline,omitempty"`
+	AffectedBids          int32                  `json:"affectedBids"`
+	CreatedAt             time.Time              `json:"createdAt"`
+}
+
+func newAmendment(ctx context.Context, a *database.TenderAmendment) Amendment {
+	result := Amendment{
+		ID:           a.ID.String(),
+		TenderID:     a.TenderID.String(),
+		Version:      a.Version,
+		Reason:       a.Reason,
+		Changes:      map[string]FieldChange{},
+		AffectedBids: a.AffectedBids,
+		CreatedAt:    a.CreatedAt,
+	}
+	if err := json.Unmarshal([]byte(a.Changes), &result.Changes); err != nil {
+		logger.FromContext(ctx).Error("newAmendment: Unmarshal err", "err", err, "amendment_id", result.ID)
+	}
+	if a.OldSubmissionDeadline.Valid {
+		result.OldSubmissionDeadline = &a.OldSubmissionDeadline.Time
+	}
+	if a.NewSubmissionDeadline.Valid {
+		result.NewSubmissionDeadline = &a.NewSubmissionDeadline.Time
+	}
+	return result
+}
+
+// amendTender оформляет изменение опубликованного тендера как поправку:
+// предложения, поданные по старым условиям, ждут подтверждения авторов
+func (s *Service) amendTender(ctx context.Context, user_id string, tender database.Tender, params EditTenderRequest) (*Tender, error) {
+	if params.Reason == "" {
+		return nil, AmendmentReason
+	}
+	terms, err := s.query.GetTenderTerms(ctx, tender.ID.String())
+	if err != nil {
+		logger.FromContext(ctx).Error("amendTender: GetTenderTerms err", "err", err)
+		return nil, UnknowError
+	}
+
+	changes := map[string]FieldChange{}
+	if params.Name != "" && params.Name != tender.Name {
+		changes["name"] = FieldChange{Old: tender.Name, New: params.Name}
+	}
+	if params.Description != "" && params.Description != tender.Description {
+		changes["description"] = FieldChange{Old: tender.Description, New: params.Description}
+	}
+	if params.Service_type != "" && params.Service_type != tender.ServiceType {
+		changes["serviceType"] = FieldChange{Old: tender.ServiceType, New: params.Service_type}
+	}
+	var deadline sql.NullTime
+	if params.SubmissionDeadline != nil {
+		new_deadline := params.SubmissionDeadline.UTC().Truncate(time.Second)
+		if !new_deadline.After(time.Now()) ||
+			(terms.SubmissionDeadline.Valid && !new_deadline.After(terms.SubmissionDeadline.Time)) {
+			return nil, DeadlineNotExtended
+		}
+		deadline = sql.NullTime{Time: new_deadline, Valid: true}
+		var old interface{}
+		if terms.SubmissionDeadline.Valid {
+			old = terms.SubmissionDeadline.Time
+		}
+		changes["submissionDeadline"] = FieldChange{Old: old, New: new_deadline}
+	}
+	if len(changes) == 0 {
+		return nil, NothingToChange
+	}
+	changes_json, err := json.Marshal(changes)
+	if err != nil {
+		logger.FromContext(ctx).Error("amendTender: Marshal err", "err", err)
+		return nil, UnknowError
+	}
+
+	new_tender, amendment, err := s.query.AmendTenderTx(ctx, database.AmendTenderParams{
+		Change: database.TenderChangeParam{
+			Tender_id:    tender.ID.String(),
+			Name:         params.Name,
+			Description:  params.Description,
+			Service_type: params.Service_type,
+		},
+		History: database.CreateTenderHistoryParams{
+			Tender_id:   tender.ID.String(),
+			Creator_id:  user_id,
+			ServiceType: tender.ServiceType,
+			Name:        tender.Name,
+			Description: tender.Description,
+			OldVersion:  tender.Version,
+		},
+		SubmissionDeadline:    deadline,
+		OldSubmissionDeadline: terms.SubmissionDeadline,
+		Author_id:             user_id,
+		Reason:                params.Reason,
+		Changes:               string(changes_json),
+		Notification: fmt.Sprintf("Тендер «%s» изменен: %s. Подтвердите или отредактируйте предложение",
+			tender.Name, params.Reason),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, TenderNotFound
+		}
+		logger.FromContext(ctx).Error("amendTender: AmendTenderTx err", "err", err)
+		return nil, UnknowError
+	}
+	result := newTender(*new_tender)
+	if deadline.Valid {
+		result.SubmissionDeadline = &deadline.Time
+	} else if terms.SubmissionDeadline.Valid {
+		result.SubmissionDeadline = &terms.SubmissionDeadline.Time
+	}
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: tender.OrganizationID.String(),
+		EntityType:     EntityTender,
+		EntityID:       result.ID,
+		Action:         ActionAmend,
+		Before:         newTender(tender),
+		After:          newAmendment(ctx, amendment),
+	})
+	return &result, nil
+}
+
+type ListAmendmentsRequest struct {
+	Username  string
+	Tender_id string
+	Offset    int32
+	Limit     int32
+}
+
+// ListAmendments - поправки видны всем, кому виден тендер
+func (s *Service) ListAmendments(ctx context.Context, params ListAmendmentsRequest) ([]Amendment, error) {
+	ctx, span := tracing.Start(ctx, "service.ListAmendments")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	tender, err := s.query.GetTender(ctx, params.Tender_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, TenderNotFound
+		}
+		logger.FromContext(ctx).Error("ListAmendments: GetTender err", "err", err)
+		return nil, UnknowError
+	}
+	if err := s.checkTenderVisible(ctx, tender, user_id); err != nil {
+		return nil, err
+	}
+	if tender.Status == "Created" {
+		if err := s.isResponsibleUser(ctx, tender.OrganizationID.String(), user_id); err != nil {
+			return nil, err
+		}
+	}
+
+	amendments, err := s.query.ListAmendments(ctx, database.ListAmendmentsParams{
+		Tender_id: tender.ID.String(),
+		Offset:    params.Offset,
+		Limit:     params.Limit,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("ListAmendments: ListAmendments err", "err", err)
+		return nil, UnknowError
+	}
+	result := []Amendment{}
+	for i := range amendments {
+		result = append(result, newAmendment(ctx, &amendments[i]))
+	}
+	return result, nil
+}
+
+type ConfirmBidRequest struct {
+	Username string
+	Bid_id   string
+}
+
+// ConfirmBid - автор подтверждает, что предложение остается в силе
+// после изменения тендера
+func (s *Service) ConfirmBid(ctx context.Context, params ConfirmBidRequest) (*Bid, error) {
+	ctx, span := tracing.Start(ctx, "service.ConfirmBid")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	bid, err := s.query.GetOffer(ctx, params.Bid_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, BidNotFound
+		}
+		logger.FromContext(ctx).Error("ConfirmBid: GetOffer err", "err", err)
+		return nil, UnknowError
+	}
+	if bid.Creator_ID.String() != user_id {
+		return nil, IsNotAuthor
+	}
+	if bid.Status == "Canceled" {
+		return nil, BidCanceled
+	}
+
+	confirmed, err := s.query.ConfirmOffer(ctx, bid.ID.String())
+	if err != nil {
+		logger.FromContext(ctx).Error("ConfirmBid: ConfirmOffer err", "err", err)
+		return nil, UnknowError
+	}
+	result := &Bid{
+		ID:         confirmed.ID.String(),
+		Name:       confirmed.Name,
+		Status:     confirmed.Status,
+		AuthorType: confirmed.AuthorType,
+		AuthorId:   confirmed.AuthorId.String(),
+		Version:    confirmed.Version,
+		CreatedAt:  confirmed.CreatedAt,
+	}
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: bid.Organization_ID.String(),
+		EntityType:     EntityBid,
+		EntityID:       result.ID,
+		Action:         ActionConfirm,
+		Before:         newBid(bid),
+		After:          result,
+	})
+	s.revealBidName(ctx, result, bid.Tender_ID.String(), bid.SealedContent)
+	return result, nil
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"tender_service/internal/database"
+	"tender_service/internal/logger"
+	"testing"
+	"time"
+)
+
+func TestSubmissionClosed(t *testing.T) {
+	deadline := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		terms database.TenderTerms
+		now   time.Time
+		want  bool
+	}{
+		{name: "no deadline", terms: database.TenderTerms{BiddingMode: BiddingModeOpen}, now: deadline, want: false},
+		{name: "open before deadline", terms: database.TenderTerms{BiddingMode: BiddingModeOpen,
+			SubmissionDeadline: sql.NullTime{Time: deadline, Valid: true}}, now: deadline.Add(-time.Second), want: false},
+		{name: "open at deadline", terms: database.TenderTerms{BiddingMode: BiddingModeOpen,
+			SubmissionDeadline: sql.NullTime{Time: deadline, Valid: true}}, now: deadline, want: false},
+		{name: "open after deadline", terms: database.TenderTerms{BiddingMode: BiddingModeOpen,
+			SubmissionDeadline: sql.NullTime{Time: deadline, Valid: true}}, now: deadline.Add(time.Second), want: true},
+		{name: "sealed after deadline", terms: database.TenderTerms{BiddingMode: BiddingModeSealed,
+			SubmissionDeadline: sql.NullTime{Time: deadline, Valid: true}}, now: deadline.Add(time.Second), want: true},
+		{name: "auction after deadline", terms: database.TenderTerms{BiddingMode: BiddingModeAuction,
+			SubmissionDeadline: sql.NullTime{Time: deadline, Valid: true}}, now: deadline.Add(time.Second), want: true},
+		// продление срока изменением тендера снова открывает подачу
+		{name: "extended deadline", terms: database.TenderTerms{BiddingMode: BiddingModeOpen,
+			SubmissionDeadline: sql.NullTime{Time: deadline.Add(24 * time.Hour), Valid: true}}, now: deadline.Add(time.Second), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := submissionClosed(&tt.terms, tt.now); got != tt.want {
+				t.Errorf("submissionClosed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAmendmentChanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes string
+		want    map[string]FieldChange
+		wantLog bool
+	}{
+		{name: "changes", changes: `{"name":{"old":"Старое","new":"Новое"}}`,
+			want: map[string]FieldChange{"name": {Old: "Старое", New: "Новое"}}},
+		{name: "empty", changes: `{}`, want: map[string]FieldChange{}},
+		{name: "corrupt", changes: `{"name":`, want: map[string]FieldChange{}, wantLog: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			ctx := logger.WithContext(context.Background(), logger.New(&logs, "debug"))
+			got := newAmendment(ctx, &database.TenderAmendment{Changes: tt.changes})
+			if len(got.Changes) != len(tt.want) {
+				t.Fatalf("changes = %v, want %v", got.Changes, tt.want)
+			}
+			for field, change := range tt.want {
+				if got.Changes[field] != change {
+					t.Errorf("changes[%s] = %v, want %v", field, got.Changes[field], change)
+				}
+			}
+			logged := strings.Contains(logs.String(), "newAmendment: Unmarshal err")
+			if logged != tt.wantLog {
+				t.Errorf("logged = %v, want %v: %s", logged, tt.wantLog, logs.String())
+			}
+		})
+	}
+}
+
+func (e *testEnv) myBid(username, bid_id string) Bid {
+	e.t.Helper()
+	bids, err := e.s.ListMyBids(e.ctx, ListMyBidsRequest{Username: username, Limit: 50})
+	if err != nil {
+		e.t.Fatalf("ListMyBids %s: %v", username, err)
+	}
+	for _, bid := range bids {
+		if bid.ID == bid_id {
+			return bid
+		}
+	}
+	e.t.Fatalf("bid %s not found for %s", bid_id, username)
+	return Bid{}
+}
+
+func TestAmendPublishedTender(t *testing.T) {
+	e := newTestEnv(t)
+	org_id := e.org("Заказчик", "buyer")
+	e.org("Поставщик А", "alice")
+	e.org("Поставщик Б", "bob")
+	deadline := time.Now().Add(time.Hour)
+	tender := e.tender(TenderParams{OrganizationId: org_id, CreatorUsername: "buyer", SubmissionDeadline: &deadline})
+	published := e.bid("alice", tender.ID)
+	draft, err := e.submitBid("bob", tender.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	amend := func(params EditTenderRequest) (*Tender, error) {
+		params.Username = "buyer"
+		params.Tender_id = tender.ID
+		return e.s.EditTender(e.ctx, params)
+	}
+	if _, err := amend(EditTenderRequest{Name: "Бумага А3"}); err != AmendmentReason {
+		t.Errorf("amend without reason err = %v, want AmendmentReason", err)
+	}
+	if _, err := amend(EditTenderRequest{Name: tender.Name, Reason: "Уточнение"}); err != NothingToChange {
+		t.Errorf("amend without changes err = %v, want NothingToChange", err)
+	}
+	earlier := deadline.Add(-time.Minute)
+	if _, err := amend(EditTenderRequest{Name: "Бумага А3", Reason: "Уточнение", SubmissionDeadline: &earlier}); err != DeadlineNotExtended {
+		t.Errorf("earlier deadline err = %v, want DeadlineNotExtended", err)
+	}
+
+	extended := deadline.Add(24 * time.Hour)
+	amended, err := amend(EditTenderRequest{Name: "Бумага А3", Reason: "Изменился формат", SubmissionDeadline: &extended})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if amended.Name != "Бумага А3" || amended.Version != 2 || amended.SubmissionDeadline == nil ||
+		!amended.SubmissionDeadline.Equal(extended.UTC().Truncate(time.Second)) {
+		t.Errorf("amended = %+v", amended)
+	}
+
+	amendments, err := e.s.ListAmendments(e.ctx, ListAmendmentsRequest{Username: "alice", Tender_id: tender.ID, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(amendments) != 1 {
+		t.Fatalf("amendments = %+v", amendments)
+	}
+	amendment := amendments[0]
+	if amendment.Reason != "Изменился формат" || amendment.AffectedBids != 2 || amendment.NewSubmissionDeadline == nil {
+		t.Errorf("amendment = %+v", amendment)
+	}
+	if change := amendment.Changes["name"]; change.Old != tender.Name || change.New != "Бумага А3" {
+		t.Errorf("name change = %+v", change)
+	}
+	if _, ok := amendment.Changes["submissionDeadline"]; !ok {
+		t.Errorf("changes = %v, want submissionDeadline", amendment.Changes)
+	}
+	if got := e.audited(EntityTender, tender.ID); !reflect.DeepEqual(got, []string{ActionCreate, ActionAmend}) {
+		t.Errorf("tender audit = %v, want create and amend", got)
+	}
+
+	notifications, err := e.s.ListNotifications(e.ctx, ListNotificationsRequest{Username: "alice", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notifications) != 1 || !strings.Contains(notifications[0].Message, "Изменился формат") {
+		t.Errorf("notifications = %+v", notifications)
+	}
+
+	if !e.myBid("alice", published.ID).NeedsConfirmation || !e.myBid("bob", draft.ID).NeedsConfirmation {
+		t.Fatal("bids submitted before the amendment must need confirmation")
+	}
+	if _, err := e.s.ConfirmBid(e.ctx, ConfirmBidRequest{Username: "bob", Bid_id: published.ID}); err != IsNotAuthor {
+		t.Errorf("confirm by other user err = %v, want IsNotAuthor", err)
+	}
+	confirmed, err := e.s.ConfirmBid(e.ctx, ConfirmBidRequest{Username: "alice", Bid_id: published.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if confirmed.NeedsConfirmation || confirmed.Status != "Published" {
+		t.Errorf("confirmed = %+v", confirmed)
+	}
+	if got := e.audited(EntityBid, published.ID); got[len(got)-1] != ActionConfirm {
+		t.Errorf("bid audit = %v, want confirm last", got)
+	}
+
+	// редактирование предложения тоже снимает отметку
+	if _, err := e.s.EditBid(e.ctx, EditBidRequest{Username: "bob", Bid_id: draft.ID, Description: "Под формат А3"}); err != nil {
+		t.Fatal(err)
+	}
+	if e.myBid("bob", draft.ID).NeedsConfirmation {
+		t.Error("edited bid must not need confirmation")
+	}
+}
+
+func TestSubmissionDeadline(t *testing.T) {
+	e := newTestEnv(t)
+	org_id := e.org("Заказчик", "buyer")
+	e.org("Поставщик А", "alice")
+	e.org("Поставщик Б", "bob")
+	deadline := time.Now().Add(time.Hour)
+	tender := e.tender(TenderParams{OrganizationId: org_id, CreatorUsername: "buyer", SubmissionDeadline: &deadline})
+	draft := e.tender(TenderParams{OrganizationId: org_id, CreatorUsername: "buyer", Status: "Created"})
+	bid, err := e.submitBid("alice", tender.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e.exec(`UPDATE tender SET submission_deadline = $2 WHERE id = $1`, tender.ID, time.Now().Add(-time.Minute).UTC())
+	if _, err := e.submitBid("bob", tender.ID); err != SubmissionClosed {
+		t.Errorf("bid after deadline err = %v, want SubmissionClosed", err)
+	}
+	if _, err := e.s.EditBid(e.ctx, EditBidRequest{Username: "alice", Bid_id: bid.ID, Name: "Позже"}); err != SubmissionClosed {
+		t.Errorf("edit after deadline err = %v, want SubmissionClosed", err)
+	}
+
+	extended := time.Now().Add(time.Hour)
+	if _, err := e.s.EditTender(e.ctx, EditTenderRequest{
+		Username: "buyer", Tender_id: draft.ID, Reason: "Продление", SubmissionDeadline: &extended,
+	}); err != DeadlineNotPublished {
+		t.Errorf("deadline of draft err = %v, want DeadlineNotPublished", err)
+	}
+	if _, err := e.s.EditTender(e.ctx, EditTenderRequest{
+		Username: "buyer", Tender_id: tender.ID, Reason: "Продление", SubmissionDeadline: &extended,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.submitBid("bob", tender.ID); err != nil {
+		t.Errorf("bid after extension err = %v", err)
+	}
+}
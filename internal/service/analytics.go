@@ -0,0 +1,324 @@
+package service
+
+import (
+	"context"
+	"math"
+	"tender_service/internal/database"
+	"tender_service/internal/logger"
+	"tender_service/internal/tracing"
+	"time"
+)
+
+// UseAnalyticsViews переводит отчеты на материализованное представление,
+// которое обновляет RefreshAnalytics
+func (s *Service) UseAnalyticsViews() {
+	s.analyticsViews = true
+}
+
+// RefreshAnalytics обновляет снимок показателей для отчетов
+func (s *Service) RefreshAnalytics(ctx context.Context) error {
+	ctx, span := tracing.Start(ctx, "service.RefreshAnalytics")
+	defer span.End()
+
+	if err := s.query.RefreshAnalytics(ctx); err != nil {
+		logger.FromContext(ctx).Error("RefreshAnalytics: RefreshAnalytics err", "err", err)
+		return err
+	}
+	return nil
+}
+
+// AnalyticsRequest - отчет по тендерам организации. From и To ограничивают
+// дату создания тендера, для решений ответственных - дату решения.
+type AnalyticsRequest struct {
+	Username        string
+	Organization_id string
+	From            *time.Time
+	To              *time.Time
+	Service_types   []string
+}
+
+// analyticsParams проверяет, что пользователь - ответственный организации,
+// и раскрывает категории видов услуг. ok=false - под фильтр не попал ни
+// один вид услуг.
+func (s *Service) analyticsParams(ctx context.Context, params AnalyticsRequest) (database.AnalyticsParams, bool, error) {
+	result := database.AnalyticsParams{
+		Organization_id: params.Organization_id,
+		From:            params.From,
+		To:              params.To,
+		UseView:         s.analyticsViews,
+	}
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return result, false, err
+	}
+	if err := s.isResponsibleUser(ctx, params.Organization_id, user_id); err != nil {
+		return result, false, err
+	}
+	if len(params.Service_types) > 0 {
+		expanded, err := s.query.ExpandServiceTypes(ctx, params.Service_types)
+		if err != nil {
+			logger.FromContext(ctx).Error("analyticsParams: ExpandServiceTypes err", "err", err)
+			return result, false, UnknowError
+		}
+		if len(expanded) == 0 {
+			return result, false, nil
+		}
+		result.Service_types = expanded
+	}
+	return result, true, nil
+}
+
+// round2 округляет до копеек и сотых долей часа
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+func ratio(part, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return round2(part / total)
+}
+
+type TenderStatusCount struct {
+	ServiceType string `json:"serviceType"`
+	Status      string `json:"status"`
+	Count       int64  `json:"count"`
+}
+
+type TenderCounts struct {
+	Total    int64               `json:"total"`
+	ByStatus map[string]int64    `json:"byStatus"`
+	Items    []TenderStatusCount `json:"items"`
+}
+
+// CountTenders - число тендеров по статусам и видам услуг
+func (s *Service) CountTenders(ctx context.Context, params AnalyticsRequest) (*TenderCounts, error) {
+	ctx, span := tracing.Start(ctx, "service.CountTenders")
+	defer span.End()
+
+	result := &TenderCounts{ByStatus: map[string]int64{}, Items: []TenderStatusCount{}}
+	filter, ok, err := s.analyticsParams(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return result, nil
+	}
+	counts, err := s.query.CountTendersByStatus(ctx, filter)
+	if err != nil {
+		logger.FromContext(ctx).Error("CountTenders: CountTendersByStatus err", "err", err)
+		return nil, UnknowError
+	}
+	for _, item := range counts {
+		result.Total += item.Count
+		result.ByStatus[item.Status] += item.Count
+		result.Items = append(result.Items, TenderStatusCount{
+			ServiceType: item.ServiceType,
+			Status:      item.Status,
+			Count:       item.Count,
+		})
+	}
+	return result, nil
+}
+
+type BidStatsItem struct {
+	ServiceType string `json:"serviceType,omitempty"`
+	Tenders     int64  `json:"tenders"`
+	Bids        int64  `json:"bids"`
+	// AvgBids - среднее число поданных предложений на опубликованный тендер
+	AvgBids float64 `json:"avgBids"`
+}
+
+type BidStats struct {
+	BidStatsItem
+	ByServiceType []BidStatsItem `json:"byServiceType"`
+}
+
+// GetBidStats - среднее число предложений на тендер
+func (s *Service) GetBidStats(ctx context.Context, params AnalyticsRequest) (*BidStats, error) {
+	ctx, span := tracing.Start(ctx, "service.GetBidStats")
+	defer span.End()
+
+	result := &BidStats{ByServiceType: []BidStatsItem{}}
+	filter, ok, err := s.analyticsParams(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return result, nil
+	}
+	stats, err := s.query.GetBidStats(ctx, filter)
+	if err != nil {
+		logger.FromContext(ctx).Error("GetBidStats: GetBidStats err", "err", err)
+		return nil, UnknowError
+	}
+	for _, item := range stats {
+		result.Tenders += item.Tenders
+		result.Bids += item.Bids
+		result.ByServiceType = append(result.ByServiceType, BidStatsItem{
+			ServiceType: item.ServiceType,
+			Tenders:     item.Tenders,
+			Bids:        item.Bids,
+			AvgBids:     ratio(float64(item.Bids), float64(item.Tenders)),
+		})
+	}
+	result.AvgBids = ratio(float64(result.Bids), float64(result.Tenders))
+	return result, nil
+}
+
+type AwardTimeItem struct {
+	ServiceType string  `json:"serviceType,omitempty"`
+	Tenders     int64   `json:"tenders"`
+	AvgHours    float64 `json:"avgHours"`
+	MinHours    float64 `json:"minHours"`
+	MaxHours    float64 `json:"maxHours"`
+}
+
+type AwardTime struct {
+	AwardTimeItem
+	ByServiceType []AwardTimeItem `json:"byServiceType"`
+}
+
+// GetAwardTime - время от публикации тендера до выбора победителя в часах
+func (s *Service) GetAwardTime(ctx context.Context, params AnalyticsRequest) (*AwardTime, error) {
+	ctx, span := tracing.Start(ctx, "service.GetAwardTime")
+	defer span.End()
+
+	result := &AwardTime{ByServiceType: []AwardTimeItem{}}
+	filter, ok, err := s.analyticsParams(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return result, nil
+	}
+	times, err := s.query.GetAwardTimes(ctx, filter)
+	if err != nil {
+		logger.FromContext(ctx).Error("GetAwardTime: GetAwardTimes err", "err", err)
+		return nil, UnknowError
+	}
+	var total_hours float64
+	for n, item := range times {
+		if n == 0 || item.MinHours < result.MinHours {
+			result.MinHours = item.MinHours
+		}
+		if item.MaxHours > result.MaxHours {
+			result.MaxHours = item.MaxHours
+		}
+		result.Tenders += item.Tenders
+		total_hours += item.AvgHours * float64(item.Tenders)
+		result.ByServiceType = append(result.ByServiceType, AwardTimeItem{
+			ServiceType: item.ServiceType,
+			Tenders:     item.Tenders,
+			AvgHours:    round2(item.AvgHours),
+			MinHours:    round2(item.MinHours),
+			MaxHours:    round2(item.MaxHours),
+		})
+	}
+	result.AvgHours = ratio(total_hours, float64(result.Tenders))
+	result.MinHours = round2(result.MinHours)
+	result.MaxHours = round2(result.MaxHours)
+	return result, nil
+}
+
+type SavingsItem struct {
+	ServiceType string  `json:"serviceType,omitempty"`
+	Tenders     int64   `json:"tenders"`
+	Budget      float64 `json:"estimatedBudget"`
+	Awarded     float64 `json:"awardedPrice"`
+	Savings     float64 `json:"savings"`
+	// SavingsPercent - экономия в процентах от оценки бюджета
+	SavingsPercent float64 `json:"savingsPercent"`
+}
+
+func newSavingsItem(service_type string, tenders int64, budget, awarded float64) SavingsItem {
+	return SavingsItem{
+		ServiceType:    service_type,
+		Tenders:        tenders,
+		Budget:         round2(budget),
+		Awarded:        round2(awarded),
+		Savings:        round2(budget - awarded),
+		SavingsPercent: ratio((budget-awarded)*100, budget),
+	}
+}
+
+type Savings struct {
+	SavingsItem
+	ByServiceType []SavingsItem `json:"byServiceType"`
+}
+
+// GetSavings - экономия относительно оценки бюджета. Учитываются тендеры
+// с оценкой бюджета и известной ценой победителя: по лотам или по ставке
+// аукциона.
+func (s *Service) GetSavings(ctx context.Context, params AnalyticsRequest) (*Savings, error) {
+	ctx, span := tracing.Start(ctx, "service.GetSavings")
+	defer span.End()
+
+	result := &Savings{ByServiceType: []SavingsItem{}}
+	filter, ok, err := s.analyticsParams(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return result, nil
+	}
+	savings, err := s.query.GetSavings(ctx, filter)
+	if err != nil {
+		logger.FromContext(ctx).Error("GetSavings: GetSavings err", "err", err)
+		return nil, UnknowError
+	}
+	var tenders int64
+	var budget, awarded float64
+	for _, item := range savings {
+		tenders += item.Tenders
+		budget += item.Budget
+		awarded += item.Awarded
+		result.ByServiceType = append(result.ByServiceType,
+			newSavingsItem(item.ServiceType, item.Tenders, item.Budget, item.Awarded))
+	}
+	result.SavingsItem = newSavingsItem("", tenders, budget, awarded)
+	return result, nil
+}
+
+type ApprovalRate struct {
+	UserId   string `json:"userId"`
+	Username string `json:"username"`
+	Approved int64  `json:"approved"`
+	Rejected int64  `json:"rejected"`
+	// Blocked - голоса, не учтенные из-за конфликта интересов
+	Blocked int64 `json:"blocked"`
+	// Rate - доля одобрений среди учтенных решений
+	Rate float64 `json:"approvalRate"`
+}
+
+// ListApprovalRates - доля одобрений по каждому ответственному организации
+func (s *Service) ListApprovalRates(ctx context.Context, params AnalyticsRequest) ([]ApprovalRate, error) {
+	ctx, span := tracing.Start(ctx, "service.ListApprovalRates")
+	defer span.End()
+
+	result := []ApprovalRate{}
+	filter, ok, err := s.analyticsParams(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return result, nil
+	}
+	rates, err := s.query.ListApprovalRates(ctx, filter)
+	if err != nil {
+		logger.FromContext(ctx).Error("ListApprovalRates: ListApprovalRates err", "err", err)
+		return nil, UnknowError
+	}
+	for _, item := range rates {
+		result = append(result, ApprovalRate{
+			UserId:   item.User_id,
+			Username: item.Username,
+			Approved: item.Approved,
+			Rejected: item.Rejected,
+			Blocked:  item.Blocked,
+			Rate:     ratio(float64(item.Approved), float64(item.Approved+item.Rejected)),
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSavingsItem(t *testing.T) {
+	tests := []struct {
+		name            string
+		budget, awarded float64
+		want            SavingsItem
+	}{
+		{name: "savings", budget: 1000, awarded: 800,
+			want: SavingsItem{Tenders: 1, Budget: 1000, Awarded: 800, Savings: 200, SavingsPercent: 20}},
+		{name: "overrun", budget: 300, awarded: 400,
+			want: SavingsItem{Tenders: 1, Budget: 300, Awarded: 400, Savings: -100, SavingsPercent: -33.33}},
+		{name: "rounding", budget: 99.999, awarded: 33.333,
+			want: SavingsItem{Tenders: 1, Budget: 100, Awarded: 33.33, Savings: 66.67, SavingsPercent: 66.67}},
+		{name: "no budget", want: SavingsItem{Tenders: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newSavingsItem("", 1, tt.budget, tt.awarded); got != tt.want {
+				t.Errorf("newSavingsItem = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalytics(t *testing.T) {
+	e := newTestEnv(t)
+	org_id := e.org("Заказчик", "buyer")
+	other_org := e.org("Другой заказчик", "other")
+	e.org("Поставщик А", "alice")
+	e.org("Поставщик Б", "bob")
+
+	// поставка с одним лотом: alice отклонена, лот за bob, тендер закрыт
+	budget := 1000.0
+	delivery := e.tender(TenderParams{OrganizationId: org_id, CreatorUsername: "buyer", EstimatedBudget: &budget,
+		Lots: []LotParams{{Name: "Бумага", Quantity: 100}}})
+	lot_id := e.lots("buyer", delivery.ID)[0].ID
+	alice := e.bid("alice", delivery.ID, BidLot{LotID: lot_id, Price: 900})
+	bob := e.bid("bob", delivery.ID, BidLot{LotID: lot_id, Price: 800})
+	if _, err := e.decide("buyer", alice.ID, lot_id, "Rejected"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.decide("buyer", bob.ID, lot_id, "Approved"); err != nil {
+		t.Fatal(err)
+	}
+	e.exec(`UPDATE tender SET published_at = (SELECT closed_at FROM tender_lot WHERE tender_id = $1) - interval '48 hours'
+	    WHERE id = $1`, delivery.ID)
+
+	e.tender(TenderParams{Name: "Ремонт склада", OrganizationId: org_id, CreatorUsername: "buyer", ServiceType: "Construction"})
+	e.tender(TenderParams{Name: "Черновик", OrganizationId: org_id, CreatorUsername: "buyer", Status: "Created"})
+	e.tender(TenderParams{OrganizationId: other_org, CreatorUsername: "other"})
+
+	request := AnalyticsRequest{Username: "buyer", Organization_id: org_id}
+	counts, err := e.s.CountTenders(e.ctx, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want_counts := &TenderCounts{
+		Total:    3,
+		ByStatus: map[string]int64{"Closed": 1, "Created": 1, "Published": 1},
+		Items: []TenderStatusCount{
+			{ServiceType: "Construction", Status: "Published", Count: 1},
+			{ServiceType: "Delivery", Status: "Closed", Count: 1},
+			{ServiceType: "Delivery", Status: "Created", Count: 1},
+		},
+	}
+	if !reflect.DeepEqual(counts, want_counts) {
+		t.Errorf("counts = %+v, want %+v", counts, want_counts)
+	}
+
+	// черновик не учитывается, отклоненное предложение остается поданным
+	bids, err := e.s.GetBidStats(e.ctx, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want_bids := &BidStats{
+		BidStatsItem: BidStatsItem{Tenders: 2, Bids: 2, AvgBids: 1},
+		ByServiceType: []BidStatsItem{
+			{ServiceType: "Construction", Tenders: 1},
+			{ServiceType: "Delivery", Tenders: 1, Bids: 2, AvgBids: 2},
+		},
+	}
+	if !reflect.DeepEqual(bids, want_bids) {
+		t.Errorf("bid stats = %+v, want %+v", bids, want_bids)
+	}
+
+	award, err := e.s.GetAwardTime(e.ctx, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want_award := &AwardTime{
+		AwardTimeItem: AwardTimeItem{Tenders: 1, AvgHours: 48, MinHours: 48, MaxHours: 48},
+		ByServiceType: []AwardTimeItem{{ServiceType: "Delivery", Tenders: 1, AvgHours: 48, MinHours: 48, MaxHours: 48}},
+	}
+	if !reflect.DeepEqual(award, want_award) {
+		t.Errorf("award time = %+v, want %+v", award, want_award)
+	}
+
+	savings, err := e.s.GetSavings(e.ctx, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want_savings := &Savings{
+		SavingsItem:   newSavingsItem("", 1, 1000, 800),
+		ByServiceType: []SavingsItem{newSavingsItem("Delivery", 1, 1000, 800)},
+	}
+	if !reflect.DeepEqual(savings, want_savings) {
+		t.Errorf("savings = %+v, want %+v", savings, want_savings)
+	}
+
+	rates, err := e.s.ListApprovalRates(e.ctx, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want_rates := []ApprovalRate{{UserId: e.userID("buyer"), Username: "buyer", Approved: 1, Rejected: 1, Rate: 0.5}}
+	if !reflect.DeepEqual(rates, want_rates) {
+		t.Errorf("approval rates = %+v, want %+v", rates, want_rates)
+	}
+
+	if _, err := e.s.CountTenders(e.ctx, AnalyticsRequest{Username: "other", Organization_id: org_id}); err != IsNotResponsible {
+		t.Errorf("foreign organization err = %v, want IsNotResponsible", err)
+	}
+}
+
+func TestAnalyticsFilters(t *testing.T) {
+	e := newTestEnv(t)
+	org_id := e.org("Заказчик", "buyer")
+	e.tender(TenderParams{OrganizationId: org_id, CreatorUsername: "buyer"})
+	e.tender(TenderParams{Name: "Ремонт склада", OrganizationId: org_id, CreatorUsername: "buyer", ServiceType: "Construction"})
+
+	tomorrow := time.Now().Add(24 * time.Hour)
+	tests := []struct {
+		name   string
+		params AnalyticsRequest
+		want   int64
+	}{
+		{name: "all", want: 2},
+		{name: "service type", params: AnalyticsRequest{Service_types: []string{"construction"}}, want: 1},
+		{name: "unknown service type", params: AnalyticsRequest{Service_types: []string{"Catering"}}, want: 0},
+		{name: "from", params: AnalyticsRequest{From: &tomorrow}, want: 0},
+		{name: "to", params: AnalyticsRequest{To: &tomorrow}, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := tt.params
+			params.Username, params.Organization_id = "buyer", org_id
+			counts, err := e.s.CountTenders(e.ctx, params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if counts.Total != tt.want {
+				t.Errorf("total = %d, want %d", counts.Total, tt.want)
+			}
+		})
+	}
+
+	// до первого обновления снимка отчеты читают живые таблицы
+	e.s.UseAnalyticsViews()
+	total := func() int64 {
+		t.Helper()
+		counts, err := e.s.CountTenders(e.ctx, AnalyticsRequest{Username: "buyer", Organization_id: org_id})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return counts.Total
+	}
+	if got := total(); got != 2 {
+		t.Errorf("before refresh total = %d, want 2", got)
+	}
+	if err := e.s.RefreshAnalytics(e.ctx); err != nil {
+		t.Fatal(err)
+	}
+	e.tender(TenderParams{Name: "Поставка тонера", OrganizationId: org_id, CreatorUsername: "buyer"})
+	if got := total(); got != 2 {
+		t.Errorf("snapshot total = %d, want 2", got)
+	}
+	if err := e.s.RefreshAnalytics(e.ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := total(); got != 3 {
+		t.Errorf("refreshed total = %d, want 3", got)
+	}
+}
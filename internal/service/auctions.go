@@ -0,0 +1,491 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"tender_service/internal/database"
+	"tender_service/internal/logger"
+	"tender_service/internal/tracing"
+	"time"
+)
+
+var (
+	TenderNotAuction  = fmt.Errorf("Тендер проводится не в режиме аукциона")
+	AuctionNotFound   = fmt.Errorf("Аукцион по тендеру не настроен")
+	AuctionStarted    = fmt.Errorf("Правила аукциона нельзя менять после начала торгов")
+	AuctionNotRunning = fmt.Errorf("Аукцион сейчас не идет")
+	AuctionRunning    = fmt.Errorf("Аукцион еще не завершен")
+	PriceTooHigh      = fmt.Errorf("Цена должна быть ниже лучшей ставки как минимум на шаг аукциона")
+	BidNotInTender    = fmt.Errorf("Предложение относится к другому тендеру")
+	BidNotPublished   = fmt.Errorf("Ставки принимаются только от опубликованных предложений")
+)
+
+const (
+	DecrementAbsolute = "absolute"
+	DecrementPercent  = "percent"
+)
+
+const (
+	AuctionScheduled = "scheduled"
+	AuctionActive    = "running"
+	AuctionEnded     = "ended"
+	AuctionClosed    = "closed"
+)
+
+// цены хранятся с точностью до копеек, сравниваются в копейках
+func toCents(price float64) int64 {
+	return int64(math.Round(price * 100))
+}
+
+// maxNextPrice - наибольшая цена, которую примет аукцион следующей ставкой
+func maxNextPrice(auction *database.Auction, best sql.NullFloat64) (float64, bool) {
+	if !best.Valid {
+		if auction.StartPrice.Valid {
+			return auction.StartPrice.Float64, true
+		}
+		return 0, false
+	}
+	best_cents := toCents(best.Float64)
+	step := toCents(auction.DecrementValue)
+	if auction.DecrementType == DecrementPercent {
+		step = int64(math.Ceil(float64(best_cents) * auction.DecrementValue / 100))
+	}
+	if step < 1 {
+		step = 1
+	}
+	return float64(best_cents-step) / 100, true
+}
+
+func auctionStatus(auction *database.Auction, now time.Time) string {
+	switch {
+	case auction.ClosedAt.Valid:
+		return AuctionClosed
+	case now.Before(auction.StartAt):
+		return AuctionScheduled
+	case now.Before(auction.EndAt):
+		return AuctionActive
+	default:
+		return AuctionEnded
+	}
+}
+
+type AuctionStanding struct {
+	Rank int `json:"rank"`
+	// Participant - обезличенное имя участника, номер по первой ставке
+	Participant    string    `json:"participant"`
+	BidID          string    `json:"bidId,omitempty"`
+	BidName        string    `json:"bidName,omitempty"`
+	OrganizationID string    `json:"organizationId,omitempty"`
+	Price          float64   `json:"price"`
+	BidAt          time.Time `json:"bidAt"`
+	BidCount       int32     `json:"bidCount"`
+	IsYou          bool      `json:"isYou,omitempty"`
+}
+
+type Auction struct {
+	TenderID        string            `json:"tenderId"`
+	Status          string            `json:"status"`
+	StartAt         time.Time         `json:"startAt"`
+	EndAt           time.Time         `json:"endAt"`
+	OriginalEndAt   time.Time         `json:"originalEndAt"`
+	StartPrice      *float64          `json:"startPrice,omitempty"`
+	DecrementType   string            `json:"decrementType"`
+	DecrementValue  float64           `json:"decrementValue"`
+	ExtensionWindow int32             `json:"extensionWindow"`
+	Extension       int32             `json:"extension"`
+	Extensions      int32             `json:"extensions"`
+	BestPrice       *float64          `json:"bestPrice,omitempty"`
+	MaxNextPrice    *float64          `json:"maxNextPrice,omitempty"`
+	ProposedBidID   string            `json:"proposedBidId,omitempty"`
+	ClosedAt        *time.Time        `json:"closedAt,omitempty"`
+	Leaderboard     []AuctionStanding `json:"leaderboard,omitempty"`
+}
+
+func newAuction(a *database.Auction, now time.Time) Auction {
+	result := Auction{
+		TenderID:        a.TenderID.String(),
+		Status:          auctionStatus(a, now),
+		StartAt:         a.StartAt,
+		EndAt:           a.EndAt,
+		OriginalEndAt:   a.OriginalEndAt,
+		DecrementType:   a.DecrementType,
+		DecrementValue:  a.DecrementValue,
+		ExtensionWindow: a.ExtensionWindow,
+		Extension:       a.Extension,
+		Extensions:      a.Extensions,
+		ProposedBidID:   a.ProposedOfferID.String,
+	}
+	if a.StartPrice.Valid {
+		result.StartPrice = &a.StartPrice.Float64
+	}
+	if a.ClosedAt.Valid {
+		result.ClosedAt = &a.ClosedAt.Time
+	}
+	return result
+}
+
+// auctionTender загружает тендер и проверяет, что он в режиме аукциона
+func (s *Service) auctionTender(ctx context.Context, op, tender_id string) (database.Tender, error) {
+	tender, err := s.query.GetTender(ctx, tender_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return tender, TenderNotFound
+		}
+		logger.FromContext(ctx).Error(op+": GetTender err", "err", err)
+		return tender, UnknowError
+	}
+	if tender.BiddingMode != BiddingModeAuction {
+		return tender, TenderNotAuction
+	}
+	return tender, nil
+}
+
+type SetAuctionRequest struct {
+	Username        string
+	Tender_id       string
+	StartAt         time.Time
+	EndAt           time.Time
+	StartPrice      *float64
+	DecrementType   string
+	DecrementValue  float64
+	ExtensionWindow int32
+	Extension       int32
+}
+
+// SetAuction задает правила аукциона. Менять их можно только до начала
+// торгов и пока нет ни одной ставки.
+func (s *Service) SetAuction(ctx context.Context, params SetAuctionRequest) (*Auction, error) {
+	ctx, span := tracing.Start(ctx, "service.SetAuction")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	tender, err := s.auctionTender(ctx, "SetAuction", params.Tender_id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.isResponsibleUser(ctx, tender.OrganizationID.String(), user_id); err != nil {
+		return nil, err
+	}
+	if tender.Status == "Closed" {
+		return nil, NotAllowValue
+	}
+	now := time.Now().UTC()
+	before, err := s.query.GetAuction(ctx, tender.ID.String())
+	if err != nil && err != sql.ErrNoRows {
+		logger.FromContext(ctx).Error("SetAuction: GetAuction err", "err", err)
+		return nil, UnknowError
+	}
+	if before != nil && !now.Before(before.StartAt) {
+		return nil, AuctionStarted
+	}
+	start_at := params.StartAt.UTC().Truncate(time.Second)
+	end_at := params.EndAt.UTC().Truncate(time.Second)
+	if !start_at.After(now) || !end_at.After(start_at) {
+		return nil, NotAllowValue
+	}
+	var start_price sql.NullFloat64
+	if params.StartPrice != nil {
+		start_price = sql.NullFloat64{Float64: float64(toCents(*params.StartPrice)) / 100, Valid: true}
+	}
+
+	auction, err := s.query.SetAuction(ctx, database.AuctionParams{
+		Tender_id:       tender.ID.String(),
+		StartAt:         start_at,
+		EndAt:           end_at,
+		StartPrice:      start_price,
+		DecrementType:   params.DecrementType,
+		DecrementValue:  params.DecrementValue,
+		ExtensionWindow: params.ExtensionWindow,
+		Extension:       params.Extension,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, AuctionStarted
+		}
+		logger.FromContext(ctx).Error("SetAuction: SetAuction err", "err", err)
+		return nil, UnknowError
+	}
+	result := newAuction(auction, now)
+	entry := auditEntry{
+		ActorID:        user_id,
+		OrganizationID: tender.OrganizationID.String(),
+		EntityType:     EntityTender,
+		EntityID:       result.TenderID,
+		Action:         ActionSetAuction,
+		After:          result,
+	}
+	if before != nil {
+		entry.Before = newAuction(before, now)
+	}
+	s.audit(ctx, entry)
+	return &result, nil
+}
+
+type PlaceAuctionBidRequest struct {
+	Username  string
+	Tender_id string
+	Bid_id    string
+	Price     float64
+}
+
+// PlaceAuctionBid принимает ставку от имени предложения. Ставка в последние
+// ExtensionWindow секунд продлевает аукцион, чтобы у остальных участников
+// было время ответить.
+func (s *Service) PlaceAuctionBid(ctx context.Context, params PlaceAuctionBidRequest) (*Auction, error) {
+	ctx, span := tracing.Start(ctx, "service.PlaceAuctionBid")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	tender, err := s.auctionTender(ctx, "PlaceAuctionBid", params.Tender_id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkTenderVisible(ctx, tender, user_id); err != nil {
+		return nil, err
+	}
+	if tender.Status != "Published" {
+		return nil, TenderNotPublished
+	}
+	bid, err := s.query.GetOffer(ctx, params.Bid_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, BidNotFound
+		}
+		logger.FromContext(ctx).Error("PlaceAuctionBid: GetOffer err", "err", err)
+		return nil, UnknowError
+	}
+	if bid.Tender_ID != tender.ID {
+		return nil, BidNotInTender
+	}
+	if err := s.isResponsibleUser(ctx, bid.Organization_ID.String(), user_id); err != nil {
+		return nil, err
+	}
+	if bid.Status == "Canceled" {
+		return nil, BidCanceled
+	}
+	if bid.Status != "Published" {
+		return nil, BidNotPublished
+	}
+
+	now := time.Now().UTC()
+	price := float64(toCents(params.Price)) / 100
+	placed, auction, err := s.query.PlaceAuctionBidTx(ctx, database.AuctionBidParams{
+		Tender_id: tender.ID.String(),
+		Offer_id:  bid.ID.String(),
+		Bidder_id: user_id,
+		Price:     price,
+		Now:       now,
+	}, func(auction *database.Auction, best sql.NullFloat64, offer_status string) (time.Time, error) {
+		if offer_status != "Published" {
+			return auction.EndAt, BidNotPublished
+		}
+		if auctionStatus(auction, now) != AuctionActive {
+			return auction.EndAt, AuctionNotRunning
+		}
+		if max_price, ok := maxNextPrice(auction, best); ok && toCents(price) > toCents(max_price) {
+			return auction.EndAt, PriceTooHigh
+		}
+		window := time.Duration(auction.ExtensionWindow) * time.Second
+		if window > 0 && auction.EndAt.Sub(now) <= window {
+			extended := now.Add(time.Duration(auction.Extension) * time.Second).Truncate(time.Second)
+			if extended.After(auction.EndAt) {
+				return extended, nil
+			}
+		}
+		return auction.EndAt, nil
+	})
+	if err != nil {
+		switch err {
+		case sql.ErrNoRows:
+			return nil, AuctionNotFound
+		case AuctionNotRunning, PriceTooHigh, BidNotPublished:
+			return nil, err
+		}
+		logger.FromContext(ctx).Error("PlaceAuctionBid: PlaceAuctionBidTx err", "err", err)
+		return nil, UnknowError
+	}
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: bid.Organization_ID.String(),
+		EntityType:     EntityBid,
+		EntityID:       bid.ID.String(),
+		Action:         ActionAuctionBid,
+		After: map[string]interface{}{
+			"auctionBidId": placed.ID.String(),
+			"price":        placed.Price,
+			"endAt":        auction.EndAt,
+		},
+	})
+	return s.auctionView(ctx, auction, bid.Organization_ID.String(), false)
+}
+
+type GetAuctionRequest struct {
+	Username  string
+	Tender_id string
+}
+
+// GetAuction - состояние аукциона и таблица ставок. Ответственные
+// организации тендера видят участников, остальные - только номера
+// участников и цены.
+func (s *Service) GetAuction(ctx context.Context, params GetAuctionRequest) (*Auction, error) {
+	ctx, span := tracing.Start(ctx, "service.GetAuction")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	tender, err := s.auctionTender(ctx, "GetAuction", params.Tender_id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkTenderVisible(ctx, tender, user_id); err != nil {
+		return nil, err
+	}
+	full := true
+	if err := s.isResponsibleUser(ctx, tender.OrganizationID.String(), user_id); err != nil {
+		if err != IsNotResponsible {
+			return nil, err
+		}
+		if tender.Status == "Created" {
+			return nil, TenderNotPublished
+		}
+		full = false
+	}
+	auction, err := s.query.GetAuction(ctx, tender.ID.String())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, AuctionNotFound
+		}
+		logger.FromContext(ctx).Error("GetAuction: GetAuction err", "err", err)
+		return nil, UnknowError
+	}
+	org_id, _ := s.query.GetUserOrganization(ctx, user_id)
+	return s.auctionView(ctx, auction, org_id, full)
+}
+
+func (s *Service) auctionView(ctx context.Context, auction *database.Auction, org_id string, full bool) (*Auction, error) {
+	standings, err := s.query.AuctionLeaderboard(ctx, auction.TenderID.String())
+	if err != nil {
+		logger.FromContext(ctx).Error("auctionView: AuctionLeaderboard err", "err", err)
+		return nil, UnknowError
+	}
+	result := newAuction(auction, time.Now().UTC())
+
+	// участники нумеруются по порядку первой ставки, чтобы номер не менялся
+	// при движении по таблице
+	order := make([]database.AuctionStanding, len(standings))
+	copy(order, standings)
+	sort.SliceStable(order, func(i, j int) bool {
+		return order[i].FirstBidAt.Before(order[j].FirstBidAt)
+	})
+	numbers := map[string]int{}
+	for i, item := range order {
+		numbers[item.OfferID.String()] = i + 1
+	}
+
+	var best sql.NullFloat64
+	for i, item := range standings {
+		if i == 0 {
+			best = sql.NullFloat64{Float64: item.Price, Valid: true}
+		}
+		standing := AuctionStanding{
+			Rank:        i + 1,
+			Participant: fmt.Sprintf("Участник %d", numbers[item.OfferID.String()]),
+			Price:       item.Price,
+			BidAt:       item.BidAt,
+			BidCount:    item.BidCount,
+			IsYou:       org_id != "" && item.OrganizationID.String() == org_id,
+		}
+		if full || standing.IsYou {
+			standing.BidID = item.OfferID.String()
+			standing.BidName = item.OfferName
+			standing.OrganizationID = item.OrganizationID.String()
+		}
+		result.Leaderboard = append(result.Leaderboard, standing)
+	}
+	if best.Valid {
+		result.BestPrice = &best.Float64
+	}
+	if result.Status == AuctionActive || result.Status == AuctionScheduled {
+		if max_price, ok := maxNextPrice(auction, best); ok {
+			result.MaxNextPrice = &max_price
+		}
+	}
+	return &result, nil
+}
+
+// closeAuction завершает аукцион и предлагает лучшую ставку к утверждению
+func (s *Service) closeAuction(ctx context.Context, tender_id string, now time.Time) error {
+	auction, err := s.query.CloseAuctionTx(ctx, tender_id, now,
+		"Аукцион по тендеру завершен, предложение с лучшей ценой ждет решения")
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	s.audit(ctx, auditEntry{
+		EntityType: EntityTender,
+		EntityID:   tender_id,
+		Action:     ActionCloseAuction,
+		After:      newAuction(auction, now),
+	})
+	return nil
+}
+
+// CloseEndedAuctions закрывает аукционы, время которых вышло. Вызывается
+// планировщиком.
+func (s *Service) CloseEndedAuctions(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "service.CloseEndedAuctions")
+	defer span.End()
+
+	now := time.Now().UTC()
+	tenders, err := s.query.ListEndedAuctions(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+	closed := 0
+	for _, tender_id := range tenders {
+		if err := s.closeAuction(ctx, tender_id, now); err != nil {
+			logger.FromContext(ctx).Error("CloseEndedAuctions: closeAuction err", "err", err, "tender_id", tender_id)
+			continue
+		}
+		closed++
+	}
+	return closed, nil
+}
+
+// requireAuctionClosed не дает принимать решения, пока аукцион идет.
+// Если время вышло, а планировщик еще не закрыл аукцион, закрывает его сразу.
+func (s *Service) requireAuctionClosed(ctx context.Context, tender_id string) error {
+	auction, err := s.query.GetAuction(ctx, tender_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		logger.FromContext(ctx).Error("requireAuctionClosed: GetAuction err", "err", err)
+		return UnknowError
+	}
+	now := time.Now().UTC()
+	switch auctionStatus(auction, now) {
+	case AuctionClosed:
+		return nil
+	case AuctionEnded:
+		if err := s.closeAuction(ctx, tender_id, now); err != nil {
+			logger.FromContext(ctx).Error("requireAuctionClosed: closeAuction err", "err", err)
+			return UnknowError
+		}
+		return nil
+	}
+	return AuctionRunning
+}
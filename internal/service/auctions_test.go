@@ -0,0 +1,148 @@
+package service
+
+import (
+	"database/sql"
+	"tender_service/internal/database"
+	"testing"
+	"time"
+)
+
+func TestMaxNextPrice(t *testing.T) {
+	price := func(v float64) sql.NullFloat64 { return sql.NullFloat64{Float64: v, Valid: true} }
+	tests := []struct {
+		name           string
+		start          sql.NullFloat64
+		decrement_type string
+		decrement      float64
+		best           sql.NullFloat64
+		want           float64
+		wantOk         bool
+	}{
+		{name: "first bid without start price", decrement_type: DecrementAbsolute, decrement: 10},
+		{name: "first bid at start price", start: price(1000), decrement_type: DecrementAbsolute, decrement: 10, want: 1000, wantOk: true},
+		{name: "absolute step", start: price(1000), decrement_type: DecrementAbsolute, decrement: 50, best: price(1000), want: 950, wantOk: true},
+		{name: "absolute step in kopecks", decrement_type: DecrementAbsolute, decrement: 0.5, best: price(100.25), want: 99.75, wantOk: true},
+		{name: "absolute step without float drift", decrement_type: DecrementAbsolute, decrement: 0.1, best: price(0.3), want: 0.2, wantOk: true},
+		{name: "zero step still lowers by a kopeck", decrement_type: DecrementAbsolute, decrement: 0, best: price(1000), want: 999.99, wantOk: true},
+		{name: "percent step", decrement_type: DecrementPercent, decrement: 5, best: price(1000), want: 950, wantOk: true},
+		{name: "percent step rounds up to a kopeck", decrement_type: DecrementPercent, decrement: 1, best: price(33.33), want: 32.99, wantOk: true},
+		{name: "tiny percent step is at least a kopeck", decrement_type: DecrementPercent, decrement: 0.01, best: price(10), want: 9.99, wantOk: true},
+		{name: "percent step follows best price", start: price(1000), decrement_type: DecrementPercent, decrement: 10, best: price(500), want: 450, wantOk: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auction := &database.Auction{
+				StartPrice:     tt.start,
+				DecrementType:  tt.decrement_type,
+				DecrementValue: tt.decrement,
+			}
+			got, ok := maxNextPrice(auction, tt.best)
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("maxNextPrice = %v, %v; want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestAuctionStatus(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	auction := database.Auction{StartAt: start, EndAt: start.Add(time.Hour)}
+	closed := auction
+	closed.ClosedAt = sql.NullTime{Time: start.Add(2 * time.Hour), Valid: true}
+	tests := []struct {
+		name    string
+		auction database.Auction
+		now     time.Time
+		want    string
+	}{
+		{name: "before start", auction: auction, now: start.Add(-time.Second), want: AuctionScheduled},
+		{name: "at start", auction: auction, now: start, want: AuctionActive},
+		{name: "running", auction: auction, now: start.Add(30 * time.Minute), want: AuctionActive},
+		{name: "at end", auction: auction, now: start.Add(time.Hour), want: AuctionEnded},
+		{name: "closed", auction: closed, now: start.Add(30 * time.Minute), want: AuctionClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := auctionStatus(&tt.auction, tt.now); got != tt.want {
+				t.Errorf("auctionStatus = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// startAuction задает правила аукциона и сдвигает его начало в прошлое
+func startAuction(e *testEnv, username, tender_id string, decrement float64) {
+	e.t.Helper()
+	now := time.Now().UTC()
+	_, err := e.s.SetAuction(e.ctx, SetAuctionRequest{
+		Username:       username,
+		Tender_id:      tender_id,
+		StartAt:        now.Add(time.Hour),
+		EndAt:          now.Add(2 * time.Hour),
+		DecrementType:  DecrementAbsolute,
+		DecrementValue: decrement,
+	})
+	if err != nil {
+		e.t.Fatalf("SetAuction: %v", err)
+	}
+	e.exec(`UPDATE tender_auction SET start_at = $2 WHERE tender_id = $1`, tender_id, now.Add(-time.Minute))
+}
+
+func TestAuctionLeaderboardSkipsCanceledBids(t *testing.T) {
+	e := newTestEnv(t)
+	org_id := e.org("Заказчик", "buyer")
+	e.org("Поставщик 1", "alice")
+	e.org("Поставщик 2", "bob")
+	e.org("Поставщик 3", "carol")
+	tender := e.tender(TenderParams{OrganizationId: org_id, CreatorUsername: "buyer", BiddingMode: BiddingModeAuction})
+	startAuction(e, "buyer", tender.ID, 10)
+
+	bids := map[string]*Bid{}
+	for _, step := range []struct {
+		username string
+		price    float64
+	}{{"alice", 1000}, {"bob", 900}, {"carol", 800}} {
+		bids[step.username] = e.bid(step.username, tender.ID)
+		_, err := e.s.PlaceAuctionBid(e.ctx, PlaceAuctionBidRequest{
+			Username: step.username, Tender_id: tender.ID, Bid_id: bids[step.username].ID, Price: step.price,
+		})
+		if err != nil {
+			t.Fatalf("PlaceAuctionBid %s: %v", step.username, err)
+		}
+	}
+	e.bidStatus("carol", bids["carol"].ID, "Canceled")
+
+	auction, err := e.s.GetAuction(e.ctx, GetAuctionRequest{Username: "buyer", Tender_id: tender.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, standing := range auction.Leaderboard {
+		if standing.BidID == bids["carol"].ID {
+			t.Fatalf("canceled bid is ranked: %+v", standing)
+		}
+	}
+	if auction.BestPrice == nil || auction.MaxNextPrice == nil {
+		t.Fatalf("best = %v, max next = %v", auction.BestPrice, auction.MaxNextPrice)
+	}
+	if len(auction.Leaderboard) != 2 || auction.Leaderboard[0].BidID != bids["bob"].ID {
+		t.Fatalf("leaderboard = %+v, want bob first of two", auction.Leaderboard)
+	}
+	if *auction.BestPrice != 900 || *auction.MaxNextPrice != 890 {
+		t.Errorf("best = %v, max next = %v, want 900 and 890", *auction.BestPrice, *auction.MaxNextPrice)
+	}
+
+	// лидер таблицы и проверка шага ставки считают одну и ту же лучшую цену
+	_, err = e.s.PlaceAuctionBid(e.ctx, PlaceAuctionBidRequest{
+		Username: "alice", Tender_id: tender.ID, Bid_id: bids["alice"].ID, Price: *auction.MaxNextPrice,
+	})
+	if err != nil {
+		t.Fatalf("bid at max next price: %v", err)
+	}
+	auction, err = e.s.GetAuction(e.ctx, GetAuctionRequest{Username: "buyer", Tender_id: tender.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(auction.Leaderboard) != 2 || auction.Leaderboard[0].BidID != bids["alice"].ID {
+		t.Errorf("leaderboard = %+v, want alice first of two", auction.Leaderboard)
+	}
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"tender_service/internal/database"
+	"tender_service/internal/logger"
+	"tender_service/internal/tracing"
+	"time"
+)
+
+const (
+	EntityTender        = "tender"
+	EntityBid           = "bid"
+	EntityReview        = "review"
+	EntityEmployee      = "employee"
+	EntityTemplate      = "tender_template"
+	EntityServiceType   = "service_type"
+	EntityQuestion      = "tender_question"
+	EntityLot           = "tender_lot"
+	EntityInvitation    = "tender_invitation"
+	EntityQualification = "vendor_qualification"
+	EntityOrganization  = "organization"
+)
+
+const (
+	ActionCreate       = "create"
+	ActionEdit         = "edit"
+	ActionChangeStatus = "change_status"
+	ActionRollback     = "rollback"
+	ActionDecision     = "decision"
+	ActionDeactivate   = "deactivate"
+	ActionDelete       = "delete"
+	ActionClone        = "clone"
+	ActionAnswer       = "answer"
+	ActionAmend        = "amend"
+	ActionConfirm      = "confirm"
+	ActionOpenBids     = "open_bids"
+	ActionSetAuction   = "set_auction"
+	ActionAuctionBid   = "auction_bid"
+	ActionCloseAuction = "close_auction"
+	ActionAward        = "award"
+	ActionCancelLot    = "cancel_lot"
+	ActionInvite       = "invite"
+	ActionRespond      = "respond"
+	ActionRevoke       = "revoke"
+	ActionConflict     = "conflict_of_interest"
+	ActionSetPolicy    = "set_conflict_policy"
+	ActionReply        = "reply"
+	ActionDispute      = "dispute"
+	ActionModerate     = "moderate"
+)
+
+// RequestMeta - данные HTTP запроса, которые попадают в журнал аудита
+type RequestMeta struct {
+	RequestID string
+	IP        string
+}
+
+type requestMetaKey struct{}
+
+func WithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaKey{}, meta)
+}
+
+func RequestMetaFrom(ctx context.Context) RequestMeta {
+	meta, _ := ctx.Value(requestMetaKey{}).(RequestMeta)
+	return meta
+}
+
+type auditEntry struct {
+	ActorID        string
+	OrganizationID string
+	EntityType     string
+	EntityID       string
+	Action         string
+	Before         interface{}
+	After          interface{}
+}
+
+func auditJSON(ctx context.Context, v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		logger.FromContext(ctx).Error("auditJSON: Marshal err", "err", err)
+		return ""
+	}
+	return string(data)
+}
+
+// auditTimeout ограничивает запись в журнал, которая не отменяется
+// вместе с запросом
+const auditTimeout = 10 * time.Second
+
+// audit дописывает запись в журнал. Ошибка записи не отменяет уже
+// выполненное действие, поэтому только логируется. Действие к этому
+// моменту уже зафиксировано, поэтому запись не должна прерываться, если
+// клиент отключился: контекст запроса отвязывается от его отмены.
+func (s *Service) audit(ctx context.Context, entry auditEntry) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), auditTimeout)
+	defer cancel()
+	meta := RequestMetaFrom(ctx)
+	err := s.query.AppendAuditLog(ctx, database.AppendAuditLogParams{
+		ActorID:        entry.ActorID,
+		OrganizationID: entry.OrganizationID,
+		EntityType:     entry.EntityType,
+		EntityID:       entry.EntityID,
+		Action:         entry.Action,
+		Before:         auditJSON(ctx, entry.Before),
+		After:          auditJSON(ctx, entry.After),
+		RequestID:      meta.RequestID,
+		IP:             meta.IP,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("audit: AppendAuditLog err", "err", err)
+	}
+}
+
+type AuditRecord struct {
+	ID             int64           `json:"id"`
+	ActorID        string          `json:"actorId"`
+	OrganizationID string          `json:"organizationId"`
+	EntityType     string          `json:"entityType"`
+	EntityID       string          `json:"entityId"`
+	Action         string          `json:"action"`
+	Before         json.RawMessage `json:"before,omitempty"`
+	After          json.RawMessage `json:"after,omitempty"`
+	RequestID      string          `json:"requestId"`
+	IP             string          `json:"ip"`
+	PrevHash       string          `json:"prevHash"`
+	Hash           string          `json:"hash"`
+	CreatedAt      time.Time       `json:"createdAt"`
+}
+
+type ListAuditRequest struct {
+	Username       string
+	OrganizationID string
+	ActorID        string
+	EntityType     string
+	EntityID       string
+	Action         string
+	From           *time.Time
+	To             *time.Time
+	Offset         int32
+	Limit          int32
+}
+
+// ListAudit доступен администраторам по всем организациям и ответственным
+// только в пределах своей организации
+func (s *Service) ListAudit(ctx context.Context, params ListAuditRequest) ([]AuditRecord, error) {
+	ctx, span := tracing.Start(ctx, "service.ListAudit")
+	defer span.End()
+
+	if _, err := s.requireAdmin(ctx, params.Username); err != nil {
+		if err != IsNotAdmin {
+			return nil, err
+		}
+		if params.OrganizationID == "" {
+			return nil, IsNotResponsible
+		}
+		user_id, err := s.fetchUserID(ctx, params.Username)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.isResponsibleUser(ctx, params.OrganizationID, user_id); err != nil {
+			return nil, err
+		}
+	}
+
+	logs, err := s.query.ListAuditLog(ctx, database.ListAuditLogParams{
+		OrganizationID: params.OrganizationID,
+		ActorID:        params.ActorID,
+		EntityType:     params.EntityType,
+		EntityID:       params.EntityID,
+		Action:         params.Action,
+		From:           params.From,
+		To:             params.To,
+		Offset:         params.Offset,
+		Limit:          params.Limit,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("ListAudit: ListAuditLog err", "err", err)
+		return nil, UnknowError
+	}
+	records := []AuditRecord{}
+	for _, item := range logs {
+		record := AuditRecord{
+			ID:             item.ID,
+			ActorID:        item.ActorID,
+			OrganizationID: item.OrganizationID,
+			EntityType:     item.EntityType,
+			EntityID:       item.EntityID,
+			Action:         item.Action,
+			RequestID:      item.RequestID,
+			IP:             item.IP,
+			PrevHash:       item.PrevHash,
+			Hash:           item.Hash,
+			CreatedAt:      item.CreatedAt,
+		}
+		if item.Before != "" {
+			record.Before = json.RawMessage(item.Before)
+		}
+		if item.After != "" {
+			record.After = json.RawMessage(item.After)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+type AuditVerification struct {
+	Valid      bool  `json:"valid"`
+	Checked    int64 `json:"checked"`
+	BrokenAtID int64 `json:"brokenAtId,omitempty"`
+}
+
+func (s *Service) VerifyAudit(ctx context.Context, username string) (*AuditVerification, error) {
+	ctx, span := tracing.Start(ctx, "service.VerifyAudit")
+	defer span.End()
+
+	if _, err := s.requireAdmin(ctx, username); err != nil {
+		return nil, err
+	}
+	broken_id, checked, err := s.query.VerifyAuditChain(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Error("VerifyAudit: VerifyAuditChain err", "err", err)
+		return nil, UnknowError
+	}
+	return &AuditVerification{
+		Valid:      broken_id == 0,
+		Checked:    checked,
+		BrokenAtID: broken_id,
+	}, nil
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"tender_service/internal/database"
+	"tender_service/internal/logger"
+	"tender_service/internal/tracing"
+)
+
+var (
+	ConflictBlocked       = fmt.Errorf("Голос не учтен: конфликт интересов с автором предложения")
+	InvalidConflictPolicy = fmt.Errorf("Политика конфликта интересов может быть flag или block")
+)
+
+// политика организации для голосов с конфликтом интересов
+const (
+	ConflictPolicyFlag  = "flag"
+	ConflictPolicyBlock = "block"
+)
+
+type Conflict struct {
+	Decision string `json:"decision"`
+	LotID    string `json:"lotId,omitempty"`
+	Declared bool   `json:"declared"`
+	Detected bool   `json:"detected"`
+	Comment  string `json:"comment,omitempty"`
+	Policy   string `json:"policy"`
+	Blocked  bool   `json:"blocked"`
+}
+
+// checkConflict определяет конфликт интересов голосующего: заявленный им
+// самим или найденный автоматически, когда он состоит в одной организации
+// с автором предложения. Политика берется у организации тендера.
+func (s *Service) checkConflict(ctx context.Context, user_id string, tender database.Tender, bid *database.OfferFull, params DecisionRequest) (database.DecisionConflict, error) {
+	conflict := database.DecisionConflict{
+		Declared: params.ConflictDeclared,
+		Comment:  params.ConflictComment,
+	}
+	detected, err := s.query.SharesOrganization(ctx, user_id, bid.AuthorId.String())
+	if err != nil {
+		logger.FromContext(ctx).Error("checkConflict: SharesOrganization err", "err", err)
+		return conflict, UnknowError
+	}
+	conflict.Detected = detected
+	if !conflict.Declared && !conflict.Detected {
+		return conflict, nil
+	}
+	conflict.Policy, err = s.query.GetConflictPolicy(ctx, tender.OrganizationID.String())
+	if err != nil {
+		logger.FromContext(ctx).Error("checkConflict: GetConflictPolicy err", "err", err)
+		return conflict, UnknowError
+	}
+	conflict.Blocked = conflict.Policy == ConflictPolicyBlock
+	return conflict, nil
+}
+
+// recordDecision записывает голос и, если у голосующего конфликт
+// интересов, отдельную запись в журнал аудита
+func (s *Service) recordDecision(ctx context.Context, tender database.Tender, params database.NewDecisionParams) error {
+	if err := s.query.NewDecision(ctx, params); err != nil {
+		return err
+	}
+	if params.Conflict.Policy != "" {
+		s.audit(ctx, auditEntry{
+			ActorID:        params.User_id,
+			OrganizationID: tender.OrganizationID.String(),
+			EntityType:     EntityBid,
+			EntityID:       params.Offer_id,
+			Action:         ActionConflict,
+			After: Conflict{
+				Decision: params.Decision,
+				LotID:    params.Lot_id,
+				Declared: params.Conflict.Declared,
+				Detected: params.Conflict.Detected,
+				Comment:  params.Conflict.Comment,
+				Policy:   params.Conflict.Policy,
+				Blocked:  params.Conflict.Blocked,
+			},
+		})
+	}
+	return nil
+}
+
+// blockDecision сохраняет заблокированный голос без последствий
+// для предложения и возвращает ConflictBlocked
+func (s *Service) blockDecision(ctx context.Context, user_id string, tender database.Tender, bid *database.OfferFull, lots []database.Lot, params DecisionRequest, conflict database.DecisionConflict) error {
+	if params.Desicion != "Approved" && params.Desicion != "Rejected" {
+		return InvalidDecisionVallue
+	}
+	if params.Lot_id != "" {
+		if len(lots) == 0 {
+			return TenderHasNoLots
+		}
+		if _, err := s.findLot(ctx, tender.ID.String(), params.Lot_id); err != nil {
+			return err
+		}
+	}
+	err := s.recordDecision(ctx, tender, database.NewDecisionParams{
+		Offer_id: bid.ID.String(),
+		User_id:  user_id,
+		Decision: params.Desicion,
+		Lot_id:   params.Lot_id,
+		Conflict: conflict,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("blockDecision: NewDecision err", "err", err)
+		return UnknowError
+	}
+	return ConflictBlocked
+}
+
+type ConflictPolicy struct {
+	OrganizationID string `json:"organizationId"`
+	Policy         string `json:"policy"`
+}
+
+type ConflictPolicyRequest struct {
+	Username        string
+	Organization_id string
+	// Policy - новая политика, пустая при чтении
+	Policy string
+}
+
+func (s *Service) GetConflictPolicy(ctx context.Context, params ConflictPolicyRequest) (*ConflictPolicy, error) {
+	ctx, span := tracing.Start(ctx, "service.GetConflictPolicy")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.isResponsibleUser(ctx, params.Organization_id, user_id); err != nil {
+		return nil, err
+	}
+	policy, err := s.query.GetConflictPolicy(ctx, params.Organization_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, OrganizationNotFound
+		}
+		logger.FromContext(ctx).Error("GetConflictPolicy: GetConflictPolicy err", "err", err)
+		return nil, UnknowError
+	}
+	return &ConflictPolicy{OrganizationID: params.Organization_id, Policy: policy}, nil
+}
+
+// SetConflictPolicy меняет политику организации. Действует на голоса,
+// поданные после изменения, уже записанные голоса не пересматриваются.
+func (s *Service) SetConflictPolicy(ctx context.Context, params ConflictPolicyRequest) (*ConflictPolicy, error) {
+	ctx, span := tracing.Start(ctx, "service.SetConflictPolicy")
+	defer span.End()
+
+	if params.Policy != ConflictPolicyFlag && params.Policy != ConflictPolicyBlock {
+		return nil, InvalidConflictPolicy
+	}
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.isResponsibleUser(ctx, params.Organization_id, user_id); err != nil {
+		return nil, err
+	}
+	current, err := s.query.GetConflictPolicy(ctx, params.Organization_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, OrganizationNotFound
+		}
+		logger.FromContext(ctx).Error("SetConflictPolicy: GetConflictPolicy err", "err", err)
+		return nil, UnknowError
+	}
+	if err := s.query.SetConflictPolicy(ctx, params.Organization_id, params.Policy); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, OrganizationNotFound
+		}
+		logger.FromContext(ctx).Error("SetConflictPolicy: SetConflictPolicy err", "err", err)
+		return nil, UnknowError
+	}
+	result := &ConflictPolicy{OrganizationID: params.Organization_id, Policy: params.Policy}
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: params.Organization_id,
+		EntityType:     EntityOrganization,
+		EntityID:       params.Organization_id,
+		Action:         ActionSetPolicy,
+		Before:         ConflictPolicy{OrganizationID: params.Organization_id, Policy: current},
+		After:          result,
+	})
+	return result, nil
+}
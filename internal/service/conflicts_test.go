@@ -0,0 +1,129 @@
+package service
+
+import (
+	"reflect"
+	"tender_service/internal/database/dbtest"
+	"testing"
+)
+
+// conflictEnv - заказчик с двумя ответственными, один из которых состоит
+// и в организации поставщика alice
+type conflictEnv struct {
+	*testEnv
+	buyer_org    string
+	supplier_org string
+}
+
+func newConflictEnv(t *testing.T) *conflictEnv {
+	e := newTestEnv(t)
+	buyer_org := e.org("Заказчик", "buyer1", "buyer2")
+	supplier_org := dbtest.Organization(t, e.db, "Поставщик", dbtest.Employee(t, e.db, "alice"), e.userID("buyer2"))
+	e.org("Другой поставщик", "bob")
+	return &conflictEnv{testEnv: e, buyer_org: buyer_org, supplier_org: supplier_org}
+}
+
+// approvals возвращает голоса по предложению: пользователь -> учтен ли голос
+func (e *conflictEnv) approvals(bid_id string) map[string]bool {
+	e.t.Helper()
+	rows, err := e.db.Query(`SELECT e.username, NOT a.blocked FROM approval a JOIN employee e ON e.id = a.user_id
+	    WHERE a.offer_id = $1`, bid_id)
+	if err != nil {
+		e.t.Fatalf("approval: %v", err)
+	}
+	defer rows.Close()
+	result := map[string]bool{}
+	for rows.Next() {
+		var username string
+		var counted bool
+		if err := rows.Scan(&username, &counted); err != nil {
+			e.t.Fatalf("approval: %v", err)
+		}
+		result[username] = counted
+	}
+	return result
+}
+
+func TestConflictFlagged(t *testing.T) {
+	e := newConflictEnv(t)
+	policy, err := e.s.GetConflictPolicy(e.ctx, ConflictPolicyRequest{Username: "buyer1", Organization_id: e.buyer_org})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.Policy != ConflictPolicyFlag {
+		t.Errorf("default policy = %q, want flag", policy.Policy)
+	}
+	tender := e.tender(TenderParams{OrganizationId: e.buyer_org, CreatorUsername: "buyer1"})
+	bid := e.bid("alice", tender.ID)
+
+	if _, err := e.decide("buyer1", bid.ID, "", "Approved"); err != nil {
+		t.Fatal(err)
+	}
+	// голос с конфликтом при политике flag учитывается и закрывает кворум
+	approved, err := e.decide("buyer2", bid.ID, "", "Approved")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approved.Status != "Approved" {
+		t.Errorf("bid = %s, want Approved", approved.Status)
+	}
+	if got := e.approvals(bid.ID); !reflect.DeepEqual(got, map[string]bool{"buyer1": true, "buyer2": true}) {
+		t.Errorf("approvals = %v", got)
+	}
+	want := []string{ActionCreate, ActionChangeStatus, ActionDecision, ActionConflict, ActionDecision}
+	if got := e.audited(EntityBid, bid.ID); !reflect.DeepEqual(got, want) {
+		t.Errorf("audit = %v, want %v", got, want)
+	}
+}
+
+func TestConflictBlocked(t *testing.T) {
+	e := newConflictEnv(t)
+	set := func(username, policy string) (*ConflictPolicy, error) {
+		return e.s.SetConflictPolicy(e.ctx, ConflictPolicyRequest{Username: username, Organization_id: e.buyer_org, Policy: policy})
+	}
+	if _, err := set("buyer1", "ignore"); err != InvalidConflictPolicy {
+		t.Errorf("invalid policy err = %v, want InvalidConflictPolicy", err)
+	}
+	if _, err := set("alice", ConflictPolicyBlock); err != IsNotResponsible {
+		t.Errorf("policy by outsider err = %v, want IsNotResponsible", err)
+	}
+	if _, err := set("buyer1", ConflictPolicyBlock); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.audited(EntityOrganization, e.buyer_org); !reflect.DeepEqual(got, []string{ActionSetPolicy}) {
+		t.Errorf("organization audit = %v, want set_policy", got)
+	}
+
+	tender := e.tender(TenderParams{OrganizationId: e.buyer_org, CreatorUsername: "buyer1"})
+	alice := e.bid("alice", tender.ID)
+	bob := e.bid("bob", tender.ID)
+
+	// найденный автоматически конфликт блокирует голос
+	if _, err := e.decide("buyer2", alice.ID, "", "Approved"); err != ConflictBlocked {
+		t.Errorf("blocked vote err = %v, want ConflictBlocked", err)
+	}
+	if _, err := e.decide("buyer1", alice.ID, "", "Approved"); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.approvals(alice.ID); !reflect.DeepEqual(got, map[string]bool{"buyer1": true, "buyer2": false}) {
+		t.Errorf("approvals = %v", got)
+	}
+	if status, err := e.s.FetchTenderStatus(e.ctx, "buyer1", tender.ID); err != nil || status != "Published" {
+		t.Errorf("tender = %s, %v; blocked vote must not count towards quorum", status, err)
+	}
+
+	// заявленный самим голосующим конфликт тоже блокирует голос
+	_, err := e.s.DecisionSubmit(e.ctx, DecisionRequest{
+		Username: "buyer1", Bid_id: bob.ID, Desicion: "Rejected",
+		ConflictDeclared: true, ConflictComment: "Родственник в компании",
+	})
+	if err != ConflictBlocked {
+		t.Errorf("declared conflict err = %v, want ConflictBlocked", err)
+	}
+	rejected := e.myBid("bob", bob.ID)
+	if rejected.Status != "Published" {
+		t.Errorf("bob bid = %s, blocked rejection must not cancel it", rejected.Status)
+	}
+	if got := e.audited(EntityBid, bob.ID); got[len(got)-1] != ActionConflict {
+		t.Errorf("bob audit = %v, want conflict last", got)
+	}
+}
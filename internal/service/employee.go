@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/lib/pq"
+	"tender_service/internal/database"
+	"tender_service/internal/logger"
+	"tender_service/internal/tracing"
+	"time"
+)
+
+var (
+	IsNotAdmin       = fmt.Errorf("Пользователь не является администратором")
+	EmployeeNotFound = fmt.Errorf("Сотрудник с таким id не существует")
+	UsernameTaken    = fmt.Errorf("Пользователь с таким именем уже существует")
+)
+
+const (
+	RoleResponsible = "responsible"
+	RoleAdmin       = "admin"
+)
+
+type Employee struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	FirstName string    `json:"firstName"`
+	LastName  string    `json:"lastName"`
+	IsActive  bool      `json:"isActive"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func newEmployee(user *database.User) Employee {
+	return Employee{
+		ID:        user.ID.String(),
+		Username:  user.Username,
+		FirstName: user.Firstname,
+		LastName:  user.Lastname,
+		IsActive:  user.IsActive,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+	}
+}
+
+type Organization struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Role string `json:"role"`
+}
+
+type Profile struct {
+	Employee
+	Organizations []Organization `json:"organizations"`
+	Roles         []string       `json:"roles"`
+}
+
+// requireAdmin проверяет, что пользователь активен и является администратором
+func (s *Service) requireAdmin(ctx context.Context, username string) (*database.User, error) {
+	user, err := s.query.FetchUserByUsername(ctx, username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, UserNotFound
+		}
+		logger.FromContext(ctx).Error("requireAdmin: FetchUserByUsername err", "err", err)
+		return nil, UnknowError
+	}
+	if !user.IsActive {
+		return nil, UserDeactivated
+	}
+	if !user.IsAdmin {
+		return nil, IsNotAdmin
+	}
+	return user, nil
+}
+
+func (s *Service) FetchProfile(ctx context.Context, username string) (*Profile, error) {
+	ctx, span := tracing.Start(ctx, "service.FetchProfile")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.query.FetchUser(ctx, user_id)
+	if err != nil {
+		logger.FromContext(ctx).Error("FetchProfile: FetchUser err", "err", err)
+		return nil, UnknowError
+	}
+	return s.buildProfile(ctx, user)
+}
+
+func (s *Service) buildProfile(ctx context.Context, user *database.User) (*Profile, error) {
+	orgs, err := s.query.ListUserOrganizations(ctx, user.ID.String())
+	if err != nil {
+		logger.FromContext(ctx).Error("buildProfile: ListUserOrganizations err", "err", err)
+		return nil, UnknowError
+	}
+	profile := Profile{
+		Employee:      newEmployee(user),
+		Organizations: []Organization{},
+		Roles:         []string{},
+	}
+	for _, org := range orgs {
+		profile.Organizations = append(profile.Organizations, Organization{
+			ID:   org.ID.String(),
+			Name: org.Name,
+			Type: org.Type,
+			Role: RoleResponsible,
+		})
+	}
+	if len(orgs) > 0 {
+		profile.Roles = append(profile.Roles, RoleResponsible)
+	}
+	if user.IsAdmin {
+		profile.Roles = append(profile.Roles, RoleAdmin)
+	}
+	return &profile, nil
+}
+
+type EditProfileRequest struct {
+	Username  string
+	FirstName string
+	LastName  string
+}
+
+func (s *Service) EditProfile(ctx context.Context, params EditProfileRequest) (*Profile, error) {
+	ctx, span := tracing.Start(ctx, "service.EditProfile")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	before, err := s.query.FetchUser(ctx, user_id)
+	if err != nil {
+		logger.FromContext(ctx).Error("EditProfile: FetchUser err", "err", err)
+		return nil, UnknowError
+	}
+	user, err := s.query.UpdateUserName(ctx, database.UpdateUserNameParams{
+		User_id:   user_id,
+		FirstName: params.FirstName,
+		LastName:  params.LastName,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("EditProfile: UpdateUserName err", "err", err)
+		return nil, UnknowError
+	}
+	s.audit(ctx, auditEntry{
+		ActorID:    user_id,
+		EntityType: EntityEmployee,
+		EntityID:   user_id,
+		Action:     ActionEdit,
+		Before:     newEmployee(before),
+		After:      newEmployee(user),
+	})
+	return s.buildProfile(ctx, user)
+}
+
+type SearchEmployeesRequest struct {
+	Username string
+	Query    string
+	Offset   int32
+	Limit    int32
+}
+
+func (s *Service) SearchEmployees(ctx context.Context, params SearchEmployeesRequest) ([]Employee, error) {
+	ctx, span := tracing.Start(ctx, "service.SearchEmployees")
+	defer span.End()
+
+	if _, err := s.requireAdmin(ctx, params.Username); err != nil {
+		return nil, err
+	}
+	users, err := s.query.SearchEmployees(ctx, database.SearchEmployeesParams{
+		Query:  params.Query,
+		Offset: params.Offset,
+		Limit:  params.Limit,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("SearchEmployees: SearchEmployees err", "err", err)
+		return nil, UnknowError
+	}
+	employees := []Employee{}
+	for i := range users {
+		employees = append(employees, newEmployee(&users[i]))
+	}
+	return employees, nil
+}
+
+type CreateEmployeeRequest struct {
+	AdminUsername string
+	Username      string
+	FirstName     string
+	LastName      string
+}
+
+func (s *Service) CreateEmployee(ctx context.Context, params CreateEmployeeRequest) (*Employee, error) {
+	ctx, span := tracing.Start(ctx, "service.CreateEmployee")
+	defer span.End()
+
+	admin, err := s.requireAdmin(ctx, params.AdminUsername)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.query.CreateEmployee(ctx, database.CreateEmployeeParams{
+		Username:  params.Username,
+		FirstName: params.FirstName,
+		LastName:  params.LastName,
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return nil, UsernameTaken
+		}
+		logger.FromContext(ctx).Error("CreateEmployee: CreateEmployee err", "err", err)
+		return nil, UnknowError
+	}
+	employee := newEmployee(user)
+	s.audit(ctx, auditEntry{
+		ActorID:    admin.ID.String(),
+		EntityType: EntityEmployee,
+		EntityID:   employee.ID,
+		Action:     ActionCreate,
+		After:      employee,
+	})
+	return &employee, nil
+}
+
+type DeactivateEmployeeRequest struct {
+	AdminUsername string
+	Employee_id   string
+}
+
+func (s *Service) DeactivateEmployee(ctx context.Context, params DeactivateEmployeeRequest) (*Employee, error) {
+	ctx, span := tracing.Start(ctx, "service.DeactivateEmployee")
+	defer span.End()
+
+	admin, err := s.requireAdmin(ctx, params.AdminUsername)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.query.DeactivateEmployee(ctx, params.Employee_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, EmployeeNotFound
+		}
+		logger.FromContext(ctx).Error("DeactivateEmployee: DeactivateEmployee err", "err", err)
+		return nil, UnknowError
+	}
+	employee := newEmployee(user)
+	s.audit(ctx, auditEntry{
+		ActorID:    admin.ID.String(),
+		EntityType: EntityEmployee,
+		EntityID:   employee.ID,
+		Action:     ActionDeactivate,
+		After:      employee,
+	})
+	return &employee, nil
+}
+
+// UserOrganizationID возвращает организацию пользователя или пустую строку,
+// если пользователь не найден или не состоит в организации
+func (s *Service) UserOrganizationID(ctx context.Context, username string) string {
+	user, err := s.query.FetchUserByUsername(ctx, username)
+	if err != nil {
+		return ""
+	}
+	org_id, err := s.query.GetUserOrganization(ctx, user.ID.String())
+	if err != nil {
+		return ""
+	}
+	return org_id
+}
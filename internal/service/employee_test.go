@@ -0,0 +1,115 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEmployeeProfile(t *testing.T) {
+	e := newTestEnv(t)
+	org_id := e.org("Заказчик", "buyer")
+	e.admin("root")
+
+	profile, err := e.s.FetchProfile(e.ctx, "buyer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(profile.Roles, []string{RoleResponsible}) {
+		t.Errorf("roles = %v, want responsible", profile.Roles)
+	}
+	if len(profile.Organizations) != 1 || profile.Organizations[0].ID != org_id || profile.Organizations[0].Role != RoleResponsible {
+		t.Errorf("organizations = %+v", profile.Organizations)
+	}
+
+	profile, err = e.s.FetchProfile(e.ctx, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(profile.Roles, []string{RoleAdmin}) || len(profile.Organizations) != 0 {
+		t.Errorf("admin profile = %+v", profile)
+	}
+
+	profile, err = e.s.EditProfile(e.ctx, EditProfileRequest{Username: "buyer", FirstName: "Иван", LastName: "Петров"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.FirstName != "Иван" || profile.LastName != "Петров" || len(profile.Organizations) != 1 {
+		t.Errorf("edited profile = %+v", profile)
+	}
+	if got := e.audited(EntityEmployee, profile.ID); !reflect.DeepEqual(got, []string{ActionEdit}) {
+		t.Errorf("audit = %v, want edit", got)
+	}
+
+	if _, err := e.s.FetchProfile(e.ctx, "nobody"); err != UserNotFound {
+		t.Errorf("unknown user err = %v, want UserNotFound", err)
+	}
+}
+
+func TestEmployeeDirectory(t *testing.T) {
+	e := newTestEnv(t)
+	e.org("Заказчик", "buyer")
+	e.admin("root")
+
+	if _, err := e.s.SearchEmployees(e.ctx, SearchEmployeesRequest{Username: "buyer", Limit: 10}); err != IsNotAdmin {
+		t.Errorf("search by non-admin err = %v, want IsNotAdmin", err)
+	}
+	if _, err := e.s.CreateEmployee(e.ctx, CreateEmployeeRequest{AdminUsername: "buyer", Username: "x"}); err != IsNotAdmin {
+		t.Errorf("create by non-admin err = %v, want IsNotAdmin", err)
+	}
+
+	created, err := e.s.CreateEmployee(e.ctx, CreateEmployeeRequest{
+		AdminUsername: "root", Username: "new_hire", FirstName: "Анна", LastName: "Смирнова",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created.IsActive || created.FirstName != "Анна" {
+		t.Errorf("created = %+v", created)
+	}
+	if _, err := e.s.CreateEmployee(e.ctx, CreateEmployeeRequest{AdminUsername: "root", Username: "new_hire"}); err != UsernameTaken {
+		t.Errorf("duplicate err = %v, want UsernameTaken", err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "Смирн", want: []string{"new_hire"}},
+		{query: "NEW_", want: []string{"new_hire"}},
+		{query: "%", want: nil},
+		{query: "", want: []string{"buyer", "new_hire", "root"}},
+	}
+	for _, tt := range tests {
+		found, err := e.s.SearchEmployees(e.ctx, SearchEmployeesRequest{Username: "root", Query: tt.query, Limit: 10})
+		if err != nil {
+			t.Fatalf("SearchEmployees(%q): %v", tt.query, err)
+		}
+		var got []string
+		for _, employee := range found {
+			got = append(got, employee.Username)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SearchEmployees(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	deactivated, err := e.s.DeactivateEmployee(e.ctx, DeactivateEmployeeRequest{AdminUsername: "root", Employee_id: created.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deactivated.IsActive {
+		t.Error("employee must be deactivated")
+	}
+	if _, err := e.s.FetchProfile(e.ctx, "new_hire"); err != UserDeactivated {
+		t.Errorf("deactivated profile err = %v, want UserDeactivated", err)
+	}
+	_, err = e.s.DeactivateEmployee(e.ctx, DeactivateEmployeeRequest{
+		AdminUsername: "root", Employee_id: "5f1b2c9e-0000-4000-8000-000000000001",
+	})
+	if err != EmployeeNotFound {
+		t.Errorf("unknown employee err = %v, want EmployeeNotFound", err)
+	}
+	if got := e.audited(EntityEmployee, created.ID); !reflect.DeepEqual(got, []string{ActionCreate, ActionDeactivate}) {
+		t.Errorf("audit = %v, want create and deactivate", got)
+	}
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"tender_service/internal/database"
+	"tender_service/internal/export"
+	"tender_service/internal/logger"
+	"tender_service/internal/tracing"
+	"time"
+)
+
+// OpenExport вызывается после проверки прав, перед первой строкой выгрузки.
+// Ошибки, возникшие после этого, клиенту уже не передать - они только логируются.
+type OpenExport func() (export.Writer, error)
+
+type ExportRequest struct {
+	Username  string
+	Tender_id string
+}
+
+func exportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// streamExport открывает выгрузку, пишет заголовок и строки из fill
+func streamExport(ctx context.Context, op string, open OpenExport, header []string, fill func(write func([]string) error) error) error {
+	w, err := open()
+	if err != nil {
+		logger.FromContext(ctx).Error(op+": open err", "err", err)
+		return UnknowError
+	}
+	if err := w.WriteRow(header); err != nil {
+		logger.FromContext(ctx).Error(op+": WriteRow err", "err", err)
+		return UnknowError
+	}
+	if err := fill(w.WriteRow); err != nil {
+		logger.FromContext(ctx).Error(op+": export err", "err", err)
+		w.Close()
+		return UnknowError
+	}
+	if err := w.Close(); err != nil {
+		logger.FromContext(ctx).Error(op+": Close err", "err", err)
+		return UnknowError
+	}
+	return nil
+}
+
+// ExportMyTenders выгружает тендеры пользователя, как FetchMyTenders, но без пагинации
+func (s *Service) ExportMyTenders(ctx context.Context, params ExportRequest, open OpenExport) error {
+	ctx, span := tracing.Start(ctx, "service.ExportMyTenders")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return err
+	}
+
+	header := []string{"id", "name", "description", "serviceType", "status", "version", "createdAt", "updatedAt"}
+	return streamExport(ctx, "ExportMyTenders", open, header, func(write func([]string) error) error {
+		return s.query.ExportMyTenders(ctx, user_id, func(t database.Tender) error {
+			return write([]string{
+				t.ID.String(),
+				t.Name,
+				t.Description,
+				t.ServiceType,
+				t.Status,
+				strconv.Itoa(int(t.Version)),
+				exportTime(t.CreatedAt),
+				exportTime(t.UpdatedAt),
+			})
+		})
+	})
+}
+
+// ExportTenderBids выгружает предложения по тендеру с итогами голосования
+// и отзывами. Видимость та же, что у TenderListBids.
+func (s *Service) ExportTenderBids(ctx context.Context, params ExportRequest, open OpenExport) error {
+	ctx, span := tracing.Start(ctx, "service.ExportTenderBids")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return err
+	}
+	tender, err := s.query.GetTender(ctx, params.Tender_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return TenderNotFound
+		}
+		logger.FromContext(ctx).Error("ExportTenderBids: GetTender err", "err", err)
+		return UnknowError
+	}
+	if err := s.checkTenderVisible(ctx, tender, user_id); err != nil {
+		return err
+	}
+	terms, err := s.query.GetTenderTerms(ctx, tender.ID.String())
+	if err != nil {
+		logger.FromContext(ctx).Error("ExportTenderBids: GetTenderTerms err", "err", err)
+		return UnknowError
+	}
+	if isSealed(terms) {
+		return BidsSealed
+	}
+	org_id, _ := s.query.GetUserOrganization(ctx, user_id)
+
+	header := []string{"id", "name", "description", "status", "authorType", "authorId", "version", "createdAt",
+		"approvedCount", "rejectedCount", "reviewCount", "lastReview"}
+	return streamExport(ctx, "ExportTenderBids", open, header, func(write func([]string) error) error {
+		return s.query.ExportTenderOffers(ctx, tender.ID.String(), org_id, func(o database.OfferExport) error {
+			return write([]string{
+				o.ID.String(),
+				o.Name,
+				o.Description,
+				o.Status,
+				o.AuthorType,
+				o.AuthorId.String(),
+				strconv.Itoa(int(o.Version)),
+				exportTime(o.CreatedAt),
+				strconv.Itoa(int(o.ApprovedCount)),
+				strconv.Itoa(int(o.RejectedCount)),
+				strconv.Itoa(int(o.ReviewCount)),
+				o.LastReview,
+			})
+		})
+	})
+}
+
+// ExportTenderApprovals выгружает журнал решений по предложениям тендера.
+// Доступно только ответственным организации, создавшей тендер.
+func (s *Service) ExportTenderApprovals(ctx context.Context, params ExportRequest, open OpenExport) error {
+	ctx, span := tracing.Start(ctx, "service.ExportTenderApprovals")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return err
+	}
+	tender, err := s.query.GetTender(ctx, params.Tender_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return TenderNotFound
+		}
+		logger.FromContext(ctx).Error("ExportTenderApprovals: GetTender err", "err", err)
+		return UnknowError
+	}
+	if err := s.isResponsibleUser(ctx, tender.OrganizationID.String(), user_id); err != nil {
+		return err
+	}
+
+	header := []string{"id", "bidId", "bidName", "username", "decision", "conflict", "conflictComment", "createdAt"}
+	return streamExport(ctx, "ExportTenderApprovals", open, header, func(write func([]string) error) error {
+		return s.query.ExportTenderApprovals(ctx, tender.ID.String(), func(a database.ApprovalExport) error {
+			return write([]string{
+				a.ID,
+				a.OfferID,
+				a.OfferName,
+				a.Username,
+				a.Decision,
+				a.Conflict,
+				a.ConflictComment,
+				exportTime(a.CreatedAt),
+			})
+		})
+	})
+}
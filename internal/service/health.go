@@ -0,0 +1,39 @@
+package service
+
+import (
+	"context"
+	"tender_service/internal/database"
+	"tender_service/internal/logger"
+)
+
+type Readiness struct {
+	Ready                    bool   `json:"ready"`
+	Database                 string `json:"database"`
+	MigrationVersion         int64  `json:"migrationVersion"`
+	ExpectedMigrationVersion int64  `json:"expectedMigrationVersion"`
+}
+
+// CheckReadiness проверяет доступность базы и что в ней применены все миграции
+func (s *Service) CheckReadiness(ctx context.Context) Readiness {
+	var report Readiness
+	expected, err := database.ExpectedMigrationVersion()
+	if err != nil {
+		logger.FromContext(ctx).Error("CheckReadiness: ExpectedMigrationVersion", "err", err)
+	}
+	report.ExpectedMigrationVersion = expected
+
+	if err := s.query.Ping(ctx); err != nil {
+		report.Database = "unavailable"
+		return report
+	}
+	report.Database = "ok"
+
+	version, err := s.query.MigrationVersion(ctx)
+	if err != nil {
+		logger.FromContext(ctx).Error("CheckReadiness: MigrationVersion", "err", err)
+		return report
+	}
+	report.MigrationVersion = version
+	report.Ready = version >= expected
+	return report
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"tender_service/internal/database"
+	"tender_service/internal/logger"
+	"tender_service/internal/metrics"
+	"tender_service/internal/tracing"
+)
+
+const (
+	ImportRowOk    = "ok"
+	ImportRowError = "error"
+)
+
+// ImportRow - строка файла импорта. Error заполняется, если строку не
+// удалось разобрать или она не прошла проверку полей.
+type ImportRow struct {
+	Line   int
+	Params TenderParams
+	Error  string
+}
+
+type ImportTendersRequest struct {
+	Username string
+	Rows     []ImportRow
+	DryRun   bool
+}
+
+type ImportRowResult struct {
+	Line     int    `json:"line"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	TenderID string `json:"tenderId,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun   bool              `json:"dryRun"`
+	Total    int               `json:"total"`
+	Valid    int               `json:"valid"`
+	Invalid  int               `json:"invalid"`
+	Imported int               `json:"imported"`
+	Rows     []ImportRowResult `json:"rows"`
+}
+
+// ImportTenders проверяет все строки и, если ошибок нет и это не dryRun,
+// создает тендеры в одной транзакции: либо все, либо ни одного.
+func (s *Service) ImportTenders(ctx context.Context, params ImportTendersRequest) (*ImportReport, error) {
+	ctx, span := tracing.Start(ctx, "service.ImportTenders")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{
+		DryRun: params.DryRun,
+		Total:  len(params.Rows),
+		Rows:   make([]ImportRowResult, len(params.Rows)),
+	}
+	responsible := map[string]error{}
+	service_types := map[string]string{}
+	for n, row := range params.Rows {
+		result := ImportRowResult{Line: row.Line, Status: ImportRowOk, Error: row.Error}
+		if result.Error == "" {
+			code, ok := service_types[row.Params.ServiceType]
+			if !ok {
+				code, err = s.resolveServiceType(ctx, row.Params.ServiceType)
+				if err == UnknowError {
+					return nil, err
+				}
+				service_types[row.Params.ServiceType] = code
+			}
+			if code == "" {
+				result.Error = ServiceTypeNotFound.Error()
+			} else {
+				params.Rows[n].Params.ServiceType = code
+			}
+		}
+		if result.Error == "" {
+			org_id := row.Params.OrganizationId
+			check, ok := responsible[org_id]
+			if !ok {
+				check = s.isResponsibleUser(ctx, org_id, user_id)
+				responsible[org_id] = check
+			}
+			if check != nil {
+				result.Error = check.Error()
+			}
+		}
+		if result.Error != "" {
+			result.Status = ImportRowError
+			report.Invalid++
+		} else {
+			report.Valid++
+		}
+		report.Rows[n] = result
+	}
+	if params.DryRun || report.Invalid > 0 {
+		return report, nil
+	}
+
+	items := make([]database.CreateTenderParams, len(params.Rows))
+	for n, row := range params.Rows {
+		items[n] = database.CreateTenderParams{
+			OrganizationID: row.Params.OrganizationId,
+			CreatorID:      user_id,
+			Status:         row.Params.Status,
+			ServiceType:    row.Params.ServiceType,
+			Name:           row.Params.Name,
+			Description:    row.Params.Description,
+		}
+	}
+	created, failed, err := s.query.CreateTendersTx(ctx, items)
+	if err != nil {
+		logger.FromContext(ctx).Error("ImportTenders: CreateTendersTx err", "err", err, "row", failed)
+		if failed < 0 {
+			return nil, UnknowError
+		}
+		report.Valid--
+		report.Invalid++
+		report.Rows[failed].Status = ImportRowError
+		report.Rows[failed].Error = UnknowError.Error()
+		return report, nil
+	}
+
+	for n, row := range created {
+		report.Rows[n].TenderID = row.ID
+		p := params.Rows[n].Params
+		s.audit(ctx, auditEntry{
+			ActorID:        user_id,
+			OrganizationID: p.OrganizationId,
+			EntityType:     EntityTender,
+			EntityID:       row.ID,
+			Action:         ActionCreate,
+			After: &Tender{
+				ID:          row.ID,
+				Name:        p.Name,
+				Description: p.Description,
+				Status:      p.Status,
+				ServiceType: p.ServiceType,
+				Version:     row.Version,
+				CreatedAt:   row.CreatedAt,
+			},
+		})
+	}
+	report.Imported = len(created)
+	metrics.TendersCreated.Add(float64(len(created)))
+	return report, nil
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func importRow(line int, org_id, service_type string) ImportRow {
+	return ImportRow{Line: line, Params: TenderParams{
+		Name:           "Импорт " + service_type,
+		Description:    "Из файла",
+		ServiceType:    service_type,
+		Status:         "Created",
+		OrganizationId: org_id,
+	}}
+}
+
+func (e *testEnv) myTenders(username string) []string {
+	e.t.Helper()
+	tenders, err := e.s.FetchMyTenders(e.ctx, ListMyTendersRequest{Username: username, Limit: 50})
+	if err != nil {
+		e.t.Fatalf("FetchMyTenders: %v", err)
+	}
+	var ids []string
+	for _, tender := range tenders {
+		ids = append(ids, tender.ID)
+	}
+	return ids
+}
+
+func TestImportTendersDryRun(t *testing.T) {
+	e := newTestEnv(t)
+	org_id := e.org("Заказчик", "buyer")
+	other_org := e.org("Чужая", "stranger")
+
+	rows := []ImportRow{
+		importRow(2, org_id, "delivery"),
+		importRow(3, org_id, "Космос"),
+		importRow(4, other_org, "Delivery"),
+		{Line: 5, Error: "name: обязательное поле"},
+	}
+	report, err := e.s.ImportTenders(e.ctx, ImportTendersRequest{Username: "buyer", Rows: rows, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total != 4 || report.Valid != 1 || report.Invalid != 3 || report.Imported != 0 {
+		t.Errorf("report = %+v", report)
+	}
+	want := []ImportRowResult{
+		{Line: 2, Status: ImportRowOk},
+		{Line: 3, Status: ImportRowError, Error: ServiceTypeNotFound.Error()},
+		{Line: 4, Status: ImportRowError, Error: IsNotResponsible.Error()},
+		{Line: 5, Status: ImportRowError, Error: "name: обязательное поле"},
+	}
+	if !reflect.DeepEqual(report.Rows, want) {
+		t.Errorf("rows = %+v, want %+v", report.Rows, want)
+	}
+	if ids := e.myTenders("buyer"); len(ids) != 0 {
+		t.Errorf("dry run created tenders: %v", ids)
+	}
+}
+
+func TestImportTendersAllOrNothing(t *testing.T) {
+	e := newTestEnv(t)
+	org_id := e.org("Заказчик", "buyer")
+
+	// одна ошибочная строка отменяет весь импорт
+	report, err := e.s.ImportTenders(e.ctx, ImportTendersRequest{Username: "buyer", Rows: []ImportRow{
+		importRow(2, org_id, "Delivery"),
+		importRow(3, org_id, "Космос"),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Imported != 0 || report.Invalid != 1 {
+		t.Errorf("report with invalid row = %+v", report)
+	}
+	if ids := e.myTenders("buyer"); len(ids) != 0 {
+		t.Errorf("invalid import created tenders: %v", ids)
+	}
+
+	report, err = e.s.ImportTenders(e.ctx, ImportTendersRequest{Username: "buyer", Rows: []ImportRow{
+		importRow(2, org_id, "delivery"),
+		importRow(3, org_id, "Construction"),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Imported != 2 || report.Valid != 2 || report.Invalid != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+	ids := e.myTenders("buyer")
+	if len(ids) != 2 {
+		t.Fatalf("tenders = %v, want 2", ids)
+	}
+	for _, row := range report.Rows {
+		if row.TenderID == "" {
+			t.Errorf("row %d has no tender id", row.Line)
+			continue
+		}
+		if got := e.audited(EntityTender, row.TenderID); !reflect.DeepEqual(got, []string{ActionCreate}) {
+			t.Errorf("audit of %s = %v, want create", row.TenderID, got)
+		}
+	}
+	// код типа услуги приводится к справочнику
+	var service_type string
+	if err := e.db.QueryRow(`SELECT service_type FROM tender WHERE id = $1`, report.Rows[0].TenderID).Scan(&service_type); err != nil {
+		t.Fatal(err)
+	}
+	if service_type != "Delivery" {
+		t.Errorf("service_type = %q, want Delivery", service_type)
+	}
+
+	if _, err := e.s.ImportTenders(e.ctx, ImportTendersRequest{Username: "nobody"}); err != UserNotFound {
+		t.Errorf("unknown user err = %v, want UserNotFound", err)
+	}
+}
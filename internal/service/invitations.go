@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"tender_service/internal/database"
+	"tender_service/internal/logger"
+	"tender_service/internal/tracing"
+	"time"
+)
+
+var (
+	TenderNotInviteOnly  = fmt.Errorf("Тендер открыт для всех организаций")
+	InvitationNotFound   = fmt.Errorf("Приглашение с таким id не существует")
+	InvitationAnswered   = fmt.Errorf("На приглашение уже получен ответ")
+	NotInvited           = fmt.Errorf("Организация не приглашена к участию в тендере")
+	InvitationPending    = fmt.Errorf("Примите приглашение, чтобы подать предложение")
+	OrganizationNotFound = fmt.Errorf("Организация с таким id не существует")
+	SelfInvitation       = fmt.Errorf("Нельзя пригласить организацию, которая проводит тендер")
+)
+
+// видимость тендера
+const (
+	TenderPublic     = "public"
+	TenderInviteOnly = "invite_only"
+)
+
+const (
+	InvitationStatusPending  = "Pending"
+	InvitationStatusAccepted = "Accepted"
+	InvitationStatusDeclined = "Declined"
+)
+
+type Invitation struct {
+	ID               string     `json:"id"`
+	TenderID         string     `json:"tenderId"`
+	TenderName       string     `json:"tenderName"`
+	OrganizationID   string     `json:"organizationId"`
+	OrganizationName string     `json:"organizationName"`
+	InvitedBy        string     `json:"invitedBy"`
+	Status           string     `json:"status"`
+	RespondedBy      string     `json:"respondedBy,omitempty"`
+	RespondedAt      *time.Time `json:"respondedAt,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+}
+
+func newInvitation(i *database.Invitation) Invitation {
+	result := Invitation{
+		ID:               i.ID.String(),
+		TenderID:         i.TenderID.String(),
+		TenderName:       i.TenderName,
+		OrganizationID:   i.OrganizationID.String(),
+		OrganizationName: i.OrganizationName,
+		InvitedBy:        i.InvitedBy,
+		Status:           i.Status,
+		RespondedBy:      i.RespondedBy.String,
+		CreatedAt:        i.CreatedAt,
+	}
+	if i.RespondedAt.Valid {
+		result.RespondedAt = &i.RespondedAt.Time
+	}
+	return result
+}
+
+// checkInvited - в тендер по приглашению подают предложения только
+// организации, принявшие приглашение
+func (s *Service) checkInvited(ctx context.Context, tender_id, org_id string) error {
+	status, err := s.query.GetInvitationStatus(ctx, tender_id, org_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return NotInvited
+		}
+		logger.FromContext(ctx).Error("checkInvited: GetInvitationStatus err", "err", err)
+		return UnknowError
+	}
+	switch status {
+	case InvitationStatusAccepted:
+		return nil
+	case InvitationStatusPending:
+		return InvitationPending
+	default:
+		return NotInvited
+	}
+}
+
+// checkTenderVisible - тендер по приглашению для непричастных организаций
+// не существует: они не видят ни вопросов, ни лотов, ни аукциона, ни
+// предложений. Вызывается во всех операциях над тендером, доступных не
+// только его ответственным.
+func (s *Service) checkTenderVisible(ctx context.Context, tender database.Tender, user_id string) error {
+	if tender.Visibility != TenderInviteOnly {
+		return nil
+	}
+	visible, err := s.query.CanViewTender(ctx, tender.ID.String(), user_id)
+	if err != nil {
+		logger.FromContext(ctx).Error("checkTenderVisible: CanViewTender err", "err", err)
+		return UnknowError
+	}
+	if !visible {
+		return TenderNotFound
+	}
+	return nil
+}
+
+type InviteOrganizationsRequest struct {
+	Username         string
+	Tender_id        string
+	Organization_ids []string
+}
+
+// InviteOrganizations приглашает организации в тендер по приглашению.
+// Возвращает только новые приглашения: повторное приглашение организации,
+// которая еще не ответила или согласилась, ничего не меняет.
+func (s *Service) InviteOrganizations(ctx context.Context, params InviteOrganizationsRequest) ([]Invitation, error) {
+	ctx, span := tracing.Start(ctx, "service.InviteOrganizations")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	tender, err := s.query.GetTender(ctx, params.Tender_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, TenderNotFound
+		}
+		logger.FromContext(ctx).Error("InviteOrganizations: GetTender err", "err", err)
+		return nil, UnknowError
+	}
+	if err := s.isResponsibleUser(ctx, tender.OrganizationID.String(), user_id); err != nil {
+		return nil, err
+	}
+	if tender.Visibility != TenderInviteOnly {
+		return nil, TenderNotInviteOnly
+	}
+	for _, org_id := range params.Organization_ids {
+		if org_id == tender.OrganizationID.String() {
+			return nil, SelfInvitation
+		}
+	}
+
+	invitations, err := s.query.InviteOrganizationsTx(ctx, database.InviteOrganizationsParams{
+		Tender_id:        tender.ID.String(),
+		Invited_by:       user_id,
+		Organization_ids: params.Organization_ids,
+		Notification:     fmt.Sprintf("Ваша организация приглашена к участию в тендере «%s»", tender.Name),
+	})
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return nil, OrganizationNotFound
+		}
+		logger.FromContext(ctx).Error("InviteOrganizations: InviteOrganizationsTx err", "err", err)
+		return nil, UnknowError
+	}
+	result := []Invitation{}
+	for i := range invitations {
+		result = append(result, newInvitation(&invitations[i]))
+	}
+	if len(result) > 0 {
+		s.audit(ctx, auditEntry{
+			ActorID:        user_id,
+			OrganizationID: tender.OrganizationID.String(),
+			EntityType:     EntityTender,
+			EntityID:       tender.ID.String(),
+			Action:         ActionInvite,
+			After:          result,
+		})
+	}
+	return result, nil
+}
+
+type ListTenderInvitationsRequest struct {
+	Username  string
+	Tender_id string
+}
+
+// ListTenderInvitations - список приглашений видит только организация тендера
+func (s *Service) ListTenderInvitations(ctx context.Context, params ListTenderInvitationsRequest) ([]Invitation, error) {
+	ctx, span := tracing.Start(ctx, "service.ListTenderInvitations")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	tender, err := s.query.GetTender(ctx, params.Tender_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, TenderNotFound
+		}
+		logger.FromContext(ctx).Error("ListTenderInvitations: GetTender err", "err", err)
+		return nil, UnknowError
+	}
+	if err := s.isResponsibleUser(ctx, tender.OrganizationID.String(), user_id); err != nil {
+		return nil, err
+	}
+
+	invitations, err := s.query.ListTenderInvitations(ctx, tender.ID.String())
+	if err != nil {
+		logger.FromContext(ctx).Error("ListTenderInvitations: ListTenderInvitations err", "err", err)
+		return nil, UnknowError
+	}
+	result := []Invitation{}
+	for i := range invitations {
+		result = append(result, newInvitation(&invitations[i]))
+	}
+	return result, nil
+}
+
+type ListMyInvitationsRequest struct {
+	Username string
+	Status   string
+	Offset   int32
+	Limit    int32
+}
+
+// ListMyInvitations - приглашения организаций пользователя
+func (s *Service) ListMyInvitations(ctx context.Context, params ListMyInvitationsRequest) ([]Invitation, error) {
+	ctx, span := tracing.Start(ctx, "service.ListMyInvitations")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	invitations, err := s.query.ListUserInvitations(ctx, database.ListUserInvitationsParams{
+		User_id: user_id,
+		Status:  params.Status,
+		Offset:  params.Offset,
+		Limit:   params.Limit,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("ListMyInvitations: ListUserInvitations err", "err", err)
+		return nil, UnknowError
+	}
+	result := []Invitation{}
+	for i := range invitations {
+		result = append(result, newInvitation(&invitations[i]))
+	}
+	return result, nil
+}
+
+type RespondInvitationRequest struct {
+	Username      string
+	Invitation_id string
+	Decision      string
+}
+
+// RespondInvitation - ответственный приглашенной организации принимает
+// или отклоняет приглашение, автор тендера получает уведомление
+func (s *Service) RespondInvitation(ctx context.Context, params RespondInvitationRequest) (*Invitation, error) {
+	ctx, span := tracing.Start(ctx, "service.RespondInvitation")
+	defer span.End()
+
+	if params.Decision != InvitationStatusAccepted && params.Decision != InvitationStatusDeclined {
+		return nil, InvalidDecisionVallue
+	}
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	invitation, err := s.query.GetInvitation(ctx, params.Invitation_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, InvitationNotFound
+		}
+		logger.FromContext(ctx).Error("RespondInvitation: GetInvitation err", "err", err)
+		return nil, UnknowError
+	}
+	if err := s.isResponsibleUser(ctx, invitation.OrganizationID.String(), user_id); err != nil {
+		return nil, err
+	}
+	if invitation.Status != InvitationStatusPending {
+		return nil, InvitationAnswered
+	}
+
+	kind, verb := "invitation_accepted", "приняла"
+	if params.Decision == InvitationStatusDeclined {
+		kind, verb = "invitation_declined", "отклонила"
+	}
+	responded, err := s.query.RespondInvitationTx(ctx, database.RespondInvitationParams{
+		Invitation_id: invitation.ID.String(),
+		User_id:       user_id,
+		Status:        params.Decision,
+		Kind:          kind,
+		Notification: fmt.Sprintf("Организация «%s» %s приглашение к участию в тендере «%s»",
+			invitation.OrganizationName, verb, invitation.TenderName),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, InvitationAnswered
+		}
+		logger.FromContext(ctx).Error("RespondInvitation: RespondInvitationTx err", "err", err)
+		return nil, UnknowError
+	}
+	result := newInvitation(responded)
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: result.OrganizationID,
+		EntityType:     EntityInvitation,
+		EntityID:       result.ID,
+		Action:         ActionRespond,
+		Before:         newInvitation(invitation),
+		After:          result,
+	})
+	return &result, nil
+}
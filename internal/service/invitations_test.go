@@ -0,0 +1,176 @@
+package service
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// visibleTenders - имена тендеров из общего списка, которые видит пользователь
+func (e *testEnv) visibleTenders(username string) []string {
+	e.t.Helper()
+	tenders, err := e.s.FetchPublishedTenders(e.ctx, ListTendersRequest{Username: username, Limit: 50})
+	if err != nil {
+		e.t.Fatalf("FetchPublishedTenders %s: %v", username, err)
+	}
+	var names []string
+	for _, tender := range tenders {
+		names = append(names, tender.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestInviteOnlyTender(t *testing.T) {
+	e := newTestEnv(t)
+	org_id := e.org("Заказчик", "buyer")
+	alice_org := e.org("Поставщик А", "alice")
+	e.org("Поставщик Б", "bob")
+	carol_org := e.org("Поставщик В", "carol")
+	public := e.tender(TenderParams{Name: "Открытый", OrganizationId: org_id, CreatorUsername: "buyer"})
+	private := e.tender(TenderParams{Name: "Закрытый", OrganizationId: org_id, CreatorUsername: "buyer", Visibility: TenderInviteOnly})
+	if private.Visibility != TenderInviteOnly {
+		t.Fatalf("visibility = %q", private.Visibility)
+	}
+
+	invite := func(tender_id string, org_ids ...string) ([]Invitation, error) {
+		return e.s.InviteOrganizations(e.ctx, InviteOrganizationsRequest{Username: "buyer", Tender_id: tender_id, Organization_ids: org_ids})
+	}
+	if _, err := invite(public.ID, alice_org); err != TenderNotInviteOnly {
+		t.Errorf("invite to public tender err = %v, want TenderNotInviteOnly", err)
+	}
+	if _, err := invite(private.ID, org_id); err != SelfInvitation {
+		t.Errorf("self invitation err = %v, want SelfInvitation", err)
+	}
+	if _, err := invite(private.ID, "5f1b2c9e-0000-4000-8000-000000000001"); err != OrganizationNotFound {
+		t.Errorf("unknown organization err = %v, want OrganizationNotFound", err)
+	}
+	invitations, err := invite(private.ID, alice_org, carol_org)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(invitations) != 2 || invitations[0].Status != InvitationStatusPending {
+		t.Fatalf("invitations = %+v", invitations)
+	}
+	if again, err := invite(private.ID, alice_org); err != nil || len(again) != 0 {
+		t.Errorf("repeated invitation = %+v, %v; want nothing new", again, err)
+	}
+
+	both := []string{"Закрытый", "Открытый"}
+	tests := []struct {
+		username string
+		want     []string
+	}{
+		{username: "", want: []string{"Открытый"}},
+		{username: "bob", want: []string{"Открытый"}},
+		{username: "alice", want: both},
+		{username: "buyer", want: both},
+	}
+	for _, tt := range tests {
+		if got := e.visibleTenders(tt.username); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tenders for %q = %v, want %v", tt.username, got, tt.want)
+		}
+	}
+
+	// для неприглашенной организации тендера нет ни в одном разделе
+	if _, err := e.s.AskQuestion(e.ctx, AskQuestionRequest{Username: "bob", Tender_id: private.ID, Question: "?"}); err != TenderNotFound {
+		t.Errorf("question from uninvited err = %v, want TenderNotFound", err)
+	}
+	if _, err := e.s.ListLots(e.ctx, ListLotsRequest{Username: "bob", Tender_id: private.ID}); err != TenderNotFound {
+		t.Errorf("lots for uninvited err = %v, want TenderNotFound", err)
+	}
+	if _, err := e.s.ListAmendments(e.ctx, ListAmendmentsRequest{Username: "bob", Tender_id: private.ID, Limit: 10}); err != TenderNotFound {
+		t.Errorf("amendments for uninvited err = %v, want TenderNotFound", err)
+	}
+	if _, err := e.submitBid("bob", private.ID); err != NotInvited {
+		t.Errorf("bid from uninvited err = %v, want NotInvited", err)
+	}
+	if _, err := e.submitBid("alice", private.ID); err != InvitationPending {
+		t.Errorf("bid before accepting err = %v, want InvitationPending", err)
+	}
+
+	mine, err := e.s.ListMyInvitations(e.ctx, ListMyInvitationsRequest{Username: "alice", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mine) != 1 || mine[0].TenderName != "Закрытый" || mine[0].OrganizationID != alice_org {
+		t.Fatalf("alice invitations = %+v", mine)
+	}
+	respond := func(username, invitation_id, decision string) (*Invitation, error) {
+		return e.s.RespondInvitation(e.ctx, RespondInvitationRequest{Username: username, Invitation_id: invitation_id, Decision: decision})
+	}
+	if _, err := respond("bob", mine[0].ID, InvitationStatusAccepted); err != IsNotResponsible {
+		t.Errorf("respond by other organization err = %v, want IsNotResponsible", err)
+	}
+	if _, err := respond("alice", mine[0].ID, "Maybe"); err != InvalidDecisionVallue {
+		t.Errorf("invalid decision err = %v, want InvalidDecisionVallue", err)
+	}
+	accepted, err := respond("alice", mine[0].ID, InvitationStatusAccepted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accepted.Status != InvitationStatusAccepted || accepted.RespondedAt == nil {
+		t.Errorf("accepted = %+v", accepted)
+	}
+	if _, err := respond("alice", mine[0].ID, InvitationStatusDeclined); err != InvitationAnswered {
+		t.Errorf("second answer err = %v, want InvitationAnswered", err)
+	}
+	if _, err := e.submitBid("alice", private.ID); err != nil {
+		t.Errorf("bid after accepting err = %v", err)
+	}
+
+	// отказавшаяся организация перестает видеть тендер
+	carol, err := e.s.ListMyInvitations(e.ctx, ListMyInvitationsRequest{Username: "carol", Limit: 10})
+	if err != nil || len(carol) != 1 {
+		t.Fatalf("carol invitations = %+v, %v", carol, err)
+	}
+	if _, err := respond("carol", carol[0].ID, InvitationStatusDeclined); err != nil {
+		t.Fatal(err)
+	}
+	if got := e.visibleTenders("carol"); !reflect.DeepEqual(got, []string{"Открытый"}) {
+		t.Errorf("tenders for declined = %v", got)
+	}
+	if _, err := e.submitBid("carol", private.ID); err != NotInvited {
+		t.Errorf("bid after declining err = %v, want NotInvited", err)
+	}
+
+	notifications, err := e.s.ListNotifications(e.ctx, ListNotificationsRequest{Username: "buyer", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := map[string]bool{}
+	for _, n := range notifications {
+		kinds[n.Kind] = true
+	}
+	if !kinds["invitation_accepted"] || !kinds["invitation_declined"] {
+		t.Errorf("buyer notifications = %+v", notifications)
+	}
+	if got := e.audited(EntityInvitation, accepted.ID); !reflect.DeepEqual(got, []string{ActionRespond}) {
+		t.Errorf("invitation audit = %v, want respond", got)
+	}
+	if got := e.audited(EntityTender, private.ID); !reflect.DeepEqual(got, []string{ActionCreate, ActionInvite}) {
+		t.Errorf("tender audit = %v, want create and invite", got)
+	}
+}
+
+// Импорт создает только публичные тендеры: условия приглашений в строках
+// импорта отклоняются при разборе файла
+func TestImportedTendersArePublic(t *testing.T) {
+	e := newTestEnv(t)
+	org_id := e.org("Заказчик", "buyer")
+	e.org("Поставщик", "bob")
+	row := importRow(2, org_id, "Delivery")
+	row.Params.Status = "Published"
+	report, err := e.s.ImportTenders(e.ctx, ImportTendersRequest{Username: "buyer", Rows: []ImportRow{row}})
+	if err != nil || report.Imported != 1 {
+		t.Fatalf("import = %+v, %v", report, err)
+	}
+	if got := e.visibleTenders("bob"); !reflect.DeepEqual(got, []string{row.Params.Name}) {
+		t.Errorf("tenders for bob = %v", got)
+	}
+	if _, err := e.s.InviteOrganizations(e.ctx, InviteOrganizationsRequest{
+		Username: "buyer", Tender_id: report.Rows[0].TenderID, Organization_ids: []string{org_id},
+	}); err != TenderNotInviteOnly {
+		t.Errorf("invite to imported tender err = %v, want TenderNotInviteOnly", err)
+	}
+}
@@ -0,0 +1,522 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"tender_service/internal/database"
+	"tender_service/internal/logger"
+	"tender_service/internal/metrics"
+	"tender_service/internal/tracing"
+	"tender_service/internal/utils"
+	"time"
+)
+
+var (
+	LotNotFound      = fmt.Errorf("Лот с таким id не существует")
+	LotClosed        = fmt.Errorf("Лот уже разыгран или отменен")
+	LotsRequired     = fmt.Errorf("Тендер разделен на лоты: укажите лоты и цены предложения")
+	LotRequired      = fmt.Errorf("Тендер разделен на лоты: укажите лот, по которому принимается решение")
+	TenderHasNoLots  = fmt.Errorf("Тендер не разделен на лоты")
+	DuplicateLot     = fmt.Errorf("Лот указан в предложении несколько раз")
+	BidNotInLot      = fmt.Errorf("Предложение не участвует в розыгрыше этого лота")
+	LotsNotSupported = fmt.Errorf("Лоты доступны только для тендеров в открытом режиме")
+	LotsLocked       = fmt.Errorf("Лоты добавляются только в тендер в статусе Created без предложений")
+	LotCancelReason  = fmt.Errorf("Отмена лота требует указать причину")
+)
+
+const (
+	LotOpen      = "Open"
+	LotAwarded   = "Awarded"
+	LotCancelled = "Cancelled"
+)
+
+type LotParams struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Quantity    float64 `json:"quantity"`
+	// ServiceType по умолчанию совпадает с видом услуг тендера
+	ServiceType string `json:"serviceType"`
+}
+
+type Lot struct {
+	ID           string     `json:"id"`
+	TenderID     string     `json:"tenderId"`
+	Number       int32      `json:"number"`
+	Name         string     `json:"name"`
+	Description  string     `json:"description"`
+	Quantity     float64    `json:"quantity"`
+	ServiceType  string     `json:"serviceType"`
+	Status       string     `json:"status"`
+	AwardedBidID string     `json:"awardedBidId,omitempty"`
+	AwardedPrice *float64   `json:"awardedPrice,omitempty"`
+	CancelReason string     `json:"cancelReason,omitempty"`
+	ClosedAt     *time.Time `json:"closedAt,omitempty"`
+}
+
+func newLot(l *database.Lot) Lot {
+	result := Lot{
+		ID:           l.ID.String(),
+		TenderID:     l.TenderID.String(),
+		Number:       l.Number,
+		Name:         l.Name,
+		Description:  l.Description,
+		Quantity:     l.Quantity,
+		ServiceType:  l.ServiceType,
+		Status:       l.Status,
+		AwardedBidID: l.AwardedOfferID.String,
+		CancelReason: l.CancelReason.String,
+	}
+	if l.AwardedPrice.Valid {
+		result.AwardedPrice = &l.AwardedPrice.Float64
+	}
+	if l.ClosedAt.Valid {
+		result.ClosedAt = &l.ClosedAt.Time
+	}
+	return result
+}
+
+// BidLot - цена предложения по одному лоту
+type BidLot struct {
+	LotID  string  `json:"lotId"`
+	Number int32   `json:"number,omitempty"`
+	Price  float64 `json:"price"`
+	Status string  `json:"status,omitempty"`
+}
+
+// resolveLots проверяет виды услуг лотов по справочнику
+func (s *Service) resolveLots(ctx context.Context, tender_service_type string, lots []LotParams) ([]database.LotParams, error) {
+	var result []database.LotParams
+	for _, lot := range lots {
+		service_type := tender_service_type
+		if lot.ServiceType != "" {
+			var err error
+			service_type, err = s.resolveServiceType(ctx, lot.ServiceType)
+			if err != nil {
+				return nil, err
+			}
+		}
+		result = append(result, database.LotParams{
+			Name:        lot.Name,
+			Description: lot.Description,
+			Quantity:    lot.Quantity,
+			ServiceType: service_type,
+		})
+	}
+	return result, nil
+}
+
+// checkBidLots сверяет лоты предложения с лотами тендера
+func (s *Service) checkBidLots(ctx context.Context, tender_id string, bid_lots []BidLot) ([]database.OfferLotParams, []BidLot, error) {
+	lots, err := s.query.ListLots(ctx, tender_id)
+	if err != nil {
+		logger.FromContext(ctx).Error("checkBidLots: ListLots err", "err", err)
+		return nil, nil, UnknowError
+	}
+	if len(lots) == 0 {
+		if len(bid_lots) > 0 {
+			return nil, nil, TenderHasNoLots
+		}
+		return nil, nil, nil
+	}
+	if len(bid_lots) == 0 {
+		return nil, nil, LotsRequired
+	}
+	by_id := map[string]*database.Lot{}
+	for i := range lots {
+		by_id[lots[i].ID.String()] = &lots[i]
+	}
+	seen := map[string]bool{}
+	var params []database.OfferLotParams
+	var result []BidLot
+	for _, bid_lot := range bid_lots {
+		lot, ok := by_id[bid_lot.LotID]
+		if !ok {
+			return nil, nil, LotNotFound
+		}
+		if lot.Status != LotOpen {
+			return nil, nil, LotClosed
+		}
+		if seen[bid_lot.LotID] {
+			return nil, nil, DuplicateLot
+		}
+		seen[bid_lot.LotID] = true
+		params = append(params, database.OfferLotParams{Lot_id: bid_lot.LotID, Price: bid_lot.Price})
+		result = append(result, BidLot{LotID: bid_lot.LotID, Number: lot.Number, Price: bid_lot.Price, Status: "Pending"})
+	}
+	return params, result, nil
+}
+
+// attachBidLots дописывает к предложениям их цены по лотам
+func (s *Service) attachBidLots(ctx context.Context, bids []Bid) {
+	if len(bids) == 0 {
+		return
+	}
+	ids := make([]string, 0, len(bids))
+	for _, bid := range bids {
+		ids = append(ids, bid.ID)
+	}
+	offer_lots, err := s.query.ListOfferLots(ctx, ids)
+	if err != nil {
+		logger.FromContext(ctx).Error("attachBidLots: ListOfferLots err", "err", err)
+		return
+	}
+	by_offer := map[string][]BidLot{}
+	for _, ol := range offer_lots {
+		offer_id := ol.OfferID.String()
+		by_offer[offer_id] = append(by_offer[offer_id], BidLot{
+			LotID:  ol.LotID.String(),
+			Number: ol.LotNumber,
+			Price:  ol.Price,
+			Status: ol.Status,
+		})
+	}
+	for i := range bids {
+		bids[i].Lots = by_offer[bids[i].ID]
+	}
+}
+
+type ListLotsRequest struct {
+	Username  string
+	Tender_id string
+}
+
+// ListLots - лоты видны всем, кому виден тендер
+func (s *Service) ListLots(ctx context.Context, params ListLotsRequest) ([]Lot, error) {
+	ctx, span := tracing.Start(ctx, "service.ListLots")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	tender, err := s.query.GetTender(ctx, params.Tender_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, TenderNotFound
+		}
+		logger.FromContext(ctx).Error("ListLots: GetTender err", "err", err)
+		return nil, UnknowError
+	}
+	if err := s.checkTenderVisible(ctx, tender, user_id); err != nil {
+		return nil, err
+	}
+	if tender.Status == "Created" {
+		if err := s.isResponsibleUser(ctx, tender.OrganizationID.String(), user_id); err != nil {
+			return nil, err
+		}
+	}
+	lots, err := s.query.ListLots(ctx, tender.ID.String())
+	if err != nil {
+		logger.FromContext(ctx).Error("ListLots: ListLots err", "err", err)
+		return nil, UnknowError
+	}
+	result := []Lot{}
+	for i := range lots {
+		result = append(result, newLot(&lots[i]))
+	}
+	return result, nil
+}
+
+type AddLotRequest struct {
+	Username  string
+	Tender_id string
+	Lot       LotParams
+}
+
+func (s *Service) AddLot(ctx context.Context, params AddLotRequest) (*Lot, error) {
+	ctx, span := tracing.Start(ctx, "service.AddLot")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	tender, err := s.query.GetTender(ctx, params.Tender_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, TenderNotFound
+		}
+		logger.FromContext(ctx).Error("AddLot: GetTender err", "err", err)
+		return nil, UnknowError
+	}
+	if err := s.isResponsibleUser(ctx, tender.OrganizationID.String(), user_id); err != nil {
+		return nil, err
+	}
+	if tender.BiddingMode != BiddingModeOpen {
+		return nil, LotsNotSupported
+	}
+	if tender.Status != "Created" {
+		return nil, LotsLocked
+	}
+	lots, err := s.resolveLots(ctx, tender.ServiceType, []LotParams{params.Lot})
+	if err != nil {
+		return nil, err
+	}
+
+	lot, err := s.query.AddLot(ctx, tender.ID.String(), lots[0])
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, LotsLocked
+		}
+		logger.FromContext(ctx).Error("AddLot: AddLot err", "err", err)
+		return nil, UnknowError
+	}
+	result := newLot(lot)
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: tender.OrganizationID.String(),
+		EntityType:     EntityLot,
+		EntityID:       result.ID,
+		Action:         ActionCreate,
+		After:          result,
+	})
+	return &result, nil
+}
+
+type CancelLotRequest struct {
+	Username  string
+	Tender_id string
+	Lot_id    string
+	Reason    string
+}
+
+// CancelLot отменяет лот, по которому не будет победителя. Когда открытых
+// лотов не остается, тендер закрывается.
+func (s *Service) CancelLot(ctx context.Context, params CancelLotRequest) (*Lot, error) {
+	ctx, span := tracing.Start(ctx, "service.CancelLot")
+	defer span.End()
+
+	if params.Reason == "" {
+		return nil, LotCancelReason
+	}
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	tender, err := s.query.GetTender(ctx, params.Tender_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, TenderNotFound
+		}
+		logger.FromContext(ctx).Error("CancelLot: GetTender err", "err", err)
+		return nil, UnknowError
+	}
+	if err := s.isResponsibleUser(ctx, tender.OrganizationID.String(), user_id); err != nil {
+		return nil, err
+	}
+	lot, err := s.findLot(ctx, tender.ID.String(), params.Lot_id)
+	if err != nil {
+		return nil, err
+	}
+	if lot.Status != LotOpen {
+		return nil, LotClosed
+	}
+
+	cancelled, closed, err := s.query.CancelLotTx(ctx, database.CancelLotParams{
+		Lot_id: lot.ID.String(),
+		Reason: params.Reason,
+		Notification: fmt.Sprintf("Лот %d «%s» тендера «%s» отменен: %s",
+			lot.Number, lot.Name, tender.Name, params.Reason),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, LotClosed
+		}
+		logger.FromContext(ctx).Error("CancelLot: CancelLotTx err", "err", err)
+		return nil, UnknowError
+	}
+	result := newLot(cancelled)
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: tender.OrganizationID.String(),
+		EntityType:     EntityLot,
+		EntityID:       result.ID,
+		Action:         ActionCancelLot,
+		Before:         newLot(lot),
+		After:          result,
+	})
+	if closed {
+		s.auditTenderClosed(ctx, user_id, tender)
+	}
+	return &result, nil
+}
+
+func (s *Service) findLot(ctx context.Context, tender_id, lot_id string) (*database.Lot, error) {
+	lots, err := s.query.ListLots(ctx, tender_id)
+	if err != nil {
+		logger.FromContext(ctx).Error("findLot: ListLots err", "err", err)
+		return nil, UnknowError
+	}
+	for i := range lots {
+		if lots[i].ID.String() == lot_id {
+			return &lots[i], nil
+		}
+	}
+	return nil, LotNotFound
+}
+
+func (s *Service) auditTenderClosed(ctx context.Context, user_id string, tender database.Tender) {
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: tender.OrganizationID.String(),
+		EntityType:     EntityTender,
+		EntityID:       tender.ID.String(),
+		Action:         ActionChangeStatus,
+		Before:         newTender(tender),
+		After:          map[string]string{"status": "Closed"},
+	})
+}
+
+// lotDecision - решение по предложению в рамках одного лота. Лот достается
+// предложению при том же кворуме, что и тендер без лотов.
+func (s *Service) lotDecision(ctx context.Context, user_id string, tender database.Tender, bid *database.OfferFull, params DecisionRequest, conflict database.DecisionConflict) (*Bid, error) {
+	if params.Desicion != "Approved" && params.Desicion != "Rejected" {
+		return nil, InvalidDecisionVallue
+	}
+	if params.Lot_id == "" {
+		return nil, LotRequired
+	}
+	lot, err := s.findLot(ctx, tender.ID.String(), params.Lot_id)
+	if err != nil {
+		return nil, err
+	}
+	if lot.Status != LotOpen {
+		return nil, LotClosed
+	}
+	if bid.Status == "Canceled" {
+		return nil, BidCanceled
+	}
+	offer_lots, err := s.query.ListOfferLots(ctx, []string{bid.ID.String()})
+	if err != nil {
+		logger.FromContext(ctx).Error("lotDecision: ListOfferLots err", "err", err)
+		return nil, UnknowError
+	}
+	participates := false
+	for _, ol := range offer_lots {
+		if ol.LotID == lot.ID && ol.Status == "Pending" {
+			participates = true
+		}
+	}
+	if !participates {
+		return nil, BidNotInLot
+	}
+
+	err = s.recordDecision(ctx, tender, database.NewDecisionParams{
+		Offer_id: bid.ID.String(),
+		User_id:  user_id,
+		Decision: params.Desicion,
+		Lot_id:   lot.ID.String(),
+		Conflict: conflict,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("lotDecision: NewDecision err", "err", err)
+		return nil, UnknowError
+	}
+	metrics.Decisions.Inc(params.Desicion)
+
+	if params.Desicion == "Rejected" {
+		remaining, err := s.query.RejectOfferLot(ctx, bid.ID.String(), lot.ID.String())
+		if err != nil {
+			logger.FromContext(ctx).Error("lotDecision: RejectOfferLot err", "err", err)
+			return nil, UnknowError
+		}
+		// предложение, отклоненное по всем своим лотам, закрывается
+		status := bid.Status
+		if remaining == 0 {
+			if _, err := s.query.ChangeOfferStatus(ctx, bid.ID.String(), "Canceled"); err != nil {
+				logger.FromContext(ctx).Error("lotDecision: ChangeOfferStatus err", "err", err)
+				return nil, UnknowError
+			}
+			status = "Canceled"
+		}
+		s.audit(ctx, auditEntry{
+			ActorID:        user_id,
+			OrganizationID: tender.OrganizationID.String(),
+			EntityType:     EntityBid,
+			EntityID:       bid.ID.String(),
+			Action:         ActionDecision,
+			Before:         newBid(bid),
+			After:          map[string]string{"decision": "Rejected", "lotId": lot.ID.String(), "status": status},
+		})
+		return s.lotDecisionResult(ctx, bid.ID.String())
+	}
+
+	approved_count, err := s.query.CountLotDecision(ctx, bid.ID.String(), lot.ID.String())
+	if err != nil {
+		logger.FromContext(ctx).Error("lotDecision: CountLotDecision err", "err", err)
+		return nil, UnknowError
+	}
+	user_count, err := s.query.ResponsibleUserCount(ctx, tender.OrganizationID.String())
+	if err != nil {
+		logger.FromContext(ctx).Error("lotDecision: ResponsibleUserCount err", "err", err)
+		return nil, UnknowError
+	}
+	if int(approved_count) < utils.Min(3, int(user_count)) {
+		s.audit(ctx, auditEntry{
+			ActorID:        user_id,
+			OrganizationID: tender.OrganizationID.String(),
+			EntityType:     EntityBid,
+			EntityID:       bid.ID.String(),
+			Action:         ActionDecision,
+			Before:         newBid(bid),
+			After:          map[string]string{"decision": "Approved", "lotId": lot.ID.String(), "status": bid.Status},
+		})
+		return s.lotDecisionResult(ctx, bid.ID.String())
+	}
+
+	metrics.QuorumReached.Inc()
+	awarded, closed, err := s.query.AwardLotTx(ctx, database.AwardLotParams{
+		Lot_id:   lot.ID.String(),
+		Offer_id: bid.ID.String(),
+		Notification: fmt.Sprintf("Предложение «%s» победило в лоте %d «%s» тендера «%s»",
+			bid.Name, lot.Number, lot.Name, tender.Name),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, LotClosed
+		}
+		logger.FromContext(ctx).Error("lotDecision: AwardLotTx err", "err", err)
+		return nil, UnknowError
+	}
+	result, err := s.lotDecisionResult(ctx, bid.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: tender.OrganizationID.String(),
+		EntityType:     EntityBid,
+		EntityID:       result.ID,
+		Action:         ActionDecision,
+		Before:         newBid(bid),
+		After:          result,
+	})
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: tender.OrganizationID.String(),
+		EntityType:     EntityLot,
+		EntityID:       awarded.ID.String(),
+		Action:         ActionAward,
+		Before:         newLot(lot),
+		After:          newLot(awarded),
+	})
+	if closed {
+		s.auditTenderClosed(ctx, user_id, tender)
+	}
+	return result, nil
+}
+
+func (s *Service) lotDecisionResult(ctx context.Context, bid_id string) (*Bid, error) {
+	bid, err := s.query.GetOffer(ctx, bid_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, BidNotFound
+		}
+		logger.FromContext(ctx).Error("lotDecisionResult: GetOffer err", "err", err)
+		return nil, UnknowError
+	}
+	result := []Bid{newBid(bid)}
+	s.attachBidLots(ctx, result)
+	return &result[0], nil
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func (e *testEnv) lots(username, tender_id string) []Lot {
+	e.t.Helper()
+	lots, err := e.s.ListLots(e.ctx, ListLotsRequest{Username: username, Tender_id: tender_id})
+	if err != nil {
+		e.t.Fatalf("ListLots: %v", err)
+	}
+	return lots
+}
+
+func (e *testEnv) decide(username, bid_id, lot_id, decision string) (*Bid, error) {
+	e.t.Helper()
+	return e.s.DecisionSubmit(e.ctx, DecisionRequest{Username: username, Bid_id: bid_id, Lot_id: lot_id, Desicion: decision})
+}
+
+func TestLotBids(t *testing.T) {
+	e := newTestEnv(t)
+	org_id := e.org("Заказчик", "buyer")
+	e.org("Поставщик", "alice")
+	tender := e.tender(TenderParams{OrganizationId: org_id, CreatorUsername: "buyer", Lots: []LotParams{
+		{Name: "Бумага", Quantity: 100},
+		{Name: "Доставка", Quantity: 1, ServiceType: "delivery"},
+	}})
+	plain := e.tender(TenderParams{OrganizationId: org_id, CreatorUsername: "buyer"})
+	lots := e.lots("alice", tender.ID)
+	if len(lots) != 2 || lots[0].Number != 1 || lots[1].ServiceType != "Delivery" || lots[0].Status != LotOpen {
+		t.Fatalf("lots = %+v", lots)
+	}
+
+	if _, err := e.submitBid("alice", tender.ID); err != LotsRequired {
+		t.Errorf("bid without lots err = %v, want LotsRequired", err)
+	}
+	if _, err := e.submitBid("alice", tender.ID, BidLot{LotID: lots[0].ID, Price: 1}, BidLot{LotID: lots[0].ID, Price: 2}); err != DuplicateLot {
+		t.Errorf("duplicate lot err = %v, want DuplicateLot", err)
+	}
+	if _, err := e.submitBid("alice", plain.ID, BidLot{LotID: lots[0].ID, Price: 1}); err != TenderHasNoLots {
+		t.Errorf("lots in plain tender err = %v, want TenderHasNoLots", err)
+	}
+	if _, err := e.s.AddLot(e.ctx, AddLotRequest{Username: "buyer", Tender_id: tender.ID, Lot: LotParams{Name: "Еще"}}); err != LotsLocked {
+		t.Errorf("lot in published tender err = %v, want LotsLocked", err)
+	}
+
+	bid, err := e.submitBid("alice", tender.ID, BidLot{LotID: lots[1].ID, Price: 500}, BidLot{LotID: lots[0].ID, Price: 9000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []BidLot{
+		{LotID: lots[1].ID, Number: 2, Price: 500, Status: "Pending"},
+		{LotID: lots[0].ID, Number: 1, Price: 9000, Status: "Pending"},
+	}
+	if !reflect.DeepEqual(bid.Lots, want) {
+		t.Errorf("bid lots = %+v, want %+v", bid.Lots, want)
+	}
+}
+
+func TestLotAwardQuorumAndClose(t *testing.T) {
+	e := newTestEnv(t)
+	org_id := e.org("Заказчик", "buyer1", "buyer2")
+	e.org("Поставщик А", "alice")
+	e.org("Поставщик Б", "bob")
+	e.org("Поставщик В", "carol")
+	tender := e.tender(TenderParams{OrganizationId: org_id, CreatorUsername: "buyer1", Lots: []LotParams{
+		{Name: "Бумага", Quantity: 100},
+		{Name: "Доставка", Quantity: 1},
+	}})
+	lots := e.lots("buyer1", tender.ID)
+	paper, delivery := lots[0], lots[1]
+	alice := e.bid("alice", tender.ID, BidLot{LotID: paper.ID, Price: 100}, BidLot{LotID: delivery.ID, Price: 50})
+	bob := e.bid("bob", tender.ID, BidLot{LotID: paper.ID, Price: 90})
+	carol := e.bid("carol", tender.ID, BidLot{LotID: paper.ID, Price: 80})
+
+	// отклонение по единственному лоту закрывает предложение
+	rejected, err := e.decide("buyer1", carol.ID, paper.ID, "Rejected")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rejected.Status != "Canceled" {
+		t.Errorf("bid rejected in its only lot = %s, want Canceled", rejected.Status)
+	}
+	if _, err := e.decide("buyer1", bob.ID, delivery.ID, "Approved"); err != BidNotInLot {
+		t.Errorf("decision on foreign lot err = %v, want BidNotInLot", err)
+	}
+
+	// кворум - оба ответственных организации
+	voted, err := e.decide("buyer1", bob.ID, paper.ID, "Approved")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if voted.Status != "Published" || e.lots("buyer1", tender.ID)[0].Status != LotOpen {
+		t.Errorf("one vote of two must not award the lot: bid %s", voted.Status)
+	}
+	awarded, err := e.decide("buyer2", bob.ID, paper.ID, "Approved")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if awarded.Status != "Approved" || len(awarded.Lots) != 1 || awarded.Lots[0].Status != LotAwarded {
+		t.Errorf("awarded bid = %+v", awarded)
+	}
+	paper = e.lots("buyer1", tender.ID)[0]
+	if paper.Status != LotAwarded || paper.AwardedBidID != bob.ID || paper.AwardedPrice == nil || *paper.AwardedPrice != 90 || paper.ClosedAt == nil {
+		t.Errorf("awarded lot = %+v", paper)
+	}
+	if _, err := e.decide("buyer1", alice.ID, paper.ID, "Approved"); err != LotClosed {
+		t.Errorf("decision on awarded lot err = %v, want LotClosed", err)
+	}
+	if status, err := e.s.FetchTenderStatus(e.ctx, "buyer1", tender.ID); err != nil || status != "Published" {
+		t.Errorf("tender with open lot = %s, %v; want Published", status, err)
+	}
+
+	if _, err := e.s.CancelLot(e.ctx, CancelLotRequest{Username: "buyer1", Tender_id: tender.ID, Lot_id: delivery.ID}); err != LotCancelReason {
+		t.Errorf("cancel without reason err = %v, want LotCancelReason", err)
+	}
+	cancelled, err := e.s.CancelLot(e.ctx, CancelLotRequest{Username: "buyer1", Tender_id: tender.ID, Lot_id: delivery.ID, Reason: "Своя доставка"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelled.Status != LotCancelled || cancelled.CancelReason != "Своя доставка" {
+		t.Errorf("cancelled lot = %+v", cancelled)
+	}
+	if status, err := e.s.FetchTenderStatus(e.ctx, "buyer1", tender.ID); err != nil || status != "Closed" {
+		t.Errorf("tender without open lots = %s, %v; want Closed", status, err)
+	}
+
+	notifications, err := e.s.ListNotifications(e.ctx, ListNotificationsRequest{Username: "alice", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notifications) != 1 || notifications[0].Kind != "lot_cancelled" {
+		t.Errorf("alice notifications = %+v", notifications)
+	}
+	if got := e.audited(EntityLot, paper.ID); !reflect.DeepEqual(got, []string{ActionAward}) {
+		t.Errorf("paper audit = %v, want award", got)
+	}
+	if got := e.audited(EntityLot, delivery.ID); !reflect.DeepEqual(got, []string{ActionCancelLot}) {
+		t.Errorf("delivery audit = %v, want cancel", got)
+	}
+	if got := e.audited(EntityTender, tender.ID); !reflect.DeepEqual(got, []string{ActionCreate, ActionChangeStatus}) {
+		t.Errorf("tender audit = %v, want create and close", got)
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"tender_service/internal/database"
+	"tender_service/internal/logger"
+	"tender_service/internal/tracing"
+	"time"
+)
+
+var NotificationNotFound = fmt.Errorf("Уведомление с таким id не существует")
+
+type Notification struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"`
+	EntityType string     `json:"entityType"`
+	EntityID   string     `json:"entityId"`
+	Message    string     `json:"message"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ReadAt     *time.Time `json:"readAt,omitempty"`
+}
+
+type ListNotificationsRequest struct {
+	Username   string
+	UnreadOnly bool
+	Offset     int32
+	Limit      int32
+}
+
+func (s *Service) ListNotifications(ctx context.Context, params ListNotificationsRequest) ([]Notification, error) {
+	ctx, span := tracing.Start(ctx, "service.ListNotifications")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.query.ListNotifications(ctx, database.ListNotificationsParams{
+		User_id:    user_id,
+		UnreadOnly: params.UnreadOnly,
+		Offset:     params.Offset,
+		Limit:      params.Limit,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("ListNotifications: ListNotifications err", "err", err)
+		return nil, UnknowError
+	}
+	result := []Notification{}
+	for _, item := range items {
+		n := Notification{
+			ID:         item.ID.String(),
+			Kind:       item.Kind,
+			EntityType: item.EntityType,
+			EntityID:   item.EntityID,
+			Message:    item.Message,
+			CreatedAt:  item.CreatedAt,
+		}
+		if item.ReadAt.Valid {
+			n.ReadAt = &item.ReadAt.Time
+		}
+		result = append(result, n)
+	}
+	return result, nil
+}
+
+func (s *Service) ReadNotification(ctx context.Context, username, notification_id string) error {
+	ctx, span := tracing.Start(ctx, "service.ReadNotification")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, username)
+	if err != nil {
+		return err
+	}
+	ok, err := s.query.MarkNotificationRead(ctx, notification_id, user_id)
+	if err != nil {
+		logger.FromContext(ctx).Error("ReadNotification: MarkNotificationRead err", "err", err)
+		return UnknowError
+	}
+	if !ok {
+		return NotificationNotFound
+	}
+	return nil
+}
@@ -0,0 +1,403 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"tender_service/internal/database"
+	"tender_service/internal/logger"
+	"tender_service/internal/tracing"
+	"time"
+)
+
+var (
+	QualificationNotFound    = fmt.Errorf("Заявка на квалификацию с таким id не существует")
+	QualificationPending     = fmt.Errorf("Заявка на квалификацию по этому виду услуг уже на рассмотрении")
+	QualificationDecided     = fmt.Errorf("Заявка на квалификацию уже рассмотрена")
+	QualificationNotApproved = fmt.Errorf("Отозвать можно только одобренную квалификацию")
+	QualificationReason      = fmt.Errorf("Отклонение или отзыв квалификации требует указать причину")
+	QualificationExpiry      = fmt.Errorf("Срок действия квалификации должен быть в будущем")
+	SelfQualification        = fmt.Errorf("Организация не может подать заявку на квалификацию самой себе")
+	NotQualified             = fmt.Errorf("Поставщик не прошел квалификацию у организации тендера по виду услуг тендера")
+	QualificationExpired     = fmt.Errorf("Срок квалификации поставщика у организации тендера истек")
+)
+
+const (
+	QualificationStatusPending  = "Pending"
+	QualificationStatusApproved = "Approved"
+	QualificationStatusRejected = "Rejected"
+	QualificationStatusRevoked  = "Revoked"
+	// QualificationStatusExpired не хранится: так показывается одобренная
+	// квалификация с истекшим сроком
+	QualificationStatusExpired = "Expired"
+)
+
+type QualificationDocument struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type Qualification struct {
+	ID                string                  `json:"id"`
+	BuyerOrgID        string                  `json:"buyerOrganizationId"`
+	BuyerOrgName      string                  `json:"buyerOrganizationName"`
+	SupplierOrgID     string                  `json:"supplierOrganizationId"`
+	SupplierOrgName   string                  `json:"supplierOrganizationName"`
+	ServiceType       string                  `json:"serviceType"`
+	Status            string                  `json:"status"`
+	Documents         []QualificationDocument `json:"documents"`
+	Comment           string                  `json:"comment,omitempty"`
+	ApplicantUsername string                  `json:"applicantUsername"`
+	Reason            string                  `json:"reason,omitempty"`
+	DecidedBy         string                  `json:"decidedBy,omitempty"`
+	DecidedAt         *time.Time              `json:"decidedAt,omitempty"`
+	ExpiresAt         *time.Time              `json:"expiresAt,omitempty"`
+	CreatedAt         time.Time               `json:"createdAt"`
+}
+
+func newQualification(ctx context.Context, q *database.Qualification) Qualification {
+	result := Qualification{
+		ID:                q.ID.String(),
+		BuyerOrgID:        q.BuyerOrgID.String(),
+		BuyerOrgName:      q.BuyerOrgName,
+		SupplierOrgID:     q.SupplierOrgID.String(),
+		SupplierOrgName:   q.SupplierOrgName,
+		ServiceType:       q.ServiceType,
+		Status:            q.Status,
+		Documents:         []QualificationDocument{},
+		Comment:           q.Comment,
+		ApplicantUsername: q.ApplicantUsername,
+		Reason:            q.Reason.String,
+		DecidedBy:         q.DecidedBy.String,
+		CreatedAt:         q.CreatedAt,
+	}
+	if err := json.Unmarshal([]byte(q.Documents), &result.Documents); err != nil {
+		logger.FromContext(ctx).Error("newQualification: Unmarshal err", "err", err, "qualification_id", result.ID)
+	}
+	if q.DecidedAt.Valid {
+		result.DecidedAt = &q.DecidedAt.Time
+	}
+	if q.ExpiresAt.Valid {
+		result.ExpiresAt = &q.ExpiresAt.Time
+		if q.Status == QualificationStatusApproved && !q.ExpiresAt.Time.After(time.Now()) {
+			result.Status = QualificationStatusExpired
+		}
+	}
+	return result
+}
+
+// checkQualified - поставщик допущен, если у организации тендера есть его
+// одобренная квалификация по виду услуг тендера или по родительской категории
+func (s *Service) checkQualified(ctx context.Context, buyer_org_id, supplier_org_id, service_type string) error {
+	qualification, err := s.query.LatestQualification(ctx, buyer_org_id, supplier_org_id, service_type)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return NotQualified
+		}
+		logger.FromContext(ctx).Error("checkQualified: LatestQualification err", "err", err)
+		return UnknowError
+	}
+	if qualification.ExpiresAt.Valid && !qualification.ExpiresAt.Time.After(time.Now()) {
+		return QualificationExpired
+	}
+	return nil
+}
+
+type ApplyQualificationRequest struct {
+	Username     string
+	Buyer_org_id string
+	ServiceType  string
+	Documents    []QualificationDocument
+	Comment      string
+}
+
+// ApplyQualification - ответственный организации поставщика подает
+// заявку на квалификацию у заказчика
+func (s *Service) ApplyQualification(ctx context.Context, params ApplyQualificationRequest) (*Qualification, error) {
+	ctx, span := tracing.Start(ctx, "service.ApplyQualification")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	supplier_org_id, err := s.query.GetUserOrganization(ctx, user_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, IsNotResponsible
+		}
+		logger.FromContext(ctx).Error("ApplyQualification: GetUserOrganization err", "err", err)
+		return nil, UnknowError
+	}
+	if supplier_org_id == params.Buyer_org_id {
+		return nil, SelfQualification
+	}
+	service_type, err := s.resolveServiceType(ctx, params.ServiceType)
+	if err != nil {
+		return nil, err
+	}
+	documents, err := json.Marshal(params.Documents)
+	if err != nil {
+		logger.FromContext(ctx).Error("ApplyQualification: Marshal err", "err", err)
+		return nil, UnknowError
+	}
+
+	qualification, err := s.query.CreateQualificationTx(ctx, database.CreateQualificationParams{
+		Buyer_org_id:    params.Buyer_org_id,
+		Supplier_org_id: supplier_org_id,
+		ServiceType:     service_type,
+		Documents:       string(documents),
+		Comment:         params.Comment,
+		Applicant_id:    user_id,
+		Notification:    fmt.Sprintf("Новая заявка на квалификацию поставщика по виду услуг %s", service_type),
+	})
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Code {
+			case "23505":
+				return nil, QualificationPending
+			case "23503":
+				return nil, OrganizationNotFound
+			}
+		}
+		logger.FromContext(ctx).Error("ApplyQualification: CreateQualificationTx err", "err", err)
+		return nil, UnknowError
+	}
+	result := newQualification(ctx, qualification)
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: supplier_org_id,
+		EntityType:     EntityQualification,
+		EntityID:       result.ID,
+		Action:         ActionCreate,
+		After:          result,
+	})
+	return &result, nil
+}
+
+type ListQualificationsRequest struct {
+	Username string
+	// AsBuyer - заявки, поданные организациям пользователя
+	AsBuyer     bool
+	Status      string
+	ServiceType string
+	Offset      int32
+	Limit       int32
+}
+
+func (s *Service) ListQualifications(ctx context.Context, params ListQualificationsRequest) ([]Qualification, error) {
+	ctx, span := tracing.Start(ctx, "service.ListQualifications")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	qualifications, err := s.query.ListQualifications(ctx, database.ListQualificationsParams{
+		User_id:     user_id,
+		AsBuyer:     params.AsBuyer,
+		Status:      params.Status,
+		ServiceType: params.ServiceType,
+		Now:         time.Now(),
+		Offset:      params.Offset,
+		Limit:       params.Limit,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("ListQualifications: ListQualifications err", "err", err)
+		return nil, UnknowError
+	}
+	result := []Qualification{}
+	for i := range qualifications {
+		result = append(result, newQualification(ctx, &qualifications[i]))
+	}
+	return result, nil
+}
+
+// fetchBuyerQualification возвращает заявку, если пользователь -
+// ответственный организации заказчика
+func (s *Service) fetchBuyerQualification(ctx context.Context, user_id, qualification_id string) (*database.Qualification, error) {
+	qualification, err := s.query.GetQualification(ctx, qualification_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, QualificationNotFound
+		}
+		logger.FromContext(ctx).Error("fetchBuyerQualification: GetQualification err", "err", err)
+		return nil, UnknowError
+	}
+	if err := s.isResponsibleUser(ctx, qualification.BuyerOrgID.String(), user_id); err != nil {
+		return nil, err
+	}
+	return qualification, nil
+}
+
+type DecideQualificationRequest struct {
+	Username         string
+	Qualification_id string
+	Decision         string
+	Reason           string
+	// ExpiresAt - срок действия одобренной квалификации, nil - бессрочно
+	ExpiresAt *time.Time
+}
+
+func (s *Service) DecideQualification(ctx context.Context, params DecideQualificationRequest) (*Qualification, error) {
+	ctx, span := tracing.Start(ctx, "service.DecideQualification")
+	defer span.End()
+
+	if params.Decision != QualificationStatusApproved && params.Decision != QualificationStatusRejected {
+		return nil, InvalidDecisionVallue
+	}
+	if params.Decision == QualificationStatusRejected && params.Reason == "" {
+		return nil, QualificationReason
+	}
+	var expires_at sql.NullTime
+	if params.Decision == QualificationStatusApproved && params.ExpiresAt != nil {
+		if !params.ExpiresAt.After(time.Now()) {
+			return nil, QualificationExpiry
+		}
+		expires_at = sql.NullTime{Time: params.ExpiresAt.UTC().Truncate(time.Second), Valid: true}
+	}
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	qualification, err := s.fetchBuyerQualification(ctx, user_id, params.Qualification_id)
+	if err != nil {
+		return nil, err
+	}
+	if qualification.Status != QualificationStatusPending {
+		return nil, QualificationDecided
+	}
+
+	kind, message := "qualification_approved",
+		fmt.Sprintf("Организация «%s» одобрила квалификацию по виду услуг %s", qualification.BuyerOrgName, qualification.ServiceType)
+	if params.Decision == QualificationStatusRejected {
+		kind, message = "qualification_rejected",
+			fmt.Sprintf("Организация «%s» отклонила квалификацию по виду услуг %s: %s",
+				qualification.BuyerOrgName, qualification.ServiceType, params.Reason)
+	}
+	decided, err := s.query.DecideQualificationTx(ctx, database.DecideQualificationParams{
+		Qualification_id: qualification.ID.String(),
+		User_id:          user_id,
+		Status:           params.Decision,
+		Reason:           params.Reason,
+		ExpiresAt:        expires_at,
+		Kind:             kind,
+		Notification:     message,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, QualificationDecided
+		}
+		logger.FromContext(ctx).Error("DecideQualification: DecideQualificationTx err", "err", err)
+		return nil, UnknowError
+	}
+	result := newQualification(ctx, decided)
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: result.BuyerOrgID,
+		EntityType:     EntityQualification,
+		EntityID:       result.ID,
+		Action:         ActionDecision,
+		Before:         newQualification(ctx, qualification),
+		After:          result,
+	})
+	return &result, nil
+}
+
+type RevokeQualificationRequest struct {
+	Username         string
+	Qualification_id string
+	Reason           string
+}
+
+// RevokeQualification исключает поставщика из списка допущенных
+func (s *Service) RevokeQualification(ctx context.Context, params RevokeQualificationRequest) (*Qualification, error) {
+	ctx, span := tracing.Start(ctx, "service.RevokeQualification")
+	defer span.End()
+
+	if params.Reason == "" {
+		return nil, QualificationReason
+	}
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	qualification, err := s.fetchBuyerQualification(ctx, user_id, params.Qualification_id)
+	if err != nil {
+		return nil, err
+	}
+	if qualification.Status != QualificationStatusApproved {
+		return nil, QualificationNotApproved
+	}
+
+	revoked, err := s.query.RevokeQualification(ctx, qualification.ID.String(), user_id, params.Reason)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, QualificationNotApproved
+		}
+		logger.FromContext(ctx).Error("RevokeQualification: RevokeQualification err", "err", err)
+		return nil, UnknowError
+	}
+	result := newQualification(ctx, revoked)
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: result.BuyerOrgID,
+		EntityType:     EntityQualification,
+		EntityID:       result.ID,
+		Action:         ActionRevoke,
+		Before:         newQualification(ctx, qualification),
+		After:          result,
+	})
+	return &result, nil
+}
+
+type ListVendorsRequest struct {
+	Username        string
+	Organization_id string
+	// ServiceType - фильтр по категории, включает вложенные виды услуг
+	ServiceType string
+}
+
+// ListVendors - список допущенных поставщиков организации заказчика
+func (s *Service) ListVendors(ctx context.Context, params ListVendorsRequest) ([]Qualification, error) {
+	ctx, span := tracing.Start(ctx, "service.ListVendors")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.isResponsibleUser(ctx, params.Organization_id, user_id); err != nil {
+		return nil, err
+	}
+	var service_types []string
+	if params.ServiceType != "" {
+		service_types, err = s.query.ExpandServiceTypes(ctx, []string{params.ServiceType})
+		if err != nil {
+			logger.FromContext(ctx).Error("ListVendors: ExpandServiceTypes err", "err", err)
+			return nil, UnknowError
+		}
+		if len(service_types) == 0 {
+			return []Qualification{}, nil
+		}
+	}
+
+	vendors, err := s.query.ListVendors(ctx, database.ListVendorsParams{
+		Buyer_org_id: params.Organization_id,
+		ServiceTypes: service_types,
+		Now:          time.Now(),
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("ListVendors: ListVendors err", "err", err)
+		return nil, UnknowError
+	}
+	result := []Qualification{}
+	for i := range vendors {
+		result = append(result, newQualification(ctx, &vendors[i]))
+	}
+	return result, nil
+}
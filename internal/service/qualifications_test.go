@@ -0,0 +1,151 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func (e *testEnv) applyQualification(username, buyer_org_id, service_type string) *Qualification {
+	e.t.Helper()
+	qualification, err := e.s.ApplyQualification(e.ctx, ApplyQualificationRequest{
+		Username:     username,
+		Buyer_org_id: buyer_org_id,
+		ServiceType:  service_type,
+		Documents:    []QualificationDocument{{Name: "Лицензия", URL: "https://example.com/license.pdf"}},
+	})
+	if err != nil {
+		e.t.Fatalf("ApplyQualification %s: %v", username, err)
+	}
+	return qualification
+}
+
+func TestQualificationGate(t *testing.T) {
+	e := newTestEnv(t)
+	e.admin("root")
+	buyer_org := e.org("Заказчик", "buyer")
+	e.org("Поставщик А", "alice")
+	e.org("Поставщик Б", "bob")
+	if _, err := e.s.CreateServiceType(e.ctx, ServiceTypeRequest{Username: "root", Code: "Roads", Parent: strPtr("Construction")}); err != nil {
+		t.Fatal(err)
+	}
+	tender := e.tender(TenderParams{OrganizationId: buyer_org, CreatorUsername: "buyer", ServiceType: "Roads", RequiresQualification: true})
+	if !tender.RequiresQualification {
+		t.Fatal("tender must require qualification")
+	}
+	if _, err := e.submitBid("alice", tender.ID); err != NotQualified {
+		t.Errorf("bid without qualification err = %v, want NotQualified", err)
+	}
+
+	if _, err := e.s.ApplyQualification(e.ctx, ApplyQualificationRequest{Username: "buyer", Buyer_org_id: buyer_org, ServiceType: "Roads"}); err != SelfQualification {
+		t.Errorf("self qualification err = %v, want SelfQualification", err)
+	}
+	// квалификация по родительской категории допускает к вложенным видам услуг
+	applied := e.applyQualification("alice", buyer_org, "construction")
+	if applied.Status != QualificationStatusPending || applied.ServiceType != "Construction" || len(applied.Documents) != 1 {
+		t.Errorf("applied = %+v", applied)
+	}
+	if _, err := e.s.ApplyQualification(e.ctx, ApplyQualificationRequest{Username: "alice", Buyer_org_id: buyer_org, ServiceType: "Construction"}); err != QualificationPending {
+		t.Errorf("second application err = %v, want QualificationPending", err)
+	}
+
+	decide := func(username string, params DecideQualificationRequest) (*Qualification, error) {
+		params.Username = username
+		params.Qualification_id = applied.ID
+		return e.s.DecideQualification(e.ctx, params)
+	}
+	if _, err := decide("buyer", DecideQualificationRequest{Decision: QualificationStatusRejected}); err != QualificationReason {
+		t.Errorf("reject without reason err = %v, want QualificationReason", err)
+	}
+	past := time.Now().Add(-time.Hour)
+	if _, err := decide("buyer", DecideQualificationRequest{Decision: QualificationStatusApproved, ExpiresAt: &past}); err != QualificationExpiry {
+		t.Errorf("past expiry err = %v, want QualificationExpiry", err)
+	}
+	if _, err := decide("bob", DecideQualificationRequest{Decision: QualificationStatusApproved}); err != IsNotResponsible {
+		t.Errorf("decision by supplier err = %v, want IsNotResponsible", err)
+	}
+	approved, err := decide("buyer", DecideQualificationRequest{Decision: QualificationStatusApproved})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approved.Status != QualificationStatusApproved || approved.ExpiresAt != nil || approved.DecidedAt == nil {
+		t.Errorf("approved = %+v", approved)
+	}
+	if _, err := decide("buyer", DecideQualificationRequest{Decision: QualificationStatusRejected, Reason: "Передумали"}); err != QualificationDecided {
+		t.Errorf("second decision err = %v, want QualificationDecided", err)
+	}
+	if _, err := e.submitBid("alice", tender.ID); err != nil {
+		t.Errorf("bid after qualification err = %v", err)
+	}
+
+	vendors, err := e.s.ListVendors(e.ctx, ListVendorsRequest{Username: "buyer", Organization_id: buyer_org, ServiceType: "Construction"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vendors) != 1 || vendors[0].ID != applied.ID {
+		t.Errorf("vendors = %+v", vendors)
+	}
+
+	if _, err := e.s.RevokeQualification(e.ctx, RevokeQualificationRequest{Username: "buyer", Qualification_id: applied.ID}); err != QualificationReason {
+		t.Errorf("revoke without reason err = %v, want QualificationReason", err)
+	}
+	revoked, err := e.s.RevokeQualification(e.ctx, RevokeQualificationRequest{Username: "buyer", Qualification_id: applied.ID, Reason: "Нарушение сроков"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked.Status != QualificationStatusRevoked || revoked.Reason != "Нарушение сроков" {
+		t.Errorf("revoked = %+v", revoked)
+	}
+	alice_org := applied.SupplierOrgID
+	if err := e.s.checkQualified(e.ctx, buyer_org, alice_org, "Roads"); err != NotQualified {
+		t.Errorf("revoked qualification err = %v, want NotQualified", err)
+	}
+	want := []string{ActionCreate, ActionDecision, ActionRevoke}
+	if got := e.audited(EntityQualification, applied.ID); !reflect.DeepEqual(got, want) {
+		t.Errorf("audit = %v, want %v", got, want)
+	}
+}
+
+func TestQualificationExpiry(t *testing.T) {
+	e := newTestEnv(t)
+	buyer_org := e.org("Заказчик", "buyer")
+	e.org("Поставщик", "bob")
+	tender := e.tender(TenderParams{OrganizationId: buyer_org, CreatorUsername: "buyer", RequiresQualification: true})
+	applied := e.applyQualification("bob", buyer_org, "Delivery")
+	expires := time.Now().Add(time.Hour)
+	if _, err := e.s.DecideQualification(e.ctx, DecideQualificationRequest{
+		Username: "buyer", Qualification_id: applied.ID, Decision: QualificationStatusApproved, ExpiresAt: &expires,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	e.exec(`UPDATE vendor_qualification SET expires_at = $2 WHERE id = $1`, applied.ID, time.Now().Add(-time.Minute).UTC())
+	if _, err := e.submitBid("bob", tender.ID); err != QualificationExpired {
+		t.Errorf("bid with expired qualification err = %v, want QualificationExpired", err)
+	}
+	mine, err := e.s.ListQualifications(e.ctx, ListQualificationsRequest{Username: "bob", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mine) != 1 || mine[0].Status != QualificationStatusExpired {
+		t.Errorf("qualifications = %+v, want one expired", mine)
+	}
+	vendors, err := e.s.ListVendors(e.ctx, ListVendorsRequest{Username: "buyer", Organization_id: buyer_org})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vendors) != 0 {
+		t.Errorf("vendors = %+v, want none after expiry", vendors)
+	}
+
+	// новая заявка после истечения срока снова допускает поставщика
+	renewed := e.applyQualification("bob", buyer_org, "Delivery")
+	if _, err := e.s.DecideQualification(e.ctx, DecideQualificationRequest{
+		Username: "buyer", Qualification_id: renewed.ID, Decision: QualificationStatusApproved,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.submitBid("bob", tender.ID); err != nil {
+		t.Errorf("bid after renewal err = %v", err)
+	}
+}
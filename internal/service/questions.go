@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"tender_service/internal/database"
+	"tender_service/internal/logger"
+	"tender_service/internal/tracing"
+	"time"
+)
+
+var (
+	QuestionNotFound   = fmt.Errorf("Вопрос с таким id не существует")
+	TenderNotPublished = fmt.Errorf("Тендер не опубликован")
+)
+
+const (
+	VisibilityPublic  = "public"
+	VisibilityPrivate = "private"
+)
+
+type Question struct {
+	ID             string     `json:"id"`
+	TenderID       string     `json:"tenderId"`
+	AuthorUsername string     `json:"authorUsername"`
+	Question       string     `json:"question"`
+	Answer         string     `json:"answer,omitempty"`
+	Visibility     string     `json:"visibility,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	AnsweredAt     *time.Time `json:"answeredAt,omitempty"`
+}
+
+func newQuestion(q *database.TenderQuestion) Question {
+	result := Question{
+		ID:             q.ID.String(),
+		TenderID:       q.TenderID.String(),
+		AuthorUsername: q.AuthorUsername,
+		Question:       q.Question,
+		Answer:         q.Answer,
+		Visibility:     q.Visibility,
+		CreatedAt:      q.CreatedAt,
+	}
+	if q.AnsweredAt.Valid {
+		result.AnsweredAt = &q.AnsweredAt.Time
+	}
+	return result
+}
+
+type AskQuestionRequest struct {
+	Username  string
+	Tender_id string
+	Question  string
+}
+
+// AskQuestion - вопрос по опубликованному тендеру может задать любой сотрудник
+func (s *Service) AskQuestion(ctx context.Context, params AskQuestionRequest) (*Question, error) {
+	ctx, span := tracing.Start(ctx, "service.AskQuestion")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	tender, err := s.query.GetTender(ctx, params.Tender_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, TenderNotFound
+		}
+		logger.FromContext(ctx).Error("AskQuestion: GetTender err", "err", err)
+		return nil, UnknowError
+	}
+	if err := s.checkTenderVisible(ctx, tender, user_id); err != nil {
+		return nil, err
+	}
+	if tender.Status != "Published" {
+		return nil, TenderNotPublished
+	}
+
+	question, err := s.query.CreateQuestion(ctx, tender.ID.String(), user_id, params.Question)
+	if err != nil {
+		logger.FromContext(ctx).Error("AskQuestion: CreateQuestion err", "err", err)
+		return nil, UnknowError
+	}
+	result := newQuestion(question)
+	org_id, _ := s.query.GetUserOrganization(ctx, user_id)
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: org_id,
+		EntityType:     EntityQuestion,
+		EntityID:       result.ID,
+		Action:         ActionCreate,
+		After:          result,
+	})
+	return &result, nil
+}
+
+type AnswerQuestionRequest struct {
+	Username    string
+	Tender_id   string
+	Question_id string
+	Answer      string
+	Visibility  string
+}
+
+// AnswerQuestion - отвечают ответственные организации тендера. Повторный
+// ответ заменяет предыдущий.
+func (s *Service) AnswerQuestion(ctx context.Context, params AnswerQuestionRequest) (*Question, error) {
+	ctx, span := tracing.Start(ctx, "service.AnswerQuestion")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	tender, err := s.query.GetTender(ctx, params.Tender_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, TenderNotFound
+		}
+		logger.FromContext(ctx).Error("AnswerQuestion: GetTender err", "err", err)
+		return nil, UnknowError
+	}
+	if err := s.isResponsibleUser(ctx, tender.OrganizationID.String(), user_id); err != nil {
+		return nil, err
+	}
+	question, err := s.query.GetQuestion(ctx, params.Question_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, QuestionNotFound
+		}
+		logger.FromContext(ctx).Error("AnswerQuestion: GetQuestion err", "err", err)
+		return nil, UnknowError
+	}
+	if question.TenderID != tender.ID {
+		return nil, QuestionNotFound
+	}
+	before := newQuestion(question)
+
+	answered, err := s.query.AnswerQuestion(ctx, database.AnswerQuestionParams{
+		Question_id: params.Question_id,
+		User_id:     user_id,
+		Answer:      params.Answer,
+		Visibility:  params.Visibility,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("AnswerQuestion: AnswerQuestion err", "err", err)
+		return nil, UnknowError
+	}
+	result := newQuestion(answered)
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: tender.OrganizationID.String(),
+		EntityType:     EntityQuestion,
+		EntityID:       result.ID,
+		Action:         ActionAnswer,
+		Before:         before,
+		After:          result,
+	})
+	return &result, nil
+}
+
+type ListQuestionsRequest struct {
+	Username  string
+	Tender_id string
+	Offset    int32
+	Limit     int32
+}
+
+// ListQuestions - ответственные видят все вопросы, остальные - вопросы
+// с публичными ответами и свои собственные
+func (s *Service) ListQuestions(ctx context.Context, params ListQuestionsRequest) ([]Question, error) {
+	ctx, span := tracing.Start(ctx, "service.ListQuestions")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	tender, err := s.query.GetTender(ctx, params.Tender_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, TenderNotFound
+		}
+		logger.FromContext(ctx).Error("ListQuestions: GetTender err", "err", err)
+		return nil, UnknowError
+	}
+	if err := s.checkTenderVisible(ctx, tender, user_id); err != nil {
+		return nil, err
+	}
+	all := true
+	if err := s.isResponsibleUser(ctx, tender.OrganizationID.String(), user_id); err != nil {
+		if err != IsNotResponsible {
+			return nil, err
+		}
+		if tender.Status == "Created" {
+			return nil, TenderNotPublished
+		}
+		all = false
+	}
+
+	questions, err := s.query.ListQuestions(ctx, database.ListQuestionsParams{
+		Tender_id: tender.ID.String(),
+		All:       all,
+		Viewer_id: user_id,
+		Offset:    params.Offset,
+		Limit:     params.Limit,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("ListQuestions: ListQuestions err", "err", err)
+		return nil, UnknowError
+	}
+	result := []Question{}
+	for _, item := range questions {
+		result = append(result, newQuestion(&item))
+	}
+	return result, nil
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func (e *testEnv) questions(username, tender_id string) []string {
+	e.t.Helper()
+	questions, err := e.s.ListQuestions(e.ctx, ListQuestionsRequest{Username: username, Tender_id: tender_id, Limit: 50})
+	if err != nil {
+		e.t.Fatalf("ListQuestions %s: %v", username, err)
+	}
+	var texts []string
+	for _, question := range questions {
+		texts = append(texts, question.Question)
+	}
+	sort.Strings(texts)
+	return texts
+}
+
+func TestQuestionsVisibility(t *testing.T) {
+	e := newTestEnv(t)
+	org_id := e.org("Заказчик", "buyer")
+	e.org("Поставщик А", "alice")
+	e.org("Поставщик Б", "bob")
+	tender := e.tender(TenderParams{OrganizationId: org_id, CreatorUsername: "buyer"})
+
+	ask := func(username, text string) *Question {
+		t.Helper()
+		question, err := e.s.AskQuestion(e.ctx, AskQuestionRequest{Username: username, Tender_id: tender.ID, Question: text})
+		if err != nil {
+			t.Fatalf("AskQuestion %s: %v", username, err)
+		}
+		return question
+	}
+	public := ask("alice", "Нужна ли доставка?")
+	private := ask("alice", "Какая у вас отсрочка?")
+	ask("bob", "Какой объем?")
+
+	answered, err := e.s.AnswerQuestion(e.ctx, AnswerQuestionRequest{
+		Username: "buyer", Tender_id: tender.ID, Question_id: public.ID,
+		Answer: "Да, до склада", Visibility: VisibilityPublic,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answered.Answer != "Да, до склада" || answered.AnsweredAt == nil {
+		t.Errorf("answered = %+v", answered)
+	}
+	if _, err := e.s.AnswerQuestion(e.ctx, AnswerQuestionRequest{
+		Username: "buyer", Tender_id: tender.ID, Question_id: private.ID,
+		Answer: "30 дней", Visibility: VisibilityPrivate,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		username string
+		want     []string
+	}{
+		{username: "buyer", want: []string{"Какая у вас отсрочка?", "Какой объем?", "Нужна ли доставка?"}},
+		{username: "alice", want: []string{"Какая у вас отсрочка?", "Нужна ли доставка?"}},
+		{username: "bob", want: []string{"Какой объем?", "Нужна ли доставка?"}},
+	}
+	for _, tt := range tests {
+		if got := e.questions(tt.username, tender.ID); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("questions for %s = %v, want %v", tt.username, got, tt.want)
+		}
+	}
+
+	want := []string{ActionCreate, ActionAnswer}
+	if got := e.audited(EntityQuestion, public.ID); !reflect.DeepEqual(got, want) {
+		t.Errorf("audit = %v, want %v", got, want)
+	}
+}
+
+func TestQuestionsAccess(t *testing.T) {
+	e := newTestEnv(t)
+	org_id := e.org("Заказчик", "buyer")
+	e.org("Поставщик", "alice")
+	draft := e.tender(TenderParams{OrganizationId: org_id, CreatorUsername: "buyer", Status: "Created"})
+	other := e.tender(TenderParams{OrganizationId: org_id, CreatorUsername: "buyer"})
+
+	if _, err := e.s.AskQuestion(e.ctx, AskQuestionRequest{Username: "alice", Tender_id: draft.ID, Question: "?"}); err != TenderNotPublished {
+		t.Errorf("question to draft err = %v, want TenderNotPublished", err)
+	}
+	if _, err := e.s.ListQuestions(e.ctx, ListQuestionsRequest{Username: "alice", Tender_id: draft.ID, Limit: 10}); err != TenderNotPublished {
+		t.Errorf("questions of draft err = %v, want TenderNotPublished", err)
+	}
+
+	question, err := e.s.AskQuestion(e.ctx, AskQuestionRequest{Username: "alice", Tender_id: other.ID, Question: "Сроки?"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.s.AnswerQuestion(e.ctx, AnswerQuestionRequest{
+		Username: "alice", Tender_id: other.ID, Question_id: question.ID, Answer: "Сам отвечу", Visibility: VisibilityPublic,
+	}); err != IsNotResponsible {
+		t.Errorf("answer by supplier err = %v, want IsNotResponsible", err)
+	}
+	// вопрос другого тендера через этот тендер не находится
+	if _, err := e.s.AnswerQuestion(e.ctx, AnswerQuestionRequest{
+		Username: "buyer", Tender_id: draft.ID, Question_id: question.ID, Answer: "Неделя", Visibility: VisibilityPublic,
+	}); err != QuestionNotFound {
+		t.Errorf("answer through other tender err = %v, want QuestionNotFound", err)
+	}
+}
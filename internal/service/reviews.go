@@ -0,0 +1,557 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"tender_service/internal/database"
+	"tender_service/internal/logger"
+	"tender_service/internal/tracing"
+	"time"
+)
+
+// ReviewEditWindow - сколько автор может изменять или удалять свой отзыв
+const ReviewEditWindow = 24 * time.Hour
+
+var (
+	ReviewNotFound  = fmt.Errorf("Отзыв с таким id не существует")
+	NotReviewAuthor = fmt.Errorf("Изменять и удалять отзыв может только его автор")
+	ReviewLocked    = fmt.Errorf("Отзыв можно изменить или удалить только в течение 24 часов после создания")
+	InvalidRating   = fmt.Errorf("Оценки quality, timeliness и communication задаются вместе, от 1 до 5")
+	ReviewAnswered  = fmt.Errorf("Отзыв, на который ответили или по которому открыт спор, изменить нельзя")
+	ReplyExists     = fmt.Errorf("На отзыв уже дан ответ")
+	DisputeExists   = fmt.Errorf("Спор по отзыву уже открывался")
+	DisputeNotOpen  = fmt.Errorf("По отзыву нет открытого спора")
+)
+
+// статусы спора по отзыву
+const (
+	DisputeOpen      = "Open"
+	DisputeUpheld    = "Upheld"
+	DisputeDismissed = "Dismissed"
+)
+
+type Ratings struct {
+	Quality       int16 `json:"quality"`
+	Timeliness    int16 `json:"timeliness"`
+	Communication int16 `json:"communication"`
+}
+
+func (r *Ratings) toDB() (*database.Ratings, error) {
+	if r == nil {
+		return nil, nil
+	}
+	for _, value := range []int16{r.Quality, r.Timeliness, r.Communication} {
+		if value < 1 || value > 5 {
+			return nil, InvalidRating
+		}
+	}
+	return &database.Ratings{Quality: r.Quality, Timeliness: r.Timeliness, Communication: r.Communication}, nil
+}
+
+type ReviewResponse struct {
+	Id               string    `json:"id"`
+	BidId            string    `json:"bidId"`
+	TenderId         string    `json:"tenderId"`
+	TenderName       string    `json:"tenderName"`
+	Description      string    `json:"description"`
+	ReviewerUsername string    `json:"reviewerUsername"`
+	OrganizationId   string    `json:"organizationId,omitempty"`
+	OrganizationName string    `json:"organizationName,omitempty"`
+	Ratings          *Ratings  `json:"ratings,omitempty"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+	// Reply - ответ поставщика на отзыв
+	Reply   *ReviewReply   `json:"reply,omitempty"`
+	Dispute *ReviewDispute `json:"dispute,omitempty"`
+}
+
+type ReviewReply struct {
+	ID             string    `json:"id"`
+	Content        string    `json:"content"`
+	AuthorUsername string    `json:"authorUsername"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+type ReviewDispute struct {
+	Status      string     `json:"status"`
+	Reason      string     `json:"reason"`
+	DisputedBy  string     `json:"disputedBy"`
+	DisputedAt  time.Time  `json:"disputedAt"`
+	ModeratedBy string     `json:"moderatedBy,omitempty"`
+	ModeratedAt *time.Time `json:"moderatedAt,omitempty"`
+	Comment     string     `json:"comment,omitempty"`
+}
+
+func newReviewResponse(r *database.Review) ReviewResponse {
+	result := ReviewResponse{
+		Id:               r.ID,
+		BidId:            r.OfferID.String(),
+		TenderId:         r.TenderID,
+		TenderName:       r.TenderName,
+		Description:      r.Description,
+		ReviewerUsername: r.CreatorUsername,
+		OrganizationName: r.OrganizationName.String,
+		CreatedAt:        r.CreatedAt,
+		UpdatedAt:        r.UpdatedAt,
+	}
+	if r.OrganizationID.Valid {
+		result.OrganizationId = r.OrganizationID.UUID.String()
+	}
+	if r.Quality.Valid {
+		result.Ratings = &Ratings{
+			Quality:       r.Quality.Int16,
+			Timeliness:    r.Timeliness.Int16,
+			Communication: r.Communication.Int16,
+		}
+	}
+	if r.ReplyID.Valid {
+		result.Reply = &ReviewReply{
+			ID:             r.ReplyID.String,
+			Content:        r.ReplyContent.String,
+			AuthorUsername: r.ReplyAuthor.String,
+			CreatedAt:      r.ReplyCreatedAt.Time,
+		}
+	}
+	if r.DisputeStatus.Valid {
+		result.Dispute = &ReviewDispute{
+			Status:      r.DisputeStatus.String,
+			Reason:      r.DisputeReason.String,
+			DisputedBy:  r.DisputedBy.String,
+			DisputedAt:  r.DisputedAt.Time,
+			ModeratedBy: r.ModeratedBy.String,
+			Comment:     r.ModerationComment.String,
+		}
+		if r.ModeratedAt.Valid {
+			result.Dispute.ModeratedAt = &r.ModeratedAt.Time
+		}
+	}
+	return result
+}
+
+// Reputation - средние оценки поставщика, 0 - оценок еще нет
+type Reputation struct {
+	OrganizationID   string  `json:"organizationId"`
+	OrganizationName string  `json:"organizationName"`
+	Score            float64 `json:"score"`
+	Quality          float64 `json:"quality"`
+	Timeliness       float64 `json:"timeliness"`
+	Communication    float64 `json:"communication"`
+	Reviews          int32   `json:"reviews"`
+}
+
+func newReputation(r *database.Reputation) Reputation {
+	return Reputation{
+		OrganizationID:   r.OrganizationID.String(),
+		OrganizationName: r.OrganizationName,
+		Score:            r.Score.Float64,
+		Quality:          r.Quality.Float64,
+		Timeliness:       r.Timeliness.Float64,
+		Communication:    r.Communication.Float64,
+		Reviews:          r.Reviews,
+	}
+}
+
+// attachReputation дописывает к предложениям репутацию их организаций
+func (s *Service) attachReputation(ctx context.Context, bids []Bid) {
+	if len(bids) == 0 {
+		return
+	}
+	ids := make([]string, 0, len(bids))
+	for _, bid := range bids {
+		ids = append(ids, bid.ID)
+	}
+	reputations, err := s.query.ListOfferReputations(ctx, ids)
+	if err != nil {
+		logger.FromContext(ctx).Error("attachReputation: ListOfferReputations err", "err", err)
+		return
+	}
+	for i := range bids {
+		if r, ok := reputations[bids[i].ID]; ok {
+			reputation := newReputation(&r)
+			bids[i].Reputation = &reputation
+		}
+	}
+}
+
+// fetchOwnReview - отзыв, который пользователь может изменить
+func (s *Service) fetchOwnReview(ctx context.Context, review_id, user_id string) (*database.Review, error) {
+	review, err := s.query.GetReview(ctx, review_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ReviewNotFound
+		}
+		logger.FromContext(ctx).Error("fetchOwnReview: GetReview err", "err", err)
+		return nil, UnknowError
+	}
+	if review.CreatorID.String() != user_id {
+		return nil, NotReviewAuthor
+	}
+	if review.ReplyID.Valid || review.DisputeStatus.Valid {
+		return nil, ReviewAnswered
+	}
+	return review, nil
+}
+
+// fetchReceivedReview - отзыв на предложение, на который пользователь может
+// ответить: он автор предложения или ответственный его организации
+func (s *Service) fetchReceivedReview(ctx context.Context, review_id, user_id string) (*database.Review, error) {
+	review, err := s.query.GetReview(ctx, review_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ReviewNotFound
+		}
+		logger.FromContext(ctx).Error("fetchReceivedReview: GetReview err", "err", err)
+		return nil, UnknowError
+	}
+	if review.OfferAuthorID == user_id {
+		return review, nil
+	}
+	if review.OfferOrgID == "" {
+		return nil, IsNotResponsible
+	}
+	if err := s.isResponsibleUser(ctx, review.OfferOrgID, user_id); err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+type EditReviewRequest struct {
+	Username  string
+	Review_id string
+	Content   string
+	Ratings   *Ratings
+}
+
+func (s *Service) EditReview(ctx context.Context, params EditReviewRequest) (*ReviewResponse, error) {
+	ctx, span := tracing.Start(ctx, "service.EditReview")
+	defer span.End()
+
+	ratings, err := params.Ratings.toDB()
+	if err != nil {
+		return nil, err
+	}
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	review, err := s.fetchOwnReview(ctx, params.Review_id, user_id)
+	if err != nil {
+		return nil, err
+	}
+	edited, err := s.query.EditReview(ctx, database.EditReviewParams{
+		Review_id: review.ID,
+		Content:   params.Content,
+		Ratings:   ratings,
+		Window:    ReviewEditWindow,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ReviewLocked
+		}
+		logger.FromContext(ctx).Error("EditReview: EditReview err", "err", err)
+		return nil, UnknowError
+	}
+	result := newReviewResponse(edited)
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: result.OrganizationId,
+		EntityType:     EntityReview,
+		EntityID:       result.Id,
+		Action:         ActionEdit,
+		Before:         newReviewResponse(review),
+		After:          result,
+	})
+	return &result, nil
+}
+
+type DeleteReviewRequest struct {
+	Username  string
+	Review_id string
+}
+
+func (s *Service) DeleteReview(ctx context.Context, params DeleteReviewRequest) error {
+	ctx, span := tracing.Start(ctx, "service.DeleteReview")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return err
+	}
+	review, err := s.fetchOwnReview(ctx, params.Review_id, user_id)
+	if err != nil {
+		return err
+	}
+	if err := s.query.DeleteReview(ctx, review.ID, ReviewEditWindow); err != nil {
+		if err == sql.ErrNoRows {
+			return ReviewLocked
+		}
+		logger.FromContext(ctx).Error("DeleteReview: DeleteReview err", "err", err)
+		return UnknowError
+	}
+	before := newReviewResponse(review)
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: before.OrganizationId,
+		EntityType:     EntityReview,
+		EntityID:       before.Id,
+		Action:         ActionDelete,
+		Before:         before,
+	})
+	return nil
+}
+
+type GetReputationRequest struct {
+	Username        string
+	Organization_id string
+}
+
+// GetReputation - репутация поставщика видна любому пользователю
+func (s *Service) GetReputation(ctx context.Context, params GetReputationRequest) (*Reputation, error) {
+	ctx, span := tracing.Start(ctx, "service.GetReputation")
+	defer span.End()
+
+	if _, err := s.fetchUserID(ctx, params.Username); err != nil {
+		return nil, err
+	}
+	reputation, err := s.query.GetReputation(ctx, params.Organization_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, OrganizationNotFound
+		}
+		logger.FromContext(ctx).Error("GetReputation: GetReputation err", "err", err)
+		return nil, UnknowError
+	}
+	result := newReputation(reputation)
+	return &result, nil
+}
+
+type ListReceivedReviewsRequest struct {
+	Username string
+	Bid_id   string
+	Offset   int32
+	Limit    int32
+}
+
+// ListReceivedReviews - отзывы заказчиков на предложения пользователя
+// и его организаций
+func (s *Service) ListReceivedReviews(ctx context.Context, params ListReceivedReviewsRequest) ([]ReviewResponse, error) {
+	ctx, span := tracing.Start(ctx, "service.ListReceivedReviews")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	reviews, err := s.query.ListReceivedReviews(ctx, database.ListReceivedReviewsParams{
+		User_id:  user_id,
+		Offer_id: params.Bid_id,
+		Offset:   params.Offset,
+		Limit:    params.Limit,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("ListReceivedReviews: ListReceivedReviews err", "err", err)
+		return nil, UnknowError
+	}
+	result := []ReviewResponse{}
+	for i := range reviews {
+		result = append(result, newReviewResponse(&reviews[i]))
+	}
+	return result, nil
+}
+
+type ReplyReviewRequest struct {
+	Username  string
+	Review_id string
+	Content   string
+}
+
+// ReplyReview - единственный ответ поставщика на отзыв, автор отзыва
+// получает уведомление
+func (s *Service) ReplyReview(ctx context.Context, params ReplyReviewRequest) (*ReviewResponse, error) {
+	ctx, span := tracing.Start(ctx, "service.ReplyReview")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	review, err := s.fetchReceivedReview(ctx, params.Review_id, user_id)
+	if err != nil {
+		return nil, err
+	}
+	if review.ReplyID.Valid {
+		return nil, ReplyExists
+	}
+	replied, err := s.query.CreateReplyTx(ctx, database.CreateReplyParams{
+		Review_id:       review.ID,
+		User_id:         user_id,
+		Organization_id: review.OfferOrgID,
+		Content:         params.Content,
+		Reviewer_id:     review.CreatorID.String(),
+		Notification:    "Поставщик ответил на ваш отзыв",
+	})
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ReplyExists
+		}
+		logger.FromContext(ctx).Error("ReplyReview: CreateReplyTx err", "err", err)
+		return nil, UnknowError
+	}
+	result := newReviewResponse(replied)
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: review.OfferOrgID,
+		EntityType:     EntityReview,
+		EntityID:       result.Id,
+		Action:         ActionReply,
+		After:          result.Reply,
+	})
+	return &result, nil
+}
+
+type DisputeReviewRequest struct {
+	Username  string
+	Review_id string
+	Reason    string
+}
+
+// DisputeReview отправляет отзыв в очередь модерации. Спор по отзыву
+// открывается один раз.
+func (s *Service) DisputeReview(ctx context.Context, params DisputeReviewRequest) (*ReviewResponse, error) {
+	ctx, span := tracing.Start(ctx, "service.DisputeReview")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	review, err := s.fetchReceivedReview(ctx, params.Review_id, user_id)
+	if err != nil {
+		return nil, err
+	}
+	if review.DisputeStatus.Valid {
+		return nil, DisputeExists
+	}
+	disputed, err := s.query.DisputeReviewTx(ctx, database.DisputeReviewParams{
+		Review_id:    review.ID,
+		User_id:      user_id,
+		Reason:       params.Reason,
+		Notification: "Поставщик оспорил отзыв, требуется модерация",
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, DisputeExists
+		}
+		logger.FromContext(ctx).Error("DisputeReview: DisputeReviewTx err", "err", err)
+		return nil, UnknowError
+	}
+	result := newReviewResponse(disputed)
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: review.OfferOrgID,
+		EntityType:     EntityReview,
+		EntityID:       result.Id,
+		Action:         ActionDispute,
+		After:          result.Dispute,
+	})
+	return &result, nil
+}
+
+type ListDisputesRequest struct {
+	Username string
+	Status   string
+	Offset   int32
+	Limit    int32
+}
+
+// ListDisputes - очередь модерации отзывов, только для администраторов
+func (s *Service) ListDisputes(ctx context.Context, params ListDisputesRequest) ([]ReviewResponse, error) {
+	ctx, span := tracing.Start(ctx, "service.ListDisputes")
+	defer span.End()
+
+	if _, err := s.requireAdmin(ctx, params.Username); err != nil {
+		return nil, err
+	}
+	if params.Status == "" {
+		params.Status = DisputeOpen
+	}
+	reviews, err := s.query.ListDisputes(ctx, database.ListDisputesParams{
+		Status: params.Status,
+		Offset: params.Offset,
+		Limit:  params.Limit,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("ListDisputes: ListDisputes err", "err", err)
+		return nil, UnknowError
+	}
+	result := []ReviewResponse{}
+	for i := range reviews {
+		result = append(result, newReviewResponse(&reviews[i]))
+	}
+	return result, nil
+}
+
+type ModerateReviewRequest struct {
+	Username  string
+	Review_id string
+	Decision  string
+	Comment   string
+}
+
+// ModerateReview - решение администратора по спору. Upheld скрывает отзыв
+// от заказчиков и исключает его из репутации.
+func (s *Service) ModerateReview(ctx context.Context, params ModerateReviewRequest) (*ReviewResponse, error) {
+	ctx, span := tracing.Start(ctx, "service.ModerateReview")
+	defer span.End()
+
+	if params.Decision != DisputeUpheld && params.Decision != DisputeDismissed {
+		return nil, InvalidDecisionVallue
+	}
+	admin, err := s.requireAdmin(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	review, err := s.query.GetReview(ctx, params.Review_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ReviewNotFound
+		}
+		logger.FromContext(ctx).Error("ModerateReview: GetReview err", "err", err)
+		return nil, UnknowError
+	}
+	if review.DisputeStatus.String != DisputeOpen {
+		return nil, DisputeNotOpen
+	}
+	notification := "Спор по отзыву отклонен, отзыв остается опубликованным"
+	if params.Decision == DisputeUpheld {
+		notification = "Спор по отзыву признан обоснованным, отзыв скрыт"
+	}
+	moderated, err := s.query.ModerateReviewTx(ctx, database.ModerateReviewParams{
+		Review_id:    review.ID,
+		User_id:      admin.ID.String(),
+		Status:       params.Decision,
+		Comment:      params.Comment,
+		Notification: notification,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, DisputeNotOpen
+		}
+		logger.FromContext(ctx).Error("ModerateReview: ModerateReviewTx err", "err", err)
+		return nil, UnknowError
+	}
+	result := newReviewResponse(moderated)
+	s.audit(ctx, auditEntry{
+		ActorID:    admin.ID.String(),
+		EntityType: EntityReview,
+		EntityID:   result.Id,
+		Action:     ActionModerate,
+		Before:     newReviewResponse(review).Dispute,
+		After:      result.Dispute,
+	})
+	return &result, nil
+}
@@ -0,0 +1,360 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"tender_service/internal/database/dbtest"
+	"testing"
+	"time"
+)
+
+// reviewEnv - тендер заказчика с опубликованным предложением поставщика alice
+type reviewEnv struct {
+	*testEnv
+	buyer_org    string
+	supplier_org string
+	offer        *Bid
+}
+
+func newReviewEnv(t *testing.T) *reviewEnv {
+	e := newTestEnv(t)
+	buyer_org := e.org("Заказчик", "buyer")
+	supplier_org := e.org("Поставщик", "alice")
+	tender := e.tender(TenderParams{OrganizationId: buyer_org, CreatorUsername: "buyer"})
+	return &reviewEnv{testEnv: e, buyer_org: buyer_org, supplier_org: supplier_org, offer: e.bid("alice", tender.ID)}
+}
+
+// review оставляет отзыв на предложение и возвращает его id
+func (e *reviewEnv) review(username, bid_id, content string, ratings *Ratings) string {
+	e.t.Helper()
+	if _, err := e.s.NewFeedBack(e.ctx, NewFeedBackRequest{Username: username, Bid_id: bid_id, Content: content, Ratings: ratings}); err != nil {
+		e.t.Fatalf("NewFeedBack: %v", err)
+	}
+	var id string
+	err := e.db.QueryRow(`SELECT id FROM review WHERE offer_id = $1 AND content = $2`, bid_id, content).Scan(&id)
+	if err != nil {
+		e.t.Fatalf("review %q: %v", content, err)
+	}
+	return id
+}
+
+func (e *reviewEnv) reputation() *Reputation {
+	e.t.Helper()
+	reputation, err := e.s.GetReputation(e.ctx, GetReputationRequest{Username: "buyer", Organization_id: e.supplier_org})
+	if err != nil {
+		e.t.Fatalf("GetReputation: %v", err)
+	}
+	return reputation
+}
+
+func TestRatedReviewsAndReputation(t *testing.T) {
+	e := newReviewEnv(t)
+	if _, err := e.s.NewFeedBack(e.ctx, NewFeedBackRequest{Username: "buyer", Bid_id: e.offer.ID, Content: "Хорошо",
+		Ratings: &Ratings{Quality: 6, Timeliness: 4, Communication: 3}}); err != InvalidRating {
+		t.Errorf("rating out of range err = %v, want InvalidRating", err)
+	}
+	if _, err := e.s.NewFeedBack(e.ctx, NewFeedBackRequest{Username: "alice", Bid_id: e.offer.ID, Content: "Сам себя"}); err != IsNotResponsible {
+		t.Errorf("review by supplier err = %v, want IsNotResponsible", err)
+	}
+
+	rated := e.review("buyer", e.offer.ID, "Хорошо, но долго", &Ratings{Quality: 5, Timeliness: 3, Communication: 4})
+	plain := e.review("buyer", e.offer.ID, "Без оценок", nil)
+
+	// отзыв без оценок в репутацию не входит
+	want := Reputation{OrganizationID: e.supplier_org, OrganizationName: "Поставщик",
+		Score: 4, Quality: 5, Timeliness: 3, Communication: 4, Reviews: 1}
+	if got := e.reputation(); !reflect.DeepEqual(*got, want) {
+		t.Errorf("reputation = %+v, want %+v", *got, want)
+	}
+	if bid := e.myBid("alice", e.offer.ID); bid.Reputation == nil || bid.Reputation.Score != 4 {
+		t.Errorf("bid reputation = %+v", bid.Reputation)
+	}
+
+	received, err := e.s.ListReceivedReviews(e.ctx, ListReceivedReviewsRequest{Username: "alice", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 2 {
+		t.Fatalf("received = %+v", received)
+	}
+
+	if _, err := e.s.EditReview(e.ctx, EditReviewRequest{Username: "alice", Review_id: rated, Content: "Отлично"}); err != NotReviewAuthor {
+		t.Errorf("edit by supplier err = %v, want NotReviewAuthor", err)
+	}
+	edited, err := e.s.EditReview(e.ctx, EditReviewRequest{Username: "buyer", Review_id: rated, Content: "Сорвали срок",
+		Ratings: &Ratings{Quality: 2, Timeliness: 1, Communication: 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Description != "Сорвали срок" || edited.Ratings == nil || edited.Ratings.Timeliness != 1 {
+		t.Errorf("edited = %+v", edited)
+	}
+	if got := e.reputation(); got.Score != 2 || got.Timeliness != 1 {
+		t.Errorf("reputation after edit = %+v", got)
+	}
+
+	if err := e.s.DeleteReview(e.ctx, DeleteReviewRequest{Username: "buyer", Review_id: plain}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.s.DeleteReview(e.ctx, DeleteReviewRequest{Username: "buyer", Review_id: plain}); err != ReviewNotFound {
+		t.Errorf("second delete err = %v, want ReviewNotFound", err)
+	}
+
+	// по истечении окна отзыв не меняется и не удаляется
+	e.exec(`UPDATE review SET created_at = created_at - interval '25 hours' WHERE id = $1`, rated)
+	if _, err := e.s.EditReview(e.ctx, EditReviewRequest{Username: "buyer", Review_id: rated, Content: "Поздно"}); err != ReviewLocked {
+		t.Errorf("late edit err = %v, want ReviewLocked", err)
+	}
+	if err := e.s.DeleteReview(e.ctx, DeleteReviewRequest{Username: "buyer", Review_id: rated}); err != ReviewLocked {
+		t.Errorf("late delete err = %v, want ReviewLocked", err)
+	}
+
+	if got := e.audited(EntityReview, rated); !reflect.DeepEqual(got, []string{ActionCreate, ActionEdit}) {
+		t.Errorf("audit = %v, want create and edit", got)
+	}
+	if got := e.audited(EntityReview, plain); !reflect.DeepEqual(got, []string{ActionCreate, ActionDelete}) {
+		t.Errorf("audit = %v, want create and delete", got)
+	}
+	if _, err := e.s.GetReputation(e.ctx, GetReputationRequest{Username: "buyer", Organization_id: "5f1b2c9e-0000-4000-8000-000000000001"}); err != OrganizationNotFound {
+		t.Errorf("unknown organization err = %v, want OrganizationNotFound", err)
+	}
+}
+
+// notified возвращает отсортированные виды уведомлений пользователя по сущности
+func (e *testEnv) notified(username, entity_id string) []string {
+	e.t.Helper()
+	rows, err := e.db.Query(`SELECT kind FROM notification
+	    WHERE user_id = $1 AND entity_id = $2 ORDER BY kind`, e.userID(username), entity_id)
+	if err != nil {
+		e.t.Fatalf("notification: %v", err)
+	}
+	defer rows.Close()
+	var kinds []string
+	for rows.Next() {
+		var kind string
+		if err := rows.Scan(&kind); err != nil {
+			e.t.Fatalf("notification: %v", err)
+		}
+		kinds = append(kinds, kind)
+	}
+	if err := rows.Err(); err != nil {
+		e.t.Fatalf("notification: %v", err)
+	}
+	return kinds
+}
+
+func TestReviewReplyAndDispute(t *testing.T) {
+	e := newReviewEnv(t)
+	e.admin("root")
+	e.org("Сторонняя", "mallory")
+	fair := e.review("buyer", e.offer.ID, "Все в срок", &Ratings{Quality: 5, Timeliness: 5, Communication: 5})
+	unfair := e.review("buyer", e.offer.ID, "Ужасно", &Ratings{Quality: 1, Timeliness: 1, Communication: 1})
+	if got := e.reputation(); got.Reviews != 2 || got.Score != 3 {
+		t.Fatalf("reputation = %+v", got)
+	}
+
+	if _, err := e.s.ReplyReview(e.ctx, ReplyReviewRequest{Username: "mallory", Review_id: fair, Content: "Спасибо"}); err != IsNotResponsible {
+		t.Errorf("reply by outsider err = %v, want IsNotResponsible", err)
+	}
+	replied, err := e.s.ReplyReview(e.ctx, ReplyReviewRequest{Username: "alice", Review_id: fair, Content: "Спасибо"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replied.Reply == nil || replied.Reply.Content != "Спасибо" || replied.Reply.AuthorUsername != "alice" {
+		t.Errorf("reply = %+v", replied.Reply)
+	}
+	if _, err := e.s.ReplyReview(e.ctx, ReplyReviewRequest{Username: "alice", Review_id: fair, Content: "Еще раз"}); err != ReplyExists {
+		t.Errorf("second reply err = %v, want ReplyExists", err)
+	}
+	if _, err := e.s.EditReview(e.ctx, EditReviewRequest{Username: "buyer", Review_id: fair, Content: "Передумал"}); err != ReviewAnswered {
+		t.Errorf("edit after reply err = %v, want ReviewAnswered", err)
+	}
+	if got := e.notified("buyer", fair); !reflect.DeepEqual(got, []string{"review_reply"}) {
+		t.Errorf("buyer notifications = %v, want review_reply", got)
+	}
+
+	disputed, err := e.s.DisputeReview(e.ctx, DisputeReviewRequest{Username: "alice", Review_id: unfair, Reason: "Заказ выполнен"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if disputed.Dispute == nil || disputed.Dispute.Status != DisputeOpen || disputed.Dispute.DisputedBy != "alice" {
+		t.Errorf("dispute = %+v", disputed.Dispute)
+	}
+	if _, err := e.s.DisputeReview(e.ctx, DisputeReviewRequest{Username: "alice", Review_id: unfair, Reason: "Еще раз"}); err != DisputeExists {
+		t.Errorf("second dispute err = %v, want DisputeExists", err)
+	}
+	if err := e.s.DeleteReview(e.ctx, DeleteReviewRequest{Username: "buyer", Review_id: unfair}); err != ReviewAnswered {
+		t.Errorf("delete under dispute err = %v, want ReviewAnswered", err)
+	}
+	if got := e.notified("root", unfair); !reflect.DeepEqual(got, []string{"review_disputed"}) {
+		t.Errorf("admin notifications = %v, want review_disputed", got)
+	}
+
+	if _, err := e.s.ListDisputes(e.ctx, ListDisputesRequest{Username: "buyer", Limit: 10}); err != IsNotAdmin {
+		t.Errorf("disputes for non-admin err = %v, want IsNotAdmin", err)
+	}
+	queue, err := e.s.ListDisputes(e.ctx, ListDisputesRequest{Username: "root", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 1 || queue[0].Id != unfair {
+		t.Errorf("open disputes = %+v", queue)
+	}
+	if got := e.audited(EntityReview, fair); !reflect.DeepEqual(got, []string{ActionCreate, ActionReply}) {
+		t.Errorf("audit = %v, want create and reply", got)
+	}
+	if got := e.audited(EntityReview, unfair); !reflect.DeepEqual(got, []string{ActionCreate, ActionDispute}) {
+		t.Errorf("audit = %v, want create and dispute", got)
+	}
+}
+
+func TestReviewModeration(t *testing.T) {
+	e := newReviewEnv(t)
+	e.admin("root")
+	kept := e.review("buyer", e.offer.ID, "Медленно", &Ratings{Quality: 3, Timeliness: 2, Communication: 4})
+	hidden := e.review("buyer", e.offer.ID, "Ужасно", &Ratings{Quality: 1, Timeliness: 1, Communication: 1})
+	for _, review_id := range []string{kept, hidden} {
+		if _, err := e.s.DisputeReview(e.ctx, DisputeReviewRequest{Username: "alice", Review_id: review_id, Reason: "Несправедливо"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := e.s.ModerateReview(e.ctx, ModerateReviewRequest{Username: "root", Review_id: hidden, Decision: "Deleted"}); err != InvalidDecisionVallue {
+		t.Errorf("unknown decision err = %v, want InvalidDecisionVallue", err)
+	}
+	if _, err := e.s.ModerateReview(e.ctx, ModerateReviewRequest{Username: "buyer", Review_id: hidden, Decision: DisputeUpheld}); err != IsNotAdmin {
+		t.Errorf("moderation by non-admin err = %v, want IsNotAdmin", err)
+	}
+
+	moderated, err := e.s.ModerateReview(e.ctx, ModerateReviewRequest{Username: "root", Review_id: hidden, Decision: DisputeUpheld, Comment: "Не подтверждено"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := moderated.Dispute; d == nil || d.Status != DisputeUpheld || d.ModeratedBy != "root" || d.ModeratedAt == nil || d.Comment != "Не подтверждено" {
+		t.Errorf("moderated dispute = %+v", d)
+	}
+	if _, err := e.s.ModerateReview(e.ctx, ModerateReviewRequest{Username: "root", Review_id: kept, Decision: DisputeDismissed}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.s.ModerateReview(e.ctx, ModerateReviewRequest{Username: "root", Review_id: hidden, Decision: DisputeDismissed}); err != DisputeNotOpen {
+		t.Errorf("second decision err = %v, want DisputeNotOpen", err)
+	}
+
+	// в репутацию входит только отзыв со спором, отклоненным модератором
+	want := Reputation{OrganizationID: e.supplier_org, OrganizationName: "Поставщик",
+		Score: 3, Quality: 3, Timeliness: 2, Communication: 4, Reviews: 1}
+	if got := e.reputation(); !reflect.DeepEqual(*got, want) {
+		t.Errorf("reputation = %+v, want %+v", *got, want)
+	}
+	queue, err := e.s.ListDisputes(e.ctx, ListDisputesRequest{Username: "root", Status: DisputeUpheld, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 1 || queue[0].Id != hidden {
+		t.Errorf("upheld disputes = %+v", queue)
+	}
+	if queue, _ := e.s.ListDisputes(e.ctx, ListDisputesRequest{Username: "root", Limit: 10}); len(queue) != 0 {
+		t.Errorf("open disputes = %+v, want none", queue)
+	}
+
+	// автор отзыва и оспоривший поставщик узнают о решении
+	if got := e.notified("buyer", hidden); !reflect.DeepEqual(got, []string{"review_moderated"}) {
+		t.Errorf("buyer notifications = %v, want review_moderated", got)
+	}
+	if got := e.notified("alice", hidden); !reflect.DeepEqual(got, []string{"review_moderated", "review_received"}) {
+		t.Errorf("alice notifications = %v, want review_moderated and review_received", got)
+	}
+	if got := e.audited(EntityReview, hidden); !reflect.DeepEqual(got, []string{ActionCreate, ActionDispute, ActionModerate}) {
+		t.Errorf("audit = %v, want create, dispute and moderate", got)
+	}
+}
+
+func TestOfferAuthorReviews(t *testing.T) {
+	e := newReviewEnv(t)
+	e.admin("root")
+	other_org := e.org("Другой заказчик", "other")
+	e.exec(`INSERT INTO organization_responsible (organization_id, user_id) VALUES ($1, $2)`,
+		e.supplier_org, dbtest.Employee(t, e.db, "bob"))
+
+	// прошлые тендеры: alice у обоих заказчиков, bob из той же организации
+	past := e.tender(TenderParams{OrganizationId: other_org, CreatorUsername: "other"})
+	own := e.tender(TenderParams{OrganizationId: e.buyer_org, CreatorUsername: "buyer"})
+	past_bid := e.bid("alice", past.ID)
+	bob_bid := e.bid("bob", own.ID)
+	excellent := e.review("buyer", e.offer.ID, "Отлично", &Ratings{Quality: 5, Timeliness: 5, Communication: 5})
+	poor := e.review("other", past_bid.ID, "Плохо", &Ratings{Quality: 2, Timeliness: 2, Communication: 2})
+	colleague := e.review("buyer", bob_bid.ID, "Коллега", &Ratings{Quality: 4, Timeliness: 4, Communication: 4})
+	plain := e.review("buyer", e.offer.ID, "Без оценок", nil)
+	e.exec(`UPDATE review SET created_at = created_at - interval '10 days' WHERE id = $1`, excellent)
+	e.exec(`UPDATE review SET created_at = created_at - interval '5 days' WHERE id = $1`, poor)
+	e.exec(`UPDATE review SET created_at = created_at - interval '3 days' WHERE id = $1`, colleague)
+
+	current := e.tender(TenderParams{Name: "Поставка тонера", OrganizationId: e.buyer_org, CreatorUsername: "buyer"})
+	e.bid("alice", current.ID)
+
+	week_ago := time.Now().Add(-7 * 24 * time.Hour)
+	tests := []struct {
+		name   string
+		params OfferAuthorReviewsRequest
+		want   []string
+	}{
+		{name: "author", want: []string{plain, poor, excellent}},
+		{name: "reviewer organization", params: OfferAuthorReviewsRequest{Reviewer_org_id: other_org}, want: []string{poor}},
+		{name: "from", params: OfferAuthorReviewsRequest{From: &week_ago}, want: []string{plain, poor}},
+		{name: "to", params: OfferAuthorReviewsRequest{To: &week_ago}, want: []string{excellent}},
+		{name: "min rating", params: OfferAuthorReviewsRequest{MinRating: 4}, want: []string{excellent}},
+		{name: "max rating", params: OfferAuthorReviewsRequest{MaxRating: 3}, want: []string{poor}},
+		{name: "organization", params: OfferAuthorReviewsRequest{ByOrganization: true}, want: []string{plain, colleague, poor, excellent}},
+		{name: "page", params: OfferAuthorReviewsRequest{ByOrganization: true, Offset: 1, Limit: 2}, want: []string{colleague, poor}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := tt.params
+			params.Tender_ID, params.AuthorUsername, params.RequesterUsername = current.ID, "alice", "buyer"
+			reviews, err := e.s.OfferAuthorReviews(e.ctx, params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, review := range reviews {
+				got = append(got, review.Id)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reviews = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	_, err := e.s.OfferAuthorReviews(e.ctx, OfferAuthorReviewsRequest{Tender_ID: current.ID, AuthorUsername: "alice", RequesterUsername: "other"})
+	if err != IsNotResponsible {
+		t.Errorf("foreign tender err = %v, want IsNotResponsible", err)
+	}
+	_, err = e.s.OfferAuthorReviews(e.ctx, OfferAuthorReviewsRequest{Tender_ID: current.ID, AuthorUsername: "bob", RequesterUsername: "buyer"})
+	if err != BidNotFound {
+		t.Errorf("author without bid err = %v, want BidNotFound", err)
+	}
+
+	// отзыв, скрытый модератором, в истории не показывается
+	if _, err := e.s.DisputeReview(e.ctx, DisputeReviewRequest{Username: "alice", Review_id: poor, Reason: "Несправедливо"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.s.ModerateReview(e.ctx, ModerateReviewRequest{Username: "root", Review_id: poor, Decision: DisputeUpheld}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		e.review("buyer", bob_bid.ID, fmt.Sprintf("Отзыв %d", i), nil)
+	}
+	reviews, err := e.s.OfferAuthorReviews(e.ctx, OfferAuthorReviewsRequest{Tender_ID: current.ID, AuthorUsername: "alice",
+		RequesterUsername: "buyer", ByOrganization: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reviews) != 5 {
+		t.Errorf("default page = %d reviews, want 5", len(reviews))
+	}
+	for _, review := range reviews {
+		if review.Id == poor {
+			t.Error("upheld review must be hidden")
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"tender_service/internal/database"
+	"tender_service/internal/logger"
+	"tender_service/internal/sealing"
+	"tender_service/internal/tracing"
+	"time"
+)
+
+var (
+	SealingDisabled    = fmt.Errorf("Закрытые торги не настроены на сервере")
+	DeadlineRequired   = fmt.Errorf("Для закрытого тендера нужен срок подачи предложений")
+	SubmissionClosed   = fmt.Errorf("Срок подачи предложений истек")
+	BidsSealed         = fmt.Errorf("Предложения закрытого тендера еще не вскрыты")
+	TenderNotSealed    = fmt.Errorf("Тендер проводится в открытом режиме")
+	DeadlineNotPassed  = fmt.Errorf("Срок подачи предложений еще не истек")
+	BidsAlreadyOpened  = fmt.Errorf("Предложения тендера уже вскрыты")
+	BidOpeningNotFound = fmt.Errorf("Предложения тендера еще не вскрывались")
+)
+
+const (
+	BiddingModeOpen    = "open"
+	BiddingModeSealed  = "sealed"
+	BiddingModeAuction = "auction"
+)
+
+// UseSealer включает закрытые торги
+func (s *Service) UseSealer(sealer *sealing.Sealer) {
+	s.sealer = sealer
+}
+
+// submissionClosed - у тендера задан срок подачи предложений и он истек.
+// Срок действует во всех режимах торгов.
+func submissionClosed(terms *database.TenderTerms, now time.Time) bool {
+	return terms.SubmissionDeadline.Valid && now.After(terms.SubmissionDeadline.Time)
+}
+
+// isSealed - предложения тендера зашифрованы и еще не вскрыты
+func isSealed(terms *database.TenderTerms) bool {
+	return terms.BiddingMode == BiddingModeSealed && !terms.BidsOpenedAt.Valid
+}
+
+// sealedAAD связывает шифротекст с тендером и автором: перенесенный в чужое
+// предложение или в предложение того же автора на другом тендере, он не
+// расшифруется
+func sealedAAD(tender_id, author_id string) string {
+	return tender_id + ":" + author_id
+}
+
+// sealBid шифрует содержимое предложения автора на тендер
+func (s *Service) sealBid(tender_id, author_id, name, description string) ([]byte, error) {
+	if s.sealer == nil {
+		return nil, SealingDisabled
+	}
+	plaintext, err := json.Marshal(database.OpenedContent{Name: name, Description: description})
+	if err != nil {
+		return nil, err
+	}
+	return s.sealer.Seal(plaintext, sealedAAD(tender_id, author_id))
+}
+
+func (s *Service) unsealBid(tender_id, author_id string, sealed []byte) (database.OpenedContent, error) {
+	var content database.OpenedContent
+	if s.sealer == nil {
+		return content, SealingDisabled
+	}
+	plaintext, err := s.sealer.Open(sealed, sealedAAD(tender_id, author_id))
+	if err != nil {
+		return content, err
+	}
+	err = json.Unmarshal(plaintext, &content)
+	return content, err
+}
+
+// revealBidName подставляет название закрытого предложения для его автора.
+// Если расшифровать не удалось, название остается пустым.
+func (s *Service) revealBidName(ctx context.Context, bid *Bid, tender_id string, sealed []byte) {
+	if len(sealed) == 0 {
+		return
+	}
+	content, err := s.unsealBid(tender_id, bid.AuthorId, sealed)
+	if err != nil {
+		logger.FromContext(ctx).Error("revealBidName: unsealBid err", "err", err, "bid_id", bid.ID)
+		return
+	}
+	bid.Name = content.Name
+}
+
+type BidOpening struct {
+	TenderID string    `json:"tenderId"`
+	OpenedBy string    `json:"openedBy"`
+	BidCount int32     `json:"bidCount"`
+	OpenedAt time.Time `json:"openedAt"`
+}
+
+func newBidOpening(o *database.BidOpening) BidOpening {
+	return BidOpening{
+		TenderID: o.TenderID.String(),
+		OpenedBy: o.OpenedBy,
+		BidCount: o.BidCount,
+		OpenedAt: o.OpenedAt,
+	}
+}
+
+type OpenBidsRequest struct {
+	Username  string
+	Tender_id string
+}
+
+// OpenBids - вскрытие конвертов: после срока подачи ответственный
+// организации тендера расшифровывает все предложения разом
+func (s *Service) OpenBids(ctx context.Context, params OpenBidsRequest) (*BidOpening, error) {
+	ctx, span := tracing.Start(ctx, "service.OpenBids")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	tender, err := s.query.GetTender(ctx, params.Tender_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, TenderNotFound
+		}
+		logger.FromContext(ctx).Error("OpenBids: GetTender err", "err", err)
+		return nil, UnknowError
+	}
+	if err := s.isResponsibleUser(ctx, tender.OrganizationID.String(), user_id); err != nil {
+		return nil, err
+	}
+	terms, err := s.query.GetTenderTerms(ctx, tender.ID.String())
+	if err != nil {
+		logger.FromContext(ctx).Error("OpenBids: GetTenderTerms err", "err", err)
+		return nil, UnknowError
+	}
+	if terms.BiddingMode != BiddingModeSealed {
+		return nil, TenderNotSealed
+	}
+	if terms.BidsOpenedAt.Valid {
+		return nil, BidsAlreadyOpened
+	}
+	if !terms.SubmissionDeadline.Valid || time.Now().Before(terms.SubmissionDeadline.Time) {
+		return nil, DeadlineNotPassed
+	}
+	if s.sealer == nil {
+		return nil, SealingDisabled
+	}
+
+	opening, err := s.query.OpenSealedOffersTx(ctx, tender.ID.String(), user_id,
+		func(offer database.SealedOffer) (database.OpenedContent, error) {
+			content, err := s.unsealBid(tender.ID.String(), offer.AuthorID, offer.Content)
+			if err != nil {
+				return content, fmt.Errorf("offer %s: %v", offer.ID, err)
+			}
+			return content, nil
+		})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, BidsAlreadyOpened
+		}
+		logger.FromContext(ctx).Error("OpenBids: OpenSealedOffersTx err", "err", err)
+		return nil, UnknowError
+	}
+	result := newBidOpening(opening)
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: tender.OrganizationID.String(),
+		EntityType:     EntityTender,
+		EntityID:       result.TenderID,
+		Action:         ActionOpenBids,
+		After:          result,
+	})
+	return &result, nil
+}
+
+type GetBidOpeningRequest struct {
+	Username  string
+	Tender_id string
+}
+
+func (s *Service) GetBidOpening(ctx context.Context, params GetBidOpeningRequest) (*BidOpening, error) {
+	ctx, span := tracing.Start(ctx, "service.GetBidOpening")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	tender, err := s.query.GetTender(ctx, params.Tender_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, TenderNotFound
+		}
+		logger.FromContext(ctx).Error("GetBidOpening: GetTender err", "err", err)
+		return nil, UnknowError
+	}
+	if err := s.checkTenderVisible(ctx, tender, user_id); err != nil {
+		return nil, err
+	}
+	if tender.BiddingMode != BiddingModeSealed {
+		return nil, TenderNotSealed
+	}
+	opening, err := s.query.GetBidOpening(ctx, tender.ID.String())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, BidOpeningNotFound
+		}
+		logger.FromContext(ctx).Error("GetBidOpening: GetBidOpening err", "err", err)
+		return nil, UnknowError
+	}
+	result := newBidOpening(opening)
+	return &result, nil
+}
+
+// editSealedBid перешифровывает измененное содержимое закрытого предложения.
+// История версий для него не ведется, чтобы не хранить старое содержимое
+// в открытом виде.
+func (s *Service) editSealedBid(ctx context.Context, user_id string, bid *database.OfferFull, params EditBidRequest) (*Bid, error) {
+	terms, err := s.query.GetTenderTerms(ctx, bid.Tender_ID.String())
+	if err != nil {
+		logger.FromContext(ctx).Error("editSealedBid: GetTenderTerms err", "err", err)
+		return nil, UnknowError
+	}
+	if submissionClosed(terms, time.Now()) {
+		return nil, SubmissionClosed
+	}
+	author_id := bid.Creator_ID.String()
+	content, err := s.unsealBid(bid.Tender_ID.String(), author_id, bid.SealedContent)
+	if err != nil {
+		if err == SealingDisabled {
+			return nil, err
+		}
+		logger.FromContext(ctx).Error("editSealedBid: unsealBid err", "err", err)
+		return nil, UnknowError
+	}
+	if params.Name != "" {
+		content.Name = params.Name
+	}
+	if params.Description != "" {
+		content.Description = params.Description
+	}
+	sealed, err := s.sealBid(bid.Tender_ID.String(), author_id, content.Name, content.Description)
+	if err != nil {
+		logger.FromContext(ctx).Error("editSealedBid: sealBid err", "err", err)
+		return nil, UnknowError
+	}
+	new_bid, err := s.query.ResealOffer(ctx, bid.ID.String(), sealed)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, BidNotFound
+		}
+		logger.FromContext(ctx).Error("editSealedBid: ResealOffer err", "err", err)
+		return nil, UnknowError
+	}
+	result := &Bid{
+		ID:         new_bid.ID.String(),
+		Name:       new_bid.Name,
+		Status:     new_bid.Status,
+		AuthorType: new_bid.AuthorType,
+		AuthorId:   new_bid.AuthorId.String(),
+		Version:    new_bid.Version,
+		CreatedAt:  new_bid.CreatedAt,
+	}
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: bid.Organization_ID.String(),
+		EntityType:     EntityBid,
+		EntityID:       result.ID,
+		Action:         ActionEdit,
+		Before:         newBid(bid),
+		After:          result,
+	})
+	result.Name = content.Name
+	return result, nil
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"bytes"
+	"tender_service/internal/sealing"
+	"testing"
+)
+
+func TestSealBidBindsTenderAndAuthor(t *testing.T) {
+	sealer, err := sealing.New(bytes.Repeat([]byte{7}, sealing.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Service{}
+	if _, err := s.sealBid("tender-1", "author-1", "name", "description"); err != SealingDisabled {
+		t.Fatalf("sealBid without sealer err = %v, want SealingDisabled", err)
+	}
+	s.UseSealer(sealer)
+
+	sealed, err := s.sealBid("tender-1", "author-1", "Поставка", "Описание")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		tender_id string
+		author_id string
+		wantErr   bool
+	}{
+		{name: "same tender and author", tender_id: "tender-1", author_id: "author-1"},
+		{name: "other tender", tender_id: "tender-2", author_id: "author-1", wantErr: true},
+		{name: "other author", tender_id: "tender-1", author_id: "author-2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := s.unsealBid(tt.tender_id, tt.author_id, sealed)
+			if tt.wantErr {
+				if err != sealing.ErrCorrupted {
+					t.Fatalf("unsealBid err = %v, want ErrCorrupted", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unsealBid: %v", err)
+			}
+			if content.Name != "Поставка" || content.Description != "Описание" {
+				t.Errorf("unsealBid = %+v", content)
+			}
+		})
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"log"
 	"sync"
 	"tender_service/internal/database"
+	"tender_service/internal/logger"
+	"tender_service/internal/metrics"
+	"tender_service/internal/sealing"
+	"tender_service/internal/tracing"
 	"tender_service/internal/utils"
 	"time"
 )
@@ -25,11 +28,21 @@ var (
 	IsNotAuthor           = fmt.Errorf("Пользователь не является автором")
 	BidCanceled           = fmt.Errorf("Предложение уже закрыто")
 	InvalidDecisionVallue = fmt.Errorf("Неверное значение поля decision")
+	UserDeactivated       = fmt.Errorf("Пользователь деактивирован")
+	TemplateNotFound      = fmt.Errorf("Шаблон с таким id не существует")
+	TenderNotClosed       = fmt.Errorf("Клонировать можно только закрытый тендер")
+	AmendmentReason       = fmt.Errorf("Изменение опубликованного тендера требует указать причину")
+	DeadlineNotExtended   = fmt.Errorf("Новый срок подачи должен быть позже текущего")
+	DeadlineNotPublished  = fmt.Errorf("Срок подачи продлевается только у опубликованного тендера")
+	NothingToChange       = fmt.Errorf("Не указано ни одного изменения")
 )
 
 type Service struct {
-	query *database.Queries
-	mu    sync.Mutex
+	query  *database.Queries
+	mu     sync.Mutex
+	sealer *sealing.Sealer
+	// analyticsViews - отчеты читают материализованное представление
+	analyticsViews bool
 }
 
 func New(query *database.Queries) *Service {
@@ -38,10 +51,26 @@ func New(query *database.Queries) *Service {
 	}
 }
 
+// fetchUserID возвращает id активного пользователя по его имени
+func (s *Service) fetchUserID(ctx context.Context, username string) (string, error) {
+	user, err := s.query.FetchUserByUsername(ctx, username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", UserNotFound
+		}
+		logger.FromContext(ctx).Error("fetchUserID: FetchUserByUsername err", "err", err)
+		return "", UnknowError
+	}
+	if !user.IsActive {
+		return "", UserDeactivated
+	}
+	return user.ID.String(), nil
+}
+
 func (s *Service) isResponsibleUser(ctx context.Context, org_id, user_id string) error {
 	valid, err := s.query.IsResponsible(ctx, org_id, user_id)
 	if err != nil {
-		log.Println("check IsResponsible error -", err)
+		logger.FromContext(ctx).Error("check IsResponsible error", "err", err)
 		return UnknowError
 	}
 	if !valid {
@@ -51,29 +80,84 @@ func (s *Service) isResponsibleUser(ctx context.Context, org_id, user_id string)
 }
 
 type Tender struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Status      string    `json:"status"`
-	ServiceType string    `json:"serviceType"`
-	Version     int32     `json:"version"`
-	CreatedAt   time.Time `json:"createdAt"`
+	ID                 string      `json:"id"`
+	Name               string      `json:"name"`
+	Description        string      `json:"description"`
+	Status             string      `json:"status"`
+	ServiceType        string      `json:"serviceType"`
+	Version            int32       `json:"version"`
+	CreatedAt          time.Time   `json:"createdAt"`
+	EvaluationCriteria []Criterion `json:"evaluationCriteria,omitempty"`
+	SubmissionDeadline *time.Time  `json:"submissionDeadline,omitempty"`
+	DecisionDeadline   *time.Time  `json:"decisionDeadline,omitempty"`
+	BiddingMode        string      `json:"biddingMode,omitempty"`
+	Lots               []Lot       `json:"lots,omitempty"`
+	Visibility         string      `json:"visibility,omitempty"`
+	// RequiresQualification - предложения принимаются только от
+	// допущенных поставщиков организации тендера
+	RequiresQualification bool `json:"requiresQualification,omitempty"`
+	// EstimatedBudget - оценка бюджета для расчета экономии
+	EstimatedBudget *float64 `json:"estimatedBudget,omitempty"`
+}
+
+func newTender(t database.Tender) Tender {
+	return Tender{
+		ID:          t.ID.String(),
+		Name:        t.Name,
+		Description: t.Description,
+		Status:      t.Status,
+		ServiceType: t.ServiceType,
+		Version:     t.Version,
+		CreatedAt:   t.CreatedAt,
+		BiddingMode: t.BiddingMode,
+		Visibility:  t.Visibility,
+	}
 }
 
 type ListTendersRequest struct {
 	Service_type []string
 	Offset       int32
 	Limit        int32
+	// Username - необязательный: тендеры по приглашению видны только
+	// приглашенным организациям
+	Username string
 }
 
 func (s *Service) FetchPublishedTenders(ctx context.Context, params ListTendersRequest) ([]Tender, error) {
+	ctx, span := tracing.Start(ctx, "service.FetchPublishedTenders")
+	defer span.End()
+
+	var viewer_id string
+	if params.Username != "" {
+		var err error
+		viewer_id, err = s.fetchUserID(ctx, params.Username)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// фильтр по категории включает все вложенные типы
+	service_types := params.Service_type
+	if len(service_types) > 0 {
+		expanded, err := s.query.ExpandServiceTypes(ctx, service_types)
+		if err != nil {
+			logger.FromContext(ctx).Error("FetchPublishedTenders: ExpandServiceTypes err", "err", err)
+			return nil, UnknowError
+		}
+		if len(expanded) == 0 {
+			return nil, nil
+		}
+		service_types = expanded
+	}
+
 	listtenders, err := s.query.PublishedListTenders(ctx, database.ListTendersParams{
-		Service_type: params.Service_type,
+		Service_type: service_types,
 		Offset:       params.Offset,
 		Limit:        params.Limit,
+		Viewer_id:    viewer_id,
 	})
 	if err != nil {
-		log.Printf("FetchPublisgTender error: %s", err)
+		logger.FromContext(ctx).Error("FetchPublishedTenders: PublishedListTenders err", "err", err)
 		return nil, CreateTenderError
 	}
 	var listTenders []Tender
@@ -98,46 +182,76 @@ type TenderParams struct {
 	Status          string
 	OrganizationId  string
 	CreatorUsername string
+	// BiddingMode - open (по умолчанию) или sealed. Закрытому тендеру
+	// нужен срок подачи предложений.
+	BiddingMode        string
+	SubmissionDeadline *time.Time
+	// Lots делят тендер на лоты с отдельными победителями
+	Lots []LotParams
+	// Visibility - public (по умолчанию) или invite_only
+	Visibility string
+	// RequiresQualification - подавать предложения могут только поставщики
+	// с одобренной квалификацией по виду услуг тендера
+	RequiresQualification bool
+	// EstimatedBudget - необязательная оценка бюджета, с ней аналитика
+	// считает экономию по цене победителя
+	EstimatedBudget *float64
 }
 
 func (s *Service) CreateNewTender(ctx context.Context, params TenderParams) (*Tender, error) {
+	ctx, span := tracing.Start(ctx, "service.CreateNewTender")
+	defer span.End()
 
-	user_id, err := s.query.FetchUserID(ctx, params.CreatorUsername)
+	user_id, err := s.fetchUserID(ctx, params.CreatorUsername)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, UserNotFound
-		}
-		log.Println("CreateNewTender: Fetching User id error -", err)
-		return nil, UnknowError
+		return nil, err
 	}
 
 	err = s.isResponsibleUser(ctx, params.OrganizationId, user_id)
 	if err != nil {
 		return nil, err
 	}
+	params.ServiceType, err = s.resolveServiceType(ctx, params.ServiceType)
+	if err != nil {
+		return nil, err
+	}
+	if len(params.Lots) > 0 && params.BiddingMode != "" && params.BiddingMode != BiddingModeOpen {
+		return nil, LotsNotSupported
+	}
+	lots, err := s.resolveLots(ctx, params.ServiceType, params.Lots)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := database.TenderTerms{
+		BiddingMode:           params.BiddingMode,
+		Visibility:            params.Visibility,
+		RequiresQualification: params.RequiresQualification,
+	}
+	if params.EstimatedBudget != nil {
+		terms.EstimatedBudget = sql.NullFloat64{Float64: *params.EstimatedBudget, Valid: true}
+	}
+	if params.SubmissionDeadline != nil {
+		terms.SubmissionDeadline = sql.NullTime{Time: params.SubmissionDeadline.UTC().Truncate(time.Second), Valid: true}
+	}
+	if params.BiddingMode == BiddingModeSealed {
+		if s.sealer == nil {
+			return nil, SealingDisabled
+		}
+		if !terms.SubmissionDeadline.Valid || !terms.SubmissionDeadline.Time.After(time.Now()) {
+			return nil, DeadlineRequired
+		}
+	}
 
-	tender, err := s.query.CreateTender(ctx, database.CreateTenderParams{
+	return s.createTenderWithTerms(ctx, user_id, ActionCreate, database.CreateTenderParams{
 		OrganizationID: params.OrganizationId,
 		CreatorID:      user_id,
 		Status:         params.Status,
 		ServiceType:    params.ServiceType,
 		Name:           params.Name,
 		Description:    params.Description,
-	})
-	if err != nil {
-		log.Println("CreateNewTender: CreateTender error -", err)
-		return nil, UnknowError
-	}
-
-	return &Tender{
-		ID:          tender.ID,
-		Name:        params.Name,
-		Description: params.Description,
-		Status:      params.Status,
-		ServiceType: params.ServiceType,
-		Version:     tender.Version,
-		CreatedAt:   tender.CreatedAt,
-	}, nil
+		Lots:           lots,
+	}, terms)
 }
 
 type ListMyTendersRequest struct {
@@ -147,13 +261,12 @@ type ListMyTendersRequest struct {
 }
 
 func (s *Service) FetchMyTenders(ctx context.Context, params ListMyTendersRequest) ([]Tender, error) {
-	user_id, err := s.query.FetchUserID(ctx, params.Username)
+	ctx, span := tracing.Start(ctx, "service.FetchMyTenders")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, UserNotFound
-		}
-		log.Println("FetchMyTenders: fetching user id err -", err)
-		return nil, UnknowError
+		return nil, err
 	}
 
 	listtenders, err := s.query.MyListTenders(ctx, &database.MyListTendersParams{
@@ -162,7 +275,7 @@ func (s *Service) FetchMyTenders(ctx context.Context, params ListMyTendersReques
 		Limit:   params.Limit,
 	})
 	if err != nil {
-		log.Println("FetchMyTenders: MyListTenders err -", err)
+		logger.FromContext(ctx).Error("FetchMyTenders: MyListTenders err", "err", err)
 		return nil, UnknowError
 	}
 	var listTenders []Tender
@@ -181,20 +294,19 @@ func (s *Service) FetchMyTenders(ctx context.Context, params ListMyTendersReques
 }
 
 func (s *Service) FetchTenderStatus(ctx context.Context, username, tender_id string) (string, error) {
-	user_id, err := s.query.FetchUserID(ctx, username)
+	ctx, span := tracing.Start(ctx, "service.FetchTenderStatus")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, username)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return "", UserNotFound
-		}
-		log.Println("FetchTenderStatus: FetchUserID err -", err)
-		return "", UnknowError
+		return "", err
 	}
 	tender, err := s.query.GetTender(ctx, tender_id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return "", TenderNotFound
 		}
-		log.Println("FetchTenderStatus: GetTender err -", err)
+		logger.FromContext(ctx).Error("FetchTenderStatus: GetTender err", "err", err)
 		return "", UnknowError
 	}
 
@@ -208,7 +320,7 @@ func (s *Service) FetchTenderStatus(ctx context.Context, username, tender_id str
 		if err == sql.ErrNoRows {
 			return "", TenderNotFound
 		}
-		log.Println("FetchTenderStatus: CheckTenderStatus err -", err)
+		logger.FromContext(ctx).Error("FetchTenderStatus: CheckTenderStatus err", "err", err)
 		return "", UnknowError
 	}
 	return tender_status, nil
@@ -221,20 +333,19 @@ type EditTenderStatusRequest struct {
 }
 
 func (s *Service) EditTenderStatus(ctx context.Context, param EditTenderStatusRequest) (*Tender, error) {
-	user_id, err := s.query.FetchUserID(ctx, param.Username)
+	ctx, span := tracing.Start(ctx, "service.EditTenderStatus")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, param.Username)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, UserNotFound
-		}
-		log.Println("EditTenderStatus: FetchUserID err -", err)
-		return nil, UnknowError
+		return nil, err
 	}
 	tender, err := s.query.GetTender(ctx, param.Tender_id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, TenderNotFound
 		}
-		log.Println("EditTenderStatus: GetTender err -", err)
+		logger.FromContext(ctx).Error("EditTenderStatus: GetTender err", "err", err)
 		return nil, UnknowError
 	}
 
@@ -248,11 +359,11 @@ func (s *Service) EditTenderStatus(ctx context.Context, param EditTenderStatusRe
 		if err == sql.ErrNoRows {
 			return nil, TenderNotFound
 		}
-		log.Println("EditTenderStatus: ChangeTenderStatus err -", err)
+		logger.FromContext(ctx).Error("EditTenderStatus: ChangeTenderStatus err", "err", err)
 		return nil, UnknowError
 	}
 
-	return &Tender{
+	result := &Tender{
 		ID:          new_tender.ID.String(),
 		Name:        new_tender.Name,
 		Description: new_tender.Description,
@@ -260,7 +371,17 @@ func (s *Service) EditTenderStatus(ctx context.Context, param EditTenderStatusRe
 		ServiceType: new_tender.ServiceType,
 		Version:     new_tender.Version,
 		CreatedAt:   new_tender.CreatedAt,
-	}, nil
+	}
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: tender.OrganizationID.String(),
+		EntityType:     EntityTender,
+		EntityID:       result.ID,
+		Action:         ActionChangeStatus,
+		Before:         newTender(tender),
+		After:          result,
+	})
+	return result, nil
 }
 
 type EditTenderRequest struct {
@@ -269,23 +390,25 @@ type EditTenderRequest struct {
 	Name         string
 	Description  string
 	Service_type string
+	// Reason обязательна для опубликованного тендера
+	Reason             string
+	SubmissionDeadline *time.Time
 }
 
 func (s *Service) EditTender(ctx context.Context, params EditTenderRequest) (*Tender, error) {
-	user_id, err := s.query.FetchUserID(ctx, params.Username)
+	ctx, span := tracing.Start(ctx, "service.EditTender")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, UserNotFound
-		}
-		log.Println("EditTenderStatus: FetchUserID err -", err)
-		return nil, UnknowError
+		return nil, err
 	}
 	tender, err := s.query.GetTender(ctx, params.Tender_id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, TenderNotFound
 		}
-		log.Println("EditTenderStatus: GetTender err -", err)
+		logger.FromContext(ctx).Error("EditTenderStatus: GetTender err", "err", err)
 		return nil, UnknowError
 	}
 
@@ -293,6 +416,18 @@ func (s *Service) EditTender(ctx context.Context, params EditTenderRequest) (*Te
 	if err != nil {
 		return nil, err
 	}
+	if params.Service_type != "" {
+		params.Service_type, err = s.resolveServiceType(ctx, params.Service_type)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if tender.Status == "Published" {
+		return s.amendTender(ctx, user_id, tender, params)
+	}
+	if params.SubmissionDeadline != nil {
+		return nil, DeadlineNotPublished
+	}
 	new_tender, err := s.query.EditTenderWithTX(ctx, database.EditTenderWithTxParam{
 		ChangeTenderParam: database.TenderChangeParam{
 			Tender_id:    tender.ID.String(),
@@ -313,10 +448,10 @@ func (s *Service) EditTender(ctx context.Context, params EditTenderRequest) (*Te
 		if err == sql.ErrNoRows {
 			return nil, TenderNotFound
 		}
-		log.Println("EditTenderStatus: EditTenderWithTX err -", err)
+		logger.FromContext(ctx).Error("EditTenderStatus: EditTenderWithTX err", "err", err)
 		return nil, UnknowError
 	}
-	return &Tender{
+	result := &Tender{
 		ID:          new_tender.ID.String(),
 		Name:        new_tender.Name,
 		Description: new_tender.Description,
@@ -324,7 +459,17 @@ func (s *Service) EditTender(ctx context.Context, params EditTenderRequest) (*Te
 		ServiceType: new_tender.ServiceType,
 		Version:     new_tender.Version,
 		CreatedAt:   new_tender.CreatedAt,
-	}, nil
+	}
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: tender.OrganizationID.String(),
+		EntityType:     EntityTender,
+		EntityID:       result.ID,
+		Action:         ActionEdit,
+		Before:         newTender(tender),
+		After:          result,
+	})
+	return result, nil
 }
 
 type RollbackTenderRequest struct {
@@ -334,20 +479,19 @@ type RollbackTenderRequest struct {
 }
 
 func (s *Service) RollbackTender(ctx context.Context, params RollbackTenderRequest) (*Tender, error) {
-	user_id, err := s.query.FetchUserID(ctx, params.Username)
+	ctx, span := tracing.Start(ctx, "service.RollbackTender")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, UserNotFound
-		}
-		log.Println("RollbackTender: FetchUserID err -", err)
-		return nil, UnknowError
+		return nil, err
 	}
 	tender, err := s.query.GetTender(ctx, params.Tender_id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, TenderNotFound
 		}
-		log.Println("RollbackTender: GetTender err -", err)
+		logger.FromContext(ctx).Error("RollbackTender: GetTender err", "err", err)
 		return nil, UnknowError
 	}
 
@@ -361,16 +505,22 @@ func (s *Service) RollbackTender(ctx context.Context, params RollbackTenderReque
 		if err == sql.ErrNoRows {
 			return nil, TenderHistoryNotFound
 		}
-		log.Println("RollbackTender: GetTenderHistory err -", err)
+		logger.FromContext(ctx).Error("RollbackTender: GetTenderHistory err", "err", err)
 		return nil, UnknowError
 	}
+	// версия могла быть сохранена до справочника видов услуг или с кодом
+	// в другом регистре
+	service_type, err := s.resolveServiceType(ctx, tender_history.ServiceType)
+	if err != nil {
+		return nil, err
+	}
 
 	new_tender, err := s.query.EditTenderWithTX(ctx, database.EditTenderWithTxParam{
 		ChangeTenderParam: database.TenderChangeParam{
 			Tender_id:    tender.ID.String(),
 			Name:         tender_history.Name,
 			Description:  tender_history.Description,
-			Service_type: tender_history.ServiceType,
+			Service_type: service_type,
 		},
 		TenderHistoryParam: database.CreateTenderHistoryParams{
 			Tender_id:   tender.ID.String(),
@@ -385,10 +535,10 @@ func (s *Service) RollbackTender(ctx context.Context, params RollbackTenderReque
 		if err == sql.ErrNoRows {
 			return nil, TenderNotFound
 		}
-		log.Println("RollbackTender: EditTenderWithTX err -", err)
+		logger.FromContext(ctx).Error("RollbackTender: EditTenderWithTX err", "err", err)
 		return nil, UnknowError
 	}
-	return &Tender{
+	result := &Tender{
 		ID:          new_tender.ID.String(),
 		Name:        new_tender.Name,
 		Description: new_tender.Description,
@@ -396,7 +546,17 @@ func (s *Service) RollbackTender(ctx context.Context, params RollbackTenderReque
 		ServiceType: new_tender.ServiceType,
 		Version:     new_tender.Version,
 		CreatedAt:   new_tender.CreatedAt,
-	}, nil
+	}
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: tender.OrganizationID.String(),
+		EntityType:     EntityTender,
+		EntityID:       result.ID,
+		Action:         ActionRollback,
+		Before:         newTender(tender),
+		After:          result,
+	})
+	return result, nil
 }
 
 //offers
@@ -409,17 +569,41 @@ type Bid struct {
 	AuthorId   string    `json:"authorId"`
 	Version    int32     `json:"version"`
 	CreatedAt  time.Time `json:"createdAt"`
+	// NeedsConfirmation - тендер изменен после подачи, автор должен
+	// подтвердить или отредактировать предложение
+	NeedsConfirmation bool `json:"needsConfirmation,omitempty"`
+	// Lots - цены по лотам многолотового тендера
+	Lots []BidLot `json:"lots,omitempty"`
+	// Reputation - репутация организации автора по отзывам заказчиков
+	Reputation *Reputation `json:"reputation,omitempty"`
+}
+
+func newBid(o *database.OfferFull) Bid {
+	return Bid{
+		ID:                o.ID.String(),
+		Name:              o.Name,
+		Status:            o.Status,
+		AuthorType:        o.AuthorType,
+		AuthorId:          o.Creator_ID.String(),
+		Version:           o.Version,
+		CreatedAt:         o.CreatedAt,
+		NeedsConfirmation: o.NeedsConfirmation,
+	}
 }
 
 type CreateBidParam struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	TenderId    string `json:"tenderId"`
-	AuthorType  string `json:"authorType"`
-	AuthorId    string `json:"authorId"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	TenderId    string   `json:"tenderId"`
+	AuthorType  string   `json:"authorType"`
+	AuthorId    string   `json:"authorId"`
+	Lots        []BidLot `json:"lots"`
 }
 
 func (s *Service) CreateNewBid(ctx context.Context, param CreateBidParam) (*Bid, error) {
+	ctx, span := tracing.Start(ctx, "service.CreateNewBid")
+	defer span.End()
+
 	allowValue := []string{"User", "Organization"}
 	if !utils.CheckString(param.AuthorType, allowValue) {
 		return nil, NotAllowValue
@@ -430,16 +614,19 @@ func (s *Service) CreateNewBid(ctx context.Context, param CreateBidParam) (*Bid,
 		if err == sql.ErrNoRows {
 			return nil, UserNotFound
 		}
-		log.Println("CreateNewBid: Fetching User id error -", err)
+		logger.FromContext(ctx).Error("CreateNewBid: Fetching User id error", "err", err)
 		return nil, UnknowError
 	}
+	if !user.IsActive {
+		return nil, UserDeactivated
+	}
 
 	tender, err := s.query.GetTender(ctx, param.TenderId)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, TenderNotFound
 		}
-		log.Println("CreateNewBid: GetTender err -", err)
+		logger.FromContext(ctx).Error("CreateNewBid: GetTender err", "err", err)
 		return nil, UnknowError
 	}
 
@@ -447,6 +634,11 @@ func (s *Service) CreateNewBid(ctx context.Context, param CreateBidParam) (*Bid,
 	if err == nil {
 		return nil, IsResponsible
 	}
+	terms, err := s.query.GetTenderTerms(ctx, tender.ID.String())
+	if err != nil {
+		logger.FromContext(ctx).Error("CreateNewBid: GetTenderTerms err", "err", err)
+		return nil, UnknowError
+	}
 
 	org_id, err := s.query.GetUserOrganization(ctx, user.ID.String())
 	if err != nil {
@@ -455,20 +647,53 @@ func (s *Service) CreateNewBid(ctx context.Context, param CreateBidParam) (*Bid,
 		}
 		return nil, UnknowError
 	}
+	if tender.Visibility == TenderInviteOnly {
+		if err := s.checkInvited(ctx, tender.ID.String(), org_id); err != nil {
+			return nil, err
+		}
+	}
+	if terms.RequiresQualification {
+		err = s.checkQualified(ctx, tender.OrganizationID.String(), org_id, tender.ServiceType)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if submissionClosed(terms, time.Now()) {
+		return nil, SubmissionClosed
+	}
+	offer_lots, bid_lots, err := s.checkBidLots(ctx, tender.ID.String(), param.Lots)
+	if err != nil {
+		return nil, err
+	}
 
-	bid, err := s.query.CreateOffer(ctx, database.CreateOfferParam{
+	offer := database.CreateOfferParam{
 		Name:            param.Name,
 		Description:     param.Description,
 		TenderId:        param.TenderId,
 		AuthorType:      param.AuthorType,
 		AuthorId:        param.AuthorId,
 		Organization_id: org_id,
-	})
+		Lots:            offer_lots,
+	}
+	// содержимое закрытого предложения хранится только в зашифрованном виде
+	if isSealed(terms) {
+		offer.SealedContent, err = s.sealBid(tender.ID.String(), user.ID.String(), param.Name, param.Description)
+		if err != nil {
+			if err == SealingDisabled {
+				return nil, err
+			}
+			logger.FromContext(ctx).Error("CreateNewBid: sealBid err", "err", err)
+			return nil, UnknowError
+		}
+		offer.Name, offer.Description = "", ""
+	}
+
+	bid, err := s.query.CreateOffer(ctx, offer)
 	if err != nil {
-		log.Println("CreateNewBid: CreateOffer error -", err)
+		logger.FromContext(ctx).Error("CreateNewBid: CreateOffer error", "err", err)
 		return nil, UnknowError
 	}
-	return &Bid{
+	result := &Bid{
 		ID:         bid.ID.String(),
 		Name:       bid.Name,
 		Status:     bid.Status,
@@ -476,7 +701,19 @@ func (s *Service) CreateNewBid(ctx context.Context, param CreateBidParam) (*Bid,
 		AuthorId:   bid.AuthorId.String(),
 		Version:    bid.Version,
 		CreatedAt:  bid.CreatedAt,
-	}, nil
+		Lots:       bid_lots,
+	}
+	s.audit(ctx, auditEntry{
+		ActorID:        user.ID.String(),
+		OrganizationID: org_id,
+		EntityType:     EntityBid,
+		EntityID:       result.ID,
+		Action:         ActionCreate,
+		After:          result,
+	})
+	metrics.BidsSubmitted.Inc()
+	result.Name = param.Name
+	return result, nil
 
 }
 
@@ -487,13 +724,12 @@ type ListMyBidsRequest struct {
 }
 
 func (s *Service) ListMyBids(ctx context.Context, param ListMyBidsRequest) ([]Bid, error) {
-	user_id, err := s.query.FetchUserID(ctx, param.Username)
+	ctx, span := tracing.Start(ctx, "service.ListMyBids")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, param.Username)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, UserNotFound
-		}
-		log.Println("CreateNewBid: Fetching User id error -", err)
-		return nil, UnknowError
+		return nil, err
 	}
 
 	listoffers, err := s.query.MyListOffers(ctx, &database.MyListOffersParams{
@@ -502,21 +738,25 @@ func (s *Service) ListMyBids(ctx context.Context, param ListMyBidsRequest) ([]Bi
 		Limit:      param.Limit,
 	})
 	if err != nil {
-		log.Println("ListMyBids: MyListOffers err -", err)
+		logger.FromContext(ctx).Error("ListMyBids: MyListOffers err", "err", err)
 		return nil, UnknowError
 	}
 	var bidslist []Bid
 	for _, item := range listoffers {
 		bidslist = append(bidslist, Bid{
-			ID:         item.ID.String(),
-			Name:       item.Name,
-			Status:     item.Status,
-			AuthorType: item.AuthorType,
-			AuthorId:   item.AuthorId.String(),
-			Version:    item.Version,
-			CreatedAt:  item.CreatedAt,
+			ID:                item.ID.String(),
+			Name:              item.Name,
+			Status:            item.Status,
+			AuthorType:        item.AuthorType,
+			AuthorId:          item.AuthorId.String(),
+			Version:           item.Version,
+			CreatedAt:         item.CreatedAt,
+			NeedsConfirmation: item.NeedsConfirmation,
 		})
+		s.revealBidName(ctx, &bidslist[len(bidslist)-1], item.TenderId.String(), item.SealedContent)
 	}
+	s.attachBidLots(ctx, bidslist)
+	s.attachReputation(ctx, bidslist)
 	return bidslist, nil
 }
 
@@ -528,13 +768,12 @@ type TenderListBidsRequest struct {
 }
 
 func (s *Service) TenderListBids(ctx context.Context, param TenderListBidsRequest) ([]Bid, error) {
-	user_id, err := s.query.FetchUserID(ctx, param.Username)
+	ctx, span := tracing.Start(ctx, "service.TenderListBids")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, param.Username)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, UserNotFound
-		}
-		log.Println("TenderListBids: Fetching User id error -", err)
-		return nil, UnknowError
+		return nil, err
 	}
 
 	tender, err := s.query.GetTender(ctx, param.Tender_id)
@@ -542,33 +781,46 @@ func (s *Service) TenderListBids(ctx context.Context, param TenderListBidsReques
 		if err == sql.ErrNoRows {
 			return nil, TenderNotFound
 		}
-		log.Println("TenderListBids: GetTender err -", err)
+		logger.FromContext(ctx).Error("TenderListBids: GetTender err", "err", err)
 		return nil, UnknowError
 	}
+	if err := s.checkTenderVisible(ctx, tender, user_id); err != nil {
+		return nil, err
+	}
 	org_id, _ := s.query.GetUserOrganization(ctx, user_id)
+	terms, err := s.query.GetTenderTerms(ctx, tender.ID.String())
+	if err != nil {
+		logger.FromContext(ctx).Error("TenderListBids: GetTenderTerms err", "err", err)
+		return nil, UnknowError
+	}
 
 	listoffers, err := s.query.TenderListOffers(ctx, &database.TenderListOffersParams{
 		Tender_id:       tender.ID.String(),
 		Offset:          param.Offset,
 		Limit:           param.Limit,
 		Organization_id: org_id,
+		Sealed:          isSealed(terms),
 	})
 	if err != nil {
-		log.Println("TenderListBids: MyListOffers err -", err)
+		logger.FromContext(ctx).Error("TenderListBids: MyListOffers err", "err", err)
 		return nil, UnknowError
 	}
 	var bidslist []Bid
 	for _, item := range listoffers {
 		bidslist = append(bidslist, Bid{
-			ID:         item.ID.String(),
-			Name:       item.Name,
-			Status:     item.Status,
-			AuthorType: item.AuthorType,
-			AuthorId:   item.AuthorId.String(),
-			Version:    item.Version,
-			CreatedAt:  item.CreatedAt,
+			ID:                item.ID.String(),
+			Name:              item.Name,
+			Status:            item.Status,
+			AuthorType:        item.AuthorType,
+			AuthorId:          item.AuthorId.String(),
+			Version:           item.Version,
+			CreatedAt:         item.CreatedAt,
+			NeedsConfirmation: item.NeedsConfirmation,
 		})
+		s.revealBidName(ctx, &bidslist[len(bidslist)-1], item.TenderId.String(), item.SealedContent)
 	}
+	s.attachBidLots(ctx, bidslist)
+	s.attachReputation(ctx, bidslist)
 	return bidslist, nil
 }
 
@@ -578,13 +830,12 @@ type GetBidStatus struct {
 }
 
 func (s *Service) GetBidStatus(ctx context.Context, param GetBidStatus) (string, error) {
-	user_id, err := s.query.FetchUserID(ctx, param.Username)
+	ctx, span := tracing.Start(ctx, "service.GetBidStatus")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, param.Username)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return "", UserNotFound
-		}
-		log.Println("GetBidStatus: Fetching User id error -", err)
-		return "", UnknowError
+		return "", err
 	}
 
 	offer, err := s.query.GetOffer(ctx, param.BidID)
@@ -592,7 +843,7 @@ func (s *Service) GetBidStatus(ctx context.Context, param GetBidStatus) (string,
 		if err == sql.ErrNoRows {
 			return "", BidNotFound
 		}
-		log.Println("GetBidStatus: GetOffer error -", err)
+		logger.FromContext(ctx).Error("GetBidStatus: GetOffer error", "err", err)
 		return "", UnknowError
 
 	}
@@ -612,14 +863,12 @@ type ChangeBidStatus struct {
 }
 
 func (s *Service) ChangeBidStatus(ctx context.Context, param ChangeBidStatus) (*Bid, error) {
+	ctx, span := tracing.Start(ctx, "service.ChangeBidStatus")
+	defer span.End()
 
-	user_id, err := s.query.FetchUserID(ctx, param.Username)
+	user_id, err := s.fetchUserID(ctx, param.Username)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, UserNotFound
-		}
-		log.Println("ChangeBidStatus: Fetching User id error -", err)
-		return nil, UnknowError
+		return nil, err
 	}
 
 	offer, err := s.query.GetOffer(ctx, param.BidID)
@@ -627,7 +876,7 @@ func (s *Service) ChangeBidStatus(ctx context.Context, param ChangeBidStatus) (*
 		if err == sql.ErrNoRows {
 			return nil, BidNotFound
 		}
-		log.Println("ChangeBidStatus: GetOffer error -", err)
+		logger.FromContext(ctx).Error("ChangeBidStatus: GetOffer error", "err", err)
 		return nil, UnknowError
 
 	}
@@ -646,10 +895,10 @@ func (s *Service) ChangeBidStatus(ctx context.Context, param ChangeBidStatus) (*
 		if err == sql.ErrNoRows {
 			return nil, BidNotFound
 		}
-		log.Println("ChangeBidStatus: ChangeTenderStatus err -", err)
+		logger.FromContext(ctx).Error("ChangeBidStatus: ChangeTenderStatus err", "err", err)
 		return nil, UnknowError
 	}
-	return &Bid{
+	result := &Bid{
 		ID:         bid.ID.String(),
 		Name:       bid.Name,
 		Status:     bid.Status,
@@ -657,7 +906,18 @@ func (s *Service) ChangeBidStatus(ctx context.Context, param ChangeBidStatus) (*
 		AuthorId:   bid.AuthorId.String(),
 		Version:    bid.Version,
 		CreatedAt:  bid.CreatedAt,
-	}, nil
+	}
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: offer.Organization_ID.String(),
+		EntityType:     EntityBid,
+		EntityID:       result.ID,
+		Action:         ActionChangeStatus,
+		Before:         newBid(offer),
+		After:          result,
+	})
+	s.revealBidName(ctx, result, offer.Tender_ID.String(), offer.SealedContent)
+	return result, nil
 }
 
 type EditBidRequest struct {
@@ -668,20 +928,19 @@ type EditBidRequest struct {
 }
 
 func (s *Service) EditBid(ctx context.Context, params EditBidRequest) (*Bid, error) {
-	user_id, err := s.query.FetchUserID(ctx, params.Username)
+	ctx, span := tracing.Start(ctx, "service.EditBid")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, UserNotFound
-		}
-		log.Println("EditBid: FetchUserID err -", err)
-		return nil, UnknowError
+		return nil, err
 	}
 	bid, err := s.query.GetOffer(ctx, params.Bid_id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, BidNotFound
 		}
-		log.Println("EditBid: GetOffer err -", err)
+		logger.FromContext(ctx).Error("EditBid: GetOffer err", "err", err)
 		return nil, UnknowError
 	}
 
@@ -689,6 +948,17 @@ func (s *Service) EditBid(ctx context.Context, params EditBidRequest) (*Bid, err
 	if err != nil {
 		return nil, err
 	}
+	if len(bid.SealedContent) > 0 {
+		return s.editSealedBid(ctx, user_id, bid, params)
+	}
+	terms, err := s.query.GetTenderTerms(ctx, bid.Tender_ID.String())
+	if err != nil {
+		logger.FromContext(ctx).Error("EditBid: GetTenderTerms err", "err", err)
+		return nil, UnknowError
+	}
+	if submissionClosed(terms, time.Now()) {
+		return nil, SubmissionClosed
+	}
 	new_bid, err := s.query.EditOfferWithTX(ctx, database.EditOfferWithTxParam{
 		ChangeOfferParam: database.OfferChangeParam{
 			Bid_id:      bid.ID.String(),
@@ -708,10 +978,10 @@ func (s *Service) EditBid(ctx context.Context, params EditBidRequest) (*Bid, err
 		if err == sql.ErrNoRows {
 			return nil, BidNotFound
 		}
-		log.Println("EditBid: EditOfferWithTX err -", err)
+		logger.FromContext(ctx).Error("EditBid: EditOfferWithTX err", "err", err)
 		return nil, UnknowError
 	}
-	return &Bid{
+	result := &Bid{
 		ID:         new_bid.ID.String(),
 		Name:       new_bid.Name,
 		Status:     new_bid.Status,
@@ -719,7 +989,17 @@ func (s *Service) EditBid(ctx context.Context, params EditBidRequest) (*Bid, err
 		AuthorId:   new_bid.AuthorId.String(),
 		Version:    new_bid.Version,
 		CreatedAt:  new_bid.CreatedAt,
-	}, nil
+	}
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: bid.Organization_ID.String(),
+		EntityType:     EntityBid,
+		EntityID:       result.ID,
+		Action:         ActionEdit,
+		Before:         newBid(bid),
+		After:          result,
+	})
+	return result, nil
 }
 
 type RollbackOfferRequest struct {
@@ -729,22 +1009,25 @@ type RollbackOfferRequest struct {
 }
 
 func (s *Service) RollbackOffer(ctx context.Context, params RollbackOfferRequest) (*Bid, error) {
-	user_id, err := s.query.FetchUserID(ctx, params.Username)
+	ctx, span := tracing.Start(ctx, "service.RollbackOffer")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, UserNotFound
-		}
-		log.Println("RollbackOffer: FetchUserID err -", err)
-		return nil, UnknowError
+		return nil, err
 	}
 	bid, err := s.query.GetOffer(ctx, params.Offer_id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, BidNotFound
 		}
-		log.Println("RollbackOffer: GetOffer err -", err)
+		logger.FromContext(ctx).Error("RollbackOffer: GetOffer err", "err", err)
 		return nil, UnknowError
 	}
+	// у невскрытого закрытого предложения нет истории в открытом виде
+	if len(bid.SealedContent) > 0 {
+		return nil, BidsSealed
+	}
 
 	err = s.isResponsibleUser(ctx, bid.Organization_ID.String(), user_id)
 	if err != nil {
@@ -756,7 +1039,7 @@ func (s *Service) RollbackOffer(ctx context.Context, params RollbackOfferRequest
 		if err == sql.ErrNoRows {
 			return nil, OfferHistoryNotFound
 		}
-		log.Println("RollbackOffer: GetOfferHistory err -", err)
+		logger.FromContext(ctx).Error("RollbackOffer: GetOfferHistory err", "err", err)
 		return nil, UnknowError
 	}
 
@@ -778,10 +1061,10 @@ func (s *Service) RollbackOffer(ctx context.Context, params RollbackOfferRequest
 		if err == sql.ErrNoRows {
 			return nil, TenderNotFound
 		}
-		log.Println("RollbackOffer: EditOfferWithTX err -", err)
+		logger.FromContext(ctx).Error("RollbackOffer: EditOfferWithTX err", "err", err)
 		return nil, UnknowError
 	}
-	return &Bid{
+	result := &Bid{
 		ID:         new_tender.ID.String(),
 		Name:       new_tender.Name,
 		Status:     new_tender.Status,
@@ -789,50 +1072,92 @@ func (s *Service) RollbackOffer(ctx context.Context, params RollbackOfferRequest
 		AuthorId:   new_tender.AuthorId.String(),
 		Version:    new_tender.Version,
 		CreatedAt:  new_tender.CreatedAt,
-	}, nil
+	}
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: bid.Organization_ID.String(),
+		EntityType:     EntityBid,
+		EntityID:       result.ID,
+		Action:         ActionRollback,
+		Before:         newBid(bid),
+		After:          result,
+	})
+	return result, nil
 }
 
 type DecisionRequest struct {
 	Username string
 	Bid_id   string
 	Desicion string
+	// Lot_id - лот многолотового тендера, по которому принимается решение
+	Lot_id string
+	// ConflictDeclared - голосующий сам заявил о конфликте интересов
+	ConflictDeclared bool
+	ConflictComment  string
 }
 
 func (s *Service) DecisionSubmit(ctx context.Context, params DecisionRequest) (*Bid, error) {
-	user_id, err := s.query.FetchUserID(ctx, params.Username)
+	ctx, span := tracing.Start(ctx, "service.DecisionSubmit")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, UserNotFound
-		}
-		log.Println("Decision: FetchUserID err -", err)
-		return nil, UnknowError
+		return nil, err
 	}
 	bid, err := s.query.GetOffer(ctx, params.Bid_id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, BidNotFound
 		}
-		log.Println("Decision: GetOffer err -", err)
+		logger.FromContext(ctx).Error("Decision: GetOffer err", "err", err)
 		return nil, UnknowError
 	}
+	if len(bid.SealedContent) > 0 {
+		return nil, BidsSealed
+	}
 	tender, err := s.query.GetTender(ctx, bid.Tender_ID.String())
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, TenderNotFound
 		}
-		log.Println("Decision: GetTender err -", err)
+		logger.FromContext(ctx).Error("Decision: GetTender err", "err", err)
 		return nil, UnknowError
 	}
 	err = s.isResponsibleUser(ctx, tender.OrganizationID.String(), user_id)
 	if err != nil {
 		return nil, err
 	}
+	if tender.BiddingMode == BiddingModeAuction {
+		if err := s.requireAuctionClosed(ctx, tender.ID.String()); err != nil {
+			return nil, err
+		}
+	}
+	lots, err := s.query.ListLots(ctx, tender.ID.String())
+	if err != nil {
+		logger.FromContext(ctx).Error("Decision: ListLots err", "err", err)
+		return nil, UnknowError
+	}
+	conflict, err := s.checkConflict(ctx, user_id, tender, bid, params)
+	if err != nil {
+		return nil, err
+	}
+	if conflict.Blocked {
+		return nil, s.blockDecision(ctx, user_id, tender, bid, lots, params, conflict)
+	}
+	// отклонение без лота снимает предложение целиком
+	if len(lots) > 0 && (params.Lot_id != "" || params.Desicion == "Approved") {
+		return s.lotDecision(ctx, user_id, tender, bid, params, conflict)
+	}
+	if len(lots) == 0 && params.Lot_id != "" {
+		return nil, TenderHasNoLots
+	}
 
 	if params.Desicion == "Rejected" {
-		err = s.query.NewDecision(ctx, database.NewDecisionParams{
+		err = s.recordDecision(ctx, tender, database.NewDecisionParams{
 			Offer_id: bid.ID.String(),
 			User_id:  user_id,
 			Decision: "Rejected",
+			Conflict: conflict,
 		})
 		if err != nil {
 			return nil, UnknowError
@@ -842,20 +1167,32 @@ func (s *Service) DecisionSubmit(ctx context.Context, params DecisionRequest) (*
 			if err == sql.ErrNoRows {
 				return nil, BidNotFound
 			}
-			log.Println("Decision: ChangeOfferStatus err -", err)
+			logger.FromContext(ctx).Error("Decision: ChangeOfferStatus err", "err", err)
 			return nil, UnknowError
 		}
+		s.audit(ctx, auditEntry{
+			ActorID:        user_id,
+			OrganizationID: tender.OrganizationID.String(),
+			EntityType:     EntityBid,
+			EntityID:       bid.ID.String(),
+			Action:         ActionDecision,
+			Before:         newBid(bid),
+			After:          map[string]string{"decision": "Rejected", "status": "Canceled"},
+		})
+		metrics.Decisions.Inc("Rejected")
 	}
 
 	if params.Desicion == "Approved" {
-		err = s.query.NewDecision(ctx, database.NewDecisionParams{
+		err = s.recordDecision(ctx, tender, database.NewDecisionParams{
 			Offer_id: bid.ID.String(),
 			User_id:  user_id,
 			Decision: "Approved",
+			Conflict: conflict,
 		})
 		if err != nil {
 			return nil, UnknowError
 		}
+		metrics.Decisions.Inc("Approved")
 
 		approved_count, err := s.query.CountDecision(ctx, bid.ID.String())
 		if err != nil {
@@ -873,12 +1210,13 @@ func (s *Service) DecisionSubmit(ctx context.Context, params DecisionRequest) (*
 		}
 		kvorum := utils.Min(3, int(userCount))
 		if int(approved_count) >= kvorum {
+			metrics.QuorumReached.Inc()
 			new_offer, err := s.query.ChangeOfferStatus(ctx, bid.ID.String(), "Approved")
 			if err != nil {
 				if err == sql.ErrNoRows {
 					return nil, BidNotFound
 				}
-				log.Println("Decision: ChangeOfferStatus kvorum err -", err)
+				logger.FromContext(ctx).Error("Decision: ChangeOfferStatus kvorum err", "err", err)
 				return nil, UnknowError
 			}
 			_, err = s.query.ChangeTenderStatus(ctx, tender.ID.String(), "Closed")
@@ -886,10 +1224,10 @@ func (s *Service) DecisionSubmit(ctx context.Context, params DecisionRequest) (*
 				if err == sql.ErrNoRows {
 					return nil, TenderNotFound
 				}
-				log.Println("Decision: ChangeOfferStatus kvorum err -", err)
+				logger.FromContext(ctx).Error("Decision: ChangeOfferStatus kvorum err", "err", err)
 				return nil, UnknowError
 			}
-			return &Bid{
+			result := &Bid{
 				ID:         new_offer.ID.String(),
 				Name:       new_offer.Name,
 				Status:     new_offer.Status,
@@ -897,17 +1235,38 @@ func (s *Service) DecisionSubmit(ctx context.Context, params DecisionRequest) (*
 				AuthorId:   new_offer.AuthorId.String(),
 				Version:    new_offer.Version,
 				CreatedAt:  new_offer.CreatedAt,
-			}, nil
+			}
+			s.audit(ctx, auditEntry{
+				ActorID:        user_id,
+				OrganizationID: tender.OrganizationID.String(),
+				EntityType:     EntityBid,
+				EntityID:       result.ID,
+				Action:         ActionDecision,
+				Before:         newBid(bid),
+				After:          result,
+			})
+			s.audit(ctx, auditEntry{
+				ActorID:        user_id,
+				OrganizationID: tender.OrganizationID.String(),
+				EntityType:     EntityTender,
+				EntityID:       tender.ID.String(),
+				Action:         ActionChangeStatus,
+				Before:         newTender(tender),
+				After:          map[string]string{"status": "Closed"},
+			})
+			return result, nil
 		} else {
-			return &Bid{
-				ID:         bid.ID.String(),
-				Name:       bid.Name,
-				Status:     bid.Status,
-				AuthorType: bid.AuthorType,
-				AuthorId:   bid.AuthorId.String(),
-				Version:    bid.Version,
-				CreatedAt:  bid.CreatedAt,
-			}, nil
+			result := newBid(bid)
+			s.audit(ctx, auditEntry{
+				ActorID:        user_id,
+				OrganizationID: tender.OrganizationID.String(),
+				EntityType:     EntityBid,
+				EntityID:       result.ID,
+				Action:         ActionDecision,
+				Before:         result,
+				After:          map[string]string{"decision": "Approved", "status": result.Status},
+			})
+			return &result, nil
 		}
 
 	}
@@ -918,47 +1277,59 @@ type NewFeedBackRequest struct {
 	Bid_id   string
 	Content  string
 	Username string
+	// Ratings - оценки предложения, nil - отзыв без оценок
+	Ratings *Ratings
 }
 
 func (s *Service) NewFeedBack(ctx context.Context, params NewFeedBackRequest) (*Bid, error) {
-	user_id, err := s.query.FetchUserID(ctx, params.Username)
+	ctx, span := tracing.Start(ctx, "service.NewFeedBack")
+	defer span.End()
+
+	ratings, err := params.Ratings.toDB()
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, UserNotFound
-		}
-		log.Println("NewFeedBack: FetchUserID err -", err)
-		return nil, UnknowError
+		return nil, err
+	}
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
 	}
 	bid, err := s.query.GetOffer(ctx, params.Bid_id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, BidNotFound
 		}
-		log.Println("NewFeedBack: GetOffer err -", err)
+		logger.FromContext(ctx).Error("NewFeedBack: GetOffer err", "err", err)
 		return nil, UnknowError
 	}
+	if len(bid.SealedContent) > 0 {
+		return nil, BidsSealed
+	}
 	tender, err := s.query.GetTender(ctx, bid.Tender_ID.String())
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, TenderNotFound
 		}
-		log.Println("NewFeedBack: GetTender err -", err)
+		logger.FromContext(ctx).Error("NewFeedBack: GetTender err", "err", err)
 		return nil, UnknowError
 	}
 	err = s.isResponsibleUser(ctx, tender.OrganizationID.String(), user_id)
 	if err != nil {
 		return nil, err
 	}
-	err = s.query.NewReview(ctx, database.NewReviewParams{
-		Offer_id: bid.ID.String(),
-		Content:  params.Content,
-		User_id:  user_id,
+	review_id, err := s.query.NewReview(ctx, database.NewReviewParams{
+		Offer_id:        bid.ID.String(),
+		Content:         params.Content,
+		User_id:         user_id,
+		Organization_id: tender.OrganizationID.String(),
+		Ratings:         ratings,
+		Author_id:       bid.Creator_ID.String(),
+		Notification:    fmt.Sprintf("На ваше предложение «%s» оставлен отзыв", bid.Name),
 	})
 	if err != nil {
-		log.Println("NewFeedBack: NewReview err -", err)
+		logger.FromContext(ctx).Error("NewFeedBack: NewReview err", "err", err)
 		return nil, UnknowError
 	}
-	return &Bid{
+	result := &Bid{
 		ID:         bid.ID.String(),
 		Name:       bid.Name,
 		Status:     bid.Status,
@@ -966,43 +1337,56 @@ func (s *Service) NewFeedBack(ctx context.Context, params NewFeedBackRequest) (*
 		AuthorId:   bid.AuthorId.String(),
 		Version:    bid.Version,
 		CreatedAt:  bid.CreatedAt,
-	}, nil
-}
-
-type ReviewResponse struct {
-	Id          string `json:"id"`
-	Description string `json:"description"`
-	CreatedAt   string `json:"createdAt"`
+	}
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: tender.OrganizationID.String(),
+		EntityType:     EntityReview,
+		EntityID:       review_id,
+		Action:         ActionCreate,
+		After: map[string]interface{}{
+			"id":      review_id,
+			"bidId":   result.ID,
+			"content": params.Content,
+			"ratings": params.Ratings,
+		},
+	})
+	return result, nil
 }
 
 type OfferAuthorReviewsRequest struct {
 	Tender_ID         string
 	AuthorUsername    string
 	RequesterUsername string
-	Limit             int32
-	Offset            int32
+	// ByOrganization - отзывы на предложения всей организации автора
+	ByOrganization bool
+	// Reviewer_org_id - только отзывы этой организации-заказчика
+	Reviewer_org_id string
+	From            *time.Time
+	To              *time.Time
+	MinRating       int16
+	MaxRating       int16
+	Limit           int32
+	Offset          int32
 }
 
+// OfferAuthorReviews - отзывы на все прошлые предложения автора (или его
+// организации), подавшего предложение в тендер запрашивающего
 func (s *Service) OfferAuthorReviews(ctx context.Context, params OfferAuthorReviewsRequest) ([]ReviewResponse, error) {
+	ctx, span := tracing.Start(ctx, "service.OfferAuthorReviews")
+	defer span.End()
+
 	if params.Limit <= 0 {
 		params.Limit = 5
 	}
 
-	authorUser_id, err := s.query.FetchUserID(ctx, params.AuthorUsername)
+	authorUser_id, err := s.fetchUserID(ctx, params.AuthorUsername)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, UserNotFound
-		}
-		log.Println("OfferAuthorReviews: FetchUserID authorUser_id err -", err)
-		return nil, UnknowError
+		return nil, err
 	}
-	requesterUser_id, err := s.query.FetchUserID(ctx, params.RequesterUsername)
+	requesterUser_id, err := s.fetchUserID(ctx, params.RequesterUsername)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, UserNotFound
-		}
-		log.Println("OfferAuthorReviews: FetchUserID requesterUser_id err -", err)
-		return nil, UnknowError
+		return nil, err
 	}
 
 	tender, err := s.query.GetTender(ctx, params.Tender_ID)
@@ -1010,7 +1394,7 @@ func (s *Service) OfferAuthorReviews(ctx context.Context, params OfferAuthorRevi
 		if err == sql.ErrNoRows {
 			return nil, TenderNotFound
 		}
-		log.Println("OfferAuthorReviews: GetTender err -", err)
+		logger.FromContext(ctx).Error("OfferAuthorReviews: GetTender err", "err", err)
 		return nil, UnknowError
 	}
 	err = s.isResponsibleUser(ctx, tender.OrganizationID.String(), requesterUser_id)
@@ -1023,21 +1407,35 @@ func (s *Service) OfferAuthorReviews(ctx context.Context, params OfferAuthorRevi
 		if err == sql.ErrNoRows {
 			return nil, BidNotFound
 		}
-		log.Println("OfferAuthorReviews: GetOfferByAuthor err -", err)
+		logger.FromContext(ctx).Error("OfferAuthorReviews: GetOfferByAuthor err", "err", err)
 		return nil, UnknowError
 	}
-	reviews_list, err := s.query.ListReviw(ctx, database.ListReviewParam{
-		Offer_id: offer.ID.String(),
-		Limit:    params.Limit,
-		Offset:   params.Offset,
-	})
-	var reviews []ReviewResponse
-	for _, review := range reviews_list {
-		reviews = append(reviews, ReviewResponse{
-			Id:          review.ID,
-			Description: review.Description,
-			CreatedAt:   review.CreatedAt,
-		})
+	filter := database.ListAuthorReviewsParams{
+		Author_id:       authorUser_id,
+		Reviewer_org_id: params.Reviewer_org_id,
+		From:            params.From,
+		To:              params.To,
+		MinRating:       params.MinRating,
+		MaxRating:       params.MaxRating,
+		Offset:          params.Offset,
+		Limit:           params.Limit,
+	}
+	if params.ByOrganization {
+		bid, err := s.query.GetOffer(ctx, offer.ID.String())
+		if err != nil {
+			logger.FromContext(ctx).Error("OfferAuthorReviews: GetOffer err", "err", err)
+			return nil, UnknowError
+		}
+		filter.Organization_id = bid.Organization_ID.String()
+	}
+	reviews_list, err := s.query.ListAuthorReviews(ctx, filter)
+	if err != nil {
+		logger.FromContext(ctx).Error("OfferAuthorReviews: ListAuthorReviews err", "err", err)
+		return nil, UnknowError
+	}
+	reviews := []ReviewResponse{}
+	for i := range reviews_list {
+		reviews = append(reviews, newReviewResponse(&reviews_list[i]))
 	}
 
 	return reviews, nil
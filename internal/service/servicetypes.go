@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"tender_service/internal/database"
+	"tender_service/internal/logger"
+	"tender_service/internal/tracing"
+)
+
+var (
+	ServiceTypeNotFound = fmt.Errorf("Неизвестный тип услуг")
+	ServiceTypeExists   = fmt.Errorf("Тип услуг с таким кодом уже существует")
+	ServiceTypeCycle    = fmt.Errorf("Тип услуг нельзя вложить в самого себя или своего потомка")
+)
+
+// DefaultLanguage - язык названия, если запрошенного нет в справочнике
+const DefaultLanguage = "ru"
+
+type ServiceType struct {
+	Code     string            `json:"code"`
+	Parent   string            `json:"parent,omitempty"`
+	Name     string            `json:"name"`
+	Names    map[string]string `json:"names"`
+	IsActive bool              `json:"isActive"`
+	Children []ServiceType     `json:"children,omitempty"`
+}
+
+func newServiceType(ctx context.Context, t database.ServiceType, lang string) ServiceType {
+	names := map[string]string{}
+	if err := json.Unmarshal([]byte(t.Names), &names); err != nil {
+		logger.FromContext(ctx).Error("newServiceType: Unmarshal err", "err", err)
+	}
+	name := names[lang]
+	if name == "" {
+		name = names[DefaultLanguage]
+	}
+	if name == "" {
+		name = t.Code
+	}
+	return ServiceType{
+		Code:     t.Code,
+		Parent:   t.ParentCode,
+		Name:     name,
+		Names:    names,
+		IsActive: t.IsActive,
+	}
+}
+
+// resolveServiceType возвращает код типа из справочника в каноническом
+// регистре. Неактивные типы для новых тендеров недоступны.
+func (s *Service) resolveServiceType(ctx context.Context, code string) (string, error) {
+	service_type, err := s.query.GetServiceType(ctx, code)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ServiceTypeNotFound
+		}
+		logger.FromContext(ctx).Error("resolveServiceType: GetServiceType err", "err", err)
+		return "", UnknowError
+	}
+	if !service_type.IsActive {
+		return "", ServiceTypeNotFound
+	}
+	return service_type.Code, nil
+}
+
+type ListServiceTypesRequest struct {
+	Parent          string
+	Lang            string
+	IncludeInactive bool
+}
+
+func (s *Service) ListServiceTypes(ctx context.Context, params ListServiceTypesRequest) ([]ServiceType, error) {
+	ctx, span := tracing.Start(ctx, "service.ListServiceTypes")
+	defer span.End()
+
+	types, err := s.query.ListServiceTypes(ctx, database.ListServiceTypesParams{
+		Parent:          params.Parent,
+		IncludeInactive: params.IncludeInactive,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("ListServiceTypes: ListServiceTypes err", "err", err)
+		return nil, UnknowError
+	}
+	result := []ServiceType{}
+	for _, item := range types {
+		result = append(result, newServiceType(ctx, item, params.Lang))
+	}
+	return result, nil
+}
+
+// GetServiceType возвращает тип вместе с прямыми потомками
+func (s *Service) GetServiceType(ctx context.Context, code, lang string) (*ServiceType, error) {
+	ctx, span := tracing.Start(ctx, "service.GetServiceType")
+	defer span.End()
+
+	service_type, err := s.query.GetServiceType(ctx, code)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ServiceTypeNotFound
+		}
+		logger.FromContext(ctx).Error("GetServiceType: GetServiceType err", "err", err)
+		return nil, UnknowError
+	}
+	result := newServiceType(ctx, *service_type, lang)
+	children, err := s.query.ListServiceTypes(ctx, database.ListServiceTypesParams{Parent: service_type.Code})
+	if err != nil {
+		logger.FromContext(ctx).Error("GetServiceType: ListServiceTypes err", "err", err)
+		return nil, UnknowError
+	}
+	for _, item := range children {
+		result.Children = append(result.Children, newServiceType(ctx, item, lang))
+	}
+	return &result, nil
+}
+
+// ServiceTypeRequest - при редактировании nil поля не меняются,
+// пустой Parent делает тип корневым
+type ServiceTypeRequest struct {
+	Username string
+	Code     string
+	Parent   *string
+	Names    map[string]string
+	IsActive *bool
+}
+
+// checkServiceTypeParent приводит код родителя к каноническому и проверяет,
+// что он не совпадает с самим типом и не является его потомком
+func (s *Service) checkServiceTypeParent(ctx context.Context, code, parent string) (string, error) {
+	if parent == "" {
+		return "", nil
+	}
+	parent_type, err := s.query.GetServiceType(ctx, parent)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ServiceTypeNotFound
+		}
+		logger.FromContext(ctx).Error("checkServiceTypeParent: GetServiceType err", "err", err)
+		return "", UnknowError
+	}
+	descendants, err := s.query.ExpandServiceTypes(ctx, []string{code})
+	if err != nil {
+		logger.FromContext(ctx).Error("checkServiceTypeParent: ExpandServiceTypes err", "err", err)
+		return "", UnknowError
+	}
+	for _, item := range descendants {
+		if item == parent_type.Code {
+			return "", ServiceTypeCycle
+		}
+	}
+	return parent_type.Code, nil
+}
+
+func (s *Service) CreateServiceType(ctx context.Context, params ServiceTypeRequest) (*ServiceType, error) {
+	ctx, span := tracing.Start(ctx, "service.CreateServiceType")
+	defer span.End()
+
+	admin, err := s.requireAdmin(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	var parent string
+	if params.Parent != nil {
+		parent, err = s.checkServiceTypeParent(ctx, params.Code, *params.Parent)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if params.Names == nil {
+		params.Names = map[string]string{}
+	}
+	names, _ := json.Marshal(params.Names)
+	created, err := s.query.CreateServiceType(ctx, database.ServiceTypeParams{
+		Code:       params.Code,
+		ParentCode: parent,
+		Names:      string(names),
+		IsActive:   params.IsActive == nil || *params.IsActive,
+	})
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ServiceTypeExists
+		}
+		logger.FromContext(ctx).Error("CreateServiceType: CreateServiceType err", "err", err)
+		return nil, UnknowError
+	}
+	result := newServiceType(ctx, *created, DefaultLanguage)
+	s.audit(ctx, auditEntry{
+		ActorID:    admin.ID.String(),
+		EntityType: EntityServiceType,
+		EntityID:   result.Code,
+		Action:     ActionCreate,
+		After:      result,
+	})
+	return &result, nil
+}
+
+func (s *Service) EditServiceType(ctx context.Context, params ServiceTypeRequest) (*ServiceType, error) {
+	ctx, span := tracing.Start(ctx, "service.EditServiceType")
+	defer span.End()
+
+	admin, err := s.requireAdmin(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.query.GetServiceType(ctx, params.Code)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ServiceTypeNotFound
+		}
+		logger.FromContext(ctx).Error("EditServiceType: GetServiceType err", "err", err)
+		return nil, UnknowError
+	}
+	update := database.ServiceTypeParams{
+		Code:       current.Code,
+		ParentCode: current.ParentCode,
+		Names:      current.Names,
+		IsActive:   current.IsActive,
+	}
+	if params.Parent != nil {
+		update.ParentCode, err = s.checkServiceTypeParent(ctx, current.Code, *params.Parent)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if params.Names != nil {
+		names, _ := json.Marshal(params.Names)
+		update.Names = string(names)
+	}
+	if params.IsActive != nil {
+		update.IsActive = *params.IsActive
+	}
+	updated, err := s.query.UpdateServiceType(ctx, update)
+	if err != nil {
+		logger.FromContext(ctx).Error("EditServiceType: UpdateServiceType err", "err", err)
+		return nil, UnknowError
+	}
+	result := newServiceType(ctx, *updated, DefaultLanguage)
+	s.audit(ctx, auditEntry{
+		ActorID:    admin.ID.String(),
+		EntityType: EntityServiceType,
+		EntityID:   result.Code,
+		Action:     ActionEdit,
+		Before:     newServiceType(ctx, *current, DefaultLanguage),
+		After:      result,
+	})
+	return &result, nil
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestServiceTypeHierarchy(t *testing.T) {
+	e := newTestEnv(t)
+	e.admin("root")
+	e.org("Заказчик", "buyer")
+
+	if _, err := e.s.CreateServiceType(e.ctx, ServiceTypeRequest{Username: "buyer", Code: "Roads"}); err != IsNotAdmin {
+		t.Errorf("create by non-admin err = %v, want IsNotAdmin", err)
+	}
+	roads, err := e.s.CreateServiceType(e.ctx, ServiceTypeRequest{
+		Username: "root",
+		Code:     "Roads",
+		Parent:   strPtr("construction"),
+		Names:    map[string]string{"ru": "Дороги", "en": "Roads"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if roads.Parent != "Construction" || roads.Name != "Дороги" || !roads.IsActive {
+		t.Errorf("created = %+v", roads)
+	}
+	if _, err := e.s.CreateServiceType(e.ctx, ServiceTypeRequest{Username: "root", Code: "ROADS"}); err != ServiceTypeExists {
+		t.Errorf("duplicate in other case err = %v, want ServiceTypeExists", err)
+	}
+	if _, err := e.s.CreateServiceType(e.ctx, ServiceTypeRequest{Username: "root", Code: "Bridges", Parent: strPtr("Space")}); err != ServiceTypeNotFound {
+		t.Errorf("unknown parent err = %v, want ServiceTypeNotFound", err)
+	}
+	if _, err := e.s.EditServiceType(e.ctx, ServiceTypeRequest{Username: "root", Code: "Construction", Parent: strPtr("roads")}); err != ServiceTypeCycle {
+		t.Errorf("cycle err = %v, want ServiceTypeCycle", err)
+	}
+
+	construction, err := e.s.GetServiceType(e.ctx, "CONSTRUCTION", "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if construction.Code != "Construction" || construction.Name != "Construction" ||
+		len(construction.Children) != 1 || construction.Children[0].Name != "Roads" {
+		t.Errorf("construction = %+v", construction)
+	}
+
+	// фильтр по родителю находит тендеры вложенных типов
+	org_id := e.org("Дорожники", "builder")
+	road := e.tender(TenderParams{OrganizationId: org_id, CreatorUsername: "builder", ServiceType: "roads"})
+	if road.ServiceType != "Roads" {
+		t.Errorf("tender service type = %q, want Roads", road.ServiceType)
+	}
+	e.tender(TenderParams{OrganizationId: org_id, CreatorUsername: "builder", ServiceType: "Delivery"})
+	tenders, err := e.s.FetchPublishedTenders(e.ctx, ListTendersRequest{Service_type: []string{"Construction"}, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tenders) != 1 || tenders[0].ID != road.ID {
+		t.Errorf("tenders by parent = %+v", tenders)
+	}
+
+	inactive := false
+	if _, err := e.s.EditServiceType(e.ctx, ServiceTypeRequest{Username: "root", Code: "Roads", IsActive: &inactive}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.s.CreateNewTender(e.ctx, TenderParams{
+		Name: "Дорога", Description: "Асфальт", ServiceType: "Roads", Status: "Created",
+		OrganizationId: org_id, CreatorUsername: "builder",
+	}); err != ServiceTypeNotFound {
+		t.Errorf("inactive type err = %v, want ServiceTypeNotFound", err)
+	}
+	listed, err := e.s.ListServiceTypes(e.ctx, ListServiceTypesRequest{Parent: "Construction"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 0 {
+		t.Errorf("active children = %+v, want none", listed)
+	}
+	listed, err = e.s.ListServiceTypes(e.ctx, ListServiceTypesRequest{Parent: "Construction", IncludeInactive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 || listed[0].IsActive {
+		t.Errorf("all children = %+v", listed)
+	}
+	if got := e.audited(EntityServiceType, "Roads"); !reflect.DeepEqual(got, []string{ActionCreate, ActionEdit}) {
+		t.Errorf("audit = %v, want create and edit", got)
+	}
+}
+
+func TestRollbackResolvesServiceType(t *testing.T) {
+	e := newTestEnv(t)
+	org_id := e.org("Заказчик", "buyer")
+	tender := e.tender(TenderParams{OrganizationId: org_id, CreatorUsername: "buyer", Status: "Created"})
+	edit := func(service_type string) {
+		t.Helper()
+		if _, err := e.s.EditTender(e.ctx, EditTenderRequest{Username: "buyer", Tender_id: tender.ID, Service_type: service_type}); err != nil {
+			t.Fatalf("EditTender %s: %v", service_type, err)
+		}
+	}
+	edit("Construction")
+	edit("Manufacture")
+
+	// версия 1 сохранена до справочника в нижнем регистре
+	e.exec(`UPDATE tender_history SET service_type = 'delivery' WHERE tender_id = $1 AND version = 1`, tender.ID)
+	rolled, err := e.s.RollbackTender(e.ctx, RollbackTenderRequest{Username: "buyer", Tender_id: tender.ID, Version: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rolled.ServiceType != "Delivery" || rolled.Version != 4 {
+		t.Errorf("rolled back = %+v", rolled)
+	}
+
+	// к версии с отключенным типом откатиться нельзя
+	e.exec(`UPDATE service_type SET is_active = FALSE WHERE code = 'Construction'`)
+	if _, err := e.s.RollbackTender(e.ctx, RollbackTenderRequest{Username: "buyer", Tender_id: tender.ID, Version: 2}); err != ServiceTypeNotFound {
+		t.Errorf("rollback to inactive type err = %v, want ServiceTypeNotFound", err)
+	}
+}
@@ -0,0 +1,436 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"tender_service/internal/database"
+	"tender_service/internal/logger"
+	"tender_service/internal/metrics"
+	"tender_service/internal/tracing"
+	"time"
+)
+
+// Criterion - критерий оценки предложений и его вес в процентах
+type Criterion struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+}
+
+type Template struct {
+	ID                 string      `json:"id"`
+	OrganizationID     string      `json:"organizationId"`
+	Name               string      `json:"name"`
+	Description        string      `json:"description"`
+	ServiceType        string      `json:"serviceType"`
+	EvaluationCriteria []Criterion `json:"evaluationCriteria"`
+	SubmissionDays     *int32      `json:"submissionDays,omitempty"`
+	DecisionDays       *int32      `json:"decisionDays,omitempty"`
+	CreatedAt          time.Time   `json:"createdAt"`
+	UpdatedAt          time.Time   `json:"updatedAt"`
+}
+
+func parseCriteria(ctx context.Context, data string) []Criterion {
+	criteria := []Criterion{}
+	if data == "" {
+		return criteria
+	}
+	if err := json.Unmarshal([]byte(data), &criteria); err != nil {
+		logger.FromContext(ctx).Error("parseCriteria: Unmarshal err", "err", err)
+	}
+	return criteria
+}
+
+func criteriaJSON(criteria []Criterion) string {
+	if len(criteria) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(criteria)
+	return string(data)
+}
+
+func nullInt32(v *int32) sql.NullInt32 {
+	if v == nil {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: *v, Valid: true}
+}
+
+func int32Ptr(v sql.NullInt32) *int32 {
+	if !v.Valid {
+		return nil
+	}
+	return &v.Int32
+}
+
+func newTemplate(ctx context.Context, t *database.TenderTemplate) *Template {
+	return &Template{
+		ID:                 t.ID.String(),
+		OrganizationID:     t.OrganizationID.String(),
+		Name:               t.Name,
+		Description:        t.Description,
+		ServiceType:        t.ServiceType,
+		EvaluationCriteria: parseCriteria(ctx, t.EvaluationCriteria),
+		SubmissionDays:     int32Ptr(t.SubmissionDays),
+		DecisionDays:       int32Ptr(t.DecisionDays),
+		CreatedAt:          t.CreatedAt,
+		UpdatedAt:          t.UpdatedAt,
+	}
+}
+
+// templateForResponsible загружает шаблон и проверяет, что пользователь -
+// ответственный его организации
+func (s *Service) templateForResponsible(ctx context.Context, username, template_id string) (string, *database.TenderTemplate, error) {
+	user_id, err := s.fetchUserID(ctx, username)
+	if err != nil {
+		return "", nil, err
+	}
+	template, err := s.query.GetTemplate(ctx, template_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil, TemplateNotFound
+		}
+		logger.FromContext(ctx).Error("templateForResponsible: GetTemplate err", "err", err)
+		return "", nil, UnknowError
+	}
+	if err := s.isResponsibleUser(ctx, template.OrganizationID.String(), user_id); err != nil {
+		return "", nil, err
+	}
+	return user_id, template, nil
+}
+
+type TemplateRequest struct {
+	Username           string
+	Template_id        string
+	OrganizationID     string
+	Name               string
+	Description        string
+	ServiceType        string
+	EvaluationCriteria []Criterion
+	SubmissionDays     *int32
+	DecisionDays       *int32
+}
+
+func (r TemplateRequest) params() database.TemplateParams {
+	return database.TemplateParams{
+		OrganizationID:     r.OrganizationID,
+		Name:               r.Name,
+		Description:        r.Description,
+		ServiceType:        r.ServiceType,
+		EvaluationCriteria: criteriaJSON(r.EvaluationCriteria),
+		SubmissionDays:     nullInt32(r.SubmissionDays),
+		DecisionDays:       nullInt32(r.DecisionDays),
+	}
+}
+
+func (s *Service) CreateTemplate(ctx context.Context, params TemplateRequest) (*Template, error) {
+	ctx, span := tracing.Start(ctx, "service.CreateTemplate")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.isResponsibleUser(ctx, params.OrganizationID, user_id); err != nil {
+		return nil, err
+	}
+	if params.ServiceType, err = s.resolveServiceType(ctx, params.ServiceType); err != nil {
+		return nil, err
+	}
+
+	create := params.params()
+	create.CreatorID = user_id
+	template, err := s.query.CreateTemplate(ctx, create)
+	if err != nil {
+		logger.FromContext(ctx).Error("CreateTemplate: CreateTemplate err", "err", err)
+		return nil, UnknowError
+	}
+	result := newTemplate(ctx, template)
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: result.OrganizationID,
+		EntityType:     EntityTemplate,
+		EntityID:       result.ID,
+		Action:         ActionCreate,
+		After:          result,
+	})
+	return result, nil
+}
+
+type ListTemplatesRequest struct {
+	Username       string
+	OrganizationID string
+	Offset         int32
+	Limit          int32
+}
+
+func (s *Service) ListTemplates(ctx context.Context, params ListTemplatesRequest) ([]Template, error) {
+	ctx, span := tracing.Start(ctx, "service.ListTemplates")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	org_id := params.OrganizationID
+	if org_id == "" {
+		org_id, _ = s.query.GetUserOrganization(ctx, user_id)
+	}
+	if err := s.isResponsibleUser(ctx, org_id, user_id); err != nil {
+		return nil, err
+	}
+
+	templates, err := s.query.ListTemplates(ctx, database.ListTemplatesParams{
+		Organization_id: org_id,
+		Offset:          params.Offset,
+		Limit:           params.Limit,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("ListTemplates: ListTemplates err", "err", err)
+		return nil, UnknowError
+	}
+	result := []Template{}
+	for _, item := range templates {
+		result = append(result, *newTemplate(ctx, &item))
+	}
+	return result, nil
+}
+
+func (s *Service) GetTemplate(ctx context.Context, username, template_id string) (*Template, error) {
+	ctx, span := tracing.Start(ctx, "service.GetTemplate")
+	defer span.End()
+
+	_, template, err := s.templateForResponsible(ctx, username, template_id)
+	if err != nil {
+		return nil, err
+	}
+	return newTemplate(ctx, template), nil
+}
+
+func (s *Service) EditTemplate(ctx context.Context, params TemplateRequest) (*Template, error) {
+	ctx, span := tracing.Start(ctx, "service.EditTemplate")
+	defer span.End()
+
+	user_id, template, err := s.templateForResponsible(ctx, params.Username, params.Template_id)
+	if err != nil {
+		return nil, err
+	}
+	before := newTemplate(ctx, template)
+	if params.ServiceType, err = s.resolveServiceType(ctx, params.ServiceType); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.query.UpdateTemplate(ctx, params.Template_id, params.params())
+	if err != nil {
+		logger.FromContext(ctx).Error("EditTemplate: UpdateTemplate err", "err", err)
+		return nil, UnknowError
+	}
+	result := newTemplate(ctx, updated)
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: result.OrganizationID,
+		EntityType:     EntityTemplate,
+		EntityID:       result.ID,
+		Action:         ActionEdit,
+		Before:         before,
+		After:          result,
+	})
+	return result, nil
+}
+
+func (s *Service) DeleteTemplate(ctx context.Context, username, template_id string) error {
+	ctx, span := tracing.Start(ctx, "service.DeleteTemplate")
+	defer span.End()
+
+	user_id, template, err := s.templateForResponsible(ctx, username, template_id)
+	if err != nil {
+		return err
+	}
+	if err := s.query.DeleteTemplate(ctx, template_id); err != nil {
+		logger.FromContext(ctx).Error("DeleteTemplate: DeleteTemplate err", "err", err)
+		return UnknowError
+	}
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: template.OrganizationID.String(),
+		EntityType:     EntityTemplate,
+		EntityID:       template_id,
+		Action:         ActionDelete,
+		Before:         newTemplate(ctx, template),
+	})
+	return nil
+}
+
+// createTenderWithTerms создает тендер, пишет аудит и метрику
+func (s *Service) createTenderWithTerms(ctx context.Context, user_id, action string, params database.CreateTenderParams, terms database.TenderTerms) (*Tender, error) {
+	tender, err := s.query.CreateTenderWithTerms(ctx, params, terms)
+	if err != nil {
+		logger.FromContext(ctx).Error("createTenderWithTerms: CreateTenderWithTerms err", "err", err)
+		return nil, UnknowError
+	}
+	result := &Tender{
+		ID:                    tender.ID,
+		Name:                  params.Name,
+		Description:           params.Description,
+		Status:                params.Status,
+		ServiceType:           params.ServiceType,
+		Version:               tender.Version,
+		CreatedAt:             tender.CreatedAt,
+		EvaluationCriteria:    parseCriteria(ctx, terms.EvaluationCriteria),
+		BiddingMode:           terms.BiddingMode,
+		Visibility:            terms.Visibility,
+		RequiresQualification: terms.RequiresQualification,
+	}
+	if result.BiddingMode == "" {
+		result.BiddingMode = BiddingModeOpen
+	}
+	if result.Visibility == "" {
+		result.Visibility = TenderPublic
+	}
+	if terms.SubmissionDeadline.Valid {
+		result.SubmissionDeadline = &terms.SubmissionDeadline.Time
+	}
+	if terms.DecisionDeadline.Valid {
+		result.DecisionDeadline = &terms.DecisionDeadline.Time
+	}
+	if terms.EstimatedBudget.Valid {
+		result.EstimatedBudget = &terms.EstimatedBudget.Float64
+	}
+	if len(params.Lots) > 0 {
+		lots, err := s.query.ListLots(ctx, result.ID)
+		if err != nil {
+			logger.FromContext(ctx).Error("createTenderWithTerms: ListLots err", "err", err)
+			return nil, UnknowError
+		}
+		for i := range lots {
+			result.Lots = append(result.Lots, newLot(&lots[i]))
+		}
+	}
+	s.audit(ctx, auditEntry{
+		ActorID:        user_id,
+		OrganizationID: params.OrganizationID,
+		EntityType:     EntityTender,
+		EntityID:       result.ID,
+		Action:         action,
+		After:          result,
+	})
+	metrics.TendersCreated.Inc()
+	return result, nil
+}
+
+type FromTemplateRequest struct {
+	Username    string
+	Template_id string
+	Name        string
+	Description string
+	Status      string
+}
+
+// CreateTenderFromTemplate создает тендер по шаблону. Сроки считаются
+// от текущего момента по числу дней из шаблона.
+func (s *Service) CreateTenderFromTemplate(ctx context.Context, params FromTemplateRequest) (*Tender, error) {
+	ctx, span := tracing.Start(ctx, "service.CreateTenderFromTemplate")
+	defer span.End()
+
+	user_id, template, err := s.templateForResponsible(ctx, params.Username, params.Template_id)
+	if err != nil {
+		return nil, err
+	}
+
+	// тип из шаблона мог быть отключен в справочнике после создания шаблона
+	if _, err := s.resolveServiceType(ctx, template.ServiceType); err != nil {
+		return nil, err
+	}
+
+	create := database.CreateTenderParams{
+		OrganizationID: template.OrganizationID.String(),
+		CreatorID:      user_id,
+		Status:         params.Status,
+		ServiceType:    template.ServiceType,
+		Name:           template.Name,
+		Description:    template.Description,
+	}
+	if create.Status == "" {
+		create.Status = "Created"
+	}
+	if params.Name != "" {
+		create.Name = params.Name
+	}
+	if params.Description != "" {
+		create.Description = params.Description
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	terms := database.TenderTerms{EvaluationCriteria: template.EvaluationCriteria}
+	if template.SubmissionDays.Valid {
+		terms.SubmissionDeadline = sql.NullTime{Time: now.AddDate(0, 0, int(template.SubmissionDays.Int32)), Valid: true}
+	}
+	if template.DecisionDays.Valid {
+		terms.DecisionDeadline = sql.NullTime{Time: now.AddDate(0, 0, int(template.DecisionDays.Int32)), Valid: true}
+	}
+	return s.createTenderWithTerms(ctx, user_id, ActionCreate, create, terms)
+}
+
+type CloneTenderRequest struct {
+	Username  string
+	Tender_id string
+}
+
+// CloneTender копирует закрытый тендер в новый в статусе Created с версией 1.
+// Лоты копируются, сроки - нет: они относятся к прошлой закупке.
+func (s *Service) CloneTender(ctx context.Context, params CloneTenderRequest) (*Tender, error) {
+	ctx, span := tracing.Start(ctx, "service.CloneTender")
+	defer span.End()
+
+	user_id, err := s.fetchUserID(ctx, params.Username)
+	if err != nil {
+		return nil, err
+	}
+	tender, err := s.query.GetTender(ctx, params.Tender_id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, TenderNotFound
+		}
+		logger.FromContext(ctx).Error("CloneTender: GetTender err", "err", err)
+		return nil, UnknowError
+	}
+	if err := s.isResponsibleUser(ctx, tender.OrganizationID.String(), user_id); err != nil {
+		return nil, err
+	}
+	if tender.Status != "Closed" {
+		return nil, TenderNotClosed
+	}
+	terms, err := s.query.GetTenderTerms(ctx, params.Tender_id)
+	if err != nil {
+		logger.FromContext(ctx).Error("CloneTender: GetTenderTerms err", "err", err)
+		return nil, UnknowError
+	}
+	lots, err := s.query.ListLots(ctx, params.Tender_id)
+	if err != nil {
+		logger.FromContext(ctx).Error("CloneTender: ListLots err", "err", err)
+		return nil, UnknowError
+	}
+	var lot_params []database.LotParams
+	for _, lot := range lots {
+		lot_params = append(lot_params, database.LotParams{
+			Name:        lot.Name,
+			Description: lot.Description,
+			Quantity:    lot.Quantity,
+			ServiceType: lot.ServiceType,
+		})
+	}
+
+	return s.createTenderWithTerms(ctx, user_id, ActionClone, database.CreateTenderParams{
+		OrganizationID: tender.OrganizationID.String(),
+		CreatorID:      user_id,
+		Status:         "Created",
+		ServiceType:    tender.ServiceType,
+		Name:           tender.Name,
+		Description:    tender.Description,
+		Lots:           lot_params,
+	}, database.TenderTerms{
+		EvaluationCriteria:    terms.EvaluationCriteria,
+		RequiresQualification: terms.RequiresQualification,
+		EstimatedBudget:       terms.EstimatedBudget,
+	})
+}
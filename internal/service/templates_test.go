@@ -0,0 +1,164 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTemplateLifecycle(t *testing.T) {
+	e := newTestEnv(t)
+	org_id := e.org("Заказчик", "buyer")
+	e.org("Чужая", "stranger")
+	days := int32(10)
+
+	template, err := e.s.CreateTemplate(e.ctx, TemplateRequest{
+		Username:           "buyer",
+		OrganizationID:     org_id,
+		Name:               "Ежемесячная бумага",
+		Description:        "Бумага А4",
+		ServiceType:        "delivery",
+		EvaluationCriteria: []Criterion{{Name: "Цена", Weight: 70}, {Name: "Срок", Weight: 30}},
+		SubmissionDays:     &days,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if template.ServiceType != "Delivery" || len(template.EvaluationCriteria) != 2 || template.DecisionDays != nil {
+		t.Errorf("template = %+v", template)
+	}
+	if _, err := e.s.CreateTemplate(e.ctx, TemplateRequest{Username: "stranger", OrganizationID: org_id, ServiceType: "Delivery"}); err != IsNotResponsible {
+		t.Errorf("create by stranger err = %v, want IsNotResponsible", err)
+	}
+	if _, err := e.s.GetTemplate(e.ctx, "stranger", template.ID); err != IsNotResponsible {
+		t.Errorf("get by stranger err = %v, want IsNotResponsible", err)
+	}
+
+	listed, err := e.s.ListTemplates(e.ctx, ListTemplatesRequest{Username: "buyer", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 || listed[0].ID != template.ID {
+		t.Errorf("templates = %+v", listed)
+	}
+
+	edited, err := e.s.EditTemplate(e.ctx, TemplateRequest{
+		Username:    "buyer",
+		Template_id: template.ID,
+		Name:        "Ежеквартальная бумага",
+		Description: "Бумага А3",
+		ServiceType: "Delivery",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Name != "Ежеквартальная бумага" || len(edited.EvaluationCriteria) != 0 || edited.SubmissionDays != nil {
+		t.Errorf("edited = %+v", edited)
+	}
+
+	if err := e.s.DeleteTemplate(e.ctx, "buyer", template.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.s.GetTemplate(e.ctx, "buyer", template.ID); err != TemplateNotFound {
+		t.Errorf("deleted template err = %v, want TemplateNotFound", err)
+	}
+	want := []string{ActionCreate, ActionEdit, ActionDelete}
+	if got := e.audited(EntityTemplate, template.ID); !reflect.DeepEqual(got, want) {
+		t.Errorf("audit = %v, want %v", got, want)
+	}
+}
+
+func TestCreateTenderFromTemplate(t *testing.T) {
+	e := newTestEnv(t)
+	org_id := e.org("Заказчик", "buyer")
+	submission, decision := int32(7), int32(14)
+	template, err := e.s.CreateTemplate(e.ctx, TemplateRequest{
+		Username:           "buyer",
+		OrganizationID:     org_id,
+		Name:               "Ремонт офиса",
+		Description:        "Косметический ремонт",
+		ServiceType:        "Construction",
+		EvaluationCriteria: []Criterion{{Name: "Цена", Weight: 100}},
+		SubmissionDays:     &submission,
+		DecisionDays:       &decision,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now().UTC().Truncate(time.Second)
+	tender, err := e.s.CreateTenderFromTemplate(e.ctx, FromTemplateRequest{
+		Username:    "buyer",
+		Template_id: template.ID,
+		Name:        "Ремонт второго этажа",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tender.Name != "Ремонт второго этажа" || tender.Description != "Косметический ремонт" ||
+		tender.Status != "Created" || tender.ServiceType != "Construction" || tender.Version != 1 {
+		t.Errorf("tender = %+v", tender)
+	}
+	if !reflect.DeepEqual(tender.EvaluationCriteria, []Criterion{{Name: "Цена", Weight: 100}}) {
+		t.Errorf("criteria = %+v", tender.EvaluationCriteria)
+	}
+	if tender.SubmissionDeadline == nil || tender.SubmissionDeadline.Before(before.AddDate(0, 0, 7)) ||
+		tender.SubmissionDeadline.After(time.Now().AddDate(0, 0, 7)) {
+		t.Errorf("submission deadline = %v, want 7 days from now", tender.SubmissionDeadline)
+	}
+	if tender.DecisionDeadline == nil || !tender.DecisionDeadline.Equal(tender.SubmissionDeadline.AddDate(0, 0, 7)) {
+		t.Errorf("decision deadline = %v, want 14 days from now", tender.DecisionDeadline)
+	}
+
+	// отключенный тип услуг нельзя использовать для новых тендеров
+	e.exec(`UPDATE service_type SET is_active = FALSE WHERE code = 'Construction'`)
+	if _, err := e.s.CreateTenderFromTemplate(e.ctx, FromTemplateRequest{Username: "buyer", Template_id: template.ID}); err != ServiceTypeNotFound {
+		t.Errorf("inactive service type err = %v, want ServiceTypeNotFound", err)
+	}
+}
+
+func TestCloneTender(t *testing.T) {
+	e := newTestEnv(t)
+	org_id := e.org("Заказчик", "buyer")
+	e.org("Чужая", "stranger")
+	budget := 50000.0
+	source := e.tender(TenderParams{
+		OrganizationId:  org_id,
+		CreatorUsername: "buyer",
+		EstimatedBudget: &budget,
+		Lots: []LotParams{
+			{Name: "Бумага", Quantity: 100},
+			{Name: "Доставка", Quantity: 1, ServiceType: "Delivery"},
+		},
+	})
+
+	if _, err := e.s.CloneTender(e.ctx, CloneTenderRequest{Username: "buyer", Tender_id: source.ID}); err != TenderNotClosed {
+		t.Errorf("clone of published tender err = %v, want TenderNotClosed", err)
+	}
+	if _, err := e.s.EditTenderStatus(e.ctx, EditTenderStatusRequest{Username: "buyer", Tender_id: source.ID, New_status: "Closed"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.s.CloneTender(e.ctx, CloneTenderRequest{Username: "stranger", Tender_id: source.ID}); err != IsNotResponsible {
+		t.Errorf("clone by stranger err = %v, want IsNotResponsible", err)
+	}
+
+	clone, err := e.s.CloneTender(e.ctx, CloneTenderRequest{Username: "buyer", Tender_id: source.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clone.ID == source.ID || clone.Status != "Created" || clone.Version != 1 || clone.Name != source.Name {
+		t.Errorf("clone = %+v", clone)
+	}
+	if clone.EstimatedBudget == nil || *clone.EstimatedBudget != budget {
+		t.Errorf("estimated budget = %v, want %v", clone.EstimatedBudget, budget)
+	}
+	if clone.SubmissionDeadline != nil || clone.DecisionDeadline != nil {
+		t.Error("deadlines must not be copied")
+	}
+	if len(clone.Lots) != 2 || clone.Lots[0].Name != "Бумага" || clone.Lots[1].Quantity != 1 {
+		t.Errorf("lots = %+v", clone.Lots)
+	}
+	if got := e.audited(EntityTender, clone.ID); !reflect.DeepEqual(got, []string{ActionClone}) {
+		t.Errorf("audit = %v, want clone", got)
+	}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"tender_service/internal/database/dbtest"
+	"tender_service/internal/logger"
+	"testing"
+)
+
+// testEnv - сервис поверх временной схемы dbtest. Без TEST_POSTGRES_CONN
+// тесты, которые его используют, пропускаются.
+type testEnv struct {
+	t   *testing.T
+	ctx context.Context
+	db  *sql.DB
+	s   *Service
+}
+
+// testLog пишет лог сервиса в вывод теста
+type testLog struct{ t *testing.T }
+
+func (w testLog) Write(p []byte) (int, error) {
+	w.t.Log(strings.TrimSpace(string(p)))
+	return len(p), nil
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	db, query := dbtest.Queries(t)
+	ctx := logger.WithContext(context.Background(), logger.New(testLog{t}, "debug"))
+	return &testEnv{t: t, ctx: ctx, db: db, s: New(query)}
+}
+
+// org создает организацию с ответственными пользователями и возвращает ее id
+func (e *testEnv) org(name string, usernames ...string) string {
+	e.t.Helper()
+	var user_ids []string
+	for _, username := range usernames {
+		user_ids = append(user_ids, dbtest.Employee(e.t, e.db, username))
+	}
+	return dbtest.Organization(e.t, e.db, name, user_ids...)
+}
+
+func (e *testEnv) userID(username string) string {
+	e.t.Helper()
+	var id string
+	if err := e.db.QueryRow(`SELECT id FROM employee WHERE username = $1`, username).Scan(&id); err != nil {
+		e.t.Fatalf("user %s: %v", username, err)
+	}
+	return id
+}
+
+// admin создает администратора без организации
+func (e *testEnv) admin(username string) string {
+	e.t.Helper()
+	user_id := dbtest.Employee(e.t, e.db, username)
+	e.exec(`UPDATE employee SET is_admin = TRUE WHERE id = $1`, user_id)
+	return user_id
+}
+
+// audited возвращает действия из журнала аудита по сущности в порядке записи
+func (e *testEnv) audited(entity_type, entity_id string) []string {
+	e.t.Helper()
+	rows, err := e.db.Query(`SELECT action FROM audit_log
+	    WHERE entity_type = $1 AND entity_id = $2 ORDER BY id`, entity_type, entity_id)
+	if err != nil {
+		e.t.Fatalf("audit_log: %v", err)
+	}
+	defer rows.Close()
+	var actions []string
+	for rows.Next() {
+		var action string
+		if err := rows.Scan(&action); err != nil {
+			e.t.Fatalf("audit_log: %v", err)
+		}
+		actions = append(actions, action)
+	}
+	if err := rows.Err(); err != nil {
+		e.t.Fatalf("audit_log: %v", err)
+	}
+	return actions
+}
+
+// exec выполняет служебный запрос, например сдвигает сроки в прошлое
+func (e *testEnv) exec(query string, args ...interface{}) {
+	e.t.Helper()
+	if _, err := e.db.Exec(query, args...); err != nil {
+		e.t.Fatalf("exec %q: %v", query, err)
+	}
+}
+
+// tender создает опубликованный тендер на поставку, если в params не
+// указано иное
+func (e *testEnv) tender(params TenderParams) *Tender {
+	e.t.Helper()
+	if params.Name == "" {
+		params.Name = "Поставка бумаги"
+	}
+	if params.Description == "" {
+		params.Description = "Бумага А4"
+	}
+	if params.ServiceType == "" {
+		params.ServiceType = "Delivery"
+	}
+	if params.Status == "" {
+		params.Status = "Published"
+	}
+	tender, err := e.s.CreateNewTender(e.ctx, params)
+	if err != nil {
+		e.t.Fatalf("CreateNewTender: %v", err)
+	}
+	return tender
+}
+
+// submitBid подает предложение от пользователя, ошибку возвращает вызывающему
+func (e *testEnv) submitBid(username, tender_id string, lots ...BidLot) (*Bid, error) {
+	e.t.Helper()
+	return e.s.CreateNewBid(e.ctx, CreateBidParam{
+		Name:        "Предложение " + username,
+		Description: "Поставим в срок",
+		TenderId:    tender_id,
+		AuthorType:  "User",
+		AuthorId:    e.userID(username),
+		Lots:        lots,
+	})
+}
+
+// bid подает и публикует предложение
+func (e *testEnv) bid(username, tender_id string, lots ...BidLot) *Bid {
+	e.t.Helper()
+	bid, err := e.submitBid(username, tender_id, lots...)
+	if err != nil {
+		e.t.Fatalf("CreateNewBid %s: %v", username, err)
+	}
+	return e.bidStatus(username, bid.ID, "Published")
+}
+
+func (e *testEnv) bidStatus(username, bid_id, status string) *Bid {
+	e.t.Helper()
+	bid, err := e.s.ChangeBidStatus(e.ctx, ChangeBidStatus{Username: username, BidID: bid_id, Status: status})
+	if err != nil {
+		e.t.Fatalf("ChangeBidStatus %s: %v", status, err)
+	}
+	return bid
+}